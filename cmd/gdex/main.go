@@ -126,6 +126,7 @@ var (
 		utils.YilanFlag,
 		utils.VMEnableDebugFlag,
 		utils.NetworkIdFlag,
+		utils.NetworkIdMismatchFlag,
 		utils.ConstantinopleOverrideFlag,
 		utils.RPCCORSDomainFlag,
 		utils.RPCVirtualHostsFlag,
@@ -135,12 +136,15 @@ var (
 		utils.NoCompactionFlag,
 		utils.GpoBlocksFlag,
 		utils.GpoPercentileFlag,
+		utils.GpoSampleWindowFlag,
 		utils.EWASMInterpreterFlag,
 		utils.EVMInterpreterFlag,
 		utils.IndexerEnableFlag,
 		utils.IndexerPluginFlag,
 		utils.IndexerPluginFlagsFlag,
 		utils.RecoveryNetworkRPCFlag,
+		utils.ConsensusStartDelayFlag,
+		utils.HealthCheckAddrFlag,
 		configFileFlag,
 	}
 
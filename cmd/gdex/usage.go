@@ -72,6 +72,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.KeyStoreDirFlag,
 			utils.NoUSBFlag,
 			utils.NetworkIdFlag,
+			utils.NetworkIdMismatchFlag,
 			utils.TestnetFlag,
 			utils.TaipeiFlag,
 			utils.YilanFlag,
@@ -189,6 +190,7 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "BLOCK PROPOSER",
 		Flags: []cli.Flag{
 			utils.BlockProposerEnabledFlag,
+			utils.ConsensusStartDelayFlag,
 		},
 	},
 	{
@@ -211,6 +213,7 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.GpoBlocksFlag,
 			utils.GpoPercentileFlag,
+			utils.GpoSampleWindowFlag,
 		},
 	},
 	{
@@ -238,6 +241,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.MetricsInfluxDBUsernameFlag,
 			utils.MetricsInfluxDBPasswordFlag,
 			utils.MetricsInfluxDBTagsFlag,
+			utils.HealthCheckAddrFlag,
 		},
 	},
 	{
@@ -133,6 +133,10 @@ var (
 		Usage: "Network identifier (integer, 237=Mainnet, 238=Testnet, 239=Taipei, 240=Yilan) (default: 237)",
 		Value: eth.DefaultConfig.NetworkId,
 	}
+	NetworkIdMismatchFlag = cli.BoolFlag{
+		Name:  "networkid.allowmismatch",
+		Usage: "Allows a network identifier different from the chain ID of the genesis",
+	}
 	TestnetFlag = cli.BoolFlag{
 		Name:  "testnet",
 		Usage: "Taiwan network: default public testnet",
@@ -580,6 +584,11 @@ var (
 		Usage: "Suggested gas price is the given percentile of a set of recent transaction gas prices",
 		Value: eth.DefaultConfig.GPO.Percentile,
 	}
+	GpoSampleWindowFlag = cli.DurationFlag{
+		Name:  "gposamplewindow",
+		Usage: "Sample the blocks produced within this window instead of a fixed number of blocks (0 = disabled)",
+		Value: eth.DefaultConfig.GPO.SampleWindow,
+	}
 	WhisperEnabledFlag = cli.BoolFlag{
 		Name:  "shh",
 		Usage: "Enable Whisper",
@@ -671,6 +680,16 @@ var (
 		Usage: "RPC URL of the recovery network",
 		Value: "https://mainnet.infura.io",
 	}
+	ConsensusStartDelayFlag = cli.DurationFlag{
+		Name:  "consensus.start-delay",
+		Usage: "Delay before consensus starts, allowing peers to connect (0 = start at once)",
+		Value: dex.DefaultConfig.ConsensusStartDelay,
+	}
+	HealthCheckAddrFlag = cli.StringFlag{
+		Name:  "healthcheck.addr",
+		Usage: "Listening address of the HTTP health check endpoint (disabled if empty)",
+		Value: "",
+	}
 )
 
 // MakeDataDir retrieves the currently requested data directory, terminating
@@ -1060,6 +1079,9 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 	if ctx.GlobalIsSet(GpoPercentileFlag.Name) {
 		cfg.Percentile = ctx.GlobalInt(GpoPercentileFlag.Name)
 	}
+	if ctx.GlobalIsSet(GpoSampleWindowFlag.Name) {
+		cfg.SampleWindow = ctx.GlobalDuration(GpoSampleWindowFlag.Name)
+	}
 }
 
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
@@ -1226,9 +1248,13 @@ func SetDexConfig(ctx *cli.Context, stack *node.Node, cfg *dex.Config) {
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetworkId = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
+	if ctx.GlobalIsSet(NetworkIdMismatchFlag.Name) {
+		cfg.AllowNetworkIdMismatch = ctx.GlobalBool(NetworkIdMismatchFlag.Name)
+	}
 	if ctx.GlobalIsSet(BlockProposerEnabledFlag.Name) {
 		cfg.BlockProposerEnabled = ctx.GlobalBool(BlockProposerEnabledFlag.Name)
 	}
+	cfg.KeyRotationPasswords = MakePasswordList(ctx)
 
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheDatabaseFlag.Name) {
 		cfg.DatabaseCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
@@ -1265,6 +1291,13 @@ func SetDexConfig(ctx *cli.Context, stack *node.Node, cfg *dex.Config) {
 		cfg.RPCGasCap = new(big.Int).SetUint64(ctx.GlobalUint64(RPCGlobalGasCap.Name))
 	}
 
+	if ctx.GlobalIsSet(ConsensusStartDelayFlag.Name) {
+		cfg.ConsensusStartDelay = ctx.GlobalDuration(ConsensusStartDelayFlag.Name)
+	}
+	if ctx.GlobalIsSet(HealthCheckAddrFlag.Name) {
+		cfg.HealthCheckAddr = ctx.GlobalString(HealthCheckAddrFlag.Name)
+	}
+
 	cfg.RecoveryNetworkRPC = ctx.GlobalString(RecoveryNetworkRPCFlag.Name)
 	defaultRecoveryNetworkRPC := "https://rinkeby.infura.io"
 
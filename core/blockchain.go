@@ -81,6 +81,8 @@ type CacheConfig struct {
 	TrieCleanLimit int           // Memory allowance (MB) to use for caching trie nodes in memory
 	TrieDirtyLimit int           // Memory limit (MB) at which to start flushing dirty trie nodes to disk
 	TrieTimeLimit  time.Duration // Time limit after which to flush the current in-memory trie to disk
+
+	FlushOnFinalize bool // Flush on time limit at finalization points (see FlushFinalized), and at twice the limit otherwise
 }
 
 // BlockChain represents the canonical chain given a database with a genesis
@@ -143,6 +145,7 @@ type BlockChain struct {
 
 	badBlocks      *lru.Cache              // Bad block cache
 	shouldPreserve func(*types.Block) bool // Function used to determine whether should preserve the given block.
+	forkChoice     ForkChoice              // Veto of the reorgs chosen by total difficulty, nil if none
 
 	roundHeightMap sync.Map
 
@@ -433,6 +436,18 @@ func (bc *BlockChain) SetProcessor(processor Processor) {
 	bc.processor = processor
 }
 
+// ForkChoice decides whether a block of another branch, chosen by total
+// difficulty, may replace the current head. A non-nil error rejects the
+// block.
+type ForkChoice func(current, block *types.Block) error
+
+// SetForkChoice sets the fork choice consulted before reorganising the chain.
+func (bc *BlockChain) SetForkChoice(forkChoice ForkChoice) {
+	bc.procmu.Lock()
+	defer bc.procmu.Unlock()
+	bc.forkChoice = forkChoice
+}
+
 // SetValidator sets the validator which is used to validate incoming blocks.
 func (bc *BlockChain) SetValidator(validator Validator) {
 	bc.procmu.Lock()
@@ -747,6 +762,51 @@ func (bc *BlockChain) TrieNode(hash common.Hash) ([]byte, error) {
 	return bc.stateCache.TrieDB().Node(hash)
 }
 
+// FlushFinalized flushes the state trie of the finalized block to disk if the
+// time allowance of the trie cache is exceeded, whether or not the block is
+// still the head. The allowance is only reset by flushing the head, the
+// finalization of the newer blocks flushes their changes as well. It is a
+// no-op unless the cache is configured with FlushOnFinalize, and reports
+// whether it flushed.
+func (bc *BlockChain) FlushFinalized(block *types.Block) bool {
+	if bc.cacheConfig.Disabled || !bc.cacheConfig.FlushOnFinalize {
+		return false
+	}
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	if bc.gcproc <= bc.cacheConfig.TrieTimeLimit {
+		return false
+	}
+	if err := bc.stateCache.TrieDB().Commit(block.Root(), true); err != nil {
+		log.Error("Failed to flush finalized state", "number", block.NumberU64(), "err", err)
+		return false
+	}
+	if number := block.NumberU64(); number > lastWrite {
+		lastWrite = number
+	}
+	if bc.CurrentBlock().Hash() == block.Hash() {
+		bc.gcproc = 0
+	}
+	return true
+}
+
+// FreezeHead flushes the state trie of the head block to disk and calls fn
+// with the head block while holding the insertion lock, so the database is
+// left consistent with the head for the duration of the call.
+func (bc *BlockChain) FreezeHead(fn func(head *types.Block) error) error {
+	bc.chainmu.Lock()
+	defer bc.chainmu.Unlock()
+
+	head := bc.CurrentBlock()
+	if !bc.cacheConfig.Disabled {
+		if err := bc.stateCache.TrieDB().Commit(head.Root(), true); err != nil {
+			return err
+		}
+	}
+	return fn(head)
+}
+
 // Stop stops the blockchain service. If any imports are currently in progress
 // it will abort them using the procInterrupt.
 func (bc *BlockChain) Stop() {
@@ -1067,8 +1127,14 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 			// Find the next state trie we need to commit
 			chosen := current - triesInMemory
 
-			// If we exceeded out time allowance, flush an entire trie to disk
-			if bc.gcproc > bc.cacheConfig.TrieTimeLimit {
+			// If we exceeded out time allowance, flush an entire trie to disk. When
+			// flushing on finalization, FlushFinalized takes care of it, this is
+			// only a backstop at twice the allowance.
+			timeLimit := bc.cacheConfig.TrieTimeLimit
+			if bc.cacheConfig.FlushOnFinalize {
+				timeLimit *= 2
+			}
+			if bc.gcproc > timeLimit {
 				// If the header is missing (canonical chain behind), we're reorging a low
 				// diff sidechain. Suspend committing until this operation is completed.
 				header := bc.GetHeaderByNumber(chosen)
@@ -1120,6 +1186,16 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 			reorg = !currentPreserve && (blockPreserve || mrand.Float64() < 0.5)
 		}
 	}
+	if reorg && block.ParentHash() != currentBlock.Hash() {
+		bc.procmu.RLock()
+		forkChoice := bc.forkChoice
+		bc.procmu.RUnlock()
+		if forkChoice != nil {
+			if err := forkChoice(currentBlock, block); err != nil {
+				return NonStatTy, err
+			}
+		}
+	}
 	if reorg {
 		// Reorganise the chain if the parent is not the head block
 		if block.ParentHash() != currentBlock.Hash() {
@@ -1545,6 +1545,57 @@ func TestTrieForkGC(t *testing.T) {
 	}
 }
 
+// Tests that with FlushOnFinalize the time limit flushes of the trie cache
+// happen at the finalization points signalled by FlushFinalized, even for
+// blocks no longer at the head, and at twice the limit during insertion.
+func TestFlushOnFinalize(t *testing.T) {
+	engine := ethash.NewFaker()
+
+	db := ethdb.NewMemDatabase()
+	gspec := &Genesis{
+		Config: params.TestnetChainConfig,
+	}
+	genesis := gspec.MustCommit(db)
+	blocks, _ := GenerateChain(params.TestChainConfig, genesis, engine, db, 2*triesInMemory, func(i int, b *BlockGen) { b.SetCoinbase(common.Address{1}) })
+
+	diskdb := ethdb.NewMemDatabase()
+	gspec.MustCommit(diskdb)
+
+	cacheConfig := &CacheConfig{
+		TrieCleanLimit:  256,
+		TrieDirtyLimit:  256,
+		TrieTimeLimit:   time.Nanosecond,
+		FlushOnFinalize: true,
+	}
+	chain, err := NewBlockChain(diskdb, cacheConfig, params.TestChainConfig, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	// Nothing is flushed during insertion while all tries fit in memory.
+	if _, err := chain.InsertChain(blocks[:triesInMemory]); err != nil {
+		t.Fatalf("failed to insert into chain: %v", err)
+	}
+	finalized := blocks[triesInMemory-2]
+	if !chain.FlushFinalized(finalized) {
+		t.Fatal("finalized state behind the head not flushed")
+	}
+	for i := 1; i < triesInMemory; i++ {
+		want := blocks[i] == finalized
+		if has, _ := diskdb.Has(blocks[i].Root().Bytes()); has != want {
+			t.Errorf("block %d: state on disk mismatch: have %v, want %v", i, has, want)
+		}
+	}
+	// Without finalization, the state is flushed at twice the time limit.
+	if _, err := chain.InsertChain(blocks[triesInMemory:]); err != nil {
+		t.Fatalf("failed to insert into chain: %v", err)
+	}
+	if has, _ := diskdb.Has(blocks[triesInMemory-1].Root().Bytes()); !has {
+		t.Error("state not flushed by the time limit backstop")
+	}
+}
+
 // Tests that doing large reorgs works even if the state associated with the
 // forking point is not available any more.
 func TestLargeReorgTrieGC(t *testing.T) {
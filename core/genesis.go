@@ -374,6 +374,95 @@ func (g *Genesis) MustCommit(db ethdb.Database) *types.Block {
 	return block
 }
 
+// ReadGenesis rebuilds the genesis specification from the genesis block,
+// chain config and genesis state stored in db. Accounts staked at genesis are
+// recovered from the governance state, so committing the returned genesis
+// yields the same genesis block hash.
+func ReadGenesis(db ethdb.Database) (*Genesis, error) {
+	stored := rawdb.ReadCanonicalHash(db, 0)
+	if (stored == common.Hash{}) {
+		return nil, errors.New("genesis block not found")
+	}
+	block := rawdb.ReadBlock(db, stored, 0)
+	if block == nil {
+		return nil, fmt.Errorf("genesis block %x not found", stored)
+	}
+	config := rawdb.ReadChainConfig(db, stored)
+	if config == nil {
+		return nil, errGenesisNoConfig
+	}
+	statedb, err := state.New(block.Root(), state.NewDatabase(db))
+	if err != nil {
+		return nil, err
+	}
+
+	// Staked balances were moved into the governance contract at genesis.
+	stakers := make(map[common.Address]GenesisAccount)
+	if config.Dexcon != nil {
+		govState := &vm.GovernanceState{StateDB: statedb}
+		for _, node := range govState.Nodes() {
+			stakers[node.Owner] = GenesisAccount{
+				Staked:    node.Staked,
+				PublicKey: node.PublicKey,
+				NodeInfo: NodeInfo{
+					Name:     node.Name,
+					Email:    node.Email,
+					Location: node.Location,
+					Url:      node.Url,
+				},
+			}
+		}
+	}
+
+	alloc := make(GenesisAlloc)
+	for hexAddr, dumped := range statedb.RawDump().Accounts {
+		addr := common.HexToAddress(hexAddr)
+		if _, ok := vm.OracleContracts[addr]; ok && config.Dexcon != nil {
+			// Oracle contracts are set up by ToBlock itself.
+			continue
+		}
+		account := GenesisAccount{
+			Code:    statedb.GetCode(addr),
+			Balance: statedb.GetBalance(addr),
+			Nonce:   statedb.GetNonce(addr),
+			Staked:  big.NewInt(0),
+		}
+		if _, ok := vm.OracleContracts[addr]; ok {
+			account.Code = nil
+		}
+		if staker, ok := stakers[addr]; ok {
+			account.Balance = new(big.Int).Add(account.Balance, staker.Staked)
+			account.Staked = new(big.Int).Set(staker.Staked)
+			account.PublicKey = staker.PublicKey
+			account.NodeInfo = staker.NodeInfo
+		}
+		if len(dumped.Storage) > 0 {
+			account.Storage = make(map[common.Hash]common.Hash)
+			for hexKey := range dumped.Storage {
+				key := common.HexToHash(hexKey)
+				account.Storage[key] = statedb.GetState(addr, key)
+			}
+		}
+		alloc[addr] = account
+	}
+
+	header := block.Header()
+	return &Genesis{
+		Config:     config,
+		Nonce:      header.Nonce.Uint64(),
+		Timestamp:  header.Time,
+		ExtraData:  header.Extra,
+		GasLimit:   header.GasLimit,
+		Difficulty: header.Difficulty,
+		Mixhash:    header.MixDigest,
+		Coinbase:   header.Coinbase,
+		Alloc:      alloc,
+		Number:     header.Number.Uint64(),
+		GasUsed:    header.GasUsed,
+		ParentHash: header.ParentHash,
+	}, nil
+}
+
 // GenesisBlockForTesting creates and writes a block in which addr has the given wei balance.
 func GenesisBlockForTesting(db ethdb.Database, addr common.Address, balance *big.Int) *types.Block {
 	g := Genesis{Alloc: GenesisAlloc{addr: {Balance: balance}}}
@@ -197,6 +197,14 @@ func (g *Governance) PurgeNotarySet(round uint64) {
 	g.nodeSetCache.Purge(round)
 }
 
+// PurgeDKGCache drops the cached DKG state of all rounds, it is read again
+// from the governance state on the next query.
+func (g *Governance) PurgeDKGCache() {
+	g.dkgCacheMu.Lock()
+	defer g.dkgCacheMu.Unlock()
+	g.dkgCache.Purge()
+}
+
 func (g *Governance) NotarySet(round uint64) (map[string]struct{}, error) {
 	notarySet, err := g.nodeSetCache.GetNotarySet(round)
 	if err != nil {
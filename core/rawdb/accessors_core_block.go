@@ -15,10 +15,10 @@ func ReadCoreBlockRLP(db DatabaseReader, hash common.Hash) rlp.RawValue {
 	return data
 }
 
-func WriteCoreBlockRLP(db DatabaseWriter, hash common.Hash, rlp rlp.RawValue) {
-	if err := db.Put(coreBlockKey(hash), rlp); err != nil {
-		log.Crit("Failed to store core block", "err", err)
-	}
+// WriteCoreBlockRLP stores the RLP encoded core block. Failures are returned
+// to the caller, which may retry transient storage errors.
+func WriteCoreBlockRLP(db DatabaseWriter, hash common.Hash, rlp rlp.RawValue) error {
+	return db.Put(coreBlockKey(hash), rlp)
 }
 
 func HasCoreBlock(db DatabaseReader, hash common.Hash) bool {
@@ -42,10 +42,10 @@ func ReadCoreBlock(db DatabaseReader, hash common.Hash) *coreTypes.Block {
 	return block
 }
 
-func WriteCoreBlock(db DatabaseWriter, hash common.Hash, block *coreTypes.Block) {
+func WriteCoreBlock(db DatabaseWriter, hash common.Hash, block *coreTypes.Block) error {
 	data, err := rlp.EncodeToBytes(block)
 	if err != nil {
 		log.Crit("Failed to RLP encode core block", "err", err)
 	}
-	WriteCoreBlockRLP(db, hash, data)
+	return WriteCoreBlockRLP(db, hash, data)
 }
@@ -13,11 +13,7 @@ func ReadCoreCompactionChainTipRLP(db DatabaseReader) (rlp.RawValue, error) {
 }
 
 func WriteCoreCompactionChainTipRLP(db DatabaseWriter, rlp rlp.RawValue) error {
-	if err := db.Put(coreCompactionChainTipKey, rlp); err != nil {
-		log.Crit("Failed to store core compaction chain tip")
-		return err
-	}
-	return nil
+	return db.Put(coreCompactionChainTipKey, rlp)
 }
 
 func ReadCoreCompactionChainTip(db DatabaseReader) (coreCommon.Hash, uint64) {
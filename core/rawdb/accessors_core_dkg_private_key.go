@@ -19,11 +19,7 @@ func ReadCoreDKGPrivateKeyRLP(db DatabaseReader, round uint64) rlp.RawValue {
 }
 
 func WriteCoreDKGPrivateKeyRLP(db DatabaseWriter, round uint64, rlp rlp.RawValue) error {
-	err := db.Put(coreDKGPrivateKeyKey(round), rlp)
-	if err != nil {
-		log.Crit("Failed to store core DKG private key", "err", err, "round", round)
-	}
-	return err
+	return db.Put(coreDKGPrivateKeyKey(round), rlp)
 }
 
 func ReadCoreDKGPrivateKey(db DatabaseReader, round, reset uint64) *coreDKG.PrivateKey {
@@ -14,11 +14,7 @@ func ReadCoreDKGProtocolRLP(db DatabaseReader) rlp.RawValue {
 }
 
 func WriteCoreDKGProtocolRLP(db DatabaseWriter, rlp rlp.RawValue) error {
-	err := db.Put(coreDKGProtocolKey, rlp)
-	if err != nil {
-		log.Crit("Failed to store core DKG private key", "err", err)
-	}
-	return err
+	return db.Put(coreDKGProtocolKey, rlp)
 }
 
 func ReadCoreDKGProtocol(db DatabaseReader) *coreDb.DKGProtocolInfo {
@@ -0,0 +1,85 @@
+package rawdb
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+// RoundNotarySet is the notary set of a round, along with the CRS it was
+// sampled with and the hash of the node set it was sampled from.
+type RoundNotarySet struct {
+	CRS         common.Hash
+	NodeSetHash common.Hash
+	PublicKeys  [][]byte
+}
+
+// RoundGroupPublicKey is the DKG group public key of a round, along with the
+// CRS of the round it was recovered in.
+type RoundGroupPublicKey struct {
+	CRS       common.Hash
+	PublicKey []byte
+}
+
+func ReadRoundNotarySet(db DatabaseReader, round uint64) *RoundNotarySet {
+	data, _ := db.Get(roundNotarySetKey(round))
+	if len(data) == 0 {
+		return nil
+	}
+	set := new(RoundNotarySet)
+	if err := rlp.Decode(bytes.NewReader(data), set); err != nil {
+		log.Error("Invalid round notary set RLP", "round", round, "err", err)
+		return nil
+	}
+	return set
+}
+
+func WriteRoundNotarySet(db DatabaseWriter, round uint64, set *RoundNotarySet) error {
+	data, err := rlp.EncodeToBytes(set)
+	if err != nil {
+		return err
+	}
+	return db.Put(roundNotarySetKey(round), data)
+}
+
+func ReadRoundGroupPublicKey(db DatabaseReader, round uint64) *RoundGroupPublicKey {
+	data, _ := db.Get(roundGroupPublicKeyKey(round))
+	if len(data) == 0 {
+		return nil
+	}
+	gpk := new(RoundGroupPublicKey)
+	if err := rlp.Decode(bytes.NewReader(data), gpk); err != nil {
+		log.Error("Invalid round group public key RLP", "round", round, "err", err)
+		return nil
+	}
+	return gpk
+}
+
+func WriteRoundGroupPublicKey(db DatabaseWriter, round uint64, gpk *RoundGroupPublicKey) error {
+	data, err := rlp.EncodeToBytes(gpk)
+	if err != nil {
+		return err
+	}
+	return db.Put(roundGroupPublicKeyKey(round), data)
+}
+
+// ReadBlockFinalizationRound returns the round the block of the given hash
+// was finalized in, and whether it is recorded.
+func ReadBlockFinalizationRound(db DatabaseReader, hash common.Hash) (uint64, bool) {
+	data, _ := db.Get(finalizationRoundKey(hash))
+	if len(data) != 8 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint64(data), true
+}
+
+// WriteBlockFinalizationRound stores the round the block of the given hash
+// was finalized in.
+func WriteBlockFinalizationRound(db DatabaseWriter, hash common.Hash, round uint64) error {
+	data := make([]byte, 8)
+	binary.LittleEndian.PutUint64(data, round)
+	return db.Put(finalizationRoundKey(hash), data)
+}
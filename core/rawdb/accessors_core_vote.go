@@ -0,0 +1,87 @@
+package rawdb
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+// VoteRecord is a vote cast by the node, kept to prove it never voted for
+// conflicting blocks.
+type VoteRecord struct {
+	Type          uint8
+	Round         uint64
+	Height        uint64
+	Period        uint64
+	BlockHash     common.Hash
+	SignatureType string
+	Signature     []byte
+}
+
+// ReadVoteRecordCount returns the number of votes recorded in the round.
+func ReadVoteRecordCount(db DatabaseReader, round uint64) uint64 {
+	data, _ := db.Get(voteRecordCountKey(round))
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(data)
+}
+
+func ReadVoteRecord(db DatabaseReader, round, index uint64) *VoteRecord {
+	data, _ := db.Get(voteRecordKey(round, index))
+	if len(data) == 0 {
+		return nil
+	}
+	record := new(VoteRecord)
+	if err := rlp.Decode(bytes.NewReader(data), record); err != nil {
+		log.Error("Invalid vote record RLP", "round", round, "index", index, "err", err)
+		return nil
+	}
+	return record
+}
+
+// ReadVotedBlockHash returns the hash of the block voted for at the position,
+// period and type of the given vote, and whether there is such vote.
+func ReadVotedBlockHash(db DatabaseReader, voteType uint8, round, height, period uint64) (common.Hash, bool) {
+	data, _ := db.Get(votePositionKey(voteType, round, height, period))
+	if len(data) != common.HashLength {
+		return common.Hash{}, false
+	}
+	return common.BytesToHash(data), true
+}
+
+// WriteVoteRecord appends the vote to the records of its round, and indexes
+// the block it voted for by the vote position.
+func WriteVoteRecord(db DatabaseWriter, index uint64, record *VoteRecord) error {
+	data, err := rlp.EncodeToBytes(record)
+	if err != nil {
+		return err
+	}
+	if err := db.Put(voteRecordKey(record.Round, index), data); err != nil {
+		return err
+	}
+	key := votePositionKey(record.Type, record.Round, record.Height, record.Period)
+	if err := db.Put(key, record.BlockHash.Bytes()); err != nil {
+		return err
+	}
+	count := make([]byte, 8)
+	binary.LittleEndian.PutUint64(count, index+1)
+	return db.Put(voteRecordCountKey(record.Round), count)
+}
+
+// DeleteVoteRecord removes the vote at the index of the records of its
+// round, and its position index.
+func DeleteVoteRecord(db DatabaseDeleter, index uint64, record *VoteRecord) error {
+	if err := db.Delete(voteRecordKey(record.Round, index)); err != nil {
+		return err
+	}
+	return db.Delete(votePositionKey(record.Type, record.Round, record.Height, record.Period))
+}
+
+// DeleteVoteRecordCount removes the number of votes recorded in the round.
+func DeleteVoteRecordCount(db DatabaseDeleter, round uint64) error {
+	return db.Delete(voteRecordCountKey(round))
+}
@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"encoding/json"
+	"math/big"
 
 	"github.com/dexon-foundation/dexon/common"
 	"github.com/dexon-foundation/dexon/log"
@@ -95,3 +96,57 @@ func WritePreimages(db DatabaseWriter, preimages map[common.Hash][]byte) {
 	preimageCounter.Inc(int64(len(preimages)))
 	preimageHitCounter.Inc(int64(len(preimages)))
 }
+
+// GasPriceSnapshot is the block prices last sampled by the gas price oracle,
+// along with the unix time they were taken at.
+type GasPriceSnapshot struct {
+	Samples []*big.Int
+	Time    uint64
+}
+
+// ReadGasPriceSnapshot retrieves the last persisted gas price snapshot.
+func ReadGasPriceSnapshot(db DatabaseReader) *GasPriceSnapshot {
+	data, _ := db.Get(gasPriceSnapshotKey)
+	if len(data) == 0 {
+		return nil
+	}
+	snapshot := new(GasPriceSnapshot)
+	if err := rlp.DecodeBytes(data, snapshot); err != nil {
+		log.Error("Invalid gas price snapshot RLP", "err", err)
+		return nil
+	}
+	return snapshot
+}
+
+// WriteGasPriceSnapshot stores the gas price snapshot.
+func WriteGasPriceSnapshot(db DatabaseWriter, snapshot *GasPriceSnapshot) error {
+	data, err := rlp.EncodeToBytes(snapshot)
+	if err != nil {
+		return err
+	}
+	return db.Put(gasPriceSnapshotKey, data)
+}
+
+// ReadValidatorPeers retrieves the enode URLs of the validator peers pinned by
+// the operator.
+func ReadValidatorPeers(db DatabaseReader) []string {
+	data, _ := db.Get(validatorPeersKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var urls []string
+	if err := rlp.DecodeBytes(data, &urls); err != nil {
+		log.Error("Invalid validator peers RLP", "err", err)
+		return nil
+	}
+	return urls
+}
+
+// WriteValidatorPeers stores the enode URLs of the pinned validator peers.
+func WriteValidatorPeers(db DatabaseWriter, urls []string) error {
+	data, err := rlp.EncodeToBytes(urls)
+	if err != nil {
+		return err
+	}
+	return db.Put(validatorPeersKey, data)
+}
@@ -41,6 +41,12 @@ var (
 	// fastTrieProgressKey tracks the number of trie entries imported during fast sync.
 	fastTrieProgressKey = []byte("TrieSync")
 
+	// gasPriceSnapshotKey tracks the last price suggested by the gas price oracle.
+	gasPriceSnapshotKey = []byte("GasPriceSnapshot")
+
+	// validatorPeersKey tracks the validator peers pinned by the operator.
+	validatorPeersKey = []byte("ValidatorPeers")
+
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`, used for indexes).
 	headerPrefix       = []byte("h") // headerPrefix + num (uint64 big endian) + hash -> header
 	headerTDSuffix     = []byte("t") // headerPrefix + num (uint64 big endian) + hash + headerTDSuffix -> td
@@ -59,6 +65,12 @@ var (
 	coreDKGPrivateKeyPrefix   = []byte("DPK")
 	coreCompactionChainTipKey = []byte("CoreChainTip")
 	coreDKGProtocolKey        = []byte("CoreDKGProtocol")
+	roundNotarySetPrefix      = []byte("RNS")
+	roundGroupPublicKeyPrefix = []byte("RGPK")
+	voteRecordCountPrefix     = []byte("VC")
+	voteRecordPrefix          = []byte("VR")
+	votePositionPrefix        = []byte("VP")
+	finalizationRoundPrefix   = []byte("FR")
 
 	preimagePrefix = []byte("secure-key-")      // preimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-") // config prefix for the db
@@ -137,6 +149,55 @@ func coreDKGPrivateKeyKey(round uint64) []byte {
 	return ret
 }
 
+// roundNotarySetKey = roundNotarySetPrefix + round
+func roundNotarySetKey(round uint64) []byte {
+	ret := make([]byte, len(roundNotarySetPrefix)+8)
+	copy(ret, roundNotarySetPrefix)
+	binary.LittleEndian.PutUint64(ret[len(roundNotarySetPrefix):], round)
+	return ret
+}
+
+// roundGroupPublicKeyKey = roundGroupPublicKeyPrefix + round
+func roundGroupPublicKeyKey(round uint64) []byte {
+	ret := make([]byte, len(roundGroupPublicKeyPrefix)+8)
+	copy(ret, roundGroupPublicKeyPrefix)
+	binary.LittleEndian.PutUint64(ret[len(roundGroupPublicKeyPrefix):], round)
+	return ret
+}
+
+// voteRecordCountKey = voteRecordCountPrefix + round
+func voteRecordCountKey(round uint64) []byte {
+	ret := make([]byte, len(voteRecordCountPrefix)+8)
+	copy(ret, voteRecordCountPrefix)
+	binary.LittleEndian.PutUint64(ret[len(voteRecordCountPrefix):], round)
+	return ret
+}
+
+// voteRecordKey = voteRecordPrefix + round + index
+func voteRecordKey(round, index uint64) []byte {
+	ret := make([]byte, len(voteRecordPrefix)+16)
+	copy(ret, voteRecordPrefix)
+	binary.LittleEndian.PutUint64(ret[len(voteRecordPrefix):], round)
+	binary.LittleEndian.PutUint64(ret[len(voteRecordPrefix)+8:], index)
+	return ret
+}
+
+// votePositionKey = votePositionPrefix + type + round + height + period
+func votePositionKey(voteType uint8, round, height, period uint64) []byte {
+	ret := make([]byte, len(votePositionPrefix)+25)
+	copy(ret, votePositionPrefix)
+	ret[len(votePositionPrefix)] = voteType
+	binary.LittleEndian.PutUint64(ret[len(votePositionPrefix)+1:], round)
+	binary.LittleEndian.PutUint64(ret[len(votePositionPrefix)+9:], height)
+	binary.LittleEndian.PutUint64(ret[len(votePositionPrefix)+17:], period)
+	return ret
+}
+
+// finalizationRoundKey = finalizationRoundPrefix + hash
+func finalizationRoundKey(hash common.Hash) []byte {
+	return append(finalizationRoundPrefix, hash.Bytes()...)
+}
+
 // bloomBitsKey = bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash
 func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	key := append(append(bloomBitsPrefix, make([]byte, 10)...), hash.Bytes()...)
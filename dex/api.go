@@ -17,27 +17,835 @@
 package dex
 
 import (
+	"bytes"
 	"compress/gzip"
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"math/big"
 	"os"
+	"reflect"
+	"sort"
 	"strings"
+	"time"
 
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	coreEcdsa "github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
+
+	"github.com/dexon-foundation/dexon/accounts"
 	"github.com/dexon-foundation/dexon/common"
 	"github.com/dexon-foundation/dexon/common/hexutil"
 	"github.com/dexon-foundation/dexon/core"
 	"github.com/dexon-foundation/dexon/core/rawdb"
 	"github.com/dexon-foundation/dexon/core/state"
 	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/crypto"
 	"github.com/dexon-foundation/dexon/internal/ethapi"
+	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/p2p/enode"
 	"github.com/dexon-foundation/dexon/params"
 	"github.com/dexon-foundation/dexon/rlp"
 	"github.com/dexon-foundation/dexon/rpc"
 	"github.com/dexon-foundation/dexon/trie"
 )
 
+// maxBlocksByProposer is the maximum number of blocks returned by a single
+// dex_getBlockByProposer call.
+const maxBlocksByProposer = 256
+
+// PublicDexonAPI provides an API to access DEXON consensus related
+// information. Consensus related failures are reported with the stable error
+// codes listed in errors.go.
+type PublicDexonAPI struct {
+	dex *Dexon
+}
+
+// NewPublicDexonAPI creates a new DEXON protocol API for full nodes.
+func NewPublicDexonAPI(dex *Dexon) *PublicDexonAPI {
+	return &PublicDexonAPI{dex: dex}
+}
+
+// GetBlockByProposer returns the last n blocks proposed by the given address,
+// ordered by ascending block number. The lookup starts below the given block
+// number if any, which resumes a lookup failed with ErrScanLimitReached.
+func (api *PublicDexonAPI) GetBlockByProposer(ctx context.Context, proposer common.Address, n int,
+	before *hexutil.Uint64) ([]map[string]interface{}, error) {
+	if n <= 0 || n > maxBlocksByProposer {
+		return nil, fmt.Errorf("block count must be within [1, %d]", maxBlocksByProposer)
+	}
+	var from uint64
+	if before != nil {
+		from = uint64(*before)
+	}
+	blocks, err := api.dex.APIBackend.BlocksByProposer(ctx, proposer, n, from)
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]map[string]interface{}, 0, len(blocks))
+	for _, block := range blocks {
+		field, err := ethapi.RPCMarshalBlock(block, true, false)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// RoundRewards is the reward summary of a validator in a round.
+type RoundRewards struct {
+	Round     hexutil.Uint64 `json:"round"`
+	Validator common.Address `json:"validator"`
+	Blocks    hexutil.Uint64 `json:"blocks"`
+	Reward    *hexutil.Big   `json:"reward"`
+}
+
+// RoundRewards returns the block rewards credited to the validator in the
+// given round. A round still in progress is summed up to the latest
+// finalized block.
+func (api *PublicDexonAPI) RoundRewards(ctx context.Context, round hexutil.Uint64,
+	validator common.Address) (*RoundRewards, error) {
+	reward, blocks, err := api.dex.APIBackend.RoundRewards(ctx, uint64(round), validator)
+	if err != nil {
+		return nil, err
+	}
+	return &RoundRewards{
+		Round:     round,
+		Validator: validator,
+		Blocks:    hexutil.Uint64(blocks),
+		Reward:    (*hexutil.Big)(reward),
+	}, nil
+}
+
+// ProposedBlocks returns the blocks the given address proposed in the round,
+// including the ones of a round in progress only confirmed so far.
+func (api *PublicDexonAPI) ProposedBlocks(ctx context.Context, round hexutil.Uint64,
+	proposer common.Address) ([]*ProposedBlock, error) {
+	return api.dex.APIBackend.ProposedBlocks(ctx, uint64(round), proposer)
+}
+
+// ChainConfigAt returns the Dexcon configuration in effect at the block of
+// the given number.
+func (api *PublicDexonAPI) ChainConfigAt(number hexutil.Uint64) (*params.DexconConfig, error) {
+	return api.dex.APIBackend.ChainConfigAt(uint64(number))
+}
+
+// RoundStorageStats returns the state growth over the blocks of the round.
+func (api *PublicDexonAPI) RoundStorageStats(ctx context.Context, round hexutil.Uint64) (*RoundStorageStats, error) {
+	return api.dex.APIBackend.RoundStorageStats(ctx, uint64(round))
+}
+
+// ValidatorStatus is the participation status of the node in consensus.
+type ValidatorStatus struct {
+	Round              hexutil.Uint64  `json:"round"`
+	IsValidator        bool            `json:"isValidator"`
+	InCurrentNotarySet bool            `json:"inCurrentNotarySet"`
+	LastVotedRound     *hexutil.Uint64 `json:"lastVotedRound"`
+}
+
+// ValidatorStatus returns whether the node is a validator, whether it is in
+// the notary set of the current round, and the round it last voted in. An
+// observer node is reported as not a validator.
+func (api *PublicDexonAPI) ValidatorStatus() (*ValidatorStatus, error) {
+	round := api.dex.blockchain.CurrentBlock().Round()
+	status := &ValidatorStatus{Round: hexutil.Uint64(round)}
+	if !api.dex.config.BlockProposerEnabled {
+		return status, nil
+	}
+	status.IsValidator = true
+
+	notarySet, err := api.dex.governance.NotarySet(round)
+	if err != nil {
+		return nil, err
+	}
+	self := hex.EncodeToString(crypto.FromECDSAPub(api.dex.validatorKey()))
+	_, status.InCurrentNotarySet = notarySet[self]
+
+	if voted, ok := api.dex.network.LastVotedRound(); ok {
+		status.LastVotedRound = (*hexutil.Uint64)(&voted)
+	}
+	return status, nil
+}
+
+// Crs returns the CRS of the given round, the randomness beacon used by
+// consensus. It fails for rounds whose CRS is not proposed yet. The name is
+// not capitalized as CRS to be exposed as dex_crs.
+func (api *PublicDexonAPI) Crs(round hexutil.Uint64) (hexutil.Bytes, error) {
+	return api.dex.APIBackend.CRS(uint64(round))
+}
+
+// RPCGroupPublicKey is the DKG group public key of a round.
+type RPCGroupPublicKey struct {
+	Round     hexutil.Uint64 `json:"round"`
+	PublicKey hexutil.Bytes  `json:"publicKey"`
+}
+
+// GroupPublicKey returns the DKG group public key of the given round. The
+// notarizations of the blocks of the round verify against it. It fails for
+// rounds the DKG of which is not final yet.
+func (api *PublicDexonAPI) GroupPublicKey(round hexutil.Uint64) (*RPCGroupPublicKey, error) {
+	gpk, err := api.dex.APIBackend.GroupPublicKey(uint64(round))
+	if err != nil {
+		return nil, err
+	}
+	return &RPCGroupPublicKey{Round: round, PublicKey: gpk}, nil
+}
+
+// RPCNotarySet is the notary set of a round.
+type RPCNotarySet struct {
+	Round      hexutil.Uint64     `json:"round"`
+	TotalStake *hexutil.Big       `json:"totalStake"`
+	Members    []*NotarySetMember `json:"members"`
+}
+
+// NotarySet returns the members of the notary set of the given round with
+// their stake. It fails for rounds the CRS of which is not proposed yet.
+func (api *PublicDexonAPI) NotarySet(round hexutil.Uint64) (*RPCNotarySet, error) {
+	members, err := api.dex.APIBackend.NotarySet(uint64(round))
+	if err != nil {
+		return nil, err
+	}
+	total := new(big.Int)
+	for _, m := range members {
+		total.Add(total, m.Stake.ToInt())
+	}
+	return &RPCNotarySet{
+		Round:      round,
+		TotalStake: (*hexutil.Big)(total),
+		Members:    members,
+	}, nil
+}
+
+// GasParameters returns the minimum gas price and block gas limit of the head
+// round, with the gas price suggested by the oracle, in a single call.
+func (api *PublicDexonAPI) GasParameters(ctx context.Context) (*GasParameters, error) {
+	return api.dex.APIBackend.GasParameters(ctx)
+}
+
+// EstimateFinalityTime returns the estimated time in milliseconds until the
+// transaction of the given hash is finalized, zero if it is already.
+func (api *PublicDexonAPI) EstimateFinalityTime(ctx context.Context, hash common.Hash) (*FinalityEstimate, error) {
+	return api.dex.APIBackend.EstimateFinalityTime(ctx, hash)
+}
+
+// FinalizationDepth returns how many blocks and seconds finality currently
+// lags behind the blocks confirmed by consensus, along with the average over
+// the latest deliveries.
+func (api *PublicDexonAPI) FinalizationDepth() *FinalizationDepth {
+	return api.dex.APIBackend.RecentFinalizationDepth()
+}
+
+// CompactionProvenance returns the consensus core blocks the finalized block
+// of the given number was produced from.
+func (api *PublicDexonAPI) CompactionProvenance(number hexutil.Uint64) (*CompactionProvenance, error) {
+	return api.dex.APIBackend.CompactionProvenance(uint64(number))
+}
+
+// BlockFinalizationRound returns the round the block of the given hash
+// achieved finality in, which may be later than the round it was produced in.
+func (api *PublicDexonAPI) BlockFinalizationRound(hash common.Hash) *BlockFinalization {
+	return api.dex.APIBackend.BlockFinalizationRound(hash)
+}
+
+// PendingProposals returns the governance changes open in the head state,
+// with their tallies and the round they take effect in.
+func (api *PublicDexonAPI) PendingProposals() []*GovernanceProposal {
+	return api.dex.APIBackend.PendingProposals()
+}
+
+// ConsensusTimestamp returns the timestamp consensus agreed on for the block
+// of the given hash in milliseconds, like the block time, flagged provisional
+// if the block is not finalized yet.
+func (api *PublicDexonAPI) ConsensusTimestamp(hash common.Hash) (*ConsensusTimestamp, error) {
+	return api.dex.APIBackend.ConsensusTimestamp(hash)
+}
+
+// RPCCoreBlock is a consensus core block at its position.
+type RPCCoreBlock struct {
+	Hash          common.Hash    `json:"hash"`
+	ParentHash    common.Hash    `json:"parentHash"`
+	ProposerID    common.Hash    `json:"proposerID"`
+	Round         hexutil.Uint64 `json:"round"`
+	Height        hexutil.Uint64 `json:"height"`
+	Timestamp     hexutil.Uint64 `json:"timestamp"` // In milliseconds
+	PayloadHash   common.Hash    `json:"payloadHash"`
+	WitnessHeight hexutil.Uint64 `json:"witnessHeight"`
+	Randomness    hexutil.Bytes  `json:"randomness"`
+}
+
+// GetBlockByPosition returns the consensus core block finalized at the given
+// position.
+func (api *PublicDexonAPI) GetBlockByPosition(round, height hexutil.Uint64) (*RPCCoreBlock, error) {
+	block, err := api.dex.APIBackend.BlockByPosition(uint64(round), uint64(height))
+	if err != nil {
+		return nil, err
+	}
+	return &RPCCoreBlock{
+		Hash:          common.Hash(block.Hash),
+		ParentHash:    common.Hash(block.ParentHash),
+		ProposerID:    common.Hash(block.ProposerID.Hash),
+		Round:         hexutil.Uint64(block.Position.Round),
+		Height:        hexutil.Uint64(block.Position.Height),
+		Timestamp:     hexutil.Uint64(block.Timestamp.UnixNano() / int64(time.Millisecond)),
+		PayloadHash:   common.Hash(block.PayloadHash),
+		WitnessHeight: hexutil.Uint64(block.Witness.Height),
+		Randomness:    block.Randomness,
+	}, nil
+}
+
+// GetNotarizations returns the notarization proofs of the blocks in the given
+// inclusive range, listing the blocks not finalized separately.
+func (api *PublicDexonAPI) GetNotarizations(fromBlock, toBlock hexutil.Uint64) (*Notarizations, error) {
+	return api.dex.APIBackend.GetNotarizations(uint64(fromBlock), uint64(toBlock))
+}
+
+// RPCRoundBlockRange is the inclusive range of blocks of a round.
+type RPCRoundBlockRange struct {
+	Round    hexutil.Uint64 `json:"round"`
+	Begin    hexutil.Uint64 `json:"begin"`
+	End      hexutil.Uint64 `json:"end"`
+	Complete bool           `json:"complete"` // False if the round is still in progress
+}
+
+// RoundBlockRange returns the range of blocks of the given round. The range of
+// the round in progress ends at the latest finalized block.
+func (api *PublicDexonAPI) RoundBlockRange(round hexutil.Uint64) (*RPCRoundBlockRange, error) {
+	// Read before the range, a round found complete has its full range.
+	current := api.dex.blockchain.CurrentBlock().Round()
+	begin, end, err := api.dex.APIBackend.RoundBlockRange(uint64(round))
+	if err != nil {
+		return nil, err
+	}
+	return &RPCRoundBlockRange{
+		Round:    round,
+		Begin:    hexutil.Uint64(begin),
+		End:      hexutil.Uint64(end),
+		Complete: uint64(round) < current,
+	}, nil
+}
+
+// GetAccountNonceStatus returns the finalized, confirmed and pool nonces of
+// the address, with the missing nonces holding its queued transactions back.
+func (api *PublicDexonAPI) GetAccountNonceStatus(address common.Address) (*AccountNonceStatus, error) {
+	return api.dex.APIBackend.AccountNonceStatus(address)
+}
+
+// ChainTip returns the latest finalized block with its consensus position.
+func (api *PublicDexonAPI) ChainTip() (*ChainTip, error) {
+	return api.dex.APIBackend.ChainTip()
+}
+
+// GetLogsByRound returns the logs matching the given addresses and topics
+// emitted in the given round. Logs of a round still in progress are returned
+// up to the latest finalized block.
+func (api *PublicDexonAPI) GetLogsByRound(ctx context.Context, round hexutil.Uint64,
+	addresses []common.Address, topics [][]common.Hash) ([]*types.Log, error) {
+	logs, err := api.dex.APIBackend.GetLogsByRound(ctx, uint64(round), addresses, topics)
+	if err != nil {
+		return nil, err
+	}
+	if logs == nil {
+		logs = []*types.Log{}
+	}
+	return logs, nil
+}
+
+// RPCRound is the notification of a round change sent to subscribers.
+type RPCRound struct {
+	Round     hexutil.Uint64 `json:"round"`
+	CRS       common.Hash    `json:"crs"`
+	BeginTime hexutil.Uint64 `json:"beginTime"`
+	EndTime   hexutil.Uint64 `json:"endTime"`
+}
+
+func newRPCRound(ev RoundEvent) *RPCRound {
+	return &RPCRound{
+		Round:     hexutil.Uint64(ev.Round),
+		CRS:       ev.CRS,
+		BeginTime: hexutil.Uint64(ev.BeginTime),
+		EndTime:   hexutil.Uint64(ev.EndTime),
+	}
+}
+
+// NewRound sends a notification each time the chain advances to a new round.
+// The current round is sent immediately on subscription.
+func (api *PublicDexonAPI) NewRound(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		rounds := make(chan RoundEvent, 16)
+		roundSub := api.dex.roundNotifier.SubscribeRoundEvent(rounds)
+		defer roundSub.Unsubscribe()
+
+		current := api.dex.roundNotifier.Current()
+		notifier.Notify(rpcSub.ID, newRPCRound(current))
+
+		for {
+			select {
+			case ev := <-rounds:
+				// Skip rounds already sent as the current one.
+				if ev.Round <= current.Round {
+					continue
+				}
+				current = ev
+				notifier.Notify(rpcSub.ID, newRPCRound(ev))
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// FinalizedHeads sends a notification with the header of each block reaching
+// finality, in chain order, unlike newHeads which also fires on blocks only
+// imported. The latest finalized header is sent on subscription if latest is
+// set.
+func (api *PublicDexonAPI) FinalizedHeads(ctx context.Context, latest *bool) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		blocks := make(chan core.NewFinalizedBlockEvent, 16)
+		blockSub := api.dex.app.SubscribeNewFinalizedBlockEvent(blocks)
+		defer blockSub.Unsubscribe()
+
+		head := api.dex.blockchain.CurrentHeader()
+		if latest != nil && *latest {
+			notifier.Notify(rpcSub.ID, head)
+		}
+		last := head.Number.Uint64()
+
+		for {
+			select {
+			case ev := <-blocks:
+				// Events are sent concurrently and may arrive out of order,
+				// headers are read from the chain to keep them in order.
+				for number := last + 1; number <= ev.Block.NumberU64(); number++ {
+					header := api.dex.blockchain.GetHeaderByNumber(number)
+					if header == nil {
+						break
+					}
+					notifier.Notify(rpcSub.ID, header)
+					last = number
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// RPCTxRejection is the notification of a transaction rejected by the
+// transaction pool.
+type RPCTxRejection struct {
+	Hash   common.Hash    `json:"hash"`
+	From   common.Address `json:"from"`
+	Reason string         `json:"reason"`
+}
+
+// TxRejections sends a notification each time the transaction pool rejects a
+// transaction received from a peer or submitted through the API, with the
+// reason of the rejection. At most txRejectionRate notifications are sent per
+// second, the rejections beyond are dropped.
+func (api *PublicDexonAPI) TxRejections(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		rejectionSub := api.dex.txRejections.subscribe()
+		defer rejectionSub.Unsubscribe()
+
+		signer := types.NewEIP155Signer(api.dex.chainConfig.ChainID)
+		for {
+			select {
+			case ev := <-rejectionSub.ch:
+				// The sender is unknown if the signature is invalid.
+				from, _ := types.Sender(signer, ev.Tx)
+				notifier.Notify(rpcSub.ID, &RPCTxRejection{
+					Hash:   ev.Tx.Hash(),
+					From:   from,
+					Reason: ev.Err.Error(),
+				})
+			case <-rejectionSub.quit:
+				return
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// RPCRoundLatency is the time a completed round took to reach agreement.
+type RPCRoundLatency struct {
+	Round     hexutil.Uint64 `json:"round"`
+	BeginTime hexutil.Uint64 `json:"beginTime"`
+	EndTime   hexutil.Uint64 `json:"endTime"`
+	Latency   hexutil.Uint64 `json:"latency"` // In milliseconds
+}
+
+// RoundLatencies returns the latencies of at most the given number of latest
+// completed rounds, oldest first. All kept rounds are returned if count is
+// omitted.
+func (api *PublicDexonAPI) RoundLatencies(count *hexutil.Uint64) []*RPCRoundLatency {
+	n := roundLatencyLimit
+	if count != nil && int(*count) < n {
+		n = int(*count)
+	}
+	latencies := api.dex.roundNotifier.Latencies(n)
+	result := make([]*RPCRoundLatency, len(latencies))
+	for i, l := range latencies {
+		result[i] = &RPCRoundLatency{
+			Round:     hexutil.Uint64(l.Round),
+			BeginTime: hexutil.Uint64(l.BeginTime),
+			EndTime:   hexutil.Uint64(l.EndTime),
+			Latency:   hexutil.Uint64(l.Latency()),
+		}
+	}
+	return result
+}
+
+// BlockPropagationStats is the time the latest received blocks took to reach
+// the node after they were proposed, in milliseconds.
+type BlockPropagationStats struct {
+	CoreBlocks PropagationStats `json:"coreBlocks"` // Blocks received in the consensus
+	Blocks     PropagationStats `json:"blocks"`     // Finalized blocks propagated by peers
+}
+
+// BlockPropagationStats returns the propagation latencies of the latest
+// received blocks. Large latencies indicate network or peering problems,
+// skewed blocks a local clock behind the proposers'.
+func (api *PublicDexonAPI) BlockPropagationStats() *BlockPropagationStats {
+	pm := api.dex.protocolManager
+	return &BlockPropagationStats{
+		CoreBlocks: pm.coreBlockPropagation.Stats(),
+		Blocks:     pm.blockPropagation.Stats(),
+	}
+}
+
+// ConfigDiff is a consensus parameter whose value has diverged from genesis.
+type ConfigDiff struct {
+	Field   string      `json:"field"`
+	Genesis interface{} `json:"genesis"`
+	Current interface{} `json:"current"`           // Effective in the current round
+	Pending interface{} `json:"pending,omitempty"` // Applied in a future round
+}
+
+// GetConfigDiff returns the consensus parameters whose effective values in
+// the current round, or the values pending in governance state, differ from
+// the ones declared in genesis.
+func (api *PublicDexonAPI) GetConfigDiff() []ConfigDiff {
+	gov := api.dex.governance
+	return diffDexconConfig(
+		gov.GetStateAtRound(0).Configuration(),
+		gov.DexconConfiguration(gov.Round()),
+		gov.GetHeadState().Configuration())
+}
+
+func diffDexconConfig(genesis, current, pending *params.DexconConfig) []ConfigDiff {
+	var (
+		diffs []ConfigDiff
+		g     = reflect.ValueOf(genesis).Elem()
+		c     = reflect.ValueOf(current).Elem()
+		p     = reflect.ValueOf(pending).Elem()
+	)
+	for i := 0; i < g.NumField(); i++ {
+		gv, cv, pv := g.Field(i).Interface(), c.Field(i).Interface(), p.Field(i).Interface()
+		if configValueEqual(gv, cv) && configValueEqual(cv, pv) {
+			continue
+		}
+		diff := ConfigDiff{
+			Field:   strings.Split(g.Type().Field(i).Tag.Get("json"), ",")[0],
+			Genesis: gv,
+			Current: cv,
+		}
+		if !configValueEqual(cv, pv) {
+			diff.Pending = pv
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
+
+func configValueEqual(a, b interface{}) bool {
+	switch a := a.(type) {
+	case *big.Int:
+		b := b.(*big.Int)
+		if a == nil || b == nil {
+			return a == b
+		}
+		return a.Cmp(b) == 0
+	case []*big.Int:
+		b := b.([]*big.Int)
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !configValueEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// PrivateDexonAPI provides an API to manage the local transactions of the
+// node in the context of DEXON rounds. It signs with the node key and
+// unlocked accounts, so it is only exposed over IPC.
+type PrivateDexonAPI struct {
+	dex *Dexon
+}
+
+// NewPrivateDexonAPI creates a new private DEXON API.
+func NewPrivateDexonAPI(dex *Dexon) *PrivateDexonAPI {
+	return &PrivateDexonAPI{dex: dex}
+}
+
+// PendingTransaction is a pending local transaction annotated with the round
+// it is estimated to be included in.
+type PendingTransaction struct {
+	Hash           common.Hash     `json:"hash"`
+	From           common.Address  `json:"from"`
+	To             *common.Address `json:"to"`
+	Nonce          hexutil.Uint64  `json:"nonce"`
+	Gas            hexutil.Uint64  `json:"gas"`
+	GasPrice       *hexutil.Big    `json:"gasPrice"`
+	Value          *hexutil.Big    `json:"value"`
+	EstimatedRound hexutil.Uint64  `json:"estimatedRound"`
+}
+
+// PendingTransactions returns the pending transactions of the local accounts.
+// The inclusion round is estimated by filling blocks up to the block gas limit
+// with all pending transactions, in the order they are picked for proposals.
+// Transactions which can never be included are left out.
+func (api *PrivateDexonAPI) PendingTransactions() ([]*PendingTransaction, error) {
+	rounds, _, err := api.dex.APIBackend.TxPoolContentByRound()
+	if err != nil {
+		return nil, err
+	}
+	locals := make(map[common.Address]struct{})
+	for _, addr := range api.dex.txPool.Locals() {
+		locals[addr] = struct{}{}
+	}
+
+	order := make([]uint64, 0, len(rounds))
+	for round := range rounds {
+		order = append(order, round)
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i] < order[j] })
+
+	signer := types.NewEIP155Signer(api.dex.chainConfig.ChainID)
+	txs := make([]*PendingTransaction, 0)
+	for _, round := range order {
+		for _, tx := range rounds[round] {
+			from, _ := types.Sender(signer, tx)
+			if _, ok := locals[from]; !ok {
+				continue
+			}
+			txs = append(txs, &PendingTransaction{
+				Hash:           tx.Hash(),
+				From:           from,
+				To:             tx.To(),
+				Nonce:          hexutil.Uint64(tx.Nonce()),
+				Gas:            hexutil.Uint64(tx.Gas()),
+				GasPrice:       (*hexutil.Big)(tx.GasPrice()),
+				Value:          (*hexutil.Big)(tx.Value()),
+				EstimatedRound: hexutil.Uint64(round),
+			})
+		}
+	}
+	return txs, nil
+}
+
+// RPCVoteRecord is a vote cast by the node.
+type RPCVoteRecord struct {
+	Type          hexutil.Uint64 `json:"type"`
+	Round         hexutil.Uint64 `json:"round"`
+	Height        hexutil.Uint64 `json:"height"`
+	Period        hexutil.Uint64 `json:"period"`
+	BlockHash     common.Hash    `json:"blockHash"`
+	SignatureType string         `json:"signatureType"`
+	Signature     hexutil.Bytes  `json:"signature"`
+}
+
+// VoteHistory returns the votes cast by the node in the given inclusive range
+// of rounds, for auditing that it never voted for conflicting blocks.
+func (api *PrivateDexonAPI) VoteHistory(fromRound, toRound hexutil.Uint64) ([]*RPCVoteRecord, error) {
+	if fromRound > toRound {
+		return nil, fmt.Errorf("invalid round range %d - %d", fromRound, toRound)
+	}
+	records := api.dex.network.voteHistory.votes(uint64(fromRound), uint64(toRound))
+	votes := make([]*RPCVoteRecord, len(records))
+	for i, r := range records {
+		votes[i] = &RPCVoteRecord{
+			Type:          hexutil.Uint64(r.Type),
+			Round:         hexutil.Uint64(r.Round),
+			Height:        hexutil.Uint64(r.Height),
+			Period:        hexutil.Uint64(r.Period),
+			BlockHash:     r.BlockHash,
+			SignatureType: r.SignatureType,
+			Signature:     r.Signature,
+		}
+	}
+	return votes, nil
+}
+
+// CancelTransaction replaces the pending transaction of the given hash by a
+// zero value transfer to its sender, with a gas price high enough to replace
+// it in the pool. The sender must be the node key or an unlocked account.
+func (api *PrivateDexonAPI) CancelTransaction(ctx context.Context, hash common.Hash) (common.Hash, error) {
+	if tx, _, _, _ := rawdb.ReadTransaction(api.dex.chainDb, hash); tx != nil {
+		return common.Hash{}, fmt.Errorf("transaction %x already finalized", hash)
+	}
+	tx := api.dex.txPool.Get(hash)
+	if tx == nil {
+		return common.Hash{}, fmt.Errorf("transaction %x not pending", hash)
+	}
+	signer := types.NewEIP155Signer(api.dex.chainConfig.ChainID)
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	// The pool only replaces a transaction paying at least PriceBump percent
+	// more, never go below the pool minimum either.
+	bump := big.NewInt(100 + int64(api.dex.config.TxPool.PriceBump))
+	gasPrice := new(big.Int).Div(new(big.Int).Mul(tx.GasPrice(), bump), big.NewInt(100))
+	if gasPrice.Cmp(tx.GasPrice()) <= 0 {
+		gasPrice.Add(tx.GasPrice(), common.Big1)
+	}
+	if min := api.dex.txPool.GasPrice(); gasPrice.Cmp(min) < 0 {
+		gasPrice = min
+	}
+	cancel := types.NewTransaction(tx.Nonce(), from, common.Big0, params.TxGas, gasPrice, nil)
+
+	if key, _ := api.dex.validator(); key != nil && crypto.PubkeyToAddress(key.PublicKey) == from {
+		cancel, err = types.SignTx(cancel, signer, key)
+	} else {
+		cancel, err = api.signTx(from, cancel)
+	}
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := api.dex.APIBackend.SendTx(ctx, cancel); err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Cancelled transaction", "hash", hash, "replacement", cancel.Hash(), "nonce", cancel.Nonce())
+	return cancel.Hash(), nil
+}
+
+// signTx signs the transaction with the wallet holding the given account.
+func (api *PrivateDexonAPI) signTx(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+	if api.dex.accountManager == nil {
+		return nil, fmt.Errorf("no key for sender %x", addr)
+	}
+	account := accounts.Account{Address: addr}
+	wallet, err := api.dex.accountManager.Find(account)
+	if err != nil {
+		return nil, err
+	}
+	return wallet.SignTx(account, tx, api.dex.chainConfig.ChainID)
+}
+
+// PrivateDexonGovernanceAPI provides the governance operations of the node,
+// sending transactions signed by the node key. It is only exposed over IPC,
+// regardless of the HTTP and WebSocket module settings.
+type PrivateDexonGovernanceAPI struct {
+	dex *Dexon
+}
+
+// NewPrivateDexonGovernanceAPI creates a new DEXON governance API.
+func NewPrivateDexonGovernanceAPI(dex *Dexon) *PrivateDexonGovernanceAPI {
+	return &PrivateDexonGovernanceAPI{dex: dex}
+}
+
+// ProposeCRS sends a proposal of the signed CRS of the given round. Only
+// block proposers can propose.
+func (api *PrivateDexonGovernanceAPI) ProposeCRS(ctx context.Context, round hexutil.Uint64,
+	signedCRS hexutil.Bytes) error {
+	if !api.dex.config.BlockProposerEnabled {
+		return &ErrNotValidator{}
+	}
+	data, err := vm.PackProposeCRS(uint64(round), signedCRS)
+	if err != nil {
+		return err
+	}
+	return api.dex.governance.sendGovTx(ctx, data)
+}
+
+// PrivateValidatorAPI provides the operations on the validator key of the
+// node. It is only exposed over IPC, as it decrypts keys of the keystore.
+type PrivateValidatorAPI struct {
+	dex *Dexon
+}
+
+// NewPrivateValidatorAPI creates a new DEXON validator API.
+func NewPrivateValidatorAPI(dex *Dexon) *PrivateValidatorAPI {
+	return &PrivateValidatorAPI{dex: dex}
+}
+
+// ScheduleKeyRotation switches the validator key to the key of the keystore
+// account at the beginning of the given round, see Dexon.ScheduleKeyRotation.
+func (api *PrivateValidatorAPI) ScheduleKeyRotation(address common.Address,
+	round hexutil.Uint64, passphrase string) (bool, error) {
+	key, err := loadAccountKey(api.dex.accountManager, address, passphrase)
+	if err != nil {
+		return false, err
+	}
+	if err := api.dex.ScheduleKeyRotation(key, uint64(round)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SubmitSlashingEvidence reports the signer of conflicting consensus messages
+// to the governance contract, in a transaction sent with the validator key.
+// The evidence is validated first, so that the report does not revert.
+func (api *PrivateValidatorAPI) SubmitSlashingEvidence(ctx context.Context,
+	evidence SlashingEvidence) (bool, error) {
+	if !api.dex.config.BlockProposerEnabled {
+		return false, &ErrNotValidator{}
+	}
+	data, err := packSlashingEvidence(api.dex.governance.GetHeadState(), &evidence)
+	if err != nil {
+		return false, fmt.Errorf("invalid evidence: %v", err)
+	}
+	if err := api.dex.governance.sendGovTx(ctx, data); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // PrivateAdminAPI is the collection of Ethereum full node-related APIs
 // exposed over the private admin endpoint.
 type PrivateAdminAPI struct {
@@ -72,6 +880,27 @@ func (api *PrivateAdminAPI) ExportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// ExportFinalizedBlocks appends the finalized blocks in the given inclusive
+// range to a local file, skipping the ones not finalized yet. An export is
+// resumed by calling again from the block after the last one exported.
+func (api *PrivateAdminAPI) ExportFinalizedBlocks(file string, from, to hexutil.Uint64) (bool, error) {
+	out, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, os.ModePerm)
+	if err != nil {
+		return false, err
+	}
+	defer out.Close()
+
+	var writer io.Writer = out
+	if strings.HasSuffix(file, ".gz") {
+		writer = gzip.NewWriter(writer)
+		defer writer.(*gzip.Writer).Close()
+	}
+	if err := api.dex.ExportFinalizedBlocks(writer, uint64(from), uint64(to)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func hasAllBlocks(chain *core.BlockChain, bs []*types.Block) bool {
 	for _, b := range bs {
 		if !chain.HasBlock(b.Hash(), b.NumberU64()) {
@@ -82,6 +911,45 @@ func hasAllBlocks(chain *core.BlockChain, bs []*types.Block) bool {
 	return true
 }
 
+// ExportBlockDB exports the consensus block database into a local file.
+func (api *PrivateAdminAPI) ExportBlockDB(file string) (bool, error) {
+	out, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return false, err
+	}
+	defer out.Close()
+
+	var writer io.Writer = out
+	if strings.HasSuffix(file, ".gz") {
+		writer = gzip.NewWriter(writer)
+		defer writer.(*gzip.Writer).Close()
+	}
+	if err := api.dex.ExportBlockDB(writer); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ImportBlockDB imports the consensus block database from a local file.
+func (api *PrivateAdminAPI) ImportBlockDB(file string) (bool, error) {
+	in, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer in.Close()
+
+	var reader io.Reader = in
+	if strings.HasSuffix(file, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return false, err
+		}
+	}
+	if err := api.dex.ImportBlockDB(reader); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // ImportChain imports a blockchain from a local file.
 func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	// Make sure the can access the file to import
@@ -131,10 +999,43 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// FlushTxJournal rewrites the journal of the local transactions of the pool.
+func (api *PrivateAdminAPI) FlushTxJournal() (bool, error) {
+	if err := api.dex.FlushTxJournal(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CompactChainDB compacts the chain database.
+func (api *PrivateAdminAPI) CompactChainDB() (bool, error) {
+	if err := api.dex.CompactChainDB(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func (api *PrivateAdminAPI) IsCoreSyncing() bool {
 	return api.dex.IsCoreSyncing()
 }
 
+// StartProposing resumes the consensus paused by StopProposing.
+func (api *PrivateAdminAPI) StartProposing() (bool, error) {
+	if err := api.dex.StartProposing(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// StopProposing pauses consensus, transactions are rejected until it is
+// resumed.
+func (api *PrivateAdminAPI) StopProposing() (bool, error) {
+	if err := api.dex.StopProposing(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 func (api *PrivateAdminAPI) IsProposing() bool {
 	return api.dex.IsProposing()
 }
@@ -143,6 +1044,73 @@ func (api *PrivateAdminAPI) NotaryInfo() (*NotaryInfo, error) {
 	return api.dex.protocolManager.NotaryInfo()
 }
 
+// ReloadGovernance drops the cached round-scoped governance values, notary
+// sets, group public keys and DKG state, so they are read again from the
+// governance state. This is done at each round change anyway.
+func (api *PrivateAdminAPI) ReloadGovernance() bool {
+	api.dex.governance.Reload()
+	return true
+}
+
+// AddValidatorPeer pins the node of the given enode URL as a validator peer,
+// kept connected and exempt from the peer limit, across restarts.
+func (api *PrivateAdminAPI) AddValidatorPeer(url string) (bool, error) {
+	node, err := enode.ParseV4(url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	if err := api.dex.AddValidatorPeer(node); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RemoveValidatorPeer unpins the validator peer of the given enode URL.
+func (api *PrivateAdminAPI) RemoveValidatorPeer(url string) (bool, error) {
+	node, err := enode.ParseV4(url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	return api.dex.RemoveValidatorPeer(node)
+}
+
+// DexPeers retrieves the DEXON consensus state of all connected peers, in
+// addition to what admin_peers reports about them.
+func (api *PrivateAdminAPI) DexPeers() []*DexPeerInfo {
+	return api.dex.protocolManager.PeersInfo()
+}
+
+// CatchUp syncs the chain with the network until it reaches the target round,
+// returning the progress made. It is refused if the chain is already past the
+// target round.
+func (api *PrivateAdminAPI) CatchUp(ctx context.Context, targetRound hexutil.Uint64) (*CatchUpProgress, error) {
+	pm := api.dex.protocolManager
+	return api.dex.catchUp(ctx, uint64(targetRound), func(p *peer) {
+		pm.synchronise(p, true)
+	})
+}
+
+// CancelCatchUp stops the running catch-up, and reports whether there is one.
+func (api *PrivateAdminAPI) CancelCatchUp() bool {
+	return api.dex.cancelCatchUp()
+}
+
+// DexVersions returns the versions of the dex protocol supported by the
+// node, the primary one first.
+func (api *PrivateAdminAPI) DexVersions() []uint {
+	return api.dex.SupportedDexVersions()
+}
+
+// NegotiatedVersion returns the dex protocol version settled with the peer of
+// the given id.
+func (api *PrivateAdminAPI) NegotiatedVersion(id string) (uint, error) {
+	version, ok := api.dex.NegotiatedVersion(id)
+	if !ok {
+		return 0, fmt.Errorf("peer %s not connected", id)
+	}
+	return version, nil
+}
+
 // PublicDebugAPI is the collection of Ethereum full node APIs exposed
 // over the public debugging endpoint.
 type PublicDebugAPI struct {
@@ -194,6 +1162,213 @@ func (api *PrivateDebugAPI) Preimage(ctx context.Context, hash common.Hash) (hex
 	return nil, errors.New("unknown preimage")
 }
 
+// BlockCheck is the outcome of a single verification check of a block.
+type BlockCheck struct {
+	Name    string `json:"name"`
+	Passed  bool   `json:"passed"`
+	Skipped bool   `json:"skipped,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// VerifyBlockResult is the outcome of a dry-run verification of a block.
+type VerifyBlockResult struct {
+	Hash   common.Hash    `json:"hash"`
+	Round  hexutil.Uint64 `json:"round"`
+	Height hexutil.Uint64 `json:"height"`
+	Valid  bool           `json:"valid"`
+	Checks []*BlockCheck  `json:"checks"`
+}
+
+// VerifyBlock re-runs the application level checks of the block of the given
+// hash, either a chain block, a bad block or a consensus core block, against
+// the state of its parent. The state is never written.
+func (api *PrivateDebugAPI) VerifyBlock(blockHash common.Hash) (*VerifyBlockResult, error) {
+	chain := api.dex.blockchain
+	block := chain.GetBlockByHash(blockHash)
+	if block == nil {
+		for _, bad := range chain.BadBlocks() {
+			if bad.Hash() == blockHash {
+				block = bad
+				break
+			}
+		}
+	}
+	var coreBlock *coreTypes.Block
+	if block != nil {
+		coreBlock = new(coreTypes.Block)
+		if err := rlp.DecodeBytes(block.Header().DexconMeta, coreBlock); err != nil {
+			return nil, fmt.Errorf("invalid dexcon meta: %v", err)
+		}
+	} else if coreBlock = rawdb.ReadCoreBlock(api.dex.chainDb, blockHash); coreBlock == nil {
+		return nil, fmt.Errorf("block %x not found", blockHash)
+	}
+	return verifyBlock(chain, api.dex.governance, coreBlock, block), nil
+}
+
+// verifyBlock runs the checks of DexconApp.VerifyBlock on the core block,
+// followed by a re-execution of the chain block when given.
+func verifyBlock(chain *core.BlockChain, gov *DexconGovernance,
+	coreBlock *coreTypes.Block, block *types.Block) *VerifyBlockResult {
+	result := &VerifyBlockResult{
+		Hash:   common.Hash(coreBlock.Hash),
+		Round:  hexutil.Uint64(coreBlock.Position.Round),
+		Height: hexutil.Uint64(coreBlock.Position.Height),
+		Valid:  true,
+	}
+	failed := false
+	check := func(name string, err error) {
+		c := &BlockCheck{Name: name}
+		switch {
+		case failed:
+			c.Skipped = true
+		case err != nil:
+			c.Error = err.Error()
+			result.Valid, failed = false, true
+		default:
+			c.Passed = true
+		}
+		result.Checks = append(result.Checks, c)
+	}
+
+	var witnessHash common.Hash
+	err := rlp.DecodeBytes(coreBlock.Witness.Data, &witnessHash)
+	if err == nil {
+		if w := chain.GetBlockByNumber(coreBlock.Witness.Height); w == nil {
+			err = fmt.Errorf("witness block %d not found", coreBlock.Witness.Height)
+		} else if w.Hash() != witnessHash {
+			err = fmt.Errorf("witness hash mismatch (remote: %x local: %x)", witnessHash, w.Hash())
+		} else if _, err = chain.StateAt(w.Root()); err != nil {
+			err = fmt.Errorf("witness state unavailable: %v", err)
+		}
+	}
+	check("witness", err)
+
+	var parent *types.Block
+	if coreBlock.Position.Height == 0 {
+		err = errors.New("invalid height 0")
+	} else if parent = chain.GetBlockByNumber(coreBlock.Position.Height - 1); parent == nil {
+		err = fmt.Errorf("parent block %d not found", coreBlock.Position.Height-1)
+	} else if round := parent.Round(); coreBlock.Position.Round != round &&
+		coreBlock.Position.Round != round+1 {
+		err = fmt.Errorf("invalid round %d after round %d", coreBlock.Position.Round, round)
+	}
+	check("position", err)
+
+	var statedb *state.StateDB
+	if parent != nil {
+		if statedb, err = chain.StateAt(parent.Root()); err != nil {
+			err = fmt.Errorf("parent state unavailable: %v", err)
+		}
+	}
+	check("parentState", err)
+
+	var txs types.Transactions
+	if len(coreBlock.Payload) > 0 {
+		err = rlp.DecodeBytes(coreBlock.Payload, &txs)
+	}
+	check("payload", err)
+
+	signer := types.NewEIP155Signer(chain.Config().ChainID)
+	senders := make([]common.Address, len(txs))
+	err = nil
+	for i, tx := range txs {
+		if senders[i], err = types.Sender(signer, tx); err != nil {
+			err = fmt.Errorf("tx %x: %v", tx.Hash(), err)
+			break
+		}
+	}
+	check("sender", err)
+
+	err = nil
+	nonces := make(map[common.Address]uint64)
+	for i, tx := range txs {
+		expect, ok := nonces[senders[i]]
+		if !ok && statedb != nil {
+			expect = statedb.GetNonce(senders[i])
+		}
+		if tx.Nonce() != expect {
+			err = fmt.Errorf("tx %x: invalid nonce (expect: %d got: %d)", tx.Hash(), expect, tx.Nonce())
+			break
+		}
+		nonces[senders[i]] = expect + 1
+	}
+	check("nonce", err)
+
+	err = nil
+	if !failed {
+		minGasPrice := gov.MinGasPrice(coreBlock.Position.Round)
+		for _, tx := range txs {
+			if tx.GasPrice().Cmp(minGasPrice) < 0 {
+				err = fmt.Errorf("tx %x: gas price %v below minimum %v", tx.Hash(), tx.GasPrice(), minGasPrice)
+				break
+			}
+		}
+	}
+	check("gasPrice", err)
+
+	err = nil
+	for _, tx := range txs {
+		var intrinsic uint64
+		if intrinsic, err = core.IntrinsicGas(tx.Data(), tx.To() == nil, true); err != nil {
+			break
+		}
+		if tx.Gas() < intrinsic {
+			err = fmt.Errorf("tx %x: intrinsic gas too low (intrinsic: %d gas: %d)", tx.Hash(), intrinsic, tx.Gas())
+			break
+		}
+	}
+	check("intrinsicGas", err)
+
+	err = nil
+	balances := make(map[common.Address]*big.Int)
+	for i, tx := range txs {
+		if failed {
+			break
+		}
+		balance, ok := balances[senders[i]]
+		if !ok {
+			balance = statedb.GetBalance(senders[i])
+		}
+		if balance = new(big.Int).Sub(balance, tx.Cost()); balance.Sign() < 0 {
+			err = fmt.Errorf("tx %x: insufficient funds for gas * price + value", tx.Hash())
+			break
+		}
+		balances[senders[i]] = balance
+	}
+	check("balance", err)
+
+	err = nil
+	if !failed {
+		var gas uint64
+		limit := gov.DexconConfiguration(coreBlock.Position.Round).BlockGasLimit
+		for _, tx := range txs {
+			if gas += tx.Gas(); gas > limit {
+				err = fmt.Errorf("block gas limit reached (gas: %d limit: %d)", gas, limit)
+				break
+			}
+		}
+	}
+	check("gasLimit", err)
+
+	// Re-execute the chain block on a copy of the parent state, the copy is
+	// discarded without being committed.
+	if block == nil {
+		result.Checks = append(result.Checks, &BlockCheck{Name: "state", Skipped: true})
+		return result
+	}
+	err = nil
+	if !failed {
+		receipts, _, usedGas, perr := chain.Processor().Process(block, statedb, *chain.GetVMConfig())
+		if perr != nil {
+			err = perr
+		} else {
+			err = chain.Validator().ValidateState(block, parent, statedb, receipts, usedGas)
+		}
+	}
+	check("state", err)
+	return result
+}
+
 // BadBlockArgs represents the entries in the list returned when bad blocks are queried.
 type BadBlockArgs struct {
 	Hash  common.Hash            `json:"hash"`
@@ -224,6 +1399,152 @@ func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context) ([]*BadBlockArgs,
 	return results, nil
 }
 
+// PendingBlockResult describes a block confirmed by consensus which is not
+// delivered to the chain yet.
+type PendingBlockResult struct {
+	Hash       common.Hash    `json:"hash"`
+	ParentHash common.Hash    `json:"parentHash"`
+	Proposer   string         `json:"proposer"`
+	Round      hexutil.Uint64 `json:"round"`
+	Height     hexutil.Uint64 `json:"height"`
+	Age        string         `json:"age"`
+	WaitingOn  string         `json:"waitingOn"` // "parent" or "delivery"
+}
+
+// PendingAcks returns the blocks confirmed by consensus but not delivered to
+// the chain yet, with what each one is waiting on: the delivery of a pending
+// parent, or its own delivery. Blocks stuck here for long point at a stalled
+// finalization.
+func (api *PrivateDebugAPI) PendingAcks() []*PendingBlockResult {
+	pending := api.dex.app.pendingBlocks()
+
+	hashes := make(map[coreCommon.Hash]struct{}, len(pending))
+	for _, p := range pending {
+		hashes[p.block.Hash] = struct{}{}
+	}
+	now := time.Now()
+	results := make([]*PendingBlockResult, 0, len(pending))
+	for _, p := range pending {
+		waitingOn := "delivery"
+		if _, ok := hashes[p.block.ParentHash]; ok {
+			waitingOn = "parent"
+		}
+		results = append(results, &PendingBlockResult{
+			Hash:       common.Hash(p.block.Hash),
+			ParentHash: common.Hash(p.block.ParentHash),
+			Proposer:   p.block.ProposerID.String(),
+			Round:      hexutil.Uint64(p.block.Position.Round),
+			Height:     hexutil.Uint64(p.block.Position.Height),
+			Age:        common.PrettyDuration(now.Sub(p.confirmedAt)).String(),
+			WaitingOn:  waitingOn,
+		})
+	}
+	return results
+}
+
+// PipelineBlock is a block produced but not finalized yet, along with the
+// commit votes seen for it against the number needed to agree on it.
+type PipelineBlock struct {
+	Hash      common.Hash    `json:"hash"`
+	Proposer  string         `json:"proposer"`
+	Round     hexutil.Uint64 `json:"round"`
+	Height    hexutil.Uint64 `json:"height"`
+	Votes     int            `json:"votes"`     // Notary set members seen commit voting for the block
+	Threshold int            `json:"threshold"` // Commit votes needed for the block to be agreed on
+	Confirmed bool           `json:"confirmed"` // Whether the block is agreed on, awaiting delivery
+	Age       string         `json:"age"`       // Time since the block was proposed
+}
+
+// notaryVoteFilter returns a filter of the votes cast by the notary set of
+// the round and signed by their voter. No vote passes if the notary set is
+// unknown.
+func notaryVoteFilter(gov *DexconGovernance, round uint64, sigCache *voteSigCache) func(*coreTypes.Vote) bool {
+	notarySet, err := gov.NotarySet(round)
+	if err != nil {
+		log.Debug("Failed to get notary set", "round", round, "err", err)
+	}
+	notaries := make(map[coreTypes.NodeID]struct{}, len(notarySet))
+	for key := range notarySet {
+		b, err := hex.DecodeString(key)
+		if err != nil {
+			continue
+		}
+		pubkey, err := coreEcdsa.NewPublicKeyFromByteSlice(b)
+		if err != nil {
+			continue
+		}
+		notaries[coreTypes.NewNodeID(pubkey)] = struct{}{}
+	}
+	verify := verifyVoteSignature
+	if sigCache != nil {
+		verify = sigCache.verify
+	}
+	return func(vote *coreTypes.Vote) bool {
+		if _, ok := notaries[vote.ProposerID]; !ok {
+			return false
+		}
+		return verify(vote)
+	}
+}
+
+// FinalizationPipeline returns the blocks produced but not finalized yet,
+// ordered by height, each with the commit votes it gathered so far from its
+// notary set, signed by their voter. Blocks confirmed by consensus are
+// awaiting delivery only. Blocks lingering with votes short of the threshold
+// point at a stalled agreement.
+func (api *PrivateDebugAPI) FinalizationPipeline() []*PipelineBlock {
+	var (
+		dex       = api.dex
+		now       = time.Now()
+		confirmed = make(map[coreCommon.Hash]struct{})
+		blocks    []*coreTypes.Block
+	)
+	for _, p := range dex.app.pendingBlocks() {
+		confirmed[p.block.Hash] = struct{}{}
+		blocks = append(blocks, p.block)
+	}
+	pm := dex.protocolManager
+	if pm != nil {
+		for _, block := range pm.cache.pendingBlocks(dex.blockchain.CurrentBlock().NumberU64()) {
+			if _, ok := confirmed[block.Hash]; !ok {
+				blocks = append(blocks, block)
+			}
+		}
+	}
+
+	results := make([]*PipelineBlock, 0, len(blocks))
+	accepts := make(map[uint64]func(*coreTypes.Vote) bool)
+	for _, block := range blocks {
+		_, isConfirmed := confirmed[block.Hash]
+		result := &PipelineBlock{
+			Hash:      common.Hash(block.Hash),
+			Proposer:  block.ProposerID.String(),
+			Round:     hexutil.Uint64(block.Position.Round),
+			Height:    hexutil.Uint64(block.Position.Height),
+			Threshold: coreUtils.GetBAThreshold(dex.governance.Configuration(block.Position.Round)),
+			Confirmed: isConfirmed,
+			Age:       common.PrettyDuration(now.Sub(block.Timestamp)).String(),
+		}
+		if pm != nil {
+			round := block.Position.Round
+			accept, ok := accepts[round]
+			if !ok {
+				accept = notaryVoteFilter(dex.governance, round, pm.voteSigCache)
+				accepts[round] = accept
+			}
+			result.Votes = pm.cache.commitVoters(block.Position, block.Hash, accept)
+		}
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Height != results[j].Height {
+			return results[i].Height < results[j].Height
+		}
+		return bytes.Compare(results[i].Hash[:], results[j].Hash[:]) < 0
+	})
+	return results
+}
+
 // StorageRangeResult is the result of a debug_storageRangeAt API call.
 type StorageRangeResult struct {
 	Storage storageMap   `json:"storage"`
@@ -17,23 +17,39 @@
 package dex
 
 import (
+	"bytes"
 	"context"
+	"encoding/hex"
+	"fmt"
 	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	dexCore "github.com/dexon-foundation/dexon-consensus/core"
+	coreDb "github.com/dexon-foundation/dexon-consensus/core/db"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
 
 	"github.com/dexon-foundation/dexon/accounts"
 	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/common/hexutil"
 	"github.com/dexon-foundation/dexon/common/math"
 	"github.com/dexon-foundation/dexon/core"
 	"github.com/dexon-foundation/dexon/core/bloombits"
+	"github.com/dexon-foundation/dexon/core/rawdb"
 	"github.com/dexon-foundation/dexon/core/state"
 	"github.com/dexon-foundation/dexon/core/types"
 	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/eth/filters"
 	"github.com/dexon-foundation/dexon/eth/gasprice"
 	"github.com/dexon-foundation/dexon/internal/ethapi"
 
 	"github.com/dexon-foundation/dexon/ethdb"
 	"github.com/dexon-foundation/dexon/event"
 	"github.com/dexon-foundation/dexon/params"
+	"github.com/dexon-foundation/dexon/rlp"
 	"github.com/dexon-foundation/dexon/rpc"
 )
 
@@ -41,6 +57,11 @@ import (
 type DexAPIBackend struct {
 	dex *Dexon
 	gpo *gasprice.Oracle
+
+	logQueries *filters.QueryLimiter // Bound of the log queries run for users
+
+	gasParamsLock sync.Mutex
+	gasParams     *GasParameters // Gas parameters of the last queried head
 }
 
 // ChainConfig returns the active chain configuration.
@@ -138,12 +159,34 @@ func (b *DexAPIBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscri
 	return b.dex.BlockChain().SubscribeLogsEvent(ch)
 }
 
+// SendTx adds the transaction to the pool, unless transactions are rejected
+// for consensus being paused or the node being behind.
 func (b *DexAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	if err := b.dex.checkTxSubmission(); err != nil {
+		return err
+	}
+	err := b.sendTx(signedTx)
+	b.dex.txRejections.report([]*types.Transaction{signedTx}, []error{err})
+	return err
+}
+
+// sendTx adds the transaction to the pool regardless of the consensus
+// status, for the transactions of consensus itself.
+func (b *DexAPIBackend) sendTx(signedTx *types.Transaction) error {
 	return b.dex.txPool.AddLocal(signedTx)
 }
 
 func (b *DexAPIBackend) SendTxs(ctx context.Context, signedTxs []*types.Transaction) []error {
-	return b.dex.txPool.AddLocals(signedTxs)
+	if err := b.dex.checkTxSubmission(); err != nil {
+		errs := make([]error, len(signedTxs))
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+	errs := b.dex.txPool.AddLocals(signedTxs)
+	b.dex.txRejections.report(signedTxs, errs)
+	return errs
 }
 
 func (b *DexAPIBackend) GetPoolTransactions() (types.Transactions, error) {
@@ -174,6 +217,69 @@ func (b *DexAPIBackend) TxPoolContent() (map[common.Address]types.Transactions,
 	return b.dex.TxPool().Content()
 }
 
+// TxPoolContentByRound snapshots the pending transactions grouped by the
+// round they are estimated to be included in. Transactions which can never be
+// included are returned as unschedulable.
+func (b *DexAPIBackend) TxPoolContentByRound() (map[uint64]types.Transactions, types.Transactions, error) {
+	pending, err := b.dex.txPool.Pending()
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		gov   = b.dex.governance
+		head  = b.dex.blockchain.CurrentBlock()
+		round = head.Round()
+	)
+	rounds, unschedulable := scheduleTxsByRound(pending,
+		types.NewEIP155Signer(b.dex.chainConfig.ChainID),
+		gov.DexconConfiguration(round), round, gov.GetRoundHeight(round), head.NumberU64()+1)
+	return rounds, unschedulable, nil
+}
+
+// scheduleTxsByRound estimates the rounds the given pending transactions are
+// included in, starting from the block of the given height in the round
+// beginning at roundBegin. Blocks are filled up to the block gas limit in the
+// order transactions are picked for proposals, and rounds advance every round
+// length blocks. Transactions whose gas exceeds the block gas limit or whose
+// price is below the minimum gas price are unschedulable, along with the later
+// transactions of their senders.
+func scheduleTxsByRound(pending map[common.Address]types.Transactions, signer types.Signer,
+	config *params.DexconConfig, round, roundBegin, height uint64) (map[uint64]types.Transactions, types.Transactions) {
+	var (
+		roundEnd = roundBegin + config.RoundLength
+		gasUsed  uint64
+
+		rounds        = make(map[uint64]types.Transactions)
+		unschedulable types.Transactions
+		blocked       = make(map[common.Address]struct{})
+	)
+	for set := types.NewTransactionsByPriceAndNonce(signer, pending); ; set.Shift() {
+		tx := set.Peek()
+		if tx == nil {
+			break
+		}
+		from, _ := types.Sender(signer, tx)
+		if _, ok := blocked[from]; ok {
+			unschedulable = append(unschedulable, tx)
+			continue
+		}
+		if tx.Gas() > config.BlockGasLimit || tx.GasPrice().Cmp(config.MinGasPrice) < 0 {
+			blocked[from] = struct{}{}
+			unschedulable = append(unschedulable, tx)
+			continue
+		}
+		if gasUsed+tx.Gas() > config.BlockGasLimit {
+			height, gasUsed = height+1, 0
+		}
+		gasUsed += tx.Gas()
+		for height >= roundEnd && config.RoundLength > 0 {
+			round, roundEnd = round+1, roundEnd+config.RoundLength
+		}
+		rounds[round] = append(rounds[round], tx)
+	}
+	return rounds, unschedulable
+}
+
 func (b *DexAPIBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return b.dex.TxPool().SubscribeNewTxsEvent(ch)
 }
@@ -190,6 +296,44 @@ func (b *DexAPIBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.dex.governance.MinGasPrice(b.dex.blockchain.CurrentBlock().Round()), nil
 }
 
+// GasParameters are the gas settings in effect at the chain head.
+type GasParameters struct {
+	Round          hexutil.Uint64 `json:"round"`
+	Number         hexutil.Uint64 `json:"number"`
+	Hash           common.Hash    `json:"hash"`
+	MinGasPrice    *hexutil.Big   `json:"minGasPrice"`
+	BlockGasLimit  hexutil.Uint64 `json:"blockGasLimit"`
+	SuggestedPrice *hexutil.Big   `json:"suggestedGasPrice"` // Suggestion of the gas price oracle
+}
+
+// GasParameters returns the governance gas settings of the head round along
+// with the suggestion of the gas price oracle. The result is cached until the
+// head changes and must not be modified.
+func (b *DexAPIBackend) GasParameters(ctx context.Context) (*GasParameters, error) {
+	head := b.dex.blockchain.CurrentBlock()
+
+	b.gasParamsLock.Lock()
+	defer b.gasParamsLock.Unlock()
+
+	if b.gasParams != nil && b.gasParams.Hash == head.Hash() {
+		return b.gasParams, nil
+	}
+	suggested, err := b.gpo.SuggestPrice(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config := b.dex.governance.DexconConfiguration(head.Round())
+	b.gasParams = &GasParameters{
+		Round:          hexutil.Uint64(head.Round()),
+		Number:         hexutil.Uint64(head.NumberU64()),
+		Hash:           head.Hash(),
+		MinGasPrice:    (*hexutil.Big)(config.MinGasPrice),
+		BlockGasLimit:  hexutil.Uint64(config.BlockGasLimit),
+		SuggestedPrice: (*hexutil.Big)(suggested),
+	}
+	return b.gasParams, nil
+}
+
 func (b *DexAPIBackend) ChainDb() ethdb.Database {
 	return b.dex.ChainDb()
 }
@@ -206,7 +350,12 @@ func (b *DexAPIBackend) RPCGasCap() *big.Int {
 	return b.dex.config.RPCGasCap
 }
 
+// BloomStatus reports no indexed sections if the bloom index is disabled, so
+// that log filters fall back to scanning blocks.
 func (b *DexAPIBackend) BloomStatus() (uint64, uint64) {
+	if b.dex.bloomIndexer == nil {
+		return params.BloomBitsBlocks, 0
+	}
 	sections, _, _ := b.dex.bloomIndexer.Sections()
 	return params.BloomBitsBlocks, sections
 }
@@ -216,3 +365,805 @@ func (b *DexAPIBackend) ServiceFilter(ctx context.Context, session *bloombits.Ma
 		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.dex.bloomRequests)
 	}
 }
+
+// BlocksByProposer returns at most n of the latest blocks below the given
+// number, the chain head if zero, proposed by the given address, ordered by
+// ascending block number. The proposer of a block is its coinbase, which
+// DexconApp sets to the owner of the proposing node when the block is
+// delivered. There is no index of the blocks by proposer, the chain is walked
+// back from the start block. A lookup walking back more than
+// MaxProposerScanDepth blocks fails with ErrScanLimitReached, telling where to
+// resume it.
+func (b *DexAPIBackend) BlocksByProposer(ctx context.Context, proposer common.Address, n int,
+	before uint64) ([]*types.Block, error) {
+	var (
+		blocks []*types.Block
+		number = b.dex.blockchain.CurrentBlock().NumberU64()
+		limit  = b.dex.config.MaxProposerScanDepth
+	)
+	if before > 0 && before <= number {
+		number = before - 1
+	}
+	for depth := uint64(0); number > 0 && len(blocks) < n; depth++ {
+		if limit > 0 && depth == limit {
+			return nil, &ErrScanLimitReached{Limit: limit, Next: number + 1}
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		block := b.dex.blockchain.GetBlockByNumber(number)
+		if block == nil {
+			break
+		}
+		if block.Coinbase() == proposer {
+			blocks = append(blocks, block)
+		}
+		number--
+	}
+	for i, j := 0, len(blocks)-1; i < j; i, j = i+1, j-1 {
+		blocks[i], blocks[j] = blocks[j], blocks[i]
+	}
+	return blocks, nil
+}
+
+// RoundBlockRange returns the inclusive range of blocks belonging to the given
+// round, as recorded in the governance state by the first block of each round.
+// Round 0 begins with the genesis block. The range of a round still in
+// progress ends at the latest finalized block. Rounds not started yet, or the
+// first block of which is not recorded, are rejected.
+func (b *DexAPIBackend) RoundBlockRange(round uint64) (begin, end uint64, err error) {
+	current := b.dex.blockchain.CurrentBlock()
+	if round > current.Round() {
+		return 0, 0, &ErrRoundNotReached{Round: round, Current: current.Round()}
+	}
+	// Heights default to zero, only the one of round 0 is actually zero.
+	begin = b.dex.governance.GetRoundHeight(round)
+	if round > 0 && begin == 0 {
+		return 0, 0, &ErrRoundNotRecorded{Round: round}
+	}
+	if round == current.Round() {
+		return begin, current.NumberU64(), nil
+	}
+	next := b.dex.governance.GetRoundHeight(round + 1)
+	if next <= begin {
+		return 0, 0, &ErrRoundNotRecorded{Round: round + 1}
+	}
+	return begin, next - 1, nil
+}
+
+// RoundStorageStats is the growth of the state over the blocks of a round.
+type RoundStorageStats struct {
+	Round           hexutil.Uint64 `json:"round"`
+	FirstBlock      hexutil.Uint64 `json:"firstBlock"`
+	LastBlock       hexutil.Uint64 `json:"lastBlock"`
+	NewAccounts     hexutil.Uint64 `json:"newAccounts"`
+	DeletedAccounts hexutil.Uint64 `json:"deletedAccounts"`
+	StorageSlots    hexutil.Uint64 `json:"storageSlots"` // Storage slots set, changed or cleared
+	SizeDelta       int64          `json:"sizeDelta"`    // Approximate state size change in bytes
+}
+
+// RoundStorageStats returns the state changes of the given round, from the
+// state before its first block to the state of its last block, or of the
+// head for the current round. The states at both ends must be available.
+func (b *DexAPIBackend) RoundStorageStats(ctx context.Context, round uint64) (*RoundStorageStats, error) {
+	begin, end, err := b.RoundBlockRange(round)
+	if err != nil {
+		return nil, err
+	}
+	// The genesis block is the first block of round 0, its allocation isn't
+	// counted.
+	parent := begin
+	if parent > 0 {
+		parent--
+	}
+	oldHeader := b.dex.blockchain.GetHeaderByNumber(parent)
+	newHeader := b.dex.blockchain.GetHeaderByNumber(end)
+	if oldHeader == nil || newHeader == nil {
+		return nil, fmt.Errorf("blocks of round %d not found", round)
+	}
+	stats, err := diffState(ctx, b.dex.blockchain.StateCache(), oldHeader.Root, newHeader.Root)
+	if err != nil {
+		return nil, fmt.Errorf("state of round %d not available: %v", round, err)
+	}
+	return &RoundStorageStats{
+		Round:           hexutil.Uint64(round),
+		FirstBlock:      hexutil.Uint64(begin),
+		LastBlock:       hexutil.Uint64(end),
+		NewAccounts:     hexutil.Uint64(stats.newAccounts),
+		DeletedAccounts: hexutil.Uint64(stats.deletedAccounts),
+		StorageSlots:    hexutil.Uint64(stats.changedSlots),
+		SizeDelta:       stats.sizeDelta,
+	}, nil
+}
+
+// CRS returns the CRS of the given round. The CRS of a round is not
+// available until it is proposed by the DKG set of the previous round.
+func (b *DexAPIBackend) CRS(round uint64) ([]byte, error) {
+	crs := b.dex.governance.CRS(round)
+	if crs == (coreCommon.Hash{}) {
+		return nil, &ErrCRSNotReady{Round: round, CRSRound: b.dex.governance.CRSRound()}
+	}
+	return crs[:], nil
+}
+
+// NotarySetMember is a member of the notary set of a round.
+type NotarySetMember struct {
+	Address   common.Address `json:"address"` // Address of the node key
+	Owner     common.Address `json:"owner"`
+	PublicKey hexutil.Bytes  `json:"publicKey"`
+	Stake     *hexutil.Big   `json:"stake"`
+}
+
+// NotarySet returns the members of the notary set of the given round along
+// with their stake, as recorded in the governance state the set is sampled
+// from. Members are ordered by address. The set of a round is only known once
+// the CRS of the round is proposed.
+func (b *DexAPIBackend) NotarySet(round uint64) ([]*NotarySetMember, error) {
+	gov := b.dex.governance
+	if crs := gov.CRS(round); crs == (coreCommon.Hash{}) {
+		return nil, &ErrCRSNotReady{Round: round, CRSRound: gov.CRSRound()}
+	}
+	set, err := gov.NotarySet(round)
+	if err != nil {
+		return nil, err
+	}
+	gs := gov.GetStateForConfigAtRound(round)
+	members := make([]*NotarySetMember, 0, len(set))
+	for key := range set {
+		pk, err := hex.DecodeString(key)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := crypto.UnmarshalPubkey(pk)
+		if err != nil {
+			return nil, err
+		}
+		addr := crypto.PubkeyToAddress(*pub)
+		offset := gs.NodesOffsetByNodeKeyAddress(addr)
+		if offset.Sign() < 0 {
+			return nil, fmt.Errorf("notary %x of round %d not found", addr, round)
+		}
+		node := gs.Node(offset)
+		members = append(members, &NotarySetMember{
+			Address:   addr,
+			Owner:     node.Owner,
+			PublicKey: pk,
+			Stake:     (*hexutil.Big)(node.Staked),
+		})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return bytes.Compare(members[i].Address[:], members[j].Address[:]) < 0
+	})
+	return members, nil
+}
+
+// GroupPublicKey returns the DKG group public key of the given round, which
+// the randomness of the blocks of the round is the threshold signature of.
+func (b *DexAPIBackend) GroupPublicKey(round uint64) ([]byte, error) {
+	return b.dex.governance.GroupPublicKey(round)
+}
+
+// RoundRewards returns the block rewards credited to the validator in the
+// given round, along with the number of blocks it proposed. Rewards of a
+// round still in progress are summed up to the latest finalized block.
+func (b *DexAPIBackend) RoundRewards(ctx context.Context, round uint64,
+	validator common.Address) (*big.Int, uint64, error) {
+	begin, end, err := b.RoundBlockRange(round)
+	if err != nil {
+		return nil, 0, err
+	}
+	var (
+		reward = new(big.Int)
+		blocks uint64
+	)
+	for number := begin; number <= end; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, 0, err
+		}
+		header := b.dex.blockchain.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, 0, fmt.Errorf("block %d not found", number)
+		}
+		if header.Coinbase != validator {
+			continue
+		}
+		blocks++
+		if header.Reward != nil {
+			reward.Add(reward, header.Reward)
+		}
+	}
+	return reward, blocks, nil
+}
+
+// ProposedBlock is a block contributed by a proposer, finalized or only
+// confirmed by the consensus so far.
+type ProposedBlock struct {
+	Hash      common.Hash    `json:"hash"`     // Hash of the finalized block, zero if not finalized
+	CoreHash  common.Hash    `json:"coreHash"` // Hash of the consensus core block
+	Round     hexutil.Uint64 `json:"round"`
+	Height    hexutil.Uint64 `json:"height"`
+	Finalized bool           `json:"finalized"`
+}
+
+// ProposedBlocks returns the blocks the given address proposed in the round,
+// ordered by height. The proposer of a block is the owner of the proposing
+// node, as set in the coinbase of the finalized blocks. For a round still in
+// progress, the blocks confirmed by the consensus but not delivered yet are
+// included too.
+func (b *DexAPIBackend) ProposedBlocks(ctx context.Context, round uint64,
+	proposer common.Address) ([]*ProposedBlock, error) {
+	current := b.dex.blockchain.CurrentBlock()
+	var blocks []*ProposedBlock
+	if round <= current.Round() {
+		begin, end, err := b.RoundBlockRange(round)
+		if err != nil {
+			return nil, err
+		}
+		for number := begin; number <= end; number++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			header := b.dex.blockchain.GetHeaderByNumber(number)
+			if header == nil {
+				return nil, fmt.Errorf("block %d not found", number)
+			}
+			if header.Coinbase != proposer || len(header.DexconMeta) == 0 {
+				continue
+			}
+			var coreBlock coreTypes.Block
+			if err := rlp.DecodeBytes(header.DexconMeta, &coreBlock); err != nil {
+				return nil, fmt.Errorf("invalid consensus meta of block %d: %v", number, err)
+			}
+			blocks = append(blocks, &ProposedBlock{
+				Hash:      header.Hash(),
+				CoreHash:  common.Hash(coreBlock.Hash),
+				Round:     hexutil.Uint64(round),
+				Height:    hexutil.Uint64(number),
+				Finalized: true,
+			})
+		}
+	} else if consensusRound := b.dex.consensusRound(); round > consensusRound {
+		return nil, &ErrRoundNotReached{Round: round, Current: consensusRound}
+	}
+
+	// Blocks confirmed but not delivered yet all follow the finalized ones.
+	var gs *vm.GovernanceState
+	for _, p := range b.dex.app.pendingBlocks() {
+		if p.block.Position.Round != round || p.block.IsEmpty() {
+			continue
+		}
+		if gs == nil {
+			gs = b.dex.governance.GetStateForConfigAtRound(round)
+		}
+		node, err := gs.GetNodeByID(p.block.ProposerID)
+		if err != nil || node.Owner != proposer {
+			continue
+		}
+		blocks = append(blocks, &ProposedBlock{
+			CoreHash: common.Hash(p.block.Hash),
+			Round:    hexutil.Uint64(round),
+			Height:   hexutil.Uint64(p.block.Position.Height),
+		})
+	}
+	return blocks, nil
+}
+
+// ProvenanceBlock is a consensus core block ordered into a finalized block.
+type ProvenanceBlock struct {
+	Hash       common.Hash    `json:"hash"`
+	ParentHash common.Hash    `json:"parentHash"`
+	ProposerID common.Hash    `json:"proposerID"`
+	Round      hexutil.Uint64 `json:"round"`
+	Height     hexutil.Uint64 `json:"height"`
+	Timestamp  time.Time      `json:"timestamp"`
+}
+
+// CompactionProvenance maps a finalized block to the consensus core blocks
+// it was produced from.
+type CompactionProvenance struct {
+	Number hexutil.Uint64     `json:"number"`
+	Hash   common.Hash        `json:"hash"`
+	Blocks []*ProvenanceBlock `json:"blocks"`
+}
+
+// CompactionProvenance returns the consensus core blocks ordered into the
+// finalized block of the given number. Each finalized block carries the core
+// block it was delivered from, blocks without it, like the genesis block, have
+// no provenance available.
+func (b *DexAPIBackend) CompactionProvenance(number uint64) (*CompactionProvenance, error) {
+	if number > b.dex.blockchain.CurrentBlock().NumberU64() {
+		return nil, &ErrBlockNotFinalized{Number: number}
+	}
+	block := b.dex.blockchain.GetBlockByNumber(number)
+	if block == nil {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+	meta := block.Header().DexconMeta
+	if len(meta) == 0 {
+		return nil, fmt.Errorf("provenance of block %d not available", number)
+	}
+	var coreBlock coreTypes.Block
+	if err := rlp.DecodeBytes(meta, &coreBlock); err != nil {
+		return nil, fmt.Errorf("provenance of block %d not available: %v", number, err)
+	}
+	return &CompactionProvenance{
+		Number: hexutil.Uint64(number),
+		Hash:   block.Hash(),
+		Blocks: []*ProvenanceBlock{{
+			Hash:       common.Hash(coreBlock.Hash),
+			ParentHash: common.Hash(coreBlock.ParentHash),
+			ProposerID: common.Hash(coreBlock.ProposerID.Hash),
+			Round:      hexutil.Uint64(coreBlock.Position.Round),
+			Height:     hexutil.Uint64(coreBlock.Position.Height),
+			Timestamp:  coreBlock.Timestamp,
+		}},
+	}, nil
+}
+
+// Finalization statuses of a block.
+const (
+	FinalizationPending   = "pending"   // Not finalized yet, or unknown
+	FinalizationFinalized = "finalized" // Finalized in a recorded round
+	FinalizationUnknown   = "unknown"   // Finalized before the node recorded it, like blocks synced from peers
+)
+
+// BlockFinalization is the round a block achieved finality in, along with the
+// round it was produced in.
+type BlockFinalization struct {
+	Hash       common.Hash     `json:"hash"`
+	Status     string          `json:"status"`
+	Round      *hexutil.Uint64 `json:"round"`      // Finalization round, nil unless recorded
+	BlockRound *hexutil.Uint64 `json:"blockRound"` // Production round, nil if pending
+}
+
+// BlockFinalizationRound returns the round the block of the given hash was
+// finalized in. A block is finalized in a round later than its own if the
+// consensus confirmed blocks of the later round before delivering it. Only
+// blocks delivered by the local consensus have the round recorded.
+func (b *DexAPIBackend) BlockFinalizationRound(hash common.Hash) *BlockFinalization {
+	result := &BlockFinalization{Hash: hash, Status: FinalizationPending}
+	header := b.dex.blockchain.GetHeaderByHash(hash)
+	if header == nil {
+		return result
+	}
+	blockRound := hexutil.Uint64(header.Round)
+	result.BlockRound = &blockRound
+	round, ok := rawdb.ReadBlockFinalizationRound(b.dex.chainDb, hash)
+	if !ok {
+		result.Status = FinalizationUnknown
+		return result
+	}
+	result.Status = FinalizationFinalized
+	result.Round = (*hexutil.Uint64)(&round)
+	return result
+}
+
+// Kinds of governance proposals.
+const (
+	ProposalDKG           = "dkg"           // DKG and CRS of the next round
+	ProposalConfiguration = "configuration" // Configuration update by the owner
+)
+
+// GovernanceProposal is a governance change pending in the head state.
+// Configuration updates of the owner are applied without a vote and have no
+// tally.
+type GovernanceProposal struct {
+	Kind     string         `json:"kind"`
+	Round    hexutil.Uint64 `json:"round"`           // Round the change takes effect in
+	Proposer common.Address `json:"proposer"`        // Governance owner, zero for DKG
+	Tally    *ProposalTally `json:"tally,omitempty"` // Votes of the notary set, nil for owner updates
+	Expiry   hexutil.Uint64 `json:"expiry"`          // Last block of the round the proposal is open in
+	Changes  []ConfigDiff   `json:"changes,omitempty"`
+}
+
+// ProposalTally is the vote count of a proposal decided by the notary set.
+type ProposalTally struct {
+	For       hexutil.Uint64 `json:"for"`       // DKG successes
+	Against   hexutil.Uint64 `json:"against"`   // DKG complaints
+	Threshold hexutil.Uint64 `json:"threshold"` // Approvals needed to pass
+}
+
+// PendingProposals returns the governance changes open in the head state:
+// the DKG of the next round until its CRS is proposed, and configuration
+// updates of the owner not captured by a round yet.
+func (b *DexAPIBackend) PendingProposals() []*GovernanceProposal {
+	gov := b.dex.governance
+	round := b.dex.blockchain.CurrentBlock().Round()
+	config := gov.DexconConfiguration(round)
+	expiry := gov.GetRoundHeight(round) + config.RoundLength - 1
+	return pendingProposals(gov.GetHeadState(), config, round, expiry)
+}
+
+func pendingProposals(gs *vm.GovernanceState, config *params.DexconConfig,
+	round, expiry uint64) []*GovernanceProposal {
+	proposals := make([]*GovernanceProposal, 0, 2)
+	if dkgRound := gs.DKGRound().Uint64(); dkgRound > gs.CRSRound().Uint64() {
+		proposals = append(proposals, &GovernanceProposal{
+			Kind:  ProposalDKG,
+			Round: hexutil.Uint64(dkgRound),
+			Tally: &ProposalTally{
+				For:       hexutil.Uint64(gs.DKGSuccessesCount().Uint64()),
+				Against:   hexutil.Uint64(len(gs.DKGComplaints())),
+				Threshold: hexutil.Uint64(2*uint64(config.NotarySetSize)/3 + 1),
+			},
+			Expiry: hexutil.Uint64(expiry),
+		})
+	}
+	// The head configuration is captured at the beginning of the next round
+	// and applied after the round shift.
+	var changes []ConfigDiff
+	for _, diff := range diffDexconConfig(config, config, gs.Configuration()) {
+		if diff.Pending != nil {
+			changes = append(changes, diff)
+		}
+	}
+	if len(changes) > 0 {
+		proposals = append(proposals, &GovernanceProposal{
+			Kind:     ProposalConfiguration,
+			Round:    hexutil.Uint64(round + 1 + dexCore.ConfigRoundShift),
+			Proposer: gs.Owner(),
+			Expiry:   hexutil.Uint64(expiry),
+			Changes:  changes,
+		})
+	}
+	return proposals
+}
+
+// ChainConfigAt returns the Dexcon configuration in effect at the block of the
+// given number, the one of its round.
+func (b *DexAPIBackend) ChainConfigAt(number uint64) (*params.DexconConfig, error) {
+	if number > b.dex.blockchain.CurrentBlock().NumberU64() {
+		return nil, &ErrBlockNotFinalized{Number: number}
+	}
+	header := b.dex.blockchain.GetHeaderByNumber(number)
+	if header == nil {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+	return b.dex.governance.DexconConfiguration(header.Round), nil
+}
+
+// ConsensusTimestamp is the timestamp consensus agreed on for a block.
+type ConsensusTimestamp struct {
+	Hash        common.Hash    `json:"hash"`
+	Timestamp   hexutil.Uint64 `json:"timestamp"`   // Consensus timestamp in milliseconds
+	HeaderTime  hexutil.Uint64 `json:"headerTime"`  // Header timestamp in milliseconds, zero if provisional
+	Provisional bool           `json:"provisional"` // Whether the block is confirmed but not finalized yet
+}
+
+// ConsensusTimestamp returns the consensus timestamp of the block of the
+// given hash in milliseconds, the authoritative source of the header
+// timestamp. Blocks confirmed by consensus but not delivered yet are looked
+// up by their consensus core block hash and reported as provisional. It
+// returns nil if the block is unknown.
+func (b *DexAPIBackend) ConsensusTimestamp(hash common.Hash) (*ConsensusTimestamp, error) {
+	if header := b.dex.blockchain.GetHeaderByHash(hash); header != nil {
+		result := &ConsensusTimestamp{
+			Hash:       hash,
+			Timestamp:  hexutil.Uint64(header.Time),
+			HeaderTime: hexutil.Uint64(header.Time),
+		}
+		// The genesis block carries no consensus core block.
+		if len(header.DexconMeta) == 0 {
+			return result, nil
+		}
+		var coreBlock coreTypes.Block
+		if err := rlp.DecodeBytes(header.DexconMeta, &coreBlock); err != nil {
+			return nil, err
+		}
+		result.Timestamp = hexutil.Uint64(coreBlock.Timestamp.UnixNano() / int64(time.Millisecond))
+		return result, nil
+	}
+	if block := b.dex.app.confirmedBlock(coreCommon.Hash(hash)); block != nil {
+		return &ConsensusTimestamp{
+			Hash:        hash,
+			Timestamp:   hexutil.Uint64(block.Timestamp.UnixNano() / int64(time.Millisecond)),
+			Provisional: true,
+		}, nil
+	}
+	return nil, nil
+}
+
+// BlockByPosition returns the consensus core block finalized at the given
+// position, read from the block database. The consensus delivers blocks of a
+// single chain, so a height is the number of the chain block delivered from
+// it.
+func (b *DexAPIBackend) BlockByPosition(round, height uint64) (*coreTypes.Block, error) {
+	notFound := &ErrPositionNotFound{Round: round, Height: height}
+	if height > b.dex.blockchain.CurrentBlock().NumberU64() {
+		return nil, notFound
+	}
+	header := b.dex.blockchain.GetHeaderByNumber(height)
+	if header == nil || header.Round != round || len(header.DexconMeta) == 0 {
+		return nil, notFound
+	}
+	var meta coreTypes.Block
+	if err := rlp.DecodeBytes(header.DexconMeta, &meta); err != nil {
+		return nil, fmt.Errorf("invalid consensus meta of block %d: %v", height, err)
+	}
+	block, err := b.dex.blockDB.GetBlock(meta.Hash)
+	if err == coreDb.ErrBlockDoesNotExist {
+		return nil, notFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// Notarization is the proof of finality of a block, the threshold signature
+// of the round's notary set on the consensus core block it was delivered
+// from.
+type Notarization struct {
+	Number     hexutil.Uint64 `json:"number"`
+	Hash       common.Hash    `json:"hash"`
+	Round      hexutil.Uint64 `json:"round"`
+	Height     hexutil.Uint64 `json:"height"`
+	CoreHash   common.Hash    `json:"coreHash"`
+	Randomness hexutil.Bytes  `json:"randomness"`
+}
+
+// Notarizations is the result of a request of notarization proofs for a range
+// of blocks.
+type Notarizations struct {
+	Notarizations []*Notarization  `json:"notarizations"`
+	Missing       []hexutil.Uint64 `json:"missing"` // Blocks in the range not finalized
+}
+
+// GetNotarizations returns the notarization proofs of the blocks in the given
+// inclusive range. Blocks which are not finalized, or carry no proof like the
+// genesis block, are listed as missing. The range may cover at most the
+// configured maximum number of blocks, if any.
+func (b *DexAPIBackend) GetNotarizations(fromBlock, toBlock uint64) (*Notarizations, error) {
+	if fromBlock > toBlock {
+		return nil, fmt.Errorf("invalid block range %d - %d", fromBlock, toBlock)
+	}
+	if limit := b.dex.config.MaxNotarizations; limit > 0 && toBlock-fromBlock >= limit {
+		return nil, &ErrRangeTooLarge{From: fromBlock, To: toBlock, Limit: limit}
+	}
+	result := &Notarizations{
+		Notarizations: []*Notarization{},
+		Missing:       []hexutil.Uint64{},
+	}
+	for number := fromBlock; number <= toBlock; number++ {
+		header := b.dex.blockchain.GetHeaderByNumber(number)
+		if header == nil || len(header.Randomness) == 0 || len(header.DexconMeta) == 0 {
+			result.Missing = append(result.Missing, hexutil.Uint64(number))
+			continue
+		}
+		var coreBlock coreTypes.Block
+		if err := rlp.DecodeBytes(header.DexconMeta, &coreBlock); err != nil {
+			return nil, fmt.Errorf("invalid core block of block %d: %v", number, err)
+		}
+		result.Notarizations = append(result.Notarizations, &Notarization{
+			Number:     hexutil.Uint64(number),
+			Hash:       header.Hash(),
+			Round:      hexutil.Uint64(coreBlock.Position.Round),
+			Height:     hexutil.Uint64(coreBlock.Position.Height),
+			CoreHash:   common.Hash(coreBlock.Hash),
+			Randomness: header.Randomness,
+		})
+	}
+	return result, nil
+}
+
+// NonceRange is an inclusive range of nonces.
+type NonceRange struct {
+	From hexutil.Uint64 `json:"from"`
+	To   hexutil.Uint64 `json:"to"`
+}
+
+// AccountNonceStatus reconciles the nonces of an account across the stages a
+// transaction goes through: finalized in the chain, confirmed by consensus
+// awaiting delivery, and pending or queued in the pool.
+type AccountNonceStatus struct {
+	Address        common.Address   `json:"address"`
+	StateNonce     hexutil.Uint64   `json:"stateNonce"`     // Next nonce of the account in the head state
+	FinalizedNonce *hexutil.Uint64  `json:"finalizedNonce"` // Nonce of the latest finalized transaction, nil if none
+	ConfirmedNonce *hexutil.Uint64  `json:"confirmedNonce"` // Nonce of the latest transaction awaiting delivery, nil if none
+	PendingNonce   hexutil.Uint64   `json:"pendingNonce"`   // Next nonce after the executable transactions of the pool
+	Queued         []hexutil.Uint64 `json:"queued"`         // Nonces of the transactions held back in the pool
+	Gaps           []NonceRange     `json:"gaps"`           // Missing nonces holding queued transactions back
+}
+
+// AccountNonceStatus returns the nonce status of the address, listing the
+// ranges of missing nonces which keep its queued transactions from being
+// executed.
+func (b *DexAPIBackend) AccountNonceStatus(address common.Address) (*AccountNonceStatus, error) {
+	statedb, err := b.dex.blockchain.State()
+	if err != nil {
+		return nil, err
+	}
+	status := &AccountNonceStatus{
+		Address:      address,
+		StateNonce:   hexutil.Uint64(statedb.GetNonce(address)),
+		PendingNonce: hexutil.Uint64(b.dex.txPool.State().GetNonce(address)),
+		Queued:       []hexutil.Uint64{},
+		Gaps:         []NonceRange{},
+	}
+	if status.StateNonce > 0 {
+		nonce := status.StateNonce - 1
+		status.FinalizedNonce = &nonce
+	}
+	if nonce, ok := b.dex.app.confirmedNonce(address); ok {
+		status.ConfirmedNonce = (*hexutil.Uint64)(&nonce)
+	}
+
+	_, queued := b.dex.txPool.ContentFrom(address)
+	next := uint64(status.PendingNonce)
+	if status.ConfirmedNonce != nil && uint64(*status.ConfirmedNonce) >= next {
+		next = uint64(*status.ConfirmedNonce) + 1
+	}
+	for _, tx := range queued {
+		nonce := tx.Nonce()
+		status.Queued = append(status.Queued, hexutil.Uint64(nonce))
+		if nonce > next {
+			status.Gaps = append(status.Gaps, NonceRange{
+				From: hexutil.Uint64(next),
+				To:   hexutil.Uint64(nonce - 1),
+			})
+		}
+		if nonce >= next {
+			next = nonce + 1
+		}
+	}
+	return status, nil
+}
+
+// ChainTip is the latest finalized block with its consensus position.
+type ChainTip struct {
+	Number    hexutil.Uint64 `json:"number"`
+	Hash      common.Hash    `json:"hash"`
+	CoreHash  common.Hash    `json:"coreHash"`
+	Round     hexutil.Uint64 `json:"round"`
+	Height    hexutil.Uint64 `json:"height"`
+	Timestamp hexutil.Uint64 `json:"timestamp"` // Block time in milliseconds
+}
+
+// ChainTip returns the tip of the finalized chain, the current head, letting a
+// light client follow the finalized frontier without downloading blocks. The
+// tip of a chain holding only the genesis block has no core hash.
+func (b *DexAPIBackend) ChainTip() (*ChainTip, error) {
+	header := b.dex.blockchain.CurrentHeader()
+	tip := &ChainTip{
+		Number:    hexutil.Uint64(header.Number.Uint64()),
+		Hash:      header.Hash(),
+		Round:     hexutil.Uint64(header.Round),
+		Timestamp: hexutil.Uint64(header.Time),
+	}
+	if len(header.DexconMeta) > 0 {
+		var coreBlock coreTypes.Block
+		if err := rlp.DecodeBytes(header.DexconMeta, &coreBlock); err != nil {
+			return nil, fmt.Errorf("invalid core block of block %d: %v", header.Number, err)
+		}
+		tip.CoreHash = common.Hash(coreBlock.Hash)
+		tip.Round = hexutil.Uint64(coreBlock.Position.Round)
+		tip.Height = hexutil.Uint64(coreBlock.Position.Height)
+	}
+	return tip, nil
+}
+
+// GetLogsByRound returns the logs matching the given addresses and topics
+// emitted in blocks of the given round. The query counts against the same
+// bound as the ones of eth_getLogs.
+func (b *DexAPIBackend) GetLogsByRound(ctx context.Context, round uint64,
+	addresses []common.Address, topics [][]common.Hash) ([]*types.Log, error) {
+	begin, end, err := b.RoundBlockRange(round)
+	if err != nil {
+		return nil, err
+	}
+	filter := filters.NewRangeFilter(b, int64(begin), int64(end), addresses, topics)
+	return b.logQueries.Logs(ctx, filter)
+}
+
+// Finality statuses of a transaction.
+const (
+	TxFinalized = "finalized" // Included in a finalized block
+	TxConfirmed = "confirmed" // Included in a block confirmed by consensus, awaiting delivery
+	TxPending   = "pending"   // Executable in the transaction pool
+)
+
+// FinalityEstimate is the estimated time until a transaction is finalized,
+// in milliseconds.
+type FinalityEstimate struct {
+	Hash     common.Hash    `json:"hash"`
+	Status   string         `json:"status"`
+	Blocks   hexutil.Uint64 `json:"blocks"` // Blocks left to finalize, up to the one of the transaction
+	Estimate hexutil.Uint64 `json:"estimate"`
+	Lower    hexutil.Uint64 `json:"lower"`
+	Upper    hexutil.Uint64 `json:"upper"`
+	Samples  int            `json:"samples"` // Completed rounds the block interval is derived from
+}
+
+// EstimateFinalityTime estimates the time until the transaction of the given
+// hash is finalized from the block intervals of the latest completed rounds
+// and the number of blocks to finalize before the transaction is.
+func (b *DexAPIBackend) EstimateFinalityTime(ctx context.Context, hash common.Hash) (*FinalityEstimate, error) {
+	result := &FinalityEstimate{Hash: hash}
+	if tx, _, _, _ := rawdb.ReadTransaction(b.dex.chainDb, hash); tx != nil {
+		result.Status = TxFinalized
+		return result, nil
+	}
+
+	var blocks uint64
+	if depth, ok := b.dex.app.confirmedTxDepth(hash); ok {
+		result.Status, blocks = TxConfirmed, depth
+	} else if b.isPoolPending(hash) {
+		// The transaction is included in the block after the confirmed ones.
+		result.Status, blocks = TxPending, b.dex.app.undeliveredCount()+1
+	} else {
+		return nil, &ErrTxNotFound{Hash: hash}
+	}
+
+	gov := b.dex.governance
+	head := b.dex.blockchain.CurrentBlock().Round()
+	interval, lower, upper, samples := estimateBlockInterval(
+		b.dex.roundNotifier.Latencies(roundLatencyLimit),
+		func(round uint64) uint64 { return gov.DexconConfiguration(round).RoundLength },
+		gov.DexconConfiguration(head).MinBlockInterval)
+	result.Blocks = hexutil.Uint64(blocks)
+	result.Estimate = hexutil.Uint64(blocks * interval)
+	result.Lower = hexutil.Uint64(blocks * lower)
+	result.Upper = hexutil.Uint64(blocks * upper)
+	result.Samples = samples
+	return result, nil
+}
+
+// FinalizationDepth is how far finality lags behind the blocks confirmed by
+// consensus, currently and on average over the latest deliveries.
+type FinalizationDepth struct {
+	Head           hexutil.Uint64 `json:"head"`           // Height of the highest confirmed block
+	Finalized      hexutil.Uint64 `json:"finalized"`      // Height of the latest finalized block
+	Blocks         hexutil.Uint64 `json:"blocks"`         // Confirmed blocks awaiting finalization
+	Seconds        float64        `json:"seconds"`        // Consensus time the head is ahead of the finalized block
+	AverageBlocks  float64        `json:"averageBlocks"`  // Average block depth at the latest deliveries
+	AverageSeconds float64        `json:"averageSeconds"` // Average time depth at the latest deliveries
+	Samples        int            `json:"samples"`        // Number of deliveries the averages are over
+}
+
+// RecentFinalizationDepth returns the gap between the highest block confirmed
+// by consensus and the latest finalized block, in blocks and in consensus
+// time, along with its rolling average over the latest deliveries.
+func (b *DexAPIBackend) RecentFinalizationDepth() *FinalizationDepth {
+	current, recent := b.dex.app.recentFinalizationDepth()
+	result := &FinalizationDepth{
+		Head:      hexutil.Uint64(current.finalized + current.blocks),
+		Finalized: hexutil.Uint64(current.finalized),
+		Blocks:    hexutil.Uint64(current.blocks),
+		Seconds:   current.span.Seconds(),
+		Samples:   len(recent),
+	}
+	if len(recent) == 0 {
+		return result
+	}
+	var blocks uint64
+	var span time.Duration
+	for _, depth := range recent {
+		blocks += depth.blocks
+		span += depth.span
+	}
+	result.AverageBlocks = float64(blocks) / float64(len(recent))
+	result.AverageSeconds = span.Seconds() / float64(len(recent))
+	return result
+}
+
+// isPoolPending reports whether the transaction of the given hash is
+// executable in the transaction pool.
+func (b *DexAPIBackend) isPoolPending(hash common.Hash) bool {
+	tx := b.dex.txPool.Get(hash)
+	if tx == nil {
+		return false
+	}
+	pending, err := b.dex.txPool.Pending()
+	if err != nil {
+		return false
+	}
+	from, err := types.Sender(types.NewEIP155Signer(b.dex.chainConfig.ChainID), tx)
+	if err != nil {
+		return false
+	}
+	for _, ptx := range pending[from] {
+		if ptx.Hash() == hash {
+			return true
+		}
+	}
+	return false
+}
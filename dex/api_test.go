@@ -0,0 +1,2348 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	dexCore "github.com/dexon-foundation/dexon-consensus/core"
+	coreCrypto "github.com/dexon-foundation/dexon-consensus/core/crypto"
+	cryptoDKG "github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
+	coreEcdsa "github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/common/hexutil"
+	"github.com/dexon-foundation/dexon/consensus/dexcon"
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/crypto"
+	dexDB "github.com/dexon-foundation/dexon/dex/db"
+	"github.com/dexon-foundation/dexon/dex/downloader"
+	"github.com/dexon-foundation/dexon/eth/gasprice"
+	"github.com/dexon-foundation/dexon/params"
+	"github.com/dexon-foundation/dexon/rlp"
+	"github.com/dexon-foundation/dexon/rpc"
+)
+
+func TestGetBlockByProposer(t *testing.T) {
+	var keys []*ecdsa.PrivateKey
+	for i := 0; i < 3; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		keys = append(keys, key)
+	}
+	dex, err := newTestDexon(keys...)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+
+	// Proposers take turns, the target proposes blocks 1, 4, 7 and 10.
+	target := crypto.PubkeyToAddress(keys[0].PublicKey)
+	var expected []common.Hash
+	for i := 0; i < 10; i++ {
+		block, err := deliverTestBlock(dex, keys[i%len(keys)], 0, nil)
+		if err != nil {
+			t.Fatalf("failed to deliver block: %v", err)
+		}
+		if i%len(keys) == 0 {
+			expected = append(expected, block.Hash())
+		}
+	}
+
+	api := NewPublicDexonAPI(dex)
+	fields, err := api.GetBlockByProposer(context.Background(), target, 3, nil)
+	if err != nil {
+		t.Fatalf("failed to get blocks by proposer: %v", err)
+	}
+	if len(fields) != 3 {
+		t.Fatalf("block count mismatch: have %d, want %d", len(fields), 3)
+	}
+	for i, field := range fields {
+		if hash := field["hash"].(common.Hash); hash != expected[i+1] {
+			t.Errorf("block %d hash mismatch: have %x, want %x", i, hash, expected[i+1])
+		}
+		if miner := field["miner"].(common.Address); miner != target {
+			t.Errorf("block %d proposer mismatch: have %x, want %x", i, miner, target)
+		}
+	}
+
+	// Asking for more blocks than proposed returns everything available.
+	fields, err = api.GetBlockByProposer(context.Background(), target, 10, nil)
+	if err != nil {
+		t.Fatalf("failed to get blocks by proposer: %v", err)
+	}
+	if len(fields) != len(expected) {
+		t.Errorf("block count mismatch: have %d, want %d", len(fields), len(expected))
+	}
+
+	if _, err := api.GetBlockByProposer(context.Background(), target, 0, nil); err == nil {
+		t.Error("expect error for zero block count")
+	}
+
+	// A lookup walking back too far fails, telling where to resume it.
+	dex.config.MaxProposerScanDepth = 5
+	_, err = api.GetBlockByProposer(context.Background(), target, 4, nil)
+	limitErr, ok := err.(*ErrScanLimitReached)
+	if !ok {
+		t.Fatalf("scan limit error mismatch: have %v", err)
+	}
+	if limitErr.Next != 6 {
+		t.Fatalf("resume block mismatch: have %d, want %d", limitErr.Next, 6)
+	}
+	next := hexutil.Uint64(limitErr.Next)
+	fields, err = api.GetBlockByProposer(context.Background(), target, 2, &next)
+	if err != nil {
+		t.Fatalf("failed to resume lookup: %v", err)
+	}
+	if len(fields) != 2 || fields[0]["hash"].(common.Hash) != expected[0] ||
+		fields[1]["hash"].(common.Hash) != expected[1] {
+		t.Errorf("resumed lookup mismatch: have %d blocks", len(fields))
+	}
+
+	// Without a limit the lookup walks back as far as needed.
+	dex.config.MaxProposerScanDepth = 0
+	fields, err = api.GetBlockByProposer(context.Background(), target, 4, nil)
+	if err != nil {
+		t.Fatalf("failed to get blocks by proposer: %v", err)
+	}
+	if len(fields) != 4 {
+		t.Errorf("block count mismatch: have %d, want %d", len(fields), 4)
+	}
+}
+
+func TestGetConfigDiff(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+
+	api := NewPublicDexonAPI(dex)
+	if diffs := api.GetConfigDiff(); len(diffs) != 0 {
+		t.Fatalf("unexpected config diff: %v", diffs)
+	}
+
+	// The owner overrides lambdaBA through governance.
+	config := dex.governance.GetHeadState().Configuration()
+	lambdaBA := config.LambdaBA * 2
+	tx, err := newTestUpdateLambdaBATx(dex, key, 0, lambdaBA)
+	if err != nil {
+		t.Fatalf("failed to create tx: %v", err)
+	}
+	if _, err := deliverTestBlock(dex, key, 0, types.Transactions{tx}); err != nil {
+		t.Fatalf("failed to deliver block: %v", err)
+	}
+
+	var found bool
+	for _, diff := range api.GetConfigDiff() {
+		if diff.Field != "lambdaBA" {
+			continue
+		}
+		found = true
+		if diff.Genesis.(uint64) != config.LambdaBA || diff.Current.(uint64) != config.LambdaBA {
+			t.Errorf("effective lambdaBA changed before round shift: %v", diff)
+		}
+		if pending, ok := diff.Pending.(uint64); !ok || pending != lambdaBA {
+			t.Errorf("pending lambdaBA mismatch: have %v, want %d", diff.Pending, lambdaBA)
+		}
+	}
+	if !found {
+		t.Error("lambdaBA override not reported")
+	}
+}
+
+// newTestUpdateLambdaBATx creates a governance transaction of the owner key
+// updating lambdaBA, leaving other configurations unchanged.
+func newTestUpdateLambdaBATx(dex *Dexon, owner *ecdsa.PrivateKey, nonce uint64,
+	lambdaBA uint64) (*types.Transaction, error) {
+	config := dex.governance.GetHeadState().Configuration()
+	input, err := vm.GovernanceABI.ABI.Pack("updateConfiguration",
+		config.MinStake,
+		new(big.Int).SetUint64(config.LockupPeriod),
+		config.MinGasPrice,
+		new(big.Int).SetUint64(config.BlockGasLimit),
+		new(big.Int).SetUint64(lambdaBA),
+		new(big.Int).SetUint64(config.LambdaDKG),
+		big.NewInt(int64(config.NotaryParamAlpha*100000000)),
+		big.NewInt(int64(config.NotaryParamBeta*100000000)),
+		new(big.Int).SetUint64(config.RoundLength),
+		new(big.Int).SetUint64(config.MinBlockInterval),
+		config.FineValues)
+	if err != nil {
+		return nil, err
+	}
+	tx := types.NewTransaction(nonce, vm.GovernanceContractAddress, big.NewInt(0),
+		1000000, config.MinGasPrice, input)
+	return types.SignTx(tx, types.NewEIP155Signer(dex.chainConfig.ChainID), owner)
+}
+
+// fixedDKGSetFetcher serves a fixed DKG set to the consensus engine, so the
+// chain enters the rounds after the DKG delay without running DKG.
+type fixedDKGSetFetcher struct {
+	*DexconGovernance
+	set map[common.Address]struct{}
+}
+
+func (f *fixedDKGSetFetcher) DKGSetNodeKeyAddresses(round uint64) (map[common.Address]struct{}, error) {
+	return f.set, nil
+}
+
+func TestChainConfigAt(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	dex.engine.(*dexcon.Dexcon).SetGovStateFetcher(&fixedDKGSetFetcher{
+		DexconGovernance: dex.governance,
+		set:              map[common.Address]struct{}{crypto.PubkeyToAddress(key.PublicKey): {}},
+	})
+	genesis := dex.governance.GetHeadState().Configuration()
+	lambdaBA := genesis.LambdaBA * 2
+
+	// Block 1 is before the change, the change is in block 2 and block 3
+	// begins round 1, the following blocks begin the next rounds.
+	if _, err := deliverTestBlock(dex, key, 0, nil); err != nil {
+		t.Fatalf("failed to deliver block: %v", err)
+	}
+	tx, err := newTestUpdateLambdaBATx(dex, key, 0, lambdaBA)
+	if err != nil {
+		t.Fatalf("failed to create tx: %v", err)
+	}
+	if _, err := deliverTestBlock(dex, key, 0, types.Transactions{tx}); err != nil {
+		t.Fatalf("failed to deliver block: %v", err)
+	}
+	for round := uint64(1); round <= 1+dexCore.ConfigRoundShift; round++ {
+		if _, err := deliverTestBlock(dex, key, round, nil); err != nil {
+			t.Fatalf("failed to deliver block: %v", err)
+		}
+	}
+	if have := dex.governance.GetHeadState().Configuration().LambdaBA; have != lambdaBA {
+		t.Fatalf("lambdaBA not updated: have %d, want %d", have, lambdaBA)
+	}
+
+	// The update is captured at the beginning of round 1 and isn't in effect
+	// before round 1 + ConfigRoundShift.
+	api := NewPublicDexonAPI(dex)
+	head := dex.blockchain.CurrentBlock().NumberU64()
+	for number := uint64(0); number <= head; number++ {
+		config, err := api.ChainConfigAt(hexutil.Uint64(number))
+		if err != nil {
+			t.Fatalf("failed to get config of block %d: %v", number, err)
+		}
+		want := genesis.LambdaBA
+		if round := dex.blockchain.GetHeaderByNumber(number).Round; round >= 1+dexCore.ConfigRoundShift {
+			want = lambdaBA
+		}
+		if config.LambdaBA != want {
+			t.Errorf("block %d: lambdaBA mismatch: have %d, want %d", number, config.LambdaBA, want)
+		}
+	}
+
+	_, err = api.ChainConfigAt(hexutil.Uint64(head + 1))
+	if _, ok := err.(*ErrBlockNotFinalized); !ok {
+		t.Errorf("unexpected error for future block: %v", err)
+	}
+}
+
+func TestGetLogsByRound(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+
+	// Each round has a single configuration change emitting a log, round 1
+	// is still in progress.
+	var (
+		lambdaBA = dex.governance.GetHeadState().Configuration().LambdaBA
+		expected []common.Hash
+	)
+	for i, round := range []uint64{0, 0, 1, 1} {
+		var txs types.Transactions
+		if i%2 == 0 {
+			tx, err := newTestUpdateLambdaBATx(dex, key, uint64(i/2), lambdaBA+uint64(i))
+			if err != nil {
+				t.Fatalf("failed to create tx: %v", err)
+			}
+			txs = append(txs, tx)
+			expected = append(expected, tx.Hash())
+		}
+		if _, err := deliverTestBlock(dex, key, round, txs); err != nil {
+			t.Fatalf("failed to deliver block: %v", err)
+		}
+	}
+
+	api := NewPublicDexonAPI(dex)
+	for round, hash := range expected {
+		logs, err := api.GetLogsByRound(context.Background(), hexutil.Uint64(round),
+			[]common.Address{vm.GovernanceContractAddress}, nil)
+		if err != nil {
+			t.Fatalf("failed to get logs of round %d: %v", round, err)
+		}
+		if len(logs) != 1 {
+			t.Fatalf("round %d log count mismatch: have %d, want %d", round, len(logs), 1)
+		}
+		if logs[0].TxHash != hash {
+			t.Errorf("round %d log tx mismatch: have %x, want %x", round, logs[0].TxHash, hash)
+		}
+	}
+
+	if _, err := api.GetLogsByRound(context.Background(), 2, nil, nil); err == nil {
+		t.Error("expect error for round not started")
+	}
+}
+
+func TestRoundBlockRange(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	for _, round := range []uint64{0, 0, 1, 1, 1} {
+		if _, err := deliverTestBlock(dex, key, round, nil); err != nil {
+			t.Fatalf("failed to deliver block: %v", err)
+		}
+	}
+
+	api := NewPublicDexonAPI(dex)
+	tests := []struct {
+		round      uint64
+		begin, end uint64
+		complete   bool
+	}{
+		// Round 0 begins with the genesis block.
+		{0, 0, 2, true},
+		// The round in progress ends at the head.
+		{1, 3, 5, false},
+	}
+	for _, test := range tests {
+		r, err := api.RoundBlockRange(hexutil.Uint64(test.round))
+		if err != nil {
+			t.Fatalf("round %d: failed to get range: %v", test.round, err)
+		}
+		want := &RPCRoundBlockRange{
+			Round:    hexutil.Uint64(test.round),
+			Begin:    hexutil.Uint64(test.begin),
+			End:      hexutil.Uint64(test.end),
+			Complete: test.complete,
+		}
+		if !reflect.DeepEqual(r, want) {
+			t.Errorf("round %d: range mismatch: have %+v, want %+v", test.round, r, want)
+		}
+	}
+
+	if _, err := api.RoundBlockRange(2); err == nil {
+		t.Error("expect error for round not started")
+	}
+}
+
+func TestNewRoundSubscription(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("dex", NewPublicDexonAPI(dex)); err != nil {
+		t.Fatalf("failed to register api: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	ch := make(chan RPCRound)
+	sub, err := client.Subscribe(context.Background(), "dex", ch, "newRound")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	expect := func(round uint64) {
+		select {
+		case ev := <-ch:
+			if uint64(ev.Round) != round {
+				t.Fatalf("round mismatch: have %d, want %d", ev.Round, round)
+			}
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(time.Second):
+			t.Fatalf("round %d not notified", round)
+		}
+	}
+
+	// The current round is sent on subscription.
+	expect(0)
+
+	for round := uint64(1); round <= 2; round++ {
+		header := &types.Header{Number: new(big.Int).SetUint64(round), Round: round}
+		dex.roundNotifier.onHead(types.NewBlockWithHeader(header))
+
+		// Heads within the same round do not advance the round.
+		header = &types.Header{Number: new(big.Int).SetUint64(round + 1), Round: round}
+		dex.roundNotifier.onHead(types.NewBlockWithHeader(header))
+
+		expect(round)
+	}
+
+	select {
+	case ev := <-ch:
+		t.Errorf("unexpected notification: %+v", ev)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestRoundLatencies(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	api := NewPublicDexonAPI(dex)
+
+	if latencies := api.RoundLatencies(nil); len(latencies) != 0 {
+		t.Fatalf("unexpected latencies before any round completed: %v", latencies)
+	}
+
+	// Round r lasts r seconds, with a block in the middle of each round.
+	begin := dex.blockchain.CurrentBlock().Time()
+	number := uint64(1)
+	onHead := func(round, time uint64) {
+		header := &types.Header{
+			Number: new(big.Int).SetUint64(number),
+			Round:  round,
+			Time:   time,
+		}
+		dex.roundNotifier.onHead(types.NewBlockWithHeader(header))
+		number++
+	}
+	for round := uint64(0); round < 2; round++ {
+		interval := (round + 1) * 1000
+		onHead(round, begin+interval/2)
+		begin += interval
+		onHead(round+1, begin)
+	}
+
+	latencies := api.RoundLatencies(nil)
+	if len(latencies) != 2 {
+		t.Fatalf("latency count mismatch: have %d, want %d", len(latencies), 2)
+	}
+	for i, l := range latencies {
+		if uint64(l.Round) != uint64(i) {
+			t.Errorf("round mismatch: have %d, want %d", l.Round, i)
+		}
+		if want := uint64(i+1) * 1000; uint64(l.Latency) != want {
+			t.Errorf("round %d latency mismatch: have %d, want %d", i, l.Latency, want)
+		}
+		if uint64(l.EndTime)-uint64(l.BeginTime) != uint64(l.Latency) {
+			t.Errorf("round %d latency inconsistent with begin and end: %+v", i, l)
+		}
+	}
+
+	count := hexutil.Uint64(1)
+	latencies = api.RoundLatencies(&count)
+	if len(latencies) != 1 || latencies[0].Round != 1 {
+		t.Errorf("latest latencies mismatch: %+v", latencies)
+	}
+}
+
+func TestCRS(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("dex", NewPublicDexonAPI(dex)); err != nil {
+		t.Fatalf("failed to register api: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	// The CRS of the genesis round is derived from the genesis config.
+	genesisCRS := crypto.Keccak256([]byte(dex.chainConfig.Dexcon.GenesisCRSText))
+	var raw json.RawMessage
+	if err := client.Call(&raw, "dex_crs", hexutil.Uint64(0)); err != nil {
+		t.Fatalf("failed to get CRS: %v", err)
+	}
+	if expected := `"` + hexutil.Encode(genesisCRS) + `"`; string(raw) != expected {
+		t.Errorf("genesis CRS mismatch: have %s, want %s", raw, expected)
+	}
+
+	var crs hexutil.Bytes
+	if err := client.Call(&crs, "dex_crs", hexutil.Uint64(1)); err != nil {
+		t.Fatalf("failed to get CRS: %v", err)
+	}
+	if expected := crypto.Keccak256(genesisCRS); !bytes.Equal(crs, expected) {
+		t.Errorf("round 1 CRS mismatch: have %x, want %x", crs, expected)
+	}
+
+	if err := client.Call(&crs, "dex_crs", hexutil.Uint64(5)); err == nil {
+		t.Error("expect error for CRS not proposed")
+	}
+}
+
+func TestRoundRewards(t *testing.T) {
+	var keys []*ecdsa.PrivateKey
+	for i := 0; i < 2; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		keys = append(keys, key)
+	}
+	dex, err := newTestDexon(keys...)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+
+	// Round 1 begins at height 3 and is still in progress.
+	var (
+		validator = crypto.PubkeyToAddress(keys[0].PublicKey)
+		expected  = []*big.Int{new(big.Int), new(big.Int)}
+		blocks    = []uint64{0, 0}
+	)
+	for i, round := range []uint64{0, 0, 1, 1, 1} {
+		block, err := deliverTestBlock(dex, keys[i%len(keys)], round, nil)
+		if err != nil {
+			t.Fatalf("failed to deliver block: %v", err)
+		}
+		if block.Coinbase() == validator {
+			expected[round].Add(expected[round], block.Reward())
+			blocks[round]++
+		}
+	}
+
+	api := NewPublicDexonAPI(dex)
+	for round := range expected {
+		rewards, err := api.RoundRewards(context.Background(), hexutil.Uint64(round), validator)
+		if err != nil {
+			t.Fatalf("failed to get rewards of round %d: %v", round, err)
+		}
+		if rewards.Reward.ToInt().Sign() == 0 {
+			t.Errorf("round %d: no reward credited", round)
+		}
+		if rewards.Reward.ToInt().Cmp(expected[round]) != 0 {
+			t.Errorf("round %d reward mismatch: have %v, want %v", round, rewards.Reward, expected[round])
+		}
+		if uint64(rewards.Blocks) != blocks[round] {
+			t.Errorf("round %d block count mismatch: have %d, want %d", round, rewards.Blocks, blocks[round])
+		}
+	}
+
+	if _, err := api.RoundRewards(context.Background(), 2, validator); err == nil {
+		t.Error("expect error for round not started")
+	}
+}
+
+func TestRoundStorageStats(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+
+	// Round 0 creates an account by a transfer, and a contract setting two
+	// storage slots by its init code:
+	// PUSH1 1 PUSH1 0 SSTORE PUSH1 2 PUSH1 1 SSTORE
+	signer := types.NewEIP155Signer(dex.chainConfig.ChainID)
+	gasPrice := dex.governance.GetHeadState().MinGasPrice()
+	transfer, err := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(1),
+		params.TxGas, gasPrice, nil), signer, key)
+	if err != nil {
+		t.Fatalf("failed to sign tx: %v", err)
+	}
+	create, err := types.SignTx(types.NewContractCreation(1, big.NewInt(0), 100000, gasPrice,
+		common.FromHex("0x60016000556002600155")), signer, key)
+	if err != nil {
+		t.Fatalf("failed to sign tx: %v", err)
+	}
+	if _, err := deliverTestBlock(dex, key, 0, types.Transactions{transfer, create}); err != nil {
+		t.Fatalf("failed to deliver block: %v", err)
+	}
+	for _, round := range []uint64{0, 1} {
+		if _, err := deliverTestBlock(dex, key, round, nil); err != nil {
+			t.Fatalf("failed to deliver block: %v", err)
+		}
+	}
+
+	// Each block also sets the total supply and the last proposed height of
+	// the proposer in governance, and round 1 records its height.
+	api := NewPublicDexonAPI(dex)
+	for _, test := range []struct {
+		round       uint64
+		first, last uint64
+		accounts    uint64
+		slots       uint64
+	}{
+		{round: 0, first: 0, last: 2, accounts: 2, slots: 2 + 2},
+		{round: 1, first: 3, last: 3, accounts: 0, slots: 2 + 2},
+	} {
+		stats, err := api.RoundStorageStats(context.Background(), hexutil.Uint64(test.round))
+		if err != nil {
+			t.Fatalf("failed to get stats of round %d: %v", test.round, err)
+		}
+		if uint64(stats.FirstBlock) != test.first || uint64(stats.LastBlock) != test.last {
+			t.Errorf("round %d: block range mismatch: have %d-%d, want %d-%d",
+				test.round, stats.FirstBlock, stats.LastBlock, test.first, test.last)
+		}
+		if uint64(stats.NewAccounts) != test.accounts || stats.DeletedAccounts != 0 {
+			t.Errorf("round %d: account count mismatch: have %d new %d deleted, want %d new",
+				test.round, stats.NewAccounts, stats.DeletedAccounts, test.accounts)
+		}
+		if uint64(stats.StorageSlots) != test.slots {
+			t.Errorf("round %d: storage slot count mismatch: have %d, want %d",
+				test.round, stats.StorageSlots, test.slots)
+		}
+		if stats.SizeDelta <= 0 {
+			t.Errorf("round %d: state growth not reported: %d", test.round, stats.SizeDelta)
+		}
+	}
+
+	_, err = api.RoundStorageStats(context.Background(), 2)
+	if _, ok := err.(*ErrRoundNotReached); !ok {
+		t.Errorf("unexpected error for round not started: %v", err)
+	}
+}
+
+func TestProposedBlocks(t *testing.T) {
+	var keys []*ecdsa.PrivateKey
+	for i := 0; i < 2; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		keys = append(keys, key)
+	}
+	dex, err := newTestDexon(keys...)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+
+	// Blocks are proposed in turns, with an empty block in round 1.
+	proposed := map[common.Address][][]common.Hash{}
+	for i, round := range []uint64{0, 0, 0, 1, 1} {
+		proposer := keys[i%len(keys)]
+		if i == 4 {
+			proposer = nil
+		}
+		block, err := deliverTestBlock(dex, proposer, round, nil)
+		if err != nil {
+			t.Fatalf("failed to deliver block: %v", err)
+		}
+		if proposer != nil {
+			addr := crypto.PubkeyToAddress(proposer.PublicKey)
+			if proposed[addr] == nil {
+				proposed[addr] = make([][]common.Hash, 2)
+			}
+			proposed[addr][round] = append(proposed[addr][round], block.Hash())
+		}
+	}
+	// A block of round 1 confirmed but not delivered yet.
+	pending, err := newTestCoreBlock(dex, keys[0], 1, nil)
+	if err != nil {
+		t.Fatalf("failed to create block: %v", err)
+	}
+	dex.app.BlockConfirmed(*pending)
+
+	api := NewPublicDexonAPI(dex)
+	for i, key := range keys {
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		for round := uint64(0); round < 2; round++ {
+			blocks, err := api.ProposedBlocks(context.Background(), hexutil.Uint64(round), addr)
+			if err != nil {
+				t.Fatalf("failed to get proposed blocks of round %d: %v", round, err)
+			}
+			want := proposed[addr][round]
+			finalized := 0
+			for _, block := range blocks {
+				if uint64(block.Round) != round {
+					t.Errorf("proposer %d round %d: block of round %d", i, round, block.Round)
+				}
+				if !block.Finalized {
+					continue
+				}
+				if finalized >= len(want) || block.Hash != want[finalized] {
+					t.Errorf("proposer %d round %d: unexpected block %x", i, round, block.Hash)
+				} else if header := dex.blockchain.GetHeaderByHash(block.Hash); header.Coinbase != addr {
+					t.Errorf("proposer %d round %d: block %x proposed by %x", i, round, block.Hash, header.Coinbase)
+				}
+				finalized++
+			}
+			if finalized != len(want) {
+				t.Errorf("proposer %d round %d: finalized block count mismatch: have %d, want %d",
+					i, round, finalized, len(want))
+			}
+			// Only the first proposer has a block of round 1 in progress.
+			wantPending := 0
+			if i == 0 && round == 1 {
+				wantPending = 1
+			}
+			if n := len(blocks) - finalized; n != wantPending {
+				t.Errorf("proposer %d round %d: pending block count mismatch: have %d, want %d",
+					i, round, n, wantPending)
+			} else if n == 1 && blocks[len(blocks)-1].CoreHash != common.Hash(pending.Hash) {
+				t.Errorf("proposer %d round %d: pending block mismatch: have %x, want %x",
+					i, round, blocks[len(blocks)-1].CoreHash, pending.Hash)
+			}
+		}
+	}
+
+	if _, err := api.ProposedBlocks(context.Background(), 2, crypto.PubkeyToAddress(keys[0].PublicKey)); err == nil {
+		t.Error("expect error for round not started")
+	}
+}
+
+func TestValidatorStatus(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	dex.network = &DexconNetwork{}
+	api := NewPublicDexonAPI(dex)
+
+	// Observers are not validators.
+	status, err := api.ValidatorStatus()
+	if err != nil {
+		t.Fatalf("failed to get validator status: %v", err)
+	}
+	if status.IsValidator || status.InCurrentNotarySet || status.LastVotedRound != nil {
+		t.Errorf("observer status mismatch: %+v", status)
+	}
+
+	// The notary set is sampled from the nodes of the testnet genesis as well,
+	// pick a member and a non-member to act as.
+	notarySet, err := dex.governance.NotarySet(0)
+	if err != nil {
+		t.Fatalf("failed to get notary set: %v", err)
+	}
+	var member, nonMember *ecdsa.PrivateKey
+	for _, pk := range dex.governance.NodeSet(0) {
+		pub, err := crypto.UnmarshalPubkey(pk.Bytes())
+		if err != nil {
+			t.Fatalf("failed to unmarshal public key: %v", err)
+		}
+		if _, ok := notarySet[hex.EncodeToString(pk.Bytes())]; ok {
+			member = &ecdsa.PrivateKey{PublicKey: *pub}
+		} else {
+			nonMember = &ecdsa.PrivateKey{PublicKey: *pub}
+		}
+	}
+	if member == nil || nonMember == nil {
+		t.Fatal("failed to find notary set member and non-member")
+	}
+
+	dex.config.BlockProposerEnabled = true
+	dex.config.PrivateKey = member
+	if status, err = api.ValidatorStatus(); err != nil {
+		t.Fatalf("failed to get validator status: %v", err)
+	}
+	if !status.IsValidator || !status.InCurrentNotarySet || status.LastVotedRound != nil {
+		t.Errorf("in-set validator status mismatch: %+v", status)
+	}
+	dex.network.markVoted(0)
+	if status, err = api.ValidatorStatus(); err != nil {
+		t.Fatalf("failed to get validator status: %v", err)
+	}
+	if status.LastVotedRound == nil || *status.LastVotedRound != 0 {
+		t.Errorf("last voted round mismatch: %v", status.LastVotedRound)
+	}
+
+	dex.config.PrivateKey = nonMember
+	if status, err = api.ValidatorStatus(); err != nil {
+		t.Fatalf("failed to get validator status: %v", err)
+	}
+	if !status.IsValidator || status.InCurrentNotarySet {
+		t.Errorf("out-of-set validator status mismatch: %+v", status)
+	}
+}
+
+func TestPendingAndCancelTransaction(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	remoteKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key, remoteKey)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	api := NewPrivateDexonAPI(dex)
+
+	signer := types.NewEIP155Signer(dex.chainConfig.ChainID)
+	gasPrice := dex.governance.GetHeadState().Configuration().MinGasPrice
+	to := common.HexToAddress("0x1234")
+	newTx := func(key *ecdsa.PrivateKey, nonce uint64) *types.Transaction {
+		tx := types.NewTransaction(nonce, to, big.NewInt(1), params.TxGas, gasPrice, nil)
+		tx, err := types.SignTx(tx, signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		return tx
+	}
+
+	finalized := newTx(key, 0)
+	if _, err := deliverTestBlock(dex, key, 0, types.Transactions{finalized}); err != nil {
+		t.Fatalf("failed to deliver block: %v", err)
+	}
+	local := newTx(key, 1)
+	if err := dex.txPool.AddLocal(local); err != nil {
+		t.Fatalf("failed to add local tx: %v", err)
+	}
+	if err := dex.txPool.AddRemote(newTx(remoteKey, 0)); err != nil {
+		t.Fatalf("failed to add remote tx: %v", err)
+	}
+	for i := 0; ; i++ {
+		if pending, _ := dex.txPool.Stats(); pending == 2 {
+			break
+		}
+		if i == 100 {
+			t.Fatal("transactions not promoted to pending")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	txs, err := api.PendingTransactions()
+	if err != nil {
+		t.Fatalf("failed to get pending transactions: %v", err)
+	}
+	if len(txs) != 1 || txs[0].Hash != local.Hash() {
+		t.Fatalf("pending transactions mismatch: %+v", txs)
+	}
+	if txs[0].EstimatedRound != 0 {
+		t.Errorf("estimated round mismatch: have %d, want 0", txs[0].EstimatedRound)
+	}
+
+	if _, err := api.CancelTransaction(context.Background(), finalized.Hash()); err == nil {
+		t.Error("cancelling a finalized transaction should fail")
+	}
+	if _, err := api.CancelTransaction(context.Background(), common.Hash{1}); err == nil {
+		t.Error("cancelling an unknown transaction should fail")
+	}
+
+	hash, err := api.CancelTransaction(context.Background(), local.Hash())
+	if err != nil {
+		t.Fatalf("failed to cancel transaction: %v", err)
+	}
+	if dex.txPool.Get(local.Hash()) != nil {
+		t.Error("cancelled transaction still in pool")
+	}
+	cancel := dex.txPool.Get(hash)
+	if cancel == nil {
+		t.Fatal("replacement transaction not in pool")
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	if cancel.Nonce() != local.Nonce() || *cancel.To() != from || cancel.Value().Sign() != 0 {
+		t.Errorf("replacement transaction mismatch: nonce %d, to %x, value %v",
+			cancel.Nonce(), cancel.To(), cancel.Value())
+	}
+	if cancel.GasPrice().Cmp(local.GasPrice()) <= 0 {
+		t.Errorf("replacement gas price not raised: have %v, old %v",
+			cancel.GasPrice(), local.GasPrice())
+	}
+}
+
+func TestTxPoolContentByRound(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+
+	signer := types.NewEIP155Signer(dex.chainConfig.ChainID)
+	gasPrice := dex.governance.GetHeadState().Configuration().MinGasPrice
+	to := common.HexToAddress("0x1234")
+	var txs types.Transactions
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		tx := types.NewTransaction(nonce, to, big.NewInt(1), params.TxGas, gasPrice, nil)
+		tx, err := types.SignTx(tx, signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		txs = append(txs, tx)
+	}
+	for _, err := range dex.txPool.AddRemotes(txs) {
+		if err != nil {
+			t.Fatalf("failed to add tx: %v", err)
+		}
+	}
+	for i := 0; ; i++ {
+		if pending, _ := dex.txPool.Stats(); pending == len(txs) {
+			break
+		}
+		if i == 100 {
+			t.Fatal("transactions not promoted to pending")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	rounds, unschedulable, err := dex.APIBackend.TxPoolContentByRound()
+	if err != nil {
+		t.Fatalf("failed to get txpool content: %v", err)
+	}
+	if len(rounds) != 1 || len(rounds[0]) != len(txs) || len(unschedulable) != 0 {
+		t.Fatalf("txpool content mismatch: rounds %v, unschedulable %v", rounds, unschedulable)
+	}
+	for i, tx := range rounds[0] {
+		if tx.Hash() != txs[i].Hash() {
+			t.Errorf("tx %d mismatch: have %x, want %x", i, tx.Hash(), txs[i].Hash())
+		}
+	}
+}
+
+func TestScheduleTxsByRound(t *testing.T) {
+	signer := types.NewEIP155Signer(big.NewInt(1))
+	config := &params.DexconConfig{
+		MinGasPrice:   big.NewInt(10),
+		BlockGasLimit: 2 * params.TxGas,
+		RoundLength:   2,
+	}
+	to := common.HexToAddress("0x1234")
+	pending := make(map[common.Address]types.Transactions)
+	newTxs := func(n int, gas uint64, gasPrice int64) types.Transactions {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		var txs types.Transactions
+		for nonce := 0; nonce < n; nonce++ {
+			tx := types.NewTransaction(uint64(nonce), to, big.NewInt(1), gas, big.NewInt(gasPrice), nil)
+			tx, err := types.SignTx(tx, signer, key)
+			if err != nil {
+				t.Fatalf("failed to sign tx: %v", err)
+			}
+			txs = append(txs, tx)
+		}
+		pending[crypto.PubkeyToAddress(key.PublicKey)] = txs
+		return txs
+	}
+	scheduled := newTxs(5, params.TxGas, 10)
+	oversized := newTxs(1, 3*params.TxGas, 20)
+	cheap := newTxs(2, params.TxGas, 1)
+
+	// Start from the first block of round 3, two transactions fit a block.
+	rounds, unschedulable := scheduleTxsByRound(pending, signer, config, 3, 6, 6)
+	want := map[uint64]types.Transactions{
+		3: scheduled[:4],
+		4: scheduled[4:],
+	}
+	if len(rounds) != len(want) {
+		t.Fatalf("round count mismatch: have %d, want %d", len(rounds), len(want))
+	}
+	for round, txs := range want {
+		if len(rounds[round]) != len(txs) {
+			t.Errorf("round %d tx count mismatch: have %d, want %d", round, len(rounds[round]), len(txs))
+			continue
+		}
+		for i, tx := range txs {
+			if rounds[round][i].Hash() != tx.Hash() {
+				t.Errorf("round %d tx %d mismatch", round, i)
+			}
+		}
+	}
+
+	// The later nonce of the cheap sender is unschedulable as well.
+	wantUnschedulable := append(oversized, cheap...)
+	if len(unschedulable) != len(wantUnschedulable) {
+		t.Fatalf("unschedulable count mismatch: have %d, want %d", len(unschedulable), len(wantUnschedulable))
+	}
+	for i, tx := range wantUnschedulable {
+		if unschedulable[i].Hash() != tx.Hash() {
+			t.Errorf("unschedulable tx %d mismatch", i)
+		}
+	}
+}
+
+func TestDebugPendingAcks(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	api := NewPrivateDebugAPI(dex.chainConfig, dex)
+	if pending := api.PendingAcks(); len(pending) != 0 {
+		t.Fatalf("pending block count mismatch: have %d, want %d", len(pending), 0)
+	}
+
+	// Confirm two chained blocks without delivering them.
+	parent := coreTypes.Block{
+		Hash:     coreCommon.Hash{1},
+		Position: coreTypes.Position{Height: 1},
+	}
+	child := coreTypes.Block{
+		Hash:       coreCommon.Hash{2},
+		ParentHash: parent.Hash,
+		Position:   coreTypes.Position{Height: 2},
+	}
+	dex.app.BlockConfirmed(child)
+	dex.app.BlockConfirmed(parent)
+
+	pending := api.PendingAcks()
+	if len(pending) != 2 {
+		t.Fatalf("pending block count mismatch: have %d, want %d", len(pending), 2)
+	}
+	for i, want := range []struct {
+		hash      coreCommon.Hash
+		waitingOn string
+	}{
+		{parent.Hash, "delivery"},
+		{child.Hash, "parent"},
+	} {
+		if pending[i].Hash != common.Hash(want.hash) {
+			t.Errorf("block %d hash mismatch: have %x, want %x", i, pending[i].Hash, want.hash)
+		}
+		if pending[i].WaitingOn != want.waitingOn {
+			t.Errorf("block %d waiting on mismatch: have %s, want %s", i, pending[i].WaitingOn, want.waitingOn)
+		}
+		if pending[i].Age == "" {
+			t.Errorf("block %d age missing", i)
+		}
+	}
+}
+
+func TestDebugFinalizationPipeline(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+	dex.protocolManager = pm
+	api := NewPrivateDebugAPI(dex.chainConfig, dex)
+
+	head := dex.blockchain.CurrentBlock().NumberU64()
+	threshold := coreUtils.GetBAThreshold(dex.governance.Configuration(0))
+	if threshold < 3 {
+		t.Fatalf("threshold too low for the test: %d", threshold)
+	}
+
+	// Serve a notary set of known keys, the one sampled from the testnet
+	// genesis is of nodes with unknown keys.
+	notaries := make([]*ecdsa.PrivateKey, threshold)
+	stored := &rawdb.RoundNotarySet{
+		CRS:         common.Hash(dex.governance.CRS(0)),
+		NodeSetHash: dex.governance.nodeSetHash(0),
+	}
+	for i := range notaries {
+		if notaries[i], err = crypto.GenerateKey(); err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		stored.PublicKeys = append(stored.PublicKeys, crypto.FromECDSAPub(&notaries[i].PublicKey))
+	}
+	if err := rawdb.WriteRoundNotarySet(dex.chainDb, 0, stored); err != nil {
+		t.Fatalf("failed to write notary set: %v", err)
+	}
+	dex.governance = NewDexconGovernance(dex.APIBackend, dex.chainConfig,
+		crypto.PubkeyToAddress(key.PublicKey), keyTxSigner(key))
+	dex.governance.LoadRoundCache(0)
+	newVote := func(key *ecdsa.PrivateKey, voteType coreTypes.VoteType, hash coreCommon.Hash,
+		period uint64, pos coreTypes.Position) *coreTypes.Vote {
+		vote := coreTypes.NewVote(voteType, hash, period)
+		vote.Position = pos
+		signer := coreUtils.NewSigner(coreEcdsa.NewPrivateKeyFromECDSA(key))
+		if err := signer.SignVote(vote); err != nil {
+			t.Fatalf("failed to sign vote: %v", err)
+		}
+		return vote
+	}
+
+	// A block produced a minute ago gathering commit votes from some of the
+	// notary set only.
+	produced := &coreTypes.Block{
+		Hash:      coreCommon.Hash{1},
+		Position:  coreTypes.Position{Height: head + 2},
+		Timestamp: time.Now().Add(-time.Minute),
+	}
+	pm.cache.addBlock(produced)
+	for _, notary := range notaries[:threshold-2] {
+		for period := uint64(0); period < 2; period++ {
+			pm.cache.addVote(newVote(notary, coreTypes.VoteCom, produced.Hash, period, produced.Position))
+		}
+	}
+	// Neither pre-commit votes nor commit votes for another block count.
+	last := notaries[threshold-2]
+	pm.cache.addVote(newVote(last, coreTypes.VotePreCom, produced.Hash, 0, produced.Position))
+	pm.cache.addVote(newVote(last, coreTypes.VoteCom, coreCommon.Hash{2}, 0, produced.Position))
+	// Nor do votes out of the notary set or forged in the name of a notary.
+	outsider, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pm.cache.addVote(newVote(outsider, coreTypes.VoteCom, produced.Hash, 0, produced.Position))
+	forged := newVote(outsider, coreTypes.VoteCom, produced.Hash, 1, produced.Position)
+	forged.ProposerID = coreTypes.NewNodeID(coreEcdsa.NewPublicKeyFromECDSA(&last.PublicKey))
+	pm.cache.addVote(forged)
+	// A block agreed on, awaiting delivery.
+	confirmed := coreTypes.Block{
+		Hash:      coreCommon.Hash{3},
+		Position:  coreTypes.Position{Height: head + 1},
+		Timestamp: time.Now(),
+	}
+	dex.app.BlockConfirmed(confirmed)
+	// Blocks finalized already are left out.
+	pm.cache.addBlock(&coreTypes.Block{
+		Hash:     coreCommon.Hash{4},
+		Position: coreTypes.Position{Height: head},
+	})
+
+	pipeline := api.FinalizationPipeline()
+	if len(pipeline) != 2 {
+		t.Fatalf("pipeline length mismatch: have %d, want 2", len(pipeline))
+	}
+	if pipeline[0].Hash != common.Hash(confirmed.Hash) || !pipeline[0].Confirmed {
+		t.Errorf("confirmed block mismatch: %+v", pipeline[0])
+	}
+	block := pipeline[1]
+	if block.Hash != common.Hash(produced.Hash) || block.Confirmed {
+		t.Errorf("produced block mismatch: %+v", block)
+	}
+	if block.Votes != threshold-2 || block.Threshold != threshold {
+		t.Errorf("vote progress mismatch: have %d/%d, want %d/%d",
+			block.Votes, block.Threshold, threshold-2, threshold)
+	}
+	if !strings.HasPrefix(block.Age, "1m") {
+		t.Errorf("age mismatch: have %s, want about a minute", block.Age)
+	}
+}
+
+func TestDebugVerifyBlock(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	api := NewPrivateDebugAPI(dex.chainConfig, dex)
+
+	signer := types.NewEIP155Signer(dex.chainConfig.ChainID)
+	gasPrice := dex.governance.GetHeadState().Configuration().MinGasPrice
+	newTx := func(nonce uint64) *types.Transaction {
+		tx := types.NewTransaction(nonce, common.Address{1}, big.NewInt(1), params.TxGas, gasPrice, nil)
+		tx, err := types.SignTx(tx, signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		return tx
+	}
+
+	block, err := deliverTestBlock(dex, key, 0, types.Transactions{newTx(0)})
+	if err != nil {
+		t.Fatalf("failed to deliver block: %v", err)
+	}
+	result, err := api.VerifyBlock(block.Hash())
+	if err != nil {
+		t.Fatalf("failed to verify block: %v", err)
+	}
+	if !result.Valid {
+		t.Errorf("finalized block reported invalid: %+v", result.Checks)
+	}
+	for _, c := range result.Checks {
+		if !c.Passed {
+			t.Errorf("check %s not passed: %+v", c.Name, c)
+		}
+	}
+
+	// A core block reusing a nonce fails the nonce check, later checks are
+	// skipped and there is no chain block to re-execute.
+	head := dex.blockchain.CurrentBlock()
+	witness, err := rlp.EncodeToBytes(head.Hash())
+	if err != nil {
+		t.Fatalf("failed to encode witness: %v", err)
+	}
+	payload, err := rlp.EncodeToBytes(types.Transactions{newTx(0)})
+	if err != nil {
+		t.Fatalf("failed to encode payload: %v", err)
+	}
+	coreBlock := &coreTypes.Block{
+		Hash:     coreCommon.Hash{1},
+		Position: coreTypes.Position{Round: 0, Height: head.NumberU64() + 1},
+		Witness:  coreTypes.Witness{Height: head.NumberU64(), Data: witness},
+		Payload:  payload,
+	}
+	if err := rawdb.WriteCoreBlock(dex.chainDb, common.Hash(coreBlock.Hash), coreBlock); err != nil {
+		t.Fatalf("failed to write core block: %v", err)
+	}
+	if result, err = api.VerifyBlock(common.Hash(coreBlock.Hash)); err != nil {
+		t.Fatalf("failed to verify block: %v", err)
+	}
+	if result.Valid {
+		t.Error("block reusing a nonce reported valid")
+	}
+	var failedAt string
+	for _, c := range result.Checks {
+		switch {
+		case failedAt == "" && !c.Passed:
+			failedAt = c.Name
+			if c.Error == "" {
+				t.Errorf("failed check %s has no error", c.Name)
+			}
+		case failedAt != "" && !c.Skipped:
+			t.Errorf("check %s after failure not skipped: %+v", c.Name, c)
+		}
+	}
+	if failedAt != "nonce" {
+		t.Errorf("failed check mismatch: have %q, want %q", failedAt, "nonce")
+	}
+
+	if _, err := api.VerifyBlock(common.Hash{2}); err == nil {
+		t.Error("verifying an unknown block should fail")
+	}
+}
+
+func TestTraceFinalizedBlock(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	api := NewPrivateDebugAPI(dex.chainConfig, dex)
+
+	signer := types.NewEIP155Signer(dex.chainConfig.ChainID)
+	gasPrice := dex.governance.GetHeadState().Configuration().MinGasPrice
+	var txs types.Transactions
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		tx, err := types.SignTx(types.NewTransaction(nonce, common.Address{1},
+			big.NewInt(1), params.TxGas, gasPrice, nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		txs = append(txs, tx)
+	}
+	coreBlock, err := newTestCoreBlock(dex, key, 0, txs)
+	if err != nil {
+		t.Fatalf("failed to create block: %v", err)
+	}
+	dex.app.BlockConfirmed(*coreBlock)
+
+	// Blocks only confirmed are not traced.
+	if _, err := api.TraceFinalizedBlock(context.Background(), common.Hash(coreBlock.Hash), nil); err == nil {
+		t.Error("expect error for block not finalized")
+	}
+
+	dex.app.BlockDelivered(coreBlock.Hash, coreBlock.Position, coreBlock.Randomness)
+	block := dex.blockchain.CurrentBlock()
+	trace, err := api.TraceFinalizedBlock(context.Background(), block.Hash(), nil)
+	if err != nil {
+		t.Fatalf("failed to trace block: %v", err)
+	}
+	if trace.Hash != block.Hash() || uint64(trace.Number) != block.NumberU64() {
+		t.Errorf("block mismatch: have %x (%d), want %x (%d)", trace.Hash, trace.Number, block.Hash(), block.NumberU64())
+	}
+	if uint64(trace.Round) != coreBlock.Position.Round {
+		t.Errorf("round mismatch: have %d, want %d", trace.Round, coreBlock.Position.Round)
+	}
+	if want := uint64(coreBlock.Timestamp.UnixNano() / int64(time.Millisecond)); uint64(trace.Timestamp) != want {
+		t.Errorf("consensus timestamp mismatch: have %d, want %d", trace.Timestamp, want)
+	}
+	if want := crypto.PubkeyToAddress(key.PublicKey); trace.Proposer != want {
+		t.Errorf("proposer mismatch: have %x, want %x", trace.Proposer, want)
+	}
+	if trace.ProposerID != common.Hash(coreBlock.ProposerID.Hash) {
+		t.Errorf("proposer ID mismatch: have %x, want %x", trace.ProposerID, coreBlock.ProposerID.Hash)
+	}
+	if len(trace.Traces) != len(txs) {
+		t.Fatalf("trace count mismatch: have %d, want %d", len(trace.Traces), len(txs))
+	}
+	for i, result := range trace.Traces {
+		if result.Error != "" || result.Result == nil {
+			t.Errorf("tx %d: trace failed: %s", i, result.Error)
+		}
+	}
+
+	if _, err := api.TraceFinalizedBlock(context.Background(), dex.blockchain.Genesis().Hash(), nil); err == nil {
+		t.Error("expect error for block without consensus block")
+	}
+}
+
+func TestCompactionProvenance(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	api := NewPublicDexonAPI(dex)
+
+	if _, err := api.CompactionProvenance(0); err == nil {
+		t.Error("provenance of genesis block should not be available")
+	}
+	block, err := deliverTestBlock(dex, key, 0, nil)
+	if err != nil {
+		t.Fatalf("failed to deliver block: %v", err)
+	}
+	var coreBlock coreTypes.Block
+	if err := rlp.DecodeBytes(block.Header().DexconMeta, &coreBlock); err != nil {
+		t.Fatalf("failed to decode dexcon meta: %v", err)
+	}
+	provenance, err := api.CompactionProvenance(hexutil.Uint64(block.NumberU64()))
+	if err != nil {
+		t.Fatalf("failed to get provenance: %v", err)
+	}
+	if provenance.Hash != block.Hash() || len(provenance.Blocks) != 1 {
+		t.Fatalf("provenance mismatch: %+v", provenance)
+	}
+	proposer := coreTypes.NewNodeID(coreEcdsa.NewPrivateKeyFromECDSA(key).PublicKey())
+	if b := provenance.Blocks[0]; b.Hash != common.Hash(coreBlock.Hash) ||
+		b.ProposerID != common.Hash(proposer.Hash) ||
+		uint64(b.Height) != block.NumberU64() {
+		t.Errorf("provenance block mismatch: %+v", b)
+	}
+}
+
+func TestNotarySet(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	api := NewPublicDexonAPI(dex)
+
+	// Members are the nodes staked at genesis.
+	alloc := core.DefaultTestnetGenesisBlock().Alloc
+	alloc[crypto.PubkeyToAddress(key.PublicKey)] = core.GenesisAccount{
+		Staked:    big.NewInt(50000000000000000),
+		PublicKey: crypto.FromECDSAPub(&key.PublicKey),
+	}
+	notarySet, err := dex.governance.NotarySet(0)
+	if err != nil {
+		t.Fatalf("failed to get notary set: %v", err)
+	}
+	set, err := api.NotarySet(0)
+	if err != nil {
+		t.Fatalf("failed to get notary set: %v", err)
+	}
+	if len(set.Members) != len(notarySet) {
+		t.Fatalf("member count mismatch: have %d, want %d", len(set.Members), len(notarySet))
+	}
+	total := new(big.Int)
+	for i, m := range set.Members {
+		if _, ok := notarySet[hex.EncodeToString(m.PublicKey)]; !ok {
+			t.Errorf("member %x not in notary set", m.Address)
+		}
+		account, ok := alloc[m.Owner]
+		if !ok || !bytes.Equal(account.PublicKey, m.PublicKey) {
+			t.Fatalf("member %x not staked at genesis", m.Address)
+		}
+		if m.Stake.ToInt().Cmp(account.Staked) != 0 {
+			t.Errorf("member %x stake mismatch: have %v, want %v", m.Address, m.Stake, account.Staked)
+		}
+		if i > 0 && bytes.Compare(set.Members[i-1].Address[:], m.Address[:]) >= 0 {
+			t.Errorf("members not ordered by address")
+		}
+		total.Add(total, account.Staked)
+	}
+	if set.TotalStake.ToInt().Cmp(total) != 0 {
+		t.Errorf("total stake mismatch: have %v, want %v", set.TotalStake, total)
+	}
+
+	if _, err := api.NotarySet(100); err == nil {
+		t.Error("expect error for round without CRS")
+	} else if _, ok := err.(*ErrCRSNotReady); !ok {
+		t.Errorf("error mismatch: have %v, want %T", err, &ErrCRSNotReady{})
+	}
+}
+
+func TestGroupPublicKey(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	api := NewPublicDexonAPI(dex)
+
+	if _, err := api.GroupPublicKey(0); err == nil {
+		t.Error("expect error before DKG is final")
+	} else if _, ok := err.(*ErrDKGNotFinal); !ok {
+		t.Errorf("error mismatch: have %v, want %T", err, &ErrDKGNotFinal{})
+	}
+	if _, err := api.GroupPublicKey(100); err == nil {
+		t.Error("expect error for round without CRS")
+	} else if _, ok := err.(*ErrCRSNotReady); !ok {
+		t.Errorf("error mismatch: have %v, want %T", err, &ErrCRSNotReady{})
+	}
+
+	// Act as the DKG set of round 0 with a group private key, the recovered
+	// group public key is taken from the persisted one.
+	prv := cryptoDKG.NewPrivateKey()
+	gpk := prv.PublicKey().Bytes()
+	crs := common.Hash(dex.governance.CRS(0))
+	if err := rawdb.WriteRoundGroupPublicKey(dex.chainDb, 0,
+		&rawdb.RoundGroupPublicKey{CRS: crs, PublicKey: gpk}); err != nil {
+		t.Fatalf("failed to write group public key: %v", err)
+	}
+	dex.governance.LoadRoundCache(0)
+
+	block, err := newTestCoreBlock(dex, key, 0, nil)
+	if err != nil {
+		t.Fatalf("failed to create block: %v", err)
+	}
+	sig, err := prv.Sign(block.Hash)
+	if err != nil {
+		t.Fatalf("failed to sign block: %v", err)
+	}
+	block.Randomness = sig.Signature
+	dex.app.BlockConfirmed(*block)
+	dex.app.BlockDelivered(block.Hash, block.Position, block.Randomness)
+
+	result, err := api.GroupPublicKey(0)
+	if err != nil {
+		t.Fatalf("failed to get group public key: %v", err)
+	}
+	if result.Round != 0 || !bytes.Equal(result.PublicKey, gpk) {
+		t.Fatalf("group public key mismatch: %+v", result)
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("failed to encode group public key: %v", err)
+	}
+	want := fmt.Sprintf(`{"round":"0x0","publicKey":"%s"}`, hexutil.Encode(gpk))
+	if string(data) != want {
+		t.Errorf("encoding mismatch: have %s, want %s", data, want)
+	}
+	var decoded RPCGroupPublicKey
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to decode group public key: %v", err)
+	}
+	if !reflect.DeepEqual(&decoded, result) {
+		t.Errorf("decoding mismatch: have %+v, want %+v", decoded, result)
+	}
+
+	// The notarization of the block verifies against the key.
+	notarizations, err := api.GetNotarizations(1, 1)
+	if err != nil || len(notarizations.Notarizations) != 1 {
+		t.Fatalf("failed to get notarization: %v %+v", err, notarizations)
+	}
+	n := notarizations.Notarizations[0]
+	var pub cryptoDKG.PublicKey
+	if err := pub.Deserialize(result.PublicKey); err != nil {
+		t.Fatalf("failed to deserialize group public key: %v", err)
+	}
+	proof := coreCrypto.Signature{Type: "bls", Signature: n.Randomness}
+	if !pub.VerifySignature(coreCommon.Hash(n.CoreHash), proof) {
+		t.Error("notarization not verified by group public key")
+	}
+	other := cryptoDKG.NewPrivateKey().PublicKey().(cryptoDKG.PublicKey)
+	if other.VerifySignature(coreCommon.Hash(n.CoreHash), proof) {
+		t.Error("notarization verified by unrelated key")
+	}
+}
+
+func TestGetNotarizations(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	dex.config.MaxNotarizations = 4
+	api := NewPublicDexonAPI(dex)
+
+	blocks := make(map[uint64]*types.Block)
+	for i := 0; i < 3; i++ {
+		block, err := deliverTestBlock(dex, key, 0, nil)
+		if err != nil {
+			t.Fatalf("failed to deliver block: %v", err)
+		}
+		blocks[block.NumberU64()] = block
+	}
+
+	tests := []struct {
+		from, to  uint64
+		finalized []uint64
+		missing   []uint64
+	}{
+		// The genesis block carries no proof.
+		{0, 3, []uint64{1, 2, 3}, []uint64{0}},
+		{1, 1, []uint64{1}, []uint64{}},
+		// Blocks beyond the head are not finalized yet.
+		{2, 5, []uint64{2, 3}, []uint64{4, 5}},
+		{4, 4, []uint64{}, []uint64{4}},
+	}
+	for _, test := range tests {
+		result, err := api.GetNotarizations(hexutil.Uint64(test.from), hexutil.Uint64(test.to))
+		if err != nil {
+			t.Fatalf("range %d - %d: failed to get notarizations: %v", test.from, test.to, err)
+		}
+		if len(result.Notarizations) != len(test.finalized) {
+			t.Fatalf("range %d - %d: notarization count mismatch: have %d, want %d",
+				test.from, test.to, len(result.Notarizations), len(test.finalized))
+		}
+		for i, number := range test.finalized {
+			n, block := result.Notarizations[i], blocks[number]
+			var coreBlock coreTypes.Block
+			if err := rlp.DecodeBytes(block.Header().DexconMeta, &coreBlock); err != nil {
+				t.Fatalf("failed to decode dexcon meta: %v", err)
+			}
+			if uint64(n.Number) != number || n.Hash != block.Hash() ||
+				n.CoreHash != common.Hash(coreBlock.Hash) ||
+				!bytes.Equal(n.Randomness, block.Header().Randomness) {
+				t.Errorf("range %d - %d: notarization %d mismatch: %+v", test.from, test.to, number, n)
+			}
+		}
+		if len(result.Missing) != len(test.missing) {
+			t.Fatalf("range %d - %d: missing mismatch: have %v, want %v",
+				test.from, test.to, result.Missing, test.missing)
+		}
+		for i, number := range test.missing {
+			if uint64(result.Missing[i]) != number {
+				t.Errorf("range %d - %d: missing mismatch: have %v, want %v",
+					test.from, test.to, result.Missing, test.missing)
+			}
+		}
+	}
+
+	// Ranges beyond the cap or reversed are rejected.
+	if _, err := api.GetNotarizations(0, 4); err == nil {
+		t.Error("expect error for range exceeding the cap")
+	}
+	if _, err := api.GetNotarizations(3, 2); err == nil {
+		t.Error("expect error for reversed range")
+	}
+
+	// A zero cap means no limit.
+	dex.config.MaxNotarizations = 0
+	if result, err := api.GetNotarizations(0, 5); err != nil {
+		t.Errorf("uncapped range rejected: %v", err)
+	} else if len(result.Notarizations) != 3 {
+		t.Errorf("notarization count mismatch: have %d, want %d", len(result.Notarizations), 3)
+	}
+}
+
+func TestFinalizedHeadsSubscription(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("dex", NewPublicDexonAPI(dex)); err != nil {
+		t.Fatalf("failed to register api: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	subscribe := func(latest bool) (chan *types.Header, *rpc.ClientSubscription) {
+		ch := make(chan *types.Header, 16)
+		sub, err := client.Subscribe(context.Background(), "dex", ch, "finalizedHeads", latest)
+		if err != nil {
+			t.Fatalf("failed to subscribe: %v", err)
+		}
+		return ch, sub
+	}
+	expect := func(ch chan *types.Header, sub *rpc.ClientSubscription, number uint64) {
+		select {
+		case header := <-ch:
+			if want := dex.blockchain.GetHeaderByNumber(number); want == nil || header.Hash() != want.Hash() {
+				t.Fatalf("header mismatch: have number %d, want %d", header.Number, number)
+			}
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(time.Second):
+			t.Fatalf("block %d not notified", number)
+		}
+	}
+	expectNone := func(ch chan *types.Header) {
+		select {
+		case header := <-ch:
+			t.Fatalf("unexpected notification of block %d", header.Number)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	// The latest finalized head is only sent if requested.
+	ch, sub := subscribe(true)
+	defer sub.Unsubscribe()
+	expect(ch, sub, 0)
+	quietCh, quietSub := subscribe(false)
+	defer quietSub.Unsubscribe()
+	expectNone(quietCh)
+
+	// Blocks confirmed by consensus are not final until delivered.
+	block, err := newTestCoreBlock(dex, key, 0, nil)
+	if err != nil {
+		t.Fatalf("failed to create block: %v", err)
+	}
+	dex.app.BlockConfirmed(*block)
+	expectNone(ch)
+	dex.app.BlockDelivered(block.Hash, block.Position, block.Randomness)
+	expect(ch, sub, 1)
+	expect(quietCh, quietSub, 1)
+
+	for i := 0; i < 3; i++ {
+		if _, err := deliverTestBlock(dex, key, 0, nil); err != nil {
+			t.Fatalf("failed to deliver block: %v", err)
+		}
+	}
+	for number := uint64(2); number <= 4; number++ {
+		expect(ch, sub, number)
+	}
+	expectNone(ch)
+}
+
+func TestTxRejectionsSubscription(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("dex", NewPublicDexonAPI(dex)); err != nil {
+		t.Fatalf("failed to register api: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	ch := make(chan *RPCTxRejection, 16)
+	sub, err := client.Subscribe(context.Background(), "dex", ch, "txRejections")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	defer sub.Unsubscribe()
+	// Wait for the subscription to be registered on the feed.
+	for deadline := time.Now().Add(time.Second); dex.txRejections.count() == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("subscription not registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A transaction under the governance minimum gas price is rejected.
+	signer := types.NewEIP155Signer(dex.chainConfig.ChainID)
+	minGasPrice := dex.governance.GetHeadState().MinGasPrice()
+	gasPrice := new(big.Int).Sub(minGasPrice, big.NewInt(1))
+	tx, err := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(1),
+		params.TxGas, gasPrice, nil), signer, key)
+	if err != nil {
+		t.Fatalf("failed to sign tx: %v", err)
+	}
+	if err := dex.APIBackend.SendTx(context.Background(), tx); err != core.ErrUnderpriced {
+		t.Fatalf("unexpected error: have %v, want %v", err, core.ErrUnderpriced)
+	}
+	select {
+	case rejection := <-ch:
+		if rejection.Hash != tx.Hash() || rejection.From != crypto.PubkeyToAddress(key.PublicKey) ||
+			rejection.Reason != core.ErrUnderpriced.Error() {
+			t.Errorf("rejection mismatch: have %+v", rejection)
+		}
+	case err := <-sub.Err():
+		t.Fatalf("subscription failed: %v", err)
+	case <-time.After(time.Second):
+		t.Fatal("rejection not notified")
+	}
+
+	// Accepted transactions are not notified.
+	tx, err = types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(1),
+		params.TxGas, minGasPrice, nil), signer, key)
+	if err != nil {
+		t.Fatalf("failed to sign tx: %v", err)
+	}
+	if err := dex.APIBackend.SendTx(context.Background(), tx); err != nil {
+		t.Fatalf("failed to send tx: %v", err)
+	}
+	select {
+	case rejection := <-ch:
+		t.Errorf("unexpected rejection: %+v", rejection)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestGetAccountNonceStatus(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	api := NewPublicDexonAPI(dex)
+	address := crypto.PubkeyToAddress(key.PublicKey)
+
+	signer := types.NewEIP155Signer(dex.chainConfig.ChainID)
+	gasPrice := dex.governance.GetHeadState().Configuration().MinGasPrice
+	to := common.HexToAddress("0x1234")
+	newTx := func(nonce uint64) *types.Transaction {
+		tx := types.NewTransaction(nonce, to, big.NewInt(1), params.TxGas, gasPrice, nil)
+		tx, err := types.SignTx(tx, signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		return tx
+	}
+
+	status, err := api.GetAccountNonceStatus(address)
+	if err != nil {
+		t.Fatalf("failed to get nonce status: %v", err)
+	}
+	if status.FinalizedNonce != nil || status.ConfirmedNonce != nil || status.PendingNonce != 0 ||
+		len(status.Queued) != 0 || len(status.Gaps) != 0 {
+		t.Fatalf("fresh account status mismatch: %+v", status)
+	}
+
+	if _, err := deliverTestBlock(dex, key, 0, types.Transactions{newTx(0)}); err != nil {
+		t.Fatalf("failed to deliver block: %v", err)
+	}
+	// Nonces 3, 5 and 6 are missing.
+	var txs types.Transactions
+	for _, nonce := range []uint64{1, 2, 4, 7} {
+		txs = append(txs, newTx(nonce))
+	}
+	for _, err := range dex.txPool.AddRemotes(txs) {
+		if err != nil {
+			t.Fatalf("failed to add tx: %v", err)
+		}
+	}
+	for i := 0; ; i++ {
+		if pending, queued := dex.txPool.Stats(); pending == 2 && queued == 2 {
+			break
+		}
+		if i == 100 {
+			t.Fatal("transactions not added to the pool")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if status, err = api.GetAccountNonceStatus(address); err != nil {
+		t.Fatalf("failed to get nonce status: %v", err)
+	}
+	if status.StateNonce != 1 || status.FinalizedNonce == nil || *status.FinalizedNonce != 0 {
+		t.Errorf("finalized nonce mismatch: state %d, finalized %v", status.StateNonce, status.FinalizedNonce)
+	}
+	if status.ConfirmedNonce != nil {
+		t.Errorf("confirmed nonce mismatch: have %d, want none", *status.ConfirmedNonce)
+	}
+	if status.PendingNonce != 3 {
+		t.Errorf("pending nonce mismatch: have %d, want 3", status.PendingNonce)
+	}
+	if want := []hexutil.Uint64{4, 7}; !reflect.DeepEqual(status.Queued, want) {
+		t.Errorf("queued nonces mismatch: have %v, want %v", status.Queued, want)
+	}
+	if want := []NonceRange{{3, 3}, {5, 6}}; !reflect.DeepEqual(status.Gaps, want) {
+		t.Errorf("gaps mismatch: have %v, want %v", status.Gaps, want)
+	}
+}
+
+func TestChainTip(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	api := NewPublicDexonAPI(dex)
+
+	tip, err := api.ChainTip()
+	if err != nil {
+		t.Fatalf("failed to get tip: %v", err)
+	}
+	genesis := dex.blockchain.Genesis()
+	if tip.Hash != genesis.Hash() || tip.Number != 0 || tip.CoreHash != (common.Hash{}) {
+		t.Fatalf("genesis tip mismatch: %+v", tip)
+	}
+
+	// The tip follows the finalized blocks across rounds.
+	var last *ChainTip
+	for round := uint64(0); round <= 1; round++ {
+		block, err := deliverTestBlock(dex, key, round, nil)
+		if err != nil {
+			t.Fatalf("failed to deliver block: %v", err)
+		}
+		if tip, err = api.ChainTip(); err != nil {
+			t.Fatalf("failed to get tip: %v", err)
+		}
+		var coreBlock coreTypes.Block
+		if err := rlp.DecodeBytes(block.Header().DexconMeta, &coreBlock); err != nil {
+			t.Fatalf("failed to decode dexcon meta: %v", err)
+		}
+		if tip.Hash != block.Hash() || uint64(tip.Number) != block.NumberU64() ||
+			tip.CoreHash != common.Hash(coreBlock.Hash) || uint64(tip.Round) != round ||
+			uint64(tip.Height) != coreBlock.Position.Height || uint64(tip.Timestamp) != block.Time() {
+			t.Errorf("round %d: tip mismatch: %+v", round, tip)
+		}
+		if last != nil && (tip.Height <= last.Height || tip.Timestamp < last.Timestamp) {
+			t.Errorf("round %d: tip not advanced: have %+v, previous %+v", round, tip, last)
+		}
+		last = tip
+	}
+}
+
+func TestChainTipJSON(t *testing.T) {
+	tip := &ChainTip{
+		Number:    3,
+		Hash:      common.Hash{1},
+		CoreHash:  common.Hash{2},
+		Round:     1,
+		Height:    3,
+		Timestamp: 1546300800000,
+	}
+	data, err := json.Marshal(tip)
+	if err != nil {
+		t.Fatalf("failed to marshal tip: %v", err)
+	}
+	want := `{"number":"0x3",` +
+		`"hash":"0x0100000000000000000000000000000000000000000000000000000000000000",` +
+		`"coreHash":"0x0200000000000000000000000000000000000000000000000000000000000000",` +
+		`"round":"0x1","height":"0x3","timestamp":"0x16806b5bc00"}`
+	if string(data) != want {
+		t.Errorf("json mismatch:\nhave %s\nwant %s", data, want)
+	}
+
+	var decoded *ChainTip
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to unmarshal tip: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, tip) {
+		t.Errorf("round trip mismatch: have %+v, want %+v", decoded, tip)
+	}
+}
+
+func TestCompactionProvenanceJSON(t *testing.T) {
+	provenance := &CompactionProvenance{
+		Number: 2,
+		Hash:   common.Hash{1},
+		Blocks: []*ProvenanceBlock{{
+			Hash:       common.Hash{2},
+			ParentHash: common.Hash{3},
+			ProposerID: common.Hash{4},
+			Round:      1,
+			Height:     2,
+			Timestamp:  time.Unix(1546300800, 0).UTC(),
+		}},
+	}
+	data, err := json.Marshal(provenance)
+	if err != nil {
+		t.Fatalf("failed to marshal provenance: %v", err)
+	}
+	want := `{"number":"0x2",` +
+		`"hash":"0x0100000000000000000000000000000000000000000000000000000000000000",` +
+		`"blocks":[{"hash":"0x0200000000000000000000000000000000000000000000000000000000000000",` +
+		`"parentHash":"0x0300000000000000000000000000000000000000000000000000000000000000",` +
+		`"proposerID":"0x0400000000000000000000000000000000000000000000000000000000000000",` +
+		`"round":"0x1","height":"0x2","timestamp":"2019-01-01T00:00:00Z"}]}`
+	if string(data) != want {
+		t.Errorf("json mismatch:\nhave %s\nwant %s", data, want)
+	}
+
+	var decoded CompactionProvenance
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to unmarshal provenance: %v", err)
+	}
+	if !reflect.DeepEqual(&decoded, provenance) {
+		t.Errorf("round trip mismatch: have %+v, want %+v", decoded, provenance)
+	}
+}
+
+func TestEstimateFinalityTime(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	api := NewPublicDexonAPI(dex)
+
+	signer := types.NewEIP155Signer(dex.chainConfig.ChainID)
+	gasPrice := dex.governance.DexconConfiguration(0).MinGasPrice
+	newTx := func(nonce uint64) *types.Transaction {
+		tx, err := types.SignTx(types.NewTransaction(nonce, common.Address{1},
+			big.NewInt(1), params.TxGas, gasPrice, nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		return tx
+	}
+	if _, err := api.EstimateFinalityTime(context.Background(), common.Hash{1}); err == nil {
+		t.Error("estimated unknown transaction")
+	}
+
+	// Without completed rounds the minimum block interval is assumed, with
+	// wide bounds.
+	tx := newTx(0)
+	if err := dex.txPool.AddLocal(tx); err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+	minInterval := dex.governance.DexconConfiguration(0).MinBlockInterval
+	estimate, err := api.EstimateFinalityTime(context.Background(), tx.Hash())
+	if err != nil {
+		t.Fatalf("failed to estimate finality: %v", err)
+	}
+	if estimate.Status != TxPending || estimate.Blocks != 1 || estimate.Samples != 0 {
+		t.Errorf("estimate mismatch: have %+v, want pending in 1 block from 0 samples", estimate)
+	}
+	if uint64(estimate.Estimate) != minInterval || estimate.Lower != 0 ||
+		uint64(estimate.Upper) != 4*minInterval {
+		t.Errorf("sparse estimate mismatch: have %d [%d, %d], want %d [0, %d]",
+			estimate.Estimate, estimate.Lower, estimate.Upper, minInterval, 4*minInterval)
+	}
+
+	// Synthetic rounds with block intervals of 900ms, 1000ms and 1100ms, the
+	// interval is 1s with a deviation of about 82ms.
+	roundLength := dex.governance.DexconConfiguration(0).RoundLength
+	dex.roundNotifier.mu.Lock()
+	begin := uint64(0)
+	for i, interval := range []uint64{900, 1000, 1100} {
+		dex.roundNotifier.latencies = append(dex.roundNotifier.latencies, RoundLatency{
+			Round:     uint64(i),
+			BeginTime: begin,
+			EndTime:   begin + interval*roundLength,
+		})
+		begin += interval * roundLength
+	}
+	dex.roundNotifier.mu.Unlock()
+	if estimate, err = api.EstimateFinalityTime(context.Background(), tx.Hash()); err != nil {
+		t.Fatalf("failed to estimate finality: %v", err)
+	}
+	if estimate.Estimate != 1000 || estimate.Lower != 836 || estimate.Upper != 1163 || estimate.Samples != 3 {
+		t.Errorf("estimate mismatch: have %d [%d, %d] from %d samples, want 1000 [836, 1163] from 3",
+			estimate.Estimate, estimate.Lower, estimate.Upper, estimate.Samples)
+	}
+
+	// Finalized transactions take no more time.
+	if _, err := deliverTestBlock(dex, key, 0, types.Transactions{tx}); err != nil {
+		t.Fatalf("failed to deliver block: %v", err)
+	}
+	if estimate, err = api.EstimateFinalityTime(context.Background(), tx.Hash()); err != nil {
+		t.Fatalf("failed to estimate finality: %v", err)
+	}
+	if estimate.Status != TxFinalized || estimate.Estimate != 0 {
+		t.Errorf("estimate mismatch: have %+v, want finalized", estimate)
+	}
+}
+
+func TestFinalizationDepth(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	api := NewPublicDexonAPI(dex)
+
+	finalized, err := deliverTestBlock(dex, key, 0, nil)
+	if err != nil {
+		t.Fatalf("failed to deliver block: %v", err)
+	}
+	depth := api.FinalizationDepth()
+	if depth.Blocks != 0 || depth.Seconds != 0 || depth.Samples != 1 {
+		t.Fatalf("depth mismatch without confirmed blocks: %+v", depth)
+	}
+
+	// Confirm three blocks a second apart past the finalized one.
+	base := time.Unix(0, int64(finalized.Time())*int64(time.Millisecond))
+	for i := uint64(1); i <= 3; i++ {
+		dex.app.BlockConfirmed(coreTypes.Block{
+			Hash:      coreCommon.NewRandomHash(),
+			Position:  coreTypes.Position{Height: finalized.NumberU64() + i},
+			Timestamp: base.Add(time.Duration(i) * time.Second),
+		})
+	}
+	depth = api.FinalizationDepth()
+	if uint64(depth.Head) != finalized.NumberU64()+3 || uint64(depth.Finalized) != finalized.NumberU64() {
+		t.Errorf("heights mismatch: have head %d finalized %d", depth.Head, depth.Finalized)
+	}
+	if depth.Blocks != 3 || depth.Seconds != 3 {
+		t.Errorf("depth mismatch: have %d blocks %vs, want 3 blocks 3s", depth.Blocks, depth.Seconds)
+	}
+
+	// Finalizing the next block leaves two confirmed ones ahead.
+	if _, err := deliverTestBlock(dex, key, 0, nil); err != nil {
+		t.Fatalf("failed to deliver block: %v", err)
+	}
+	depth = api.FinalizationDepth()
+	if depth.Blocks != 2 || depth.Samples != 2 {
+		t.Fatalf("depth mismatch after delivery: %+v", depth)
+	}
+	if depth.AverageBlocks != 1 {
+		t.Errorf("average blocks mismatch: have %v, want 1", depth.AverageBlocks)
+	}
+	if depth.AverageSeconds <= 0 || depth.AverageSeconds > 1.5 {
+		t.Errorf("average seconds out of range: have %v, want (0, 1.5]", depth.AverageSeconds)
+	}
+}
+
+func TestGasParameters(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	dex.APIBackend.gpo = gasprice.NewOracle(dex.APIBackend, gasprice.Config{
+		Blocks:     20,
+		Percentile: 60,
+		Default:    big.NewInt(params.GWei),
+	})
+	api := NewPublicDexonAPI(dex)
+
+	check := func(round uint64) *GasParameters {
+		gasParams, err := api.GasParameters(context.Background())
+		if err != nil {
+			t.Fatalf("failed to get gas parameters: %v", err)
+		}
+		head := dex.blockchain.CurrentBlock()
+		config := dex.governance.DexconConfiguration(round)
+		if uint64(gasParams.Round) != round || uint64(gasParams.Number) != head.NumberU64() ||
+			gasParams.Hash != head.Hash() {
+			t.Errorf("head mismatch: have %+v, want round %d, number %d", gasParams, round, head.NumberU64())
+		}
+		if gasParams.MinGasPrice.ToInt().Cmp(config.MinGasPrice) != 0 {
+			t.Errorf("min gas price mismatch: have %v, want %v", gasParams.MinGasPrice, config.MinGasPrice)
+		}
+		if uint64(gasParams.BlockGasLimit) != config.BlockGasLimit {
+			t.Errorf("block gas limit mismatch: have %d, want %d", gasParams.BlockGasLimit, config.BlockGasLimit)
+		}
+		suggested, err := dex.APIBackend.gpo.SuggestPrice(context.Background())
+		if err != nil {
+			t.Fatalf("failed to suggest price: %v", err)
+		}
+		if gasParams.SuggestedPrice.ToInt().Cmp(suggested) != 0 {
+			t.Errorf("suggested price mismatch: have %v, want %v", gasParams.SuggestedPrice, suggested)
+		}
+		return gasParams
+	}
+
+	first := check(0)
+	if second := check(0); second != first {
+		t.Error("gas parameters not cached for the same head")
+	}
+	if _, err := deliverTestBlock(dex, key, 1, nil); err != nil {
+		t.Fatalf("failed to deliver block: %v", err)
+	}
+	if third := check(1); third == first {
+		t.Error("gas parameters not updated for the new head")
+	}
+}
+
+func TestBlockFinalizationRound(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	api := NewPublicDexonAPI(dex)
+
+	check := func(hash common.Hash, status string, blockRound, round *uint64) {
+		result := api.BlockFinalizationRound(hash)
+		if result.Hash != hash || result.Status != status {
+			t.Errorf("status mismatch: have %s, want %s", result.Status, status)
+		}
+		if (result.BlockRound == nil) != (blockRound == nil) ||
+			blockRound != nil && uint64(*result.BlockRound) != *blockRound {
+			t.Errorf("block round mismatch: have %v, want %v", result.BlockRound, blockRound)
+		}
+		if (result.Round == nil) != (round == nil) || round != nil && uint64(*result.Round) != *round {
+			t.Errorf("finalization round mismatch: have %v, want %v", result.Round, round)
+		}
+	}
+	round0, round1 := uint64(0), uint64(1)
+
+	// Blocks not delivered by the local consensus.
+	check(common.Hash{1}, FinalizationPending, nil, nil)
+	check(dex.blockchain.Genesis().Hash(), FinalizationUnknown, &round0, nil)
+
+	// Finalized in the round it was produced in.
+	block, err := deliverTestBlock(dex, key, 0, nil)
+	if err != nil {
+		t.Fatalf("failed to deliver block: %v", err)
+	}
+	check(block.Hash(), FinalizationFinalized, &round0, &round0)
+
+	// Finalized after a block of the next round is confirmed.
+	dex.app.BlockConfirmed(coreTypes.Block{
+		Hash:     coreCommon.NewRandomHash(),
+		Position: coreTypes.Position{Round: 1, Height: block.NumberU64() + 2},
+	})
+	block, err = deliverTestBlock(dex, key, 0, nil)
+	if err != nil {
+		t.Fatalf("failed to deliver block: %v", err)
+	}
+	check(block.Hash(), FinalizationFinalized, &round0, &round1)
+}
+
+func TestConsensusTimestamp(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	api := NewPublicDexonAPI(dex)
+
+	if result, err := api.ConsensusTimestamp(common.Hash{1}); err != nil || result != nil {
+		t.Errorf("unknown block: have %v (%v), want nil", result, err)
+	}
+
+	// The header carries the consensus timestamp truncated to milliseconds.
+	coreBlock, err := newTestCoreBlock(dex, key, 0, nil)
+	if err != nil {
+		t.Fatalf("failed to create core block: %v", err)
+	}
+	coreBlock.Timestamp = time.Unix(1500000000, 123456789)
+	dex.app.BlockConfirmed(*coreBlock)
+
+	result, err := api.ConsensusTimestamp(common.Hash(coreBlock.Hash))
+	if err != nil {
+		t.Fatalf("failed to get provisional timestamp: %v", err)
+	}
+	if !result.Provisional || uint64(result.Timestamp) != 1500000000123 {
+		t.Errorf("provisional timestamp mismatch: have %+v, want %d", result, 1500000000123)
+	}
+
+	dex.app.BlockDelivered(coreBlock.Hash, coreBlock.Position, coreBlock.Randomness)
+	block := dex.blockchain.CurrentBlock()
+	result, err = api.ConsensusTimestamp(block.Hash())
+	if err != nil {
+		t.Fatalf("failed to get timestamp: %v", err)
+	}
+	if result.Provisional {
+		t.Error("finalized block reported provisional")
+	}
+	if uint64(result.Timestamp) != 1500000000123 {
+		t.Errorf("timestamp mismatch: have %d, want %d", result.Timestamp, 1500000000123)
+	}
+	if uint64(result.HeaderTime) != block.Time() || block.Time() != uint64(result.Timestamp) {
+		t.Errorf("header time mismatch: have %d, header %d, want %d", result.HeaderTime, block.Time(), result.Timestamp)
+	}
+
+	// The genesis block falls back to the header timestamp.
+	genesis := dex.blockchain.Genesis()
+	if result, err := api.ConsensusTimestamp(genesis.Hash()); err != nil || result.Provisional ||
+		uint64(result.Timestamp) != genesis.Time() {
+		t.Errorf("genesis timestamp mismatch: have %+v (%v)", result, err)
+	}
+}
+
+func TestGetBlockByPosition(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	if dex.blockDB, err = dexDB.Open(dexDB.EngineMemory, dex.chainDb); err != nil {
+		t.Fatalf("failed to open block database: %v", err)
+	}
+	api := NewPublicDexonAPI(dex)
+
+	// Consensus stores the core blocks before delivering them.
+	var coreBlocks []*coreTypes.Block
+	for _, round := range []uint64{0, 0, 1} {
+		block, err := deliverTestBlock(dex, key, round, nil)
+		if err != nil {
+			t.Fatalf("failed to deliver block: %v", err)
+		}
+		var coreBlock coreTypes.Block
+		if err := rlp.DecodeBytes(block.Header().DexconMeta, &coreBlock); err != nil {
+			t.Fatalf("failed to decode core block: %v", err)
+		}
+		if err := dex.blockDB.PutBlock(coreBlock); err != nil {
+			t.Fatalf("failed to store core block: %v", err)
+		}
+		coreBlocks = append(coreBlocks, &coreBlock)
+	}
+	for _, want := range coreBlocks {
+		pos := want.Position
+		block, err := api.GetBlockByPosition(hexutil.Uint64(pos.Round), hexutil.Uint64(pos.Height))
+		if err != nil {
+			t.Fatalf("failed to get block at %v: %v", pos, err)
+		}
+		if block.Hash != common.Hash(want.Hash) || uint64(block.Round) != pos.Round ||
+			uint64(block.Height) != pos.Height {
+			t.Errorf("block at %v mismatch: have %+v", pos, block)
+		}
+	}
+
+	head := coreBlocks[len(coreBlocks)-1].Position
+	for _, tt := range []struct{ round, height uint64 }{
+		{0, 0},                        // Genesis, not from consensus
+		{0, head.Height},              // Wrong round
+		{head.Round, head.Height + 1}, // Not finalized yet
+	} {
+		_, err := api.GetBlockByPosition(hexutil.Uint64(tt.round), hexutil.Uint64(tt.height))
+		if _, ok := err.(*ErrPositionNotFound); !ok {
+			t.Errorf("position %+v: unexpected error: %v", tt, err)
+		}
+	}
+}
+
+func TestPendingProposals(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	api := NewPublicDexonAPI(dex)
+	config := dex.governance.DexconConfiguration(0)
+	expiry := hexutil.Uint64(config.RoundLength - 1)
+	threshold := hexutil.Uint64(2*uint64(config.NotarySetSize)/3 + 1)
+
+	// The DKG of round 1 is open from genesis until its CRS is proposed.
+	proposals := api.PendingProposals()
+	if len(proposals) != 1 {
+		t.Fatalf("proposal count mismatch: have %d, want 1", len(proposals))
+	}
+	if proposal := proposals[0]; proposal.Kind != ProposalDKG || proposal.Round != 1 || proposal.Tally == nil ||
+		proposal.Tally.For != 0 || proposal.Tally.Threshold != threshold || proposal.Expiry != expiry {
+		t.Errorf("dkg proposal mismatch: %+v", proposal)
+	}
+
+	// The owner updates lambdaBA, applied after the round shift.
+	lambdaBA := dex.governance.GetHeadState().Configuration().LambdaBA * 2
+	tx, err := newTestUpdateLambdaBATx(dex, key, 0, lambdaBA)
+	if err != nil {
+		t.Fatalf("failed to create tx: %v", err)
+	}
+	if _, err := deliverTestBlock(dex, key, 0, types.Transactions{tx}); err != nil {
+		t.Fatalf("failed to deliver block: %v", err)
+	}
+	proposals = api.PendingProposals()
+	if len(proposals) != 2 {
+		t.Fatalf("proposal count mismatch: have %d, want 2", len(proposals))
+	}
+	proposal := proposals[1]
+	if proposal.Kind != ProposalConfiguration || proposal.Proposer != crypto.PubkeyToAddress(key.PublicKey) ||
+		uint64(proposal.Round) != 1+dexCore.ConfigRoundShift || proposal.Expiry != expiry || proposal.Tally != nil {
+		t.Errorf("configuration proposal mismatch: %+v", proposal)
+	}
+	if len(proposal.Changes) != 1 || proposal.Changes[0].Field != "lambdaBA" ||
+		proposal.Changes[0].Pending.(uint64) != lambdaBA {
+		t.Errorf("configuration changes mismatch: %+v", proposal.Changes)
+	}
+
+	// Tallies of the DKG follow the governance state.
+	state, err := dex.blockchain.State()
+	if err != nil {
+		t.Fatalf("failed to get state: %v", err)
+	}
+	gs := &vm.GovernanceState{StateDB: state}
+	for i := 0; i < 2; i++ {
+		gs.PutDKGSuccess(common.Address{byte(i)}, true)
+		gs.IncDKGSuccessesCount()
+	}
+	gs.PushDKGComplaint([]byte{1})
+	proposals = pendingProposals(gs, config, 0, uint64(expiry))
+	if tally := proposals[0].Tally; proposals[0].Kind != ProposalDKG || tally.For != 2 || tally.Against != 1 {
+		t.Errorf("dkg tally mismatch: %+v", tally)
+	}
+
+	gs.SetCRSRound(big.NewInt(1))
+	proposals = pendingProposals(gs, config, 0, uint64(expiry))
+	if len(proposals) != 1 || proposals[0].Kind != ProposalConfiguration {
+		t.Errorf("dkg proposal not closed by the CRS: %v", proposals)
+	}
+}
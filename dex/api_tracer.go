@@ -28,6 +28,8 @@ import (
 	"sync"
 	"time"
 
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+
 	"github.com/dexon-foundation/dexon/common"
 	"github.com/dexon-foundation/dexon/common/hexutil"
 	"github.com/dexon-foundation/dexon/core"
@@ -374,6 +376,59 @@ func (api *PrivateDebugAPI) TraceBlockByHash(ctx context.Context, hash common.Ha
 	return api.traceBlock(ctx, block, config)
 }
 
+// FinalizedBlockTrace is the trace of a finalized block along with the
+// consensus context of the block.
+type FinalizedBlockTrace struct {
+	Hash       common.Hash      `json:"hash"`
+	Number     hexutil.Uint64   `json:"number"`
+	Round      hexutil.Uint64   `json:"round"`
+	Timestamp  hexutil.Uint64   `json:"timestamp"`  // Consensus timestamp in milliseconds
+	Proposer   common.Address   `json:"proposer"`   // Owner of the proposing node, zero for empty blocks
+	ProposerID common.Hash      `json:"proposerID"` // Node ID of the proposing node
+	Traces     []*txTraceResult `json:"traces"`
+}
+
+// TraceFinalizedBlock traces the transactions of the finalized block of the
+// given hash, and attaches the round, consensus timestamp and proposer of the
+// block. Blocks off the canonical chain are refused, only the canonical ones
+// are final.
+func (api *PrivateDebugAPI) TraceFinalizedBlock(ctx context.Context, hash common.Hash, config *TraceConfig) (*FinalizedBlockTrace, error) {
+	block := api.dex.blockchain.GetBlockByHash(hash)
+	if block == nil {
+		return nil, fmt.Errorf("block %#x not found", hash)
+	}
+	number := block.NumberU64()
+	if number > api.dex.blockchain.CurrentBlock().NumberU64() ||
+		rawdb.ReadCanonicalHash(api.dex.chainDb, number) != hash {
+		return nil, &ErrBlockNotFinalized{Number: number}
+	}
+	meta := block.Header().DexconMeta
+	if len(meta) == 0 {
+		return nil, fmt.Errorf("block %#x carries no consensus block", hash)
+	}
+	var coreBlock coreTypes.Block
+	if err := rlp.DecodeBytes(meta, &coreBlock); err != nil {
+		return nil, fmt.Errorf("invalid consensus meta of block %#x: %v", hash, err)
+	}
+	timestamp, err := api.dex.APIBackend.ConsensusTimestamp(hash)
+	if err != nil {
+		return nil, err
+	}
+	traces, err := api.traceBlock(ctx, block, config)
+	if err != nil {
+		return nil, err
+	}
+	return &FinalizedBlockTrace{
+		Hash:       hash,
+		Number:     hexutil.Uint64(number),
+		Round:      hexutil.Uint64(block.Round()),
+		Timestamp:  timestamp.Timestamp,
+		Proposer:   block.Coinbase(),
+		ProposerID: common.Hash(coreBlock.ProposerID.Hash),
+		Traces:     traces,
+	}, nil
+}
+
 // TraceBlock returns the structured logs created during the execution of EVM
 // and returns them as a JSON object.
 func (api *PrivateDebugAPI) TraceBlock(ctx context.Context, blob []byte, config *TraceConfig) ([]*txTraceResult, error) {
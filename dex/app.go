@@ -20,8 +20,10 @@ package dex
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"sync"
 	"time"
 
@@ -30,13 +32,20 @@ import (
 
 	"github.com/dexon-foundation/dexon/common"
 	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/core/state"
 	"github.com/dexon-foundation/dexon/core/types"
 	"github.com/dexon-foundation/dexon/ethdb"
 	"github.com/dexon-foundation/dexon/event"
 	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/metrics"
 	"github.com/dexon-foundation/dexon/rlp"
 )
 
+// prepareThrottleMeter counts the payload preparations delayed by the block
+// rate limit.
+var prepareThrottleMeter = metrics.NewRegisteredMeter("dex/app/prepare/throttled", nil)
+
 // DexconApp implements the DEXON consensus core application interface.
 type DexconApp struct {
 	txPool     *core.TxPool
@@ -47,6 +56,9 @@ type DexconApp struct {
 
 	finalizedBlockFeed event.Feed
 	scope              event.SubscriptionScope
+	events             *eventQueue     // Queue FinalizedBlockEvent is posted through, nil to disable
+	rotator            *keyRotator     // Retires the validator key at a key rotation, nil to disable
+	quorum             *quorumWatchdog // Tracks the agreement progress, nil to disable
 
 	appMu sync.RWMutex
 
@@ -56,6 +68,15 @@ type DexconApp struct {
 	addressCounter  map[common.Address]uint64
 	undeliveredNum  uint64
 	deliveredHeight uint64
+	confirmedRound  uint64 // Highest round of the blocks confirmed
+
+	depths    []finalizationDepth // Ring buffer of the depths at the latest deliveries
+	nextDepth int                 // Position in the ring buffer of the next depth
+
+	prepareLock sync.Mutex
+	lastPrepare time.Time     // Time the last payload preparation was allowed
+	prepareQuit chan struct{} // Closed to release the preparations held by the rate limit
+	quitOnce    sync.Once
 }
 
 func NewDexconApp(txPool *core.TxPool, blockchain *core.BlockChain, gov *DexconGovernance,
@@ -71,6 +92,7 @@ func NewDexconApp(txPool *core.TxPool, blockchain *core.BlockChain, gov *DexconG
 		addressCost:     map[common.Address]*big.Int{},
 		addressCounter:  map[common.Address]uint64{},
 		deliveredHeight: blockchain.CurrentBlock().NumberU64(),
+		prepareQuit:     make(chan struct{}),
 	}
 }
 
@@ -111,8 +133,54 @@ func (d *DexconApp) validateGasPrice(txs types.Transactions, round uint64) bool
 	return true
 }
 
+// throttlePrepare blocks until preparing another payload keeps the local
+// block production within MaxBlocksPerSecond. The proposal of the block is
+// delayed meanwhile. It reports false if the wait is cut short by a shutdown.
+func (d *DexconApp) throttlePrepare() bool {
+	if d.config.MaxBlocksPerSecond <= 0 {
+		return true
+	}
+	interval := time.Duration(float64(time.Second) / d.config.MaxBlocksPerSecond)
+
+	// Reserve the next slot, so concurrent preparations queue up behind it.
+	d.prepareLock.Lock()
+	now := time.Now()
+	next := d.lastPrepare.Add(interval)
+	if next.Before(now) {
+		next = now
+	}
+	d.lastPrepare = next
+	d.prepareLock.Unlock()
+
+	wait := next.Sub(now)
+	if wait <= 0 {
+		return true
+	}
+	prepareThrottleMeter.Mark(1)
+	log.Debug("Throttling block preparation", "wait", wait)
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-d.prepareQuit:
+		return false
+	}
+}
+
+// stopPreparing releases the payload preparations held by the rate limit and
+// keeps new ones from waiting, so consensus stops without delay.
+func (d *DexconApp) stopPreparing() {
+	d.quitOnce.Do(func() { close(d.prepareQuit) })
+}
+
 // PreparePayload is called when consensus core is preparing payload for block.
 func (d *DexconApp) PreparePayload(position coreTypes.Position) (payload []byte, err error) {
+	d.rotator.observe(position.Round)
+	if !d.throttlePrepare() {
+		return nil, nil
+	}
+
 	// softLimit limits the runtime of inner call to preparePayload.
 	// hardLimit limits the runtime of outer PreparePayload.
 	// If hardLimit is hit, it is possible that no payload is prepared.
@@ -183,6 +251,8 @@ func (d *DexconApp) preparePayload(ctx context.Context, position coreTypes.Posit
 	blockGasLimit := new(big.Int).SetUint64(d.gov.DexconConfiguration(position.Round).BlockGasLimit)
 	minGasPrice := d.gov.DexconConfiguration(position.Round).MinGasPrice
 	blockGasUsed := new(big.Int)
+	maxPayloadBytes := d.config.MaxBlockPayloadBytes
+	var payloadBytes uint64
 	allTxs := make([]*types.Transaction, 0, 10000)
 
 addressMap:
@@ -243,6 +313,11 @@ addressMap:
 				break addressMap
 			}
 
+			payloadBytes += uint64(tx.Size())
+			if maxPayloadBytes > 0 && rlpListSize(payloadBytes) > maxPayloadBytes {
+				break addressMap
+			}
+
 			allTxs = append(allTxs, tx)
 		}
 	}
@@ -272,8 +347,20 @@ func (d *DexconApp) PrepareWitness(consensusHeight uint64) (witness coreTypes.Wi
 	}, nil
 }
 
+// rlpListSize returns the size of an RLP list of n bytes of content.
+func rlpListSize(n uint64) uint64 {
+	size := 1 + n
+	if n >= 56 {
+		for ; n > 0; n >>= 8 {
+			size++
+		}
+	}
+	return size
+}
+
 // VerifyBlock verifies if the payloads are valid.
 func (d *DexconApp) VerifyBlock(block *coreTypes.Block) coreTypes.BlockVerifyStatus {
+	d.rotator.observe(block.Position.Round)
 	var witnessBlockHash common.Hash
 	err := rlp.DecodeBytes(block.Witness.Data, &witnessBlockHash)
 	if err != nil {
@@ -336,78 +423,86 @@ func (d *DexconApp) VerifyBlock(block *coreTypes.Block) coreTypes.BlockVerifySta
 		return coreTypes.VerifyInvalidBlock
 	}
 
+	if err := d.validateTransactions(transactions, block.Position.Round, state,
+		d.addressNonce, d.addressCost); err != nil {
+		log.Error("Invalid block payload", "err", err)
+		return coreTypes.VerifyInvalidBlock
+	}
+	return coreTypes.VerifyOK
+}
+
+// validateTransactions checks the transactions of a block against the state
+// they are executed on: the nonces of each sender must be in order and follow
+// the one of the state, their gas prices reach the minimum of the round, and
+// the balances of the state cover their costs within the block gas limit.
+// The nonces and costs of the transactions confirmed but not yet delivered,
+// if any, are accounted on top of the state.
+func (d *DexconApp) validateTransactions(transactions types.Transactions, round uint64,
+	statedb *state.StateDB, pendingNonce map[common.Address]uint64,
+	pendingCost map[common.Address]*big.Int) error {
 	addressNonce, err := d.validateNonce(transactions)
 	if err != nil {
-		log.Error("Validate nonce failed", "error", err)
-		return coreTypes.VerifyInvalidBlock
+		return err
 	}
-	if !d.validateGasPrice(transactions, block.Position.Round) {
-		log.Error("Validate gas price failed")
-		return coreTypes.VerifyInvalidBlock
+	if !d.validateGasPrice(transactions, round) {
+		return errors.New("gas price below minimum")
 	}
 
 	for address, firstNonce := range addressNonce {
 		var expectNonce uint64
-		nonce, exist := d.addressNonce[address]
+		nonce, exist := pendingNonce[address]
 		if exist {
 			expectNonce = nonce + 1
 		} else {
-			expectNonce = state.GetNonce(address)
+			expectNonce = statedb.GetNonce(address)
 		}
 
 		if expectNonce != firstNonce {
-			log.Error("Nonce check error", "expect", expectNonce, "firstNonce", firstNonce)
-			return coreTypes.VerifyInvalidBlock
+			return fmt.Errorf("nonce check error: expect %v first nonce %v", expectNonce, firstNonce)
 		}
 	}
 
 	// Calculate balance in last state (including pending state).
 	addressesBalance := map[common.Address]*big.Int{}
 	for address := range addressNonce {
-		cost, exist := d.addressCost[address]
+		cost, exist := pendingCost[address]
 		if exist {
-			addressesBalance[address] = new(big.Int).Sub(state.GetBalance(address), cost)
+			addressesBalance[address] = new(big.Int).Sub(statedb.GetBalance(address), cost)
 		} else {
-			addressesBalance[address] = state.GetBalance(address)
+			addressesBalance[address] = statedb.GetBalance(address)
 		}
 	}
 
 	// Validate if balance is enough for TXs in this block.
-	blockGasLimit := new(big.Int).SetUint64(d.gov.DexconConfiguration(block.Position.Round).BlockGasLimit)
+	blockGasLimit := new(big.Int).SetUint64(d.gov.DexconConfiguration(round).BlockGasLimit)
 	blockGasUsed := new(big.Int)
 
 	for _, tx := range transactions {
 		msg, err := tx.AsMessage(types.MakeSigner(d.blockchain.Config(), new(big.Int)))
 		if err != nil {
-			log.Error("Failed to convert tx to message", "error", err)
-			return coreTypes.VerifyInvalidBlock
+			return fmt.Errorf("failed to convert tx to message: %v", err)
 		}
 		balance := addressesBalance[msg.From()]
 		intrGas, err := core.IntrinsicGas(msg.Data(), msg.To() == nil, true)
 		if err != nil {
-			log.Error("Failed to calculate intrinsic gas", "err", err)
-			return coreTypes.VerifyInvalidBlock
+			return fmt.Errorf("failed to calculate intrinsic gas: %v", err)
 		}
 		if tx.Gas() < intrGas {
-			log.Error("Intrinsic gas too low", "txHash", tx.Hash().String(), "intrinsic", intrGas, "gas", tx.Gas())
-			return coreTypes.VerifyInvalidBlock
+			return fmt.Errorf("intrinsic gas too low: tx %x intrinsic %d gas %d", tx.Hash(), intrGas, tx.Gas())
 		}
 
 		balance = new(big.Int).Sub(balance, tx.Cost())
 		if balance.Cmp(big.NewInt(0)) < 0 {
-			log.Error("Insufficient funds for gas * price + value", "txHash", tx.Hash().String())
-			return coreTypes.VerifyInvalidBlock
+			return fmt.Errorf("insufficient funds for gas * price + value: tx %x", tx.Hash())
 		}
 
 		blockGasUsed = new(big.Int).Add(blockGasUsed, new(big.Int).SetUint64(tx.Gas()))
 		if blockGasUsed.Cmp(blockGasLimit) > 0 {
-			log.Error("Reach block gas limit", "gasUsed", blockGasUsed)
-			return coreTypes.VerifyInvalidBlock
+			return fmt.Errorf("reach block gas limit: gas used %v", blockGasUsed)
 		}
 		addressesBalance[msg.From()] = balance
 	}
-
-	return coreTypes.VerifyOK
+	return nil
 }
 
 // BlockDelivered is called when a block is add to the compaction chain.
@@ -471,13 +566,30 @@ func (d *DexconApp) BlockDelivered(
 
 	d.removeConfirmedBlock(blockHash)
 	d.deliveredHeight = block.Position.Height
+	d.recordDepthLocked()
+
+	// The block is finalized in the round consensus has reached, which is
+	// later than its own if blocks of the next round are confirmed already.
+	finalizationRound := block.Position.Round
+	if d.confirmedRound > finalizationRound {
+		finalizationRound = d.confirmedRound
+	}
+	if err := rawdb.WriteBlockFinalizationRound(d.chainDB, d.blockchain.CurrentBlock().Hash(), finalizationRound); err != nil {
+		log.Warn("Failed to write block finalization round", "hash", blockHash, "err", err)
+	}
 
 	// New blocks are finalized, notify other components.
-	go d.finalizedBlockFeed.Send(core.NewFinalizedBlockEvent{Block: d.blockchain.CurrentBlock()})
+	finalized := d.blockchain.CurrentBlock()
+	go d.finalizedBlockFeed.Send(core.NewFinalizedBlockEvent{Block: finalized})
+	if d.events != nil {
+		d.events.post(FinalizedBlockEvent{Block: finalized})
+	}
 }
 
 // BlockConfirmed is called when a block is confirmed.
 func (d *DexconApp) BlockConfirmed(block coreTypes.Block) {
+	d.rotator.observe(block.Position.Round)
+	d.quorum.onConfirmed(block.Position)
 	propBlockConfirmLatency.Update(time.Since(block.Timestamp).Nanoseconds() / 1000)
 
 	d.appMu.Lock()
@@ -487,6 +599,9 @@ func (d *DexconApp) BlockConfirmed(block coreTypes.Block) {
 	if err := d.addConfirmedBlock(&block); err != nil {
 		panic(err)
 	}
+	if block.Position.Round > d.confirmedRound {
+		d.confirmedRound = block.Position.Round
+	}
 }
 
 type addressInfo struct {
@@ -494,9 +609,10 @@ type addressInfo struct {
 }
 
 type blockInfo struct {
-	addresses map[common.Address]*addressInfo
-	block     *coreTypes.Block
-	txs       types.Transactions
+	addresses   map[common.Address]*addressInfo
+	block       *coreTypes.Block
+	txs         types.Transactions
+	confirmedAt time.Time
 }
 
 func (d *DexconApp) addConfirmedBlock(block *coreTypes.Block) error {
@@ -544,9 +660,10 @@ func (d *DexconApp) addConfirmedBlock(block *coreTypes.Block) error {
 	}
 
 	d.confirmedBlocks[block.Hash] = &blockInfo{
-		addresses: addressMap,
-		block:     block,
-		txs:       transactions,
+		addresses:   addressMap,
+		block:       block,
+		txs:         transactions,
+		confirmedAt: time.Now(),
 	}
 
 	d.undeliveredNum++
@@ -578,11 +695,141 @@ func (d *DexconApp) getConfirmedBlockByHash(hash coreCommon.Hash) (*coreTypes.Bl
 	return info.block, info.txs
 }
 
+// confirmedBlock returns a copy of the confirmed block of the given hash
+// awaiting delivery, nil if there is none.
+func (d *DexconApp) confirmedBlock(hash coreCommon.Hash) *coreTypes.Block {
+	d.appMu.RLock()
+	defer d.appMu.RUnlock()
+
+	info, exist := d.confirmedBlocks[hash]
+	if !exist {
+		return nil
+	}
+	return info.block.Clone()
+}
+
+// confirmedTxDepth returns the number of confirmed blocks, up to and
+// including the one carrying the transaction of the given hash, awaiting
+// delivery. It returns false if no confirmed block carries the transaction.
+func (d *DexconApp) confirmedTxDepth(hash common.Hash) (uint64, bool) {
+	d.appMu.RLock()
+	defer d.appMu.RUnlock()
+
+	for _, info := range d.confirmedBlocks {
+		for _, tx := range info.txs {
+			if tx.Hash() == hash {
+				return info.block.Position.Height - d.deliveredHeight, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// confirmedNonce returns the highest nonce of the transactions of the address
+// in the confirmed blocks awaiting delivery. It returns false if there are
+// none.
+func (d *DexconApp) confirmedNonce(address common.Address) (uint64, bool) {
+	d.appMu.RLock()
+	defer d.appMu.RUnlock()
+	nonce, ok := d.addressNonce[address]
+	return nonce, ok
+}
+
+// undeliveredCount returns the number of confirmed blocks awaiting delivery.
+func (d *DexconApp) undeliveredCount() uint64 {
+	d.appMu.RLock()
+	defer d.appMu.RUnlock()
+	return d.undeliveredNum
+}
+
+// finalizationDepthLimit is the number of latest deliveries the finalization
+// depth is averaged over.
+const finalizationDepthLimit = 128
+
+// finalizationDepth is how far the blocks confirmed by consensus are ahead of
+// the latest finalized block.
+type finalizationDepth struct {
+	finalized uint64        // Height of the latest finalized block
+	blocks    uint64        // Confirmed blocks above the finalized one
+	span      time.Duration // Consensus time between the finalized and the highest confirmed block
+}
+
+// depthLocked returns the current finalization depth. The caller must hold
+// appMu.
+func (d *DexconApp) depthLocked() finalizationDepth {
+	finalized := d.blockchain.CurrentBlock()
+	depth := finalizationDepth{finalized: finalized.NumberU64()}
+	for _, info := range d.confirmedBlocks {
+		height := info.block.Position.Height
+		if height <= depth.finalized+depth.blocks {
+			continue
+		}
+		depth.blocks = height - depth.finalized
+		depth.span = info.block.Timestamp.Sub(time.Unix(0, int64(finalized.Time())*int64(time.Millisecond)))
+	}
+	if depth.span < 0 {
+		depth.span = 0
+	}
+	return depth
+}
+
+// recordDepthLocked keeps the current finalization depth as the one of the
+// latest delivery. The caller must hold appMu.
+func (d *DexconApp) recordDepthLocked() {
+	depth := d.depthLocked()
+	if len(d.depths) < finalizationDepthLimit {
+		d.depths = append(d.depths, depth)
+		return
+	}
+	d.depths[d.nextDepth] = depth
+	d.nextDepth = (d.nextDepth + 1) % finalizationDepthLimit
+}
+
+// recentFinalizationDepth returns the current finalization depth along with
+// the ones at the latest deliveries.
+func (d *DexconApp) recentFinalizationDepth() (finalizationDepth, []finalizationDepth) {
+	d.appMu.RLock()
+	defer d.appMu.RUnlock()
+
+	recent := make([]finalizationDepth, len(d.depths))
+	copy(recent, d.depths)
+	return d.depthLocked(), recent
+}
+
+// pendingBlock is a block confirmed by consensus but not delivered yet.
+type pendingBlock struct {
+	block       *coreTypes.Block
+	confirmedAt time.Time
+}
+
+// pendingBlocks returns the blocks confirmed but not delivered yet, ordered
+// by height.
+func (d *DexconApp) pendingBlocks() []pendingBlock {
+	d.appMu.RLock()
+	defer d.appMu.RUnlock()
+
+	pending := make([]pendingBlock, 0, len(d.confirmedBlocks))
+	for _, info := range d.confirmedBlocks {
+		pending = append(pending, pendingBlock{
+			block:       info.block.Clone(),
+			confirmedAt: info.confirmedAt,
+		})
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].block.Position.Height < pending[j].block.Position.Height
+	})
+	return pending
+}
+
 func (d *DexconApp) SubscribeNewFinalizedBlockEvent(
 	ch chan<- core.NewFinalizedBlockEvent) event.Subscription {
 	return d.scope.Track(d.finalizedBlockFeed.Subscribe(ch))
 }
 
 func (d *DexconApp) Stop() {
+	d.stopPreparing()
+	if d.events != nil {
+		d.events.stop()
+	}
 	d.scope.Close()
 }
@@ -24,6 +24,7 @@ import (
 	"github.com/dexon-foundation/dexon/crypto"
 	"github.com/dexon-foundation/dexon/ethdb"
 	"github.com/dexon-foundation/dexon/event"
+	"github.com/dexon-foundation/dexon/params"
 	"github.com/dexon-foundation/dexon/rlp"
 )
 
@@ -2343,12 +2344,149 @@ func newDexon(masterKey *ecdsa.PrivateKey, accountNum int) (*Dexon, []*ecdsa.Pri
 	}
 
 	txPoolConfig := core.DefaultTxPoolConfig
+	txPoolConfig.Journal = ""
 	dex.txPool = core.NewTxPool(txPoolConfig, chainConfig, dex.blockchain)
 
-	dex.APIBackend = &DexAPIBackend{dex, nil}
-	dex.governance = NewDexconGovernance(dex.APIBackend, dex.chainConfig, config.PrivateKey)
+	dex.APIBackend = &DexAPIBackend{dex: dex}
+	dex.governance = NewDexconGovernance(dex.APIBackend, dex.chainConfig,
+		crypto.PubkeyToAddress(config.PrivateKey.PublicKey), keyTxSigner(config.PrivateKey))
 	engine.SetGovStateFetcher(dex.governance)
 	dex.app = NewDexconApp(dex.txPool, dex.blockchain, dex.governance, db, &config)
 
 	return dex, accounts, nil
 }
+
+func TestPreparePayloadRateLimit(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+
+	tx, err := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(1),
+		params.TxGas, dex.governance.DexconConfiguration(0).MinGasPrice, nil),
+		types.NewEIP155Signer(dex.chainConfig.ChainID), key)
+	if err != nil {
+		t.Fatalf("failed to sign tx: %v", err)
+	}
+	if err := dex.txPool.AddLocal(tx); err != nil {
+		t.Fatalf("failed to add tx: %v", err)
+	}
+
+	// prepare returns the number of non-empty payloads of n preparations.
+	prepare := func(n int) (full int, elapsed time.Duration) {
+		start := time.Now()
+		for i := 0; i < n; i++ {
+			payload, err := dex.app.PreparePayload(coreTypes.Position{Height: 1})
+			if err != nil {
+				t.Fatalf("failed to prepare payload: %v", err)
+			}
+			if len(payload) > 0 {
+				full++
+			}
+		}
+		return full, time.Since(start)
+	}
+
+	// Unlimited by default.
+	if full, _ := prepare(10); full != 10 {
+		t.Errorf("unlimited preparation throttled: %d of %d payloads", full, 10)
+	}
+
+	// Preparing blocks faster than the limit blocks until the rate allows,
+	// and no preparation is dropped.
+	dex.app.config.MaxBlocksPerSecond = 20
+	dex.app.lastPrepare = time.Time{}
+	full, elapsed := prepare(6)
+	if full != 6 {
+		t.Errorf("throttled preparation dropped: %d of %d payloads", full, 6)
+	}
+	if want := 5 * 50 * time.Millisecond; elapsed < want {
+		t.Errorf("rate not enforced: 6 blocks prepared in %v, want at least %v", elapsed, want)
+	}
+
+	// Stopping releases a preparation waiting for the rate to allow.
+	dex.app.config.MaxBlocksPerSecond = 0.1
+	prepared := make(chan struct{})
+	go func() {
+		dex.app.PreparePayload(coreTypes.Position{Height: 1})
+		close(prepared)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	dex.app.stopPreparing()
+	select {
+	case <-prepared:
+	case <-time.After(time.Second):
+		t.Error("throttled preparation not released on stop")
+	}
+}
+
+func TestPreparePayloadSizeLimit(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	signer := types.NewEIP155Signer(dex.chainConfig.ChainID)
+	gasPrice := dex.governance.DexconConfiguration(0).MinGasPrice
+
+	// Many more small transactions than the block gas limit allows.
+	var txs types.Transactions
+	for nonce := uint64(0); nonce < 200; nonce++ {
+		tx, err := types.SignTx(types.NewTransaction(nonce, common.Address{1},
+			big.NewInt(1), params.TxGas, gasPrice, nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		txs = append(txs, tx)
+	}
+	for i, err := range dex.txPool.AddLocals(txs) {
+		if err != nil {
+			t.Fatalf("failed to add tx %d: %v", i, err)
+		}
+	}
+
+	prepare := func() (types.Transactions, int) {
+		payload, err := dex.app.PreparePayload(coreTypes.Position{Height: 1})
+		if err != nil {
+			t.Fatalf("failed to prepare payload: %v", err)
+		}
+		var prepared types.Transactions
+		if err := rlp.DecodeBytes(payload, &prepared); err != nil {
+			t.Fatalf("failed to decode payload: %v", err)
+		}
+		return prepared, len(payload)
+	}
+
+	// Without a size limit the gas limit binds.
+	gasLimit := dex.governance.DexconConfiguration(0).BlockGasLimit
+	prepared, _ := prepare()
+	if want := int(gasLimit / params.TxGas); len(prepared) != want {
+		t.Fatalf("gas bound tx count mismatch: have %d, want %d", len(prepared), want)
+	}
+
+	// The size limit binds well before the gas limit.
+	limit := uint64(2000)
+	dex.app.config.MaxBlockPayloadBytes = limit
+	prepared, size := prepare()
+	if uint64(size) > limit {
+		t.Errorf("payload size %d exceeds limit %d", size, limit)
+	}
+	if len(prepared) == 0 || len(prepared) >= int(gasLimit/params.TxGas) {
+		t.Fatalf("size bound tx count %d not limited by size", len(prepared))
+	}
+	if next := uint64(size) + uint64(txs[len(prepared)].Size()); next <= limit {
+		t.Errorf("payload of %d bytes stopped short of the limit %d", size, limit)
+	}
+	for i, tx := range prepared {
+		if tx.Hash() != txs[i].Hash() {
+			t.Errorf("tx %d mismatch: have %x, want %x", i, tx.Hash(), txs[i].Hash())
+		}
+	}
+}
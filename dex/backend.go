@@ -18,10 +18,17 @@
 package dex
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
+	coreEcdsa "github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
+	coreDb "github.com/dexon-foundation/dexon-consensus/core/db"
 	"github.com/dexon-foundation/dexon-consensus/core/syncer"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
 	"github.com/dexon-foundation/dexon/accounts"
 	"github.com/dexon-foundation/dexon/consensus"
 	"github.com/dexon-foundation/dexon/consensus/dexcon"
@@ -29,6 +36,8 @@ import (
 	"github.com/dexon-foundation/dexon/core/bloombits"
 	"github.com/dexon-foundation/dexon/core/rawdb"
 	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/dex/db"
 	"github.com/dexon-foundation/dexon/dex/downloader"
 	"github.com/dexon-foundation/dexon/eth/filters"
 	"github.com/dexon-foundation/dexon/eth/gasprice"
@@ -37,6 +46,7 @@ import (
 	"github.com/dexon-foundation/dexon/indexer"
 	"github.com/dexon-foundation/dexon/internal/ethapi"
 	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/metrics"
 	"github.com/dexon-foundation/dexon/node"
 	"github.com/dexon-foundation/dexon/p2p"
 	"github.com/dexon-foundation/dexon/params"
@@ -48,8 +58,11 @@ type Dexon struct {
 	config      *Config
 	chainConfig *params.ChainConfig
 
-	// Channel for shutting down the service
-	shutdownChan chan bool // Channel for shutting down the Ethereum
+	// Channel for shutting down the service, every background loop of the
+	// service selects on it and is registered in loopWg.
+	shutdownChan chan bool
+	shutdownOnce sync.Once
+	loopWg       sync.WaitGroup
 
 	// Handlers
 	txPool          *core.TxPool
@@ -57,7 +70,8 @@ type Dexon struct {
 	protocolManager *ProtocolManager
 
 	// DB interfaces
-	chainDb ethdb.Database // Block chain database
+	chainDb ethdb.Database  // Block chain database
+	blockDB coreDb.Database // Consensus block database
 
 	eventMux       *event.TypeMux
 	engine         consensus.Engine
@@ -72,8 +86,29 @@ type Dexon struct {
 	app        *DexconApp
 	governance *DexconGovernance
 	network    *DexconNetwork
+	recovery   *Recovery
+	signer     *fencedSigner // Signer of the consensus messages
+	rotator    *keyRotator   // Key rotations scheduled on the validator
 
-	bp *blockProposer
+	rotationFile string // File the scheduled key rotation is saved in, empty if ephemeral
+	nodeKeyFile  string // File the node key is loaded from, empty if ephemeral
+
+	keyMu sync.RWMutex // Protects the validator key and signer against key rotations
+
+	txRejections *txRejectionFeed // Inbound transactions rejected by the pool
+
+	bp            *blockProposer
+	reorgGuard    *reorgGuard
+	compactor     *syncCompactor
+	flusher       *finalizeFlusher
+	roundNotifier *roundNotifier
+
+	quorumWatchdog *quorumWatchdog // Reports rounds stalled without quorum, nil if disabled
+
+	catchupLock   sync.Mutex
+	catchupCancel context.CancelFunc // Cancels the running manual catch-up
+
+	consensusPaused int32 // Whether consensus is paused with StopProposing
 
 	networkID     uint64
 	netRPCService *ethapi.PublicNetAPI
@@ -82,6 +117,26 @@ type Dexon struct {
 }
 
 func New(ctx *node.ServiceContext, config *Config) (*Dexon, error) {
+	if err := validateCacheConfig(config); err != nil {
+		return nil, err
+	}
+	if err := validateVMConfig(config); err != nil {
+		return nil, err
+	}
+	if total, exceeded := cacheBudgetExceeded(config); exceeded {
+		log.Warn("Cache allowance exceeds memory budget, node may run out of memory",
+			"total", total, "budget", config.MemoryBudget,
+			"database", config.DatabaseCache, "clean", config.TrieCleanCache,
+			"dirty", config.TrieDirtyCache)
+	}
+	// Blocks past the fast sync pivot may still be rolled back, blocks
+	// delivered by consensus are final otherwise. Indexing shallower
+	// sections risks stale bloom bits.
+	if depth := uint64(downloader.FsMinFullBlocks); !config.NoBloomIndex && config.BloomConfirms < depth {
+		log.Warn("Bloom confirmations below finality depth, logs may be indexed from a reorganized chain",
+			"confirms", config.BloomConfirms, "depth", depth)
+	}
+
 	// Consensus.
 	chainDb, err := CreateDB(ctx, config, "chaindata")
 	if err != nil {
@@ -93,6 +148,9 @@ func New(ctx *node.ServiceContext, config *Config) (*Dexon, error) {
 		return nil, genesisErr
 	}
 	log.Info("Initialised chain configuration", "config", chainConfig)
+	if err := validateNetworkId(config, chainConfig); err != nil {
+		return nil, err
+	}
 
 	if !config.SkipBcVersionCheck {
 		bcVersion := rawdb.ReadDatabaseVersion(chainDb)
@@ -102,18 +160,30 @@ func New(ctx *node.ServiceContext, config *Config) (*Dexon, error) {
 		}
 		rawdb.WriteDatabaseVersion(chainDb, core.BlockChainVersion)
 	}
+	blockDB, err := db.Open(config.BlockDBEngine, chainDb)
+	if err != nil {
+		return nil, err
+	}
+	if blockDB, ok := blockDB.(*db.DB); ok {
+		blockDB.Meter("dex/db/blockdb/")
+	}
 	engine := dexcon.New()
 
 	dex := &Dexon{
 		config:         config,
 		chainDb:        chainDb,
+		blockDB:        blockDB,
+		signer:         newFencedSigner(coreEcdsa.NewPrivateKeyFromECDSA(config.PrivateKey)),
+		rotator:        new(keyRotator),
+		rotationFile:   ctx.ResolvePath(datadirKeyRotation),
+		nodeKeyFile:    ctx.ResolvePath(datadirNodeKey),
+		txRejections:   new(txRejectionFeed),
 		chainConfig:    chainConfig,
 		eventMux:       ctx.EventMux,
 		accountManager: ctx.AccountManager,
 		shutdownChan:   make(chan bool),
 		networkID:      config.NetworkId,
 		bloomRequests:  make(chan chan *bloombits.Retrieval),
-		bloomIndexer:   NewBloomIndexer(chainDb, params.BloomBitsBlocks, params.BloomConfirms),
 		engine:         engine,
 	}
 
@@ -124,9 +194,21 @@ func New(ctx *node.ServiceContext, config *Config) (*Dexon, error) {
 			EVMInterpreter:          config.EVMInterpreter,
 			IsBlockProposer:         config.BlockProposerEnabled,
 		}
-		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieCleanLimit: config.TrieCleanCache, TrieDirtyLimit: config.TrieDirtyCache, TrieTimeLimit: config.TrieTimeout}
+		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieCleanLimit: config.TrieCleanCache, TrieDirtyLimit: config.TrieDirtyCache, TrieTimeLimit: config.TrieTimeout, FlushOnFinalize: config.FlushOnFinalize}
 	)
 	dex.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, dex.chainConfig, dex.engine, vmConfig, nil)
+	if err != nil {
+		return nil, err
+	}
+	dex.blockchain.SetForkChoice(newFinalityForkChoice(dex.blockchain))
+
+	genesisState, err := dex.blockchain.StateAt(dex.blockchain.Genesis().Root())
+	if err != nil {
+		return nil, err
+	}
+	if err := validateDexconGenesis(chainConfig, &vm.GovernanceState{StateDB: genesisState}); err != nil {
+		return nil, err
+	}
 
 	// Rewind the chain in case of an incompatible config upgrade.
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
@@ -134,7 +216,12 @@ func New(ctx *node.ServiceContext, config *Config) (*Dexon, error) {
 		dex.blockchain.SetHead(compat.RewindTo)
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
-	dex.bloomIndexer.Start(dex.blockchain)
+	if config.NoBloomIndex {
+		log.Info("Bloom index disabled, log filters scan blocks directly")
+	} else {
+		dex.bloomIndexer = NewBloomIndexer(chainDb, params.BloomBitsBlocks, config.BloomConfirms)
+		dex.bloomIndexer.Start(dex.blockchain)
+	}
 
 	if config.Indexer.Enable {
 		dex.indexer = indexer.NewIndexerFromConfig(
@@ -149,16 +236,30 @@ func New(ctx *node.ServiceContext, config *Config) (*Dexon, error) {
 	}
 	dex.txPool = core.NewTxPool(config.TxPool, dex.chainConfig, dex.blockchain)
 
-	dex.APIBackend = &DexAPIBackend{dex, nil}
+	dex.APIBackend = &DexAPIBackend{
+		dex:        dex,
+		logQueries: filters.NewQueryLimiter(config.MaxConcurrentFilters),
+	}
 	gpoParams := config.GPO
 	if gpoParams.Default == nil {
 		gpoParams.Default = config.DefaultGasPrice
 	}
 	dex.APIBackend.gpo = gasprice.NewOracle(dex.APIBackend, gpoParams)
+	if config.PersistGPO {
+		restoreGasPrice(chainDb, dex.APIBackend.gpo, config.PersistGPOWindow)
+	}
 
 	// Dexcon related objects.
-	dex.governance = NewDexconGovernance(dex.APIBackend, dex.chainConfig, config.PrivateKey)
+	dex.governance = NewDexconGovernance(dex.APIBackend, dex.chainConfig,
+		crypto.PubkeyToAddress(config.PrivateKey.PublicKey), keyTxSigner(config.PrivateKey))
+
+	// Serve the notary set and group public key computed before a restart
+	// from the database.
+	dex.governance.LoadRoundCache(dex.governance.Round())
+	dex.governance.LoadRoundCache(dex.governance.Round() + 1)
 	dex.app = NewDexconApp(dex.txPool, dex.blockchain, dex.governance, chainDb, config)
+	dex.app.events = newEventQueue(dex.eventMux)
+	dex.app.rotator = dex.rotator
 
 	// Set config fetcher so engine can fetch current system configuration from state.
 	engine.SetGovStateFetcher(dex.governance)
@@ -178,18 +279,154 @@ func New(ctx *node.ServiceContext, config *Config) (*Dexon, error) {
 		return nil, err
 	}
 
+	pm.broadcastWeights = config.BroadcastWeights
+	pm.reservedValidatorPeers = config.ReservedValidatorPeers
+	pm.evictionPolicy = config.PeerEvictionPolicy
+	pm.preferFinalizedPeers = config.PreferFinalizedSyncPeers
+	pm.handshakeExtensions = config.HandshakeExtensions
+	pm.handshakeExtension = config.HandshakeExtension
+	if config.SigVerifyCacheSize > 0 {
+		pm.voteSigCache = newVoteSigCache(config.SigVerifyCacheSize)
+	}
+	pm.consensusMsgRate = config.PeerConsensusMsgRate
+	pm.txRejections = dex.txRejections
+	pm.downloader.SetImportQueueHighWater(config.ImportQueueHighWater)
+	if config.ConsensusTraceFile != "" {
+		path := ctx.ResolvePath(config.ConsensusTraceFile)
+		if pm.consensusTracer, err = newConsensusTracer(path); err != nil {
+			return nil, fmt.Errorf("failed to open consensus trace file: %v", err)
+		}
+		log.Warn("Capturing consensus messages", "file", path)
+	}
 	dex.protocolManager = pm
 	dex.network = NewDexconNetwork(pm)
+	nodeID := coreTypes.NewNodeID(coreEcdsa.NewPublicKeyFromECDSA(&config.PrivateKey.PublicKey))
+	dex.network.voteHistory = newVoteHistory(chainDb, nodeID, config.VoteHistoryRounds)
+	dex.network.rotator = dex.rotator
+	if config.BlockProposerEnabled && config.ProposalRebroadcast.Interval > 0 {
+		dex.network.rebroadcaster = newProposalRebroadcaster(
+			config.ProposalRebroadcast,
+			nodeID,
+			pm.BroadcastCoreBlock,
+			func() uint64 { return dex.blockchain.CurrentBlock().NumberU64() })
+	}
 
-	recovery := NewRecovery(chainConfig.Recovery, config.RecoveryNetworkRPC,
-		dex.governance, config.PrivateKey)
-	watchCat := syncer.NewWatchCat(recovery, dex.governance, 10*time.Second,
+	dex.recovery = NewRecovery(chainConfig.Recovery, config.RecoveryNetworkRPC,
+		dex.governance, &config.PrivateKey.PublicKey, keyTxSigner(config.PrivateKey))
+	watchCat := syncer.NewWatchCat(dex.recovery, dex.governance, 10*time.Second,
 		time.Duration(chainConfig.Recovery.Timeout)*time.Second, log.Root())
 
 	dex.bp = NewBlockProposer(dex, watchCat, dMoment)
+	if config.BlockProposerEnabled && config.RoundQuorumTimeout > 0 {
+		dex.quorumWatchdog = newQuorumWatchdog(config.RoundQuorumTimeout,
+			dex.governance.NotarySet, dex.bp.IsProposing, dex.app.events)
+		if pm.voteSigCache != nil {
+			dex.quorumWatchdog.verify = pm.voteSigCache.verify
+		}
+		dex.app.quorum = dex.quorumWatchdog
+		pm.quorumWatchdog = dex.quorumWatchdog
+	}
+	dex.reorgGuard = newReorgGuard(dex.blockchain, dex.blockchain.CurrentHeader())
+	if config.CompactAfterSyncBlocks > 0 {
+		dex.compactor = newSyncCompactor(config.CompactAfterSyncBlocks,
+			func() uint64 { return dex.blockchain.CurrentBlock().NumberU64() },
+			dex.CompactChainDB)
+	}
+	if config.FlushOnFinalize {
+		dex.flusher = newFinalizeFlusher(dex.blockchain)
+	}
+	dex.roundNotifier = newRoundNotifier(dex.governance, dex.blockchain,
+		metrics.GetOrRegisterHistogram("dex/round/latency", nil, metrics.NewExpDecaySample(1028, 0.015)))
+	dex.roundNotifier.mux = dex.eventMux
 	return dex, nil
 }
 
+// validateCacheConfig rejects trie cache limits the state trie cannot work
+// with.
+func validateCacheConfig(config *Config) error {
+	if config.TrieCleanCache <= 0 {
+		return fmt.Errorf("invalid trie clean cache %d MB, must be positive", config.TrieCleanCache)
+	}
+	if config.TrieDirtyCache <= 0 {
+		return fmt.Errorf("invalid trie dirty cache %d MB, must be positive", config.TrieDirtyCache)
+	}
+	return nil
+}
+
+// validateNetworkId rejects a network id different from the chain id of the
+// genesis, peers of the chain would refuse the handshake for a mismatching
+// network id.
+func validateNetworkId(config *Config, chainConfig *params.ChainConfig) error {
+	if config.AllowNetworkIdMismatch || chainConfig.ChainID == nil {
+		return nil
+	}
+	if !chainConfig.ChainID.IsUint64() || chainConfig.ChainID.Uint64() != config.NetworkId {
+		return fmt.Errorf("network id %d does not match chain id %v of the genesis, "+
+			"set the network id to the chain id or explicitly allow the mismatch",
+			config.NetworkId, chainConfig.ChainID)
+	}
+	return nil
+}
+
+// validateVMConfig rejects interpreter settings that do not resolve to an
+// interpreter of this build. The EVM would otherwise silently fall back to
+// the built-in interpreter, executing blocks differently than configured.
+func validateVMConfig(config *Config) error {
+	// No external EVM-C interpreter is supported yet, the built-in EVM is
+	// the only one available.
+	if config.EWASMInterpreter != "" {
+		return fmt.Errorf("unknown EWASM interpreter %q, only the default is supported", config.EWASMInterpreter)
+	}
+	if config.EVMInterpreter != "" {
+		return fmt.Errorf("unknown EVM interpreter %q, only the default is supported", config.EVMInterpreter)
+	}
+	return nil
+}
+
+// cacheBudgetExceeded returns the total cache allowance in megabytes and
+// whether it exceeds the configured memory budget.
+func cacheBudgetExceeded(config *Config) (int, bool) {
+	total := config.DatabaseCache + config.TrieCleanCache + config.TrieDirtyCache
+	return total, config.MemoryBudget > 0 && total > config.MemoryBudget
+}
+
+// validateDexconGenesis checks that the DEXON consensus parameters of the
+// genesis are present and consistent with the initial node set. A malformed
+// genesis would otherwise produce a node that can never reach agreement.
+func validateDexconGenesis(config *params.ChainConfig, genesisState *vm.GovernanceState) error {
+	dexcon := config.Dexcon
+	if dexcon == nil {
+		return errors.New("invalid genesis: missing dexcon config")
+	}
+	switch {
+	case dexcon.GenesisCRSText == "":
+		return errors.New("invalid genesis: empty genesis CRS text")
+	case dexcon.RoundLength == 0:
+		return errors.New("invalid genesis: zero round length")
+	case dexcon.LambdaBA == 0:
+		return errors.New("invalid genesis: zero lambdaBA")
+	case dexcon.LambdaDKG == 0:
+		return errors.New("invalid genesis: zero lambdaDKG")
+	case dexcon.BlockGasLimit == 0:
+		return errors.New("invalid genesis: zero block gas limit")
+	case dexcon.MinGasPrice == nil || dexcon.MinGasPrice.Sign() < 0:
+		return errors.New("invalid genesis: missing or negative min gas price")
+	case dexcon.MinStake == nil || dexcon.MinStake.Sign() <= 0:
+		return errors.New("invalid genesis: missing or non-positive min stake")
+	}
+
+	nodes := len(genesisState.QualifiedNodes())
+	notarySetSize := genesisState.NotarySetSize().Uint64()
+	if notarySetSize == 0 {
+		return errors.New("invalid genesis: zero notary set size")
+	}
+	if notarySetSize > uint64(nodes) {
+		return fmt.Errorf("invalid genesis: notary set size (%d) larger than initial node set (%d)",
+			notarySetSize, nodes)
+	}
+	return nil
+}
+
 func (s *Dexon) Protocols() []p2p.Protocol {
 	return s.protocolManager.SubProtocols
 }
@@ -200,6 +437,10 @@ func (s *Dexon) APIs() []rpc.API {
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
+	// Bound the log queries so they can't starve the consensus.
+	filterAPI := filters.NewPublicFilterAPI(s.APIBackend, false)
+	filterAPI.SetQueryLimiter(s.APIBackend.logQueries)
+
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
 		{
@@ -210,8 +451,28 @@ func (s *Dexon) APIs() []rpc.API {
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.APIBackend, false),
+			Service:   filterAPI,
 			Public:    true,
+		}, {
+			Namespace: "dex",
+			Version:   "1.0",
+			Service:   NewPublicDexonAPI(s),
+			Public:    true,
+		}, {
+			Namespace: "dex",
+			Version:   "1.0",
+			Service:   NewPrivateDexonGovernanceAPI(s),
+			IPCOnly:   true,
+		}, {
+			Namespace: "validator",
+			Version:   "1.0",
+			Service:   NewPrivateValidatorAPI(s),
+			IPCOnly:   true,
+		}, {
+			Namespace: "dex",
+			Version:   "1.0",
+			Service:   NewPrivateDexonAPI(s),
+			IPCOnly:   true,
 		}, {
 			Namespace: "admin",
 			Version:   "1.0",
@@ -236,7 +497,13 @@ func (s *Dexon) APIs() []rpc.API {
 
 func (s *Dexon) Start(srvr *p2p.Server) error {
 	// Start the bloom bits servicing goroutines
-	s.startBloomHandlers(params.BloomBitsBlocks)
+	if s.bloomIndexer != nil {
+		threads := s.config.BloomServiceThreads
+		if threads <= 0 {
+			threads = bloomServiceThreads
+		}
+		s.startBloomHandlers(params.BloomBitsBlocks, threads)
+	}
 
 	// Start the RPC service
 	s.netRPCService = ethapi.NewPublicNetAPI(srvr, s.NetVersion())
@@ -249,11 +516,44 @@ func (s *Dexon) Start(srvr *p2p.Server) error {
 		}
 		maxPeers -= s.config.LightPeers
 	}
+	if s.config.ReservedValidatorPeers >= maxPeers {
+		return fmt.Errorf("invalid peer config: reserved validator peer count (%d) >= total peer count (%d)", s.config.ReservedValidatorPeers, maxPeers)
+	}
+	s.reorgGuard.Start(s.blockchain)
+	if s.compactor != nil {
+		s.compactor.Start(s.eventMux)
+	}
+	if s.flusher != nil {
+		s.flusher.Start(s.eventMux)
+	}
+	s.roundNotifier.Start(s.blockchain)
+
 	// Start the networking layer and the light server if requested
 	s.protocolManager.Start(srvr, maxPeers)
+	s.restoreValidatorPeers()
+	if s.config.BlockProposerEnabled {
+		s.resumeKeyRotation()
+	}
+	if s.network.rebroadcaster != nil {
+		s.network.rebroadcaster.Start()
+	}
+	if s.quorumWatchdog != nil {
+		s.quorumWatchdog.Start()
+	}
+
+	if s.config.HealthCheckAddr != "" {
+		server, err := startHealthServer(s.config.HealthCheckAddr, s.checkHealth)
+		if err != nil {
+			return err
+		}
+		s.startLoop(func() {
+			<-s.shutdownChan
+			server.Close()
+		})
+	}
 
 	if s.config.BlockProposerEnabled {
-		go func() {
+		s.startLoop(func() {
 			// Since we might be in fast sync mode when started. wait for
 			// ChainHeadEvent before starting blockproposer, or else we will trigger
 			// watchcat.
@@ -263,31 +563,139 @@ func (s *Dexon) Start(srvr *p2p.Server) error {
 				sub := s.blockchain.SubscribeChainHeadEvent(ch)
 				defer sub.Unsubscribe()
 
-				<-ch
+				select {
+				case <-ch:
+				case <-s.shutdownChan:
+					return
+				}
+			}
+
+			// Joining consensus from far behind means replaying many missed
+			// rounds, sync the chain up to the network first.
+			if !s.waitForCatchup() {
+				return
+			}
+
+			// Give the node some time to connect to its peers before consensus
+			// begins. Bootstrap proposers must not be delayed past dMoment.
+			startAt := time.Now().Add(s.config.ConsensusStartDelay)
+			if s.bp.dMoment.After(time.Now()) && startAt.After(s.bp.dMoment) {
+				startAt = time.Now()
+			}
+			log.Info("Consensus will start", "at", startAt)
+			select {
+			case <-time.After(time.Until(startAt)):
+			case <-s.shutdownChan:
+				return
 			}
-			s.bp.Start()
-		}()
+			if err := s.bp.Start(); err != nil {
+				log.Error("Failed to start consensus", "err", err)
+				return
+			}
+			log.Info("Consensus started")
+		})
 	}
 	return nil
 }
 
+// startLoop runs a background loop of the service. The loop must return once
+// shutdownChan is closed, Stop waits for it before tearing down the
+// components it uses.
+func (s *Dexon) startLoop(loop func()) {
+	s.loopWg.Add(1)
+	go func() {
+		defer s.loopWg.Done()
+		loop()
+	}()
+}
+
+// shutdown closes shutdownChan and waits for the background loops to return.
+// It is safe to call more than once.
+func (s *Dexon) shutdown() {
+	s.shutdownOnce.Do(func() { close(s.shutdownChan) })
+	s.loopWg.Wait()
+}
+
 func (s *Dexon) Stop() error {
-	s.bloomIndexer.Close()
+	// Stop the background loops first, so none of them starts consensus or
+	// touches the databases past this point.
+	s.shutdown()
+
+	// Drain consensus next, it writes to the chain and the consensus
+	// databases until stopped. Preparations held by the block rate limit
+	// are released first.
+	s.app.stopPreparing()
+	drained := stopWithTimeout("block proposer", s.bp.Stop, s.config.ShutdownTimeout)
+
+	if s.bloomIndexer != nil {
+		s.bloomIndexer.Close()
+	}
+	s.reorgGuard.Stop()
+	s.roundNotifier.Stop()
+	s.txRejections.close()
+	if s.flusher != nil {
+		s.flusher.Stop()
+	}
 	s.blockchain.Stop()
 	s.engine.Close()
 	s.protocolManager.Stop()
+	if err := s.FlushTxJournal(); err != nil {
+		log.Warn("Failed to flush transaction journal", "err", err)
+	}
 	s.txPool.Stop()
+	if s.compactor != nil {
+		s.compactor.Stop()
+	}
 	s.eventMux.Stop()
-	s.bp.Stop()
+	if s.network.rebroadcaster != nil {
+		s.network.rebroadcaster.Stop()
+	}
+	if s.quorumWatchdog != nil {
+		s.quorumWatchdog.Stop()
+	}
 	s.app.Stop()
 	if s.indexer != nil {
 		s.indexer.Stop()
 	}
+	if err := s.protocolManager.consensusTracer.close(); err != nil {
+		log.Warn("Failed to close consensus trace file", "err", err)
+	}
+	if s.config.PersistGPO {
+		persistGasPrice(s.chainDb, s.APIBackend.gpo)
+	}
+	// Closing the databases under a consensus still writing to them corrupts
+	// them, leave them to the process exit instead.
+	if !drained {
+		log.Error("Databases left open, consensus still running")
+		return nil
+	}
+	s.blockDB.Close()
 	s.chainDb.Close()
-	close(s.shutdownChan)
 	return nil
 }
 
+// stopWithTimeout calls stop, giving up waiting for it to return after the
+// timeout. It reports whether the component stopped in time, a zero timeout
+// waits forever.
+func stopWithTimeout(name string, stop func(), timeout time.Duration) bool {
+	if timeout <= 0 {
+		stop()
+		return true
+	}
+	done := make(chan struct{})
+	go func() {
+		stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		log.Error("Component did not stop in time", "component", name, "timeout", timeout)
+		return false
+	}
+}
+
 func (s *Dexon) IsCoreSyncing() bool {
 	return s.bp.IsCoreSyncing()
 }
@@ -296,18 +704,55 @@ func (s *Dexon) IsProposing() bool {
 	return s.bp.IsProposing()
 }
 
-// CreateDB creates the chain database.
+// FlushTxJournal rewrites the journal of the local transactions from the ones
+// in the pool, making sure they survive a restart. It is also done on Stop.
+func (s *Dexon) FlushTxJournal() error {
+	return s.txPool.RotateJournal()
+}
+
+// CreateDB creates the chain database, metered under the dex/db/<name>/
+// namespace.
 func CreateDB(ctx *node.ServiceContext, config *Config, name string) (ethdb.Database, error) {
 	db, err := ctx.OpenDatabase(name, config.DatabaseCache, config.DatabaseHandles)
 	if err != nil {
 		return nil, err
 	}
 	if db, ok := db.(*ethdb.LDBDatabase); ok {
-		db.Meter("eth/db/chaindata/")
+		db.Meter("dex/db/" + name + "/")
 	}
 	return db, nil
 }
 
+// SupportedDexVersions returns the versions of the dex protocol advertised
+// by the node, the primary one first.
+func (d *Dexon) SupportedDexVersions() []uint {
+	versions := make([]uint, 0, len(d.protocolManager.SubProtocols))
+	for _, proto := range d.protocolManager.SubProtocols {
+		versions = append(versions, proto.Version)
+	}
+	return versions
+}
+
+// NegotiatedVersion returns the dex protocol version settled with the given
+// peer, false if the peer is not connected.
+func (d *Dexon) NegotiatedVersion(id string) (uint, bool) {
+	p := d.protocolManager.peers.Peer(id)
+	if p == nil {
+		return 0, false
+	}
+	return uint(p.version), true
+}
+
+// DumpGenesis returns the genesis specification the node runs, rebuilt from
+// the stored genesis block and chain config including the Dexcon section.
+func (d *Dexon) DumpGenesis() (json.RawMessage, error) {
+	genesis, err := core.ReadGenesis(d.chainDb)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(genesis)
+}
+
 func (d *Dexon) AccountManager() *accounts.Manager { return d.accountManager }
 func (d *Dexon) BlockChain() *core.BlockChain      { return d.blockchain }
 func (d *Dexon) TxPool() *core.TxPool              { return d.txPool }
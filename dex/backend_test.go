@@ -0,0 +1,681 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	coreDb "github.com/dexon-foundation/dexon-consensus/core/db"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/common/hexutil"
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/bloombits"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/core/state"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/dex/db"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/metrics"
+	"github.com/dexon-foundation/dexon/node"
+	"github.com/dexon-foundation/dexon/p2p"
+	"github.com/dexon-foundation/dexon/p2p/enode"
+	"github.com/dexon-foundation/dexon/params"
+	"github.com/dexon-foundation/dexon/rlp"
+	"github.com/dexon-foundation/dexon/rpc"
+)
+
+func newTestGenesisState(t *testing.T, genesis *core.Genesis) *vm.GovernanceState {
+	db := ethdb.NewMemDatabase()
+	block := genesis.MustCommit(db)
+	statedb, err := state.New(block.Root(), state.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to open genesis state: %v", err)
+	}
+	return &vm.GovernanceState{StateDB: statedb}
+}
+
+func TestValidateDexconGenesis(t *testing.T) {
+	genesis := core.DefaultTestnetGenesisBlock()
+	genesisState := newTestGenesisState(t, genesis)
+
+	if err := validateDexconGenesis(genesis.Config, genesisState); err != nil {
+		t.Fatalf("failed to validate testnet genesis: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(config *params.DexconConfig)
+	}{
+		{"empty CRS text", func(config *params.DexconConfig) { config.GenesisCRSText = "" }},
+		{"zero round length", func(config *params.DexconConfig) { config.RoundLength = 0 }},
+		{"zero lambdaBA", func(config *params.DexconConfig) { config.LambdaBA = 0 }},
+		{"zero lambdaDKG", func(config *params.DexconConfig) { config.LambdaDKG = 0 }},
+		{"zero block gas limit", func(config *params.DexconConfig) { config.BlockGasLimit = 0 }},
+		{"nil min gas price", func(config *params.DexconConfig) { config.MinGasPrice = nil }},
+		{"nil min stake", func(config *params.DexconConfig) { config.MinStake = nil }},
+	}
+	for _, test := range tests {
+		chainConfig := *genesis.Config
+		dexconConfig := *chainConfig.Dexcon
+		test.modify(&dexconConfig)
+		chainConfig.Dexcon = &dexconConfig
+		if err := validateDexconGenesis(&chainConfig, genesisState); err == nil {
+			t.Errorf("%s: expect validation error", test.name)
+		}
+	}
+
+	chainConfig := *genesis.Config
+	chainConfig.Dexcon = nil
+	if err := validateDexconGenesis(&chainConfig, genesisState); err == nil {
+		t.Error("missing dexcon config: expect validation error")
+	}
+
+	// Without any staked node the notary set can never be filled.
+	genesis = core.DefaultTestnetGenesisBlock()
+	for addr, account := range genesis.Alloc {
+		account.Staked = big.NewInt(0)
+		genesis.Alloc[addr] = account
+	}
+	if err := validateDexconGenesis(genesis.Config, newTestGenesisState(t, genesis)); err == nil {
+		t.Error("empty node set: expect validation error")
+	}
+}
+
+func TestValidateVMConfig(t *testing.T) {
+	tests := []struct {
+		ewasm, evm string
+		valid      bool
+	}{
+		{"", "", true},
+		{"/usr/lib/libhera.so", "", false},
+		{"", "/usr/lib/libevmone.so:opt=1", false},
+		{"bogus", "bogus", false},
+	}
+	for _, test := range tests {
+		config := DefaultConfig
+		config.EWASMInterpreter, config.EVMInterpreter = test.ewasm, test.evm
+		if err := validateVMConfig(&config); (err == nil) != test.valid {
+			t.Errorf("ewasm %q evm %q: validity mismatch: have %v, want %v",
+				test.ewasm, test.evm, err == nil, test.valid)
+		}
+	}
+}
+
+func TestValidateNetworkId(t *testing.T) {
+	tests := []struct {
+		networkId uint64
+		allow     bool
+		valid     bool
+	}{
+		{237, false, true},
+		{238, false, false},
+		{238, true, true},
+	}
+	for _, test := range tests {
+		config := DefaultConfig
+		config.NetworkId, config.AllowNetworkIdMismatch = test.networkId, test.allow
+		if err := validateNetworkId(&config, params.MainnetChainConfig); (err == nil) != test.valid {
+			t.Errorf("network id %d allow %v: validity mismatch: have %v, want %v",
+				test.networkId, test.allow, err == nil, test.valid)
+		}
+	}
+
+	// A mismatching node fails to start.
+	stack, err := node.New(&node.Config{P2P: p2p.Config{NoDiscovery: true}})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	config := DefaultConfig
+	config.Genesis = core.DefaultTestnetGenesisBlock()
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		return New(ctx, &config)
+	}); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	if err := stack.Start(); err == nil {
+		stack.Stop()
+		t.Fatal("node with mismatching network id started")
+	}
+}
+
+func TestValidateCacheConfig(t *testing.T) {
+	tests := []struct {
+		clean, dirty int
+		valid        bool
+	}{
+		{256, 256, true},
+		{1, 1, true},
+		{0, 256, false},
+		{256, 0, false},
+		{-1, 256, false},
+		{256, -1, false},
+	}
+	for _, test := range tests {
+		config := DefaultConfig
+		config.TrieCleanCache, config.TrieDirtyCache = test.clean, test.dirty
+		if err := validateCacheConfig(&config); (err == nil) != test.valid {
+			t.Errorf("clean %d dirty %d: validity mismatch: have %v, want %v",
+				test.clean, test.dirty, err == nil, test.valid)
+		}
+	}
+}
+
+func TestCacheBudgetExceeded(t *testing.T) {
+	config := DefaultConfig
+	config.DatabaseCache, config.TrieCleanCache, config.TrieDirtyCache = 512, 256, 256
+
+	tests := []struct {
+		budget   int
+		exceeded bool
+	}{
+		{0, false},
+		{1024, false},
+		{1023, true},
+		{2048, false},
+	}
+	for _, test := range tests {
+		config.MemoryBudget = test.budget
+		total, exceeded := cacheBudgetExceeded(&config)
+		if total != 1024 {
+			t.Errorf("total allowance mismatch: have %d, want %d", total, 1024)
+		}
+		if exceeded != test.exceeded {
+			t.Errorf("budget %d: exceeded mismatch: have %v, want %v", test.budget, exceeded, test.exceeded)
+		}
+	}
+}
+
+// newTestNode starts a networkless node running the Dexon service, the
+// configuration is customized by the given function.
+func newTestNode(t *testing.T, configure func(*Config)) (*node.Node, *Dexon) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	stack, err := node.New(&node.Config{
+		P2P: p2p.Config{PrivateKey: key, NoDiscovery: true, MaxPeers: 1},
+	})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+
+	config := DefaultConfig
+	config.Genesis = core.DefaultTestnetGenesisBlock()
+	config.NetworkId = config.Genesis.Config.ChainID.Uint64()
+	config.PrivateKey = key
+	if configure != nil {
+		configure(&config)
+	}
+	err = stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		return New(ctx, &config)
+	})
+	if err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+
+	var dex *Dexon
+	if err := stack.Service(&dex); err != nil {
+		stack.Stop()
+		t.Fatalf("failed to get service: %v", err)
+	}
+	return stack, dex
+}
+
+func TestNoBloomIndex(t *testing.T) {
+	stack, dex := newTestNode(t, func(config *Config) {
+		config.NoBloomIndex = true
+	})
+	defer stack.Stop()
+
+	if dex.bloomIndexer != nil {
+		t.Error("bloom indexer created while disabled")
+	}
+	if _, sections := dex.APIBackend.BloomStatus(); sections != 0 {
+		t.Errorf("section count mismatch: have %d, want %d", sections, 0)
+	}
+}
+
+func TestFlushOnFinalize(t *testing.T) {
+	stack, dex := newTestNode(t, func(config *Config) {
+		config.FlushOnFinalize = true
+		config.TrieTimeout = time.Nanosecond
+		key := config.PrivateKey
+		config.Genesis.Alloc[crypto.PubkeyToAddress(key.PublicKey)] = core.GenesisAccount{
+			Balance:   big.NewInt(100000000000000000),
+			Staked:    big.NewInt(50000000000000000),
+			PublicKey: crypto.FromECDSAPub(&key.PublicKey),
+		}
+	})
+	defer stack.Stop()
+
+	// The first block of the round is always on disk, the second one only
+	// gets there through the finalization flush.
+	var block *types.Block
+	for i := 0; i < 2; i++ {
+		var err error
+		if block, err = deliverTestBlock(dex, dex.config.PrivateKey, 0, nil); err != nil {
+			t.Fatalf("failed to deliver block: %v", err)
+		}
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		if has, _ := dex.chainDb.Has(block.Root().Bytes()); has {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("finalized state not flushed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMemoryBlockDB(t *testing.T) {
+	stack, dex := newTestNode(t, func(config *Config) {
+		config.BlockDBEngine = db.EngineMemory
+		// Stake the node key so it may propose the delivered blocks.
+		key := config.PrivateKey
+		config.Genesis.Alloc[crypto.PubkeyToAddress(key.PublicKey)] = core.GenesisAccount{
+			Balance:   big.NewInt(100000000000000000),
+			Staked:    big.NewInt(50000000000000000),
+			PublicKey: crypto.FromECDSAPub(&key.PublicKey),
+		}
+	})
+	defer stack.Stop()
+
+	if _, ok := dex.blockDB.(*coreDb.MemBackedDB); !ok {
+		t.Fatalf("block database type mismatch: have %T, want %T", dex.blockDB, &coreDb.MemBackedDB{})
+	}
+
+	// Store the delivered blocks as consensus does, none of them reaches the
+	// chain database.
+	chainBlockDB := db.NewDatabase(dex.chainDb)
+	for i := 0; i < 3; i++ {
+		block, err := deliverTestBlock(dex, dex.config.PrivateKey, 0, nil)
+		if err != nil {
+			t.Fatalf("failed to deliver block: %v", err)
+		}
+		var coreBlock coreTypes.Block
+		if err := rlp.DecodeBytes(block.Header().DexconMeta, &coreBlock); err != nil {
+			t.Fatalf("failed to decode dexcon meta: %v", err)
+		}
+		if err := dex.blockDB.PutBlock(coreBlock); err != nil {
+			t.Fatalf("failed to put block: %v", err)
+		}
+		if err := dex.blockDB.PutCompactionChainTipInfo(coreBlock.Hash, block.NumberU64()); err != nil {
+			t.Fatalf("failed to put tip: %v", err)
+		}
+		if !dex.blockDB.HasBlock(coreBlock.Hash) {
+			t.Errorf("block %d not stored", block.NumberU64())
+		}
+		if chainBlockDB.HasBlock(coreBlock.Hash) {
+			t.Errorf("block %d stored into chain database", block.NumberU64())
+		}
+	}
+	if head := dex.blockchain.CurrentBlock().NumberU64(); head != 3 {
+		t.Errorf("head mismatch: have %d, want %d", head, 3)
+	}
+	if _, height := dex.blockDB.GetCompactionChainTipInfo(); height != 3 {
+		t.Errorf("tip height mismatch: have %d, want %d", height, 3)
+	}
+}
+
+func TestDumpGenesis(t *testing.T) {
+	stack, dex := newTestNode(t, func(config *Config) {
+		key := config.PrivateKey
+		config.Genesis.Alloc[crypto.PubkeyToAddress(key.PublicKey)] = core.GenesisAccount{
+			Balance:   big.NewInt(100000000000000000),
+			Staked:    big.NewInt(50000000000000000),
+			PublicKey: crypto.FromECDSAPub(&key.PublicKey),
+			NodeInfo:  core.NodeInfo{Name: "dump"},
+		}
+	})
+	defer stack.Stop()
+
+	// Advance the chain so the dump can not come from the head state.
+	if _, err := deliverTestBlock(dex, dex.config.PrivateKey, 0, nil); err != nil {
+		t.Fatalf("failed to deliver block: %v", err)
+	}
+
+	dump, err := dex.DumpGenesis()
+	if err != nil {
+		t.Fatalf("failed to dump genesis: %v", err)
+	}
+	var genesis core.Genesis
+	if err := json.Unmarshal(dump, &genesis); err != nil {
+		t.Fatalf("failed to decode genesis: %v", err)
+	}
+	if genesis.Config.Dexcon == nil {
+		t.Fatal("dexcon config missing")
+	}
+	_, hash, err := core.SetupGenesisBlock(ethdb.NewMemDatabase(), &genesis)
+	if err != nil {
+		t.Fatalf("failed to setup genesis: %v", err)
+	}
+	if want := dex.blockchain.Genesis().Hash(); hash != want {
+		t.Errorf("genesis hash mismatch: have %x, want %x", hash, want)
+	}
+
+	// The dump is deterministic.
+	again, err := dex.DumpGenesis()
+	if err != nil {
+		t.Fatalf("failed to dump genesis: %v", err)
+	}
+	if !bytes.Equal(dump, again) {
+		t.Error("genesis dump not deterministic")
+	}
+}
+
+func TestIPCOnlyAPIs(t *testing.T) {
+	stack, dex := newTestNode(t, nil)
+	defer stack.Stop()
+
+	// Governance proposals are not exposed over WebSocket, even if all the
+	// modules are.
+	listener, handler, err := rpc.StartWSEndpoint("127.0.0.1:0", dex.APIs(),
+		[]string{"dex"}, nil, true)
+	if err != nil {
+		t.Fatalf("failed to start websocket endpoint: %v", err)
+	}
+	defer listener.Close()
+
+	ws := rpc.DialInProc(handler)
+	defer ws.Close()
+	var crs hexutil.Bytes
+	if err := ws.Call(&crs, "dex_crs", hexutil.Uint64(0)); err != nil {
+		t.Errorf("failed to call public method: %v", err)
+	}
+	err = ws.Call(nil, "dex_proposeCRS", hexutil.Uint64(1), hexutil.Bytes{1})
+	if rpcErr, ok := err.(rpc.Error); !ok || rpcErr.ErrorCode() != -32601 {
+		t.Errorf("proposal method exposed over websocket: %v", err)
+	}
+	// Neither is transaction cancellation, signing with the node key.
+	err = ws.Call(nil, "dex_cancelTransaction", common.Hash{})
+	if rpcErr, ok := err.(rpc.Error); !ok || rpcErr.ErrorCode() != -32601 {
+		t.Errorf("cancel method exposed over websocket: %v", err)
+	}
+
+	// They are available in-process, as over IPC.
+	client, err := stack.Attach()
+	if err != nil {
+		t.Fatalf("failed to attach to node: %v", err)
+	}
+	defer client.Close()
+	err = client.Call(nil, "dex_proposeCRS", hexutil.Uint64(1), hexutil.Bytes{1})
+	if rpcErr, ok := err.(rpc.Error); ok && rpcErr.ErrorCode() == -32601 {
+		t.Errorf("proposal method not exposed in-process: %v", err)
+	}
+	err = client.Call(nil, "dex_cancelTransaction", common.Hash{})
+	if rpcErr, ok := err.(rpc.Error); ok && rpcErr.ErrorCode() == -32601 {
+		t.Errorf("cancel method not exposed in-process: %v", err)
+	}
+}
+
+func TestStopWithTimeout(t *testing.T) {
+	var stopped bool
+	if !stopWithTimeout("fast", func() { stopped = true }, time.Second) || !stopped {
+		t.Error("fast component not reported stopped")
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	start := time.Now()
+	if stopWithTimeout("stuck", func() { <-release }, 50*time.Millisecond) {
+		t.Error("stuck component reported stopped")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("timeout not honored: took %v", elapsed)
+	}
+}
+
+func TestStopBackgroundLoops(t *testing.T) {
+	stack, dex := newTestNode(t, func(config *Config) {
+		config.BlockProposerEnabled = true
+		config.ConsensusStartDelay = time.Hour
+		config.HealthCheckAddr = "127.0.0.1:0"
+	})
+
+	done := make(chan struct{})
+	go func() {
+		stack.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("service not stopped")
+	}
+	select {
+	case <-dex.shutdownChan:
+	default:
+		t.Fatal("shutdown channel not closed")
+	}
+
+	// Every loop has returned, the bloom handlers no longer take requests.
+	waited := make(chan struct{})
+	go func() {
+		dex.loopWg.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("background loops still running")
+	}
+	select {
+	case dex.bloomRequests <- make(chan *bloombits.Retrieval):
+		t.Error("bloom request accepted after shutdown")
+	case <-time.After(50 * time.Millisecond):
+	}
+	// Shutting down again must not close the channel twice.
+	dex.shutdown()
+}
+
+func TestFlushTxJournal(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "dex-tx-journal")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := core.DefaultTxPoolConfig
+	config.Journal = filepath.Join(dir, "transactions.rlp")
+	dex.txPool.Stop()
+	dex.txPool = core.NewTxPool(config, dex.chainConfig, dex.blockchain)
+
+	signer := types.NewEIP155Signer(dex.chainConfig.ChainID)
+	gasPrice := dex.governance.GetHeadState().Configuration().MinGasPrice
+	newTx := func(nonce uint64, gasPrice *big.Int) *types.Transaction {
+		tx := types.NewTransaction(nonce, common.HexToAddress("0x1234"), big.NewInt(1), params.TxGas, gasPrice, nil)
+		tx, err := types.SignTx(tx, signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		return tx
+	}
+	// The replaced transaction stays in the journal until it is flushed.
+	replacement := newTx(0, new(big.Int).Mul(gasPrice, big.NewInt(2)))
+	txs := []*types.Transaction{newTx(0, gasPrice), replacement, newTx(1, gasPrice)}
+	for _, tx := range txs {
+		if err := dex.txPool.AddLocal(tx); err != nil {
+			t.Fatalf("failed to add tx: %v", err)
+		}
+	}
+	journaled := func() int {
+		data, err := ioutil.ReadFile(config.Journal)
+		if err != nil {
+			t.Fatalf("failed to read journal: %v", err)
+		}
+		stream := rlp.NewStream(bytes.NewReader(data), 0)
+		n := 0
+		for {
+			if err := stream.Decode(new(types.Transaction)); err != nil {
+				return n
+			}
+			n++
+		}
+	}
+	if n := journaled(); n != len(txs) {
+		t.Fatalf("journal size mismatch: have %d, want %d", n, len(txs))
+	}
+	if err := dex.FlushTxJournal(); err != nil {
+		t.Fatalf("failed to flush journal: %v", err)
+	}
+	if n := journaled(); n != 2 {
+		t.Fatalf("flushed journal size mismatch: have %d, want %d", n, 2)
+	}
+	dex.txPool.Stop()
+
+	pool := core.NewTxPool(config, dex.chainConfig, dex.blockchain)
+	defer pool.Stop()
+	for _, tx := range []*types.Transaction{replacement, txs[2]} {
+		if pool.Get(tx.Hash()) == nil {
+			t.Errorf("tx %x not reloaded", tx.Hash())
+		}
+	}
+}
+
+func TestPersistValidatorPeers(t *testing.T) {
+	stack, dex := newTestNode(t, nil)
+	defer stack.Stop()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	node := enode.NewV4(&key.PublicKey, net.ParseIP("127.0.0.1"), 30303, 30303)
+	if err := dex.AddValidatorPeer(node); err != nil {
+		t.Fatalf("failed to add validator peer: %v", err)
+	}
+	if urls := rawdb.ReadValidatorPeers(dex.chainDb); len(urls) != 1 || urls[0] != node.String() {
+		t.Fatalf("persisted peers mismatch: %v", urls)
+	}
+
+	// Pinned peers are restored from the database on startup.
+	dex.protocolManager.peers.RemoveValidatorPeer(node.ID().String())
+	dex.restoreValidatorPeers()
+	if !dex.protocolManager.peers.IsValidatorPeer(node.ID().String()) {
+		t.Fatal("validator peer not restored")
+	}
+
+	if removed, err := dex.RemoveValidatorPeer(node); !removed || err != nil {
+		t.Fatalf("failed to remove validator peer: %v", err)
+	}
+	if urls := rawdb.ReadValidatorPeers(dex.chainDb); len(urls) != 0 {
+		t.Errorf("removed peer still persisted: %v", urls)
+	}
+}
+
+func TestDatabaseMetrics(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dex-db-metrics")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := &node.ServiceContext{Config: &node.Config{DataDir: dir}}
+	config := DefaultConfig
+	for _, name := range []string{"chaindata", "testdata"} {
+		chainDb, err := CreateDB(ctx, &config, name)
+		if err != nil {
+			t.Fatalf("%s: failed to create database: %v", name, err)
+		}
+		defer chainDb.Close()
+		if metrics.DefaultRegistry.Get("dex/db/"+name+"/disk/read") == nil {
+			t.Errorf("%s: database not metered", name)
+		}
+	}
+
+	// The consensus block database is metered on its own.
+	stack, _ := newTestNode(t, nil)
+	defer stack.Stop()
+	for _, name := range []string{"dex/db/blockdb/read", "dex/db/blockdb/write"} {
+		if metrics.DefaultRegistry.Get(name) == nil {
+			t.Errorf("block database meter %s not registered", name)
+		}
+	}
+}
+
+func TestDefaultTestnetConfig(t *testing.T) {
+	sender, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	senderAddr := crypto.PubkeyToAddress(sender.PublicKey)
+	alloc := core.GenesisAlloc{senderAddr: {Balance: big.NewInt(params.Ether)}}
+
+	var validator *ecdsa.PrivateKey
+	stack, dex := newTestNode(t, func(config *Config) {
+		validator = config.PrivateKey
+		*config = *DefaultTestnetConfig(alloc, []*ecdsa.PublicKey{&validator.PublicKey})
+		config.PrivateKey = validator
+	})
+	defer stack.Stop()
+
+	state, err := dex.blockchain.State()
+	if err != nil {
+		t.Fatalf("failed to get state: %v", err)
+	}
+	if balance := state.GetBalance(senderAddr); balance.Cmp(big.NewInt(params.Ether)) != 0 {
+		t.Errorf("allocated balance mismatch: have %v, want %v", balance, params.Ether)
+	}
+	nodes := (&vm.GovernanceState{StateDB: state}).QualifiedNodes()
+	if len(nodes) != 1 || nodes[0].Owner != crypto.PubkeyToAddress(validator.PublicKey) {
+		t.Fatalf("initial node set mismatch: %v", nodes)
+	}
+
+	signer := types.NewEIP155Signer(dex.chainConfig.ChainID)
+	tx, err := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(1), params.TxGas,
+		dex.governance.DexconConfiguration(0).MinGasPrice, nil), signer, sender)
+	if err != nil {
+		t.Fatalf("failed to sign tx: %v", err)
+	}
+	block, err := deliverTestBlock(dex, validator, 0, types.Transactions{tx})
+	if err != nil {
+		t.Fatalf("failed to deliver block: %v", err)
+	}
+	if block.NumberU64() != 1 || len(block.Transactions()) != 1 {
+		t.Fatalf("block mismatch: number %d, %d txs", block.NumberU64(), len(block.Transactions()))
+	}
+	if block.Coinbase() != crypto.PubkeyToAddress(validator.PublicKey) {
+		t.Errorf("coinbase mismatch: have %x", block.Coinbase())
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	coreCrypto "github.com/dexon-foundation/dexon-consensus/core/crypto"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/rlp"
+	"golang.org/x/crypto/sha3"
+)
+
+// blockDBVersion is the version of the format written by ExportBlockDB.
+const blockDBVersion = 1
+
+var errBlockDBChecksum = errors.New("block database checksum mismatch")
+
+// blockDBHeader precedes the blocks of an exported block database. The blocks
+// are followed by the keccak256 checksum of their RLP encoding.
+type blockDBHeader struct {
+	Version   uint64
+	Count     uint64
+	TipHash   coreCommon.Hash
+	TipHeight uint64
+}
+
+// ExportBlockDB writes the consensus blocks of the compaction chain into w,
+// so a new node could bootstrap its consensus database from a trusted
+// snapshot.
+func (s *Dexon) ExportBlockDB(w io.Writer) error {
+	head := s.blockchain.CurrentBlock()
+	header := blockDBHeader{
+		Version: blockDBVersion,
+		Count:   head.NumberU64(),
+	}
+	if head.NumberU64() > 0 {
+		var tip coreTypes.Block
+		if err := rlp.DecodeBytes(head.Header().DexconMeta, &tip); err != nil {
+			return err
+		}
+		header.TipHash, header.TipHeight = tip.Hash, tip.Position.Height
+	}
+	if err := rlp.Encode(w, &header); err != nil {
+		return err
+	}
+
+	hasher := sha3.NewLegacyKeccak256()
+	for number := uint64(1); number <= header.Count; number++ {
+		block := s.blockchain.GetBlockByNumber(number)
+		if block == nil {
+			return fmt.Errorf("block #%d not found", number)
+		}
+		var meta coreTypes.Block
+		if err := rlp.DecodeBytes(block.Header().DexconMeta, &meta); err != nil {
+			return err
+		}
+		// Prefer the complete block stored by consensus core. The meta in the
+		// header has its payload stripped, it is restored from the body.
+		data := rawdb.ReadCoreBlockRLP(s.chainDb, common.Hash(meta.Hash))
+		if len(data) == 0 {
+			var err error
+			if !meta.IsEmpty() {
+				if meta.Payload, err = rlp.EncodeToBytes(block.Transactions()); err != nil {
+					return err
+				}
+			}
+			if data, err = rlp.EncodeToBytes(&meta); err != nil {
+				return err
+			}
+		}
+		hasher.Write(data)
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return rlp.Encode(w, common.BytesToHash(hasher.Sum(nil)))
+}
+
+// ImportBlockDB reads consensus blocks written by ExportBlockDB from r into
+// the consensus database. The blocks are staged in a temporary database and
+// only written once every block matches its hash and payload hash and the
+// checksum of the stream matches, a corrupted stream leaves the database
+// untouched.
+func (s *Dexon) ImportBlockDB(r io.Reader) error {
+	stream := rlp.NewStream(r, 0)
+
+	var header blockDBHeader
+	if err := stream.Decode(&header); err != nil {
+		return err
+	}
+	if header.Version != blockDBVersion {
+		return fmt.Errorf("unsupported block database version %d, want %d",
+			header.Version, blockDBVersion)
+	}
+
+	dir, err := ioutil.TempDir("", "blockdb-import")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	staging, err := ethdb.NewLDBDatabase(dir, 16, 16)
+	if err != nil {
+		return err
+	}
+	defer staging.Close()
+
+	var (
+		hasher = sha3.NewLegacyKeccak256()
+		batch  = staging.NewBatch()
+	)
+	for i := uint64(0); i < header.Count; i++ {
+		data, err := stream.Raw()
+		if err != nil {
+			return err
+		}
+		hasher.Write(data)
+
+		var block coreTypes.Block
+		if err := rlp.DecodeBytes(data, &block); err != nil {
+			return err
+		}
+		if err := verifyImportedBlock(&block); err != nil {
+			return err
+		}
+		if err := rawdb.WriteCoreBlockRLP(batch, common.Hash(block.Hash), data); err != nil {
+			return err
+		}
+		if err := flushBatch(batch, false); err != nil {
+			return err
+		}
+	}
+	if err := flushBatch(batch, true); err != nil {
+		return err
+	}
+
+	var checksum common.Hash
+	if err := stream.Decode(&checksum); err != nil {
+		return err
+	}
+	if checksum != common.BytesToHash(hasher.Sum(nil)) {
+		return errBlockDBChecksum
+	}
+
+	// Everything is verified, move the staged blocks into the database.
+	batch = s.chainDb.NewBatch()
+	it := staging.NewIterator()
+	defer it.Release()
+	for it.Next() {
+		if err := batch.Put(it.Key(), it.Value()); err != nil {
+			return err
+		}
+		if err := flushBatch(batch, false); err != nil {
+			return err
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	if err := flushBatch(batch, true); err != nil {
+		return err
+	}
+
+	_, height := rawdb.ReadCoreCompactionChainTip(s.chainDb)
+	if header.TipHeight > height {
+		if err := rawdb.WriteCoreCompactionChainTip(s.chainDb, header.TipHash, header.TipHeight); err != nil {
+			return err
+		}
+	}
+	log.Info("Imported block database", "blocks", header.Count, "tip", header.TipHeight)
+	return nil
+}
+
+// verifyImportedBlock checks that a block matches its hash and, unless it is
+// an empty block without payload, its payload hash.
+func verifyImportedBlock(block *coreTypes.Block) error {
+	if hash, err := coreUtils.HashBlock(block); err != nil || hash != block.Hash {
+		return fmt.Errorf("invalid block %x at position %v", block.Hash, block.Position)
+	}
+	if !block.IsEmpty() && coreCrypto.Keccak256Hash(block.Payload) != block.PayloadHash {
+		return fmt.Errorf("invalid payload of block %x at position %v", block.Hash, block.Position)
+	}
+	return nil
+}
+
+// flushBatch writes the batch once it reaches the ideal batch size, or
+// whatever it holds if force is set.
+func flushBatch(batch ethdb.Batch, force bool) error {
+	if !force && batch.ValueSize() < ethdb.IdealBatchSize {
+		return nil
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	batch.Reset()
+	return nil
+}
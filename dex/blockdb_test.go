@@ -0,0 +1,165 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/rlp"
+	"golang.org/x/crypto/sha3"
+)
+
+func TestExportImportBlockDB(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	source, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	tx, err := types.SignTx(
+		types.NewTransaction(0, common.Address{}, big.NewInt(1), 21000, big.NewInt(1e9), nil),
+		types.NewEIP155Signer(source.chainConfig.ChainID), key)
+	if err != nil {
+		t.Fatalf("failed to create tx: %v", err)
+	}
+	var hashes []common.Hash
+	for i := 0; i < 5; i++ {
+		var txs types.Transactions
+		if i == 0 {
+			txs = types.Transactions{tx}
+		}
+		block, err := deliverTestBlock(source, key, 0, txs)
+		if err != nil {
+			t.Fatalf("failed to deliver block: %v", err)
+		}
+		var meta coreTypes.Block
+		if err := rlp.DecodeBytes(block.Header().DexconMeta, &meta); err != nil {
+			t.Fatalf("failed to decode dexcon meta: %v", err)
+		}
+		hashes = append(hashes, common.Hash(meta.Hash))
+	}
+
+	var buf bytes.Buffer
+	if err := source.ExportBlockDB(&buf); err != nil {
+		t.Fatalf("failed to export block db: %v", err)
+	}
+	exported := buf.Bytes()
+
+	// A corrupted checksum is rejected without advancing the tip.
+	corrupted := append([]byte{}, exported...)
+	corrupted[len(corrupted)-1] ^= 0xff
+	target, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	if err := target.ImportBlockDB(bytes.NewReader(corrupted)); err != errBlockDBChecksum {
+		t.Fatalf("corrupted import error mismatch: have %v, want %v", err, errBlockDBChecksum)
+	}
+	if _, height := rawdb.ReadCoreCompactionChainTip(target.chainDb); height != 0 {
+		t.Errorf("tip advanced by corrupted import: %d", height)
+	}
+	for _, hash := range hashes {
+		if rawdb.HasCoreBlock(target.chainDb, hash) {
+			t.Fatalf("block %x written by corrupted import", hash)
+		}
+	}
+
+	// A block whose payload does not match its payload hash is rejected,
+	// even with a consistent checksum.
+	tampered, err := tamperBlockDBPayload(exported)
+	if err != nil {
+		t.Fatalf("failed to tamper block db: %v", err)
+	}
+	if err := target.ImportBlockDB(bytes.NewReader(tampered)); err == nil {
+		t.Fatal("expect error for tampered payload")
+	}
+	for _, hash := range hashes {
+		if rawdb.HasCoreBlock(target.chainDb, hash) {
+			t.Fatalf("block %x written by tampered import", hash)
+		}
+	}
+
+	// An unknown version is rejected.
+	header := blockDBHeader{Version: blockDBVersion + 1}
+	versioned, err := rlp.EncodeToBytes(&header)
+	if err != nil {
+		t.Fatalf("failed to encode header: %v", err)
+	}
+	if err := target.ImportBlockDB(bytes.NewReader(versioned)); err == nil {
+		t.Error("expect error for unknown version")
+	}
+
+	if err := target.ImportBlockDB(bytes.NewReader(exported)); err != nil {
+		t.Fatalf("failed to import block db: %v", err)
+	}
+	for _, hash := range hashes {
+		if !rawdb.HasCoreBlock(target.chainDb, hash) {
+			t.Errorf("block %x not imported", hash)
+		}
+	}
+	tipHash, tipHeight := rawdb.ReadCoreCompactionChainTip(target.chainDb)
+	if tipHeight != 5 || common.Hash(tipHash) != hashes[4] {
+		t.Errorf("tip mismatch: have %x at %d, want %x at %d", tipHash, tipHeight, hashes[4], 5)
+	}
+}
+
+// tamperBlockDBPayload re-encodes an exported block database with the
+// payload of its first block altered, keeping block hashes and checksum
+// consistent.
+func tamperBlockDBPayload(exported []byte) ([]byte, error) {
+	stream := rlp.NewStream(bytes.NewReader(exported), 0)
+	var header blockDBHeader
+	if err := stream.Decode(&header); err != nil {
+		return nil, err
+	}
+	out, err := rlp.EncodeToBytes(&header)
+	if err != nil {
+		return nil, err
+	}
+	hasher := sha3.NewLegacyKeccak256()
+	for i := uint64(0); i < header.Count; i++ {
+		var block coreTypes.Block
+		if err := stream.Decode(&block); err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			block.Payload = append(block.Payload, 0x80)
+		}
+		data, err := rlp.EncodeToBytes(&block)
+		if err != nil {
+			return nil, err
+		}
+		hasher.Write(data)
+		out = append(out, data...)
+	}
+	checksum, err := rlp.EncodeToBytes(common.BytesToHash(hasher.Sum(nil)))
+	if err != nil {
+		return nil, err
+	}
+	return append(out, checksum...), nil
+}
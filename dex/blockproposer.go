@@ -8,12 +8,10 @@ import (
 	"time"
 
 	dexCore "github.com/dexon-foundation/dexon-consensus/core"
-	coreEcdsa "github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
 	"github.com/dexon-foundation/dexon-consensus/core/syncer"
 	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
 
 	"github.com/dexon-foundation/dexon/core"
-	"github.com/dexon-foundation/dexon/dex/db"
 	"github.com/dexon-foundation/dexon/log"
 	"github.com/dexon-foundation/dexon/rlp"
 )
@@ -86,6 +84,11 @@ func (b *blockProposer) run(c *dexCore.Consensus) {
 	atomic.StoreInt32(&b.proposing, 1)
 	<-b.stopCh
 	log.Debug("Block proposer receive stop signal")
+
+	// Wait for the consensus core to finish, so it no longer writes to the
+	// databases once they are closed.
+	c.Stop()
+	log.Info("Consensus core stopped")
 }
 
 func (b *blockProposer) Stop() {
@@ -102,6 +105,11 @@ func (b *blockProposer) Stop() {
 	log.Info("Block proposer stopped")
 }
 
+// IsRunning reports whether the block proposer is started.
+func (b *blockProposer) IsRunning() bool {
+	return atomic.LoadInt32(&b.running) == 1
+}
+
 func (b *blockProposer) IsCoreSyncing() bool {
 	return atomic.LoadInt32(&b.syncing) == 1
 }
@@ -111,10 +119,9 @@ func (b *blockProposer) IsProposing() bool {
 }
 
 func (b *blockProposer) initConsensus() *dexCore.Consensus {
-	db := db.NewDatabase(b.dex.chainDb)
-	privkey := coreEcdsa.NewPrivateKeyFromECDSA(b.dex.config.PrivateKey)
+	_, signer := b.dex.validator()
 	return dexCore.NewConsensus(b.dMoment,
-		b.dex.app, b.dex.governance, db, b.dex.network, privkey, log.Root())
+		b.dex.app, b.dex.governance, b.dex.blockDB, b.dex.network, signer, log.Root())
 }
 
 func (b *blockProposer) syncConsensus() (*dexCore.Consensus, error) {
@@ -122,11 +129,10 @@ func (b *blockProposer) syncConsensus() (*dexCore.Consensus, error) {
 	defer atomic.StoreInt32(&b.syncing, 0)
 
 	cb := b.dex.blockchain.CurrentBlock()
+	_, signer := b.dex.validator()
 
-	db := db.NewDatabase(b.dex.chainDb)
-	privkey := coreEcdsa.NewPrivateKeyFromECDSA(b.dex.config.PrivateKey)
 	consensusSync := syncer.NewConsensus(cb.NumberU64(), b.dMoment, b.dex.app,
-		b.dex.governance, db, b.dex.network, privkey, log.Root())
+		b.dex.governance, b.dex.blockDB, b.dex.network, signer, log.Root())
 
 	// Start the watchCat.
 	b.watchCat.Start()
@@ -157,7 +163,7 @@ func (b *blockProposer) syncConsensus() (*dexCore.Consensus, error) {
 	}
 
 	// Sync all blocks in compaction chain to core.
-	_, coreHeight := db.GetCompactionChainTipInfo()
+	_, coreHeight := b.dex.blockDB.GetCompactionChainTipInfo()
 
 Loop:
 	for {
@@ -30,8 +30,8 @@ import (
 )
 
 const (
-	// bloomServiceThreads is the number of goroutines used globally by an Ethereum
-	// instance to service bloombits lookups for all running filters.
+	// bloomServiceThreads is the default number of goroutines used globally by
+	// an Ethereum instance to service bloombits lookups for all running filters.
 	bloomServiceThreads = 16
 
 	// bloomFilterThreads is the number of goroutines used locally per filter to
@@ -49,9 +49,10 @@ const (
 
 // startBloomHandlers starts a batch of goroutines to accept bloom bit database
 // retrievals from possibly a range of filters and serving the data to satisfy.
-func (dex *Dexon) startBloomHandlers(sectionSize uint64) {
-	for i := 0; i < bloomServiceThreads; i++ {
-		go func() {
+// They run until the service shuts down.
+func (dex *Dexon) startBloomHandlers(sectionSize uint64, threads int) {
+	for i := 0; i < threads; i++ {
+		dex.startLoop(func() {
 			for {
 				select {
 				case <-dex.shutdownChan:
@@ -75,7 +76,7 @@ func (dex *Dexon) startBloomHandlers(sectionSize uint64) {
 					request <- task
 				}
 			}
-		}()
+		})
 	}
 }
 
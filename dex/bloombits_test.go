@@ -0,0 +1,194 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/common/bitutil"
+	"github.com/dexon-foundation/dexon/core/bloombits"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/dex/downloader"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/params"
+)
+
+func TestBloomIndexerConfirms(t *testing.T) {
+	if DefaultConfig.BloomConfirms != params.BloomConfirms {
+		t.Errorf("default confirms mismatch: have %d, want %d",
+			DefaultConfig.BloomConfirms, params.BloomConfirms)
+	}
+
+	// With the head at block 17, sections of 8 blocks are complete up to
+	// block 15 without confirmations and up to block 7 with 4 of them.
+	for _, test := range []struct {
+		confirms uint64
+		sections uint64
+	}{
+		{confirms: 0, sections: 2},
+		{confirms: 4, sections: 1},
+	} {
+		pm, db := newTestProtocolManagerMust(t, downloader.FullSync, 17, nil, nil)
+		indexer := NewBloomIndexer(db, 8, test.confirms)
+		indexer.Start(pm.blockchain)
+
+		var sections uint64
+		for deadline := time.Now().Add(3 * time.Second); time.Now().Before(deadline); {
+			if sections, _, _ = indexer.Sections(); sections >= test.sections {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		// Give the indexer the chance to index sections beyond the expected.
+		time.Sleep(2 * bloomThrottling)
+		sections, _, _ = indexer.Sections()
+		indexer.Close()
+		pm.Stop()
+
+		if sections != test.sections {
+			t.Errorf("confirms %d: section count mismatch: have %d, want %d",
+				test.confirms, sections, test.sections)
+		}
+	}
+}
+
+// slowDatabase is a memory database taking the given time for a read, like
+// a database on disk.
+type slowDatabase struct {
+	*ethdb.MemDatabase
+	latency time.Duration
+}
+
+func (db *slowDatabase) Get(key []byte) ([]byte, error) {
+	time.Sleep(db.latency)
+	return db.MemDatabase.Get(key)
+}
+
+// newTestBloomService creates a node serving the bloom bits of the given
+// number of sections, each bit having the section number set.
+func newTestBloomService(sectionSize, sections uint64, latency time.Duration) *Dexon {
+	db := &slowDatabase{MemDatabase: ethdb.NewMemDatabase(), latency: latency}
+	for section := uint64(0); section < sections; section++ {
+		head := common.BigToHash(new(big.Int).SetUint64(section + 1))
+		rawdb.WriteCanonicalHash(db, head, (section+1)*sectionSize-1)
+
+		bits := make([]byte, sectionSize/8)
+		bits[section%uint64(len(bits))] = 0x80
+		for bit := uint(0); bit < 16; bit++ {
+			rawdb.WriteBloomBits(db, bit, section, head, bitutil.CompressBytes(bits))
+		}
+	}
+	return &Dexon{
+		chainDb:       db,
+		bloomRequests: make(chan chan *bloombits.Retrieval),
+		shutdownChan:  make(chan bool),
+	}
+}
+
+// retrieveBloomBits requests the bloom bits of the sections the way the
+// filters do.
+func retrieveBloomBits(dex *Dexon, bit uint, sections []uint64) *bloombits.Retrieval {
+	request := make(chan *bloombits.Retrieval)
+	dex.bloomRequests <- request
+	request <- &bloombits.Retrieval{Bit: bit, Sections: sections}
+	return <-request
+}
+
+func TestBloomHandlersShutdown(t *testing.T) {
+	const sectionSize = 4096
+	dex := newTestBloomService(sectionSize, 2, 0)
+	dex.startBloomHandlers(sectionSize, 4)
+
+	task := retrieveBloomBits(dex, 3, []uint64{0, 1})
+	if task.Error != nil {
+		t.Fatalf("failed to retrieve bloom bits: %v", task.Error)
+	}
+	for i, bits := range task.Bitsets {
+		want := make([]byte, sectionSize/8)
+		want[i] = 0x80
+		if !bytes.Equal(bits, want) {
+			t.Errorf("section %d: bloom bits mismatch", i)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		dex.shutdown()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("bloom handlers not stopped")
+	}
+	select {
+	case dex.bloomRequests <- make(chan *bloombits.Retrieval):
+		t.Error("request accepted after shutdown")
+	case <-time.After(50 * time.Millisecond):
+	}
+	// Shutting down again is a no-op.
+	dex.shutdown()
+}
+
+// BenchmarkBloomHandlers measures the bloom bits retrieval throughput of log
+// filters on a database with disk-like read latency.
+func BenchmarkBloomHandlers(b *testing.B) {
+	const (
+		sectionSize = 4096
+		sections    = 16
+		requesters  = 32
+	)
+	for _, threads := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("threads=%d", threads), func(b *testing.B) {
+			dex := newTestBloomService(sectionSize, sections, 50*time.Microsecond)
+			dex.startBloomHandlers(sectionSize, threads)
+			defer dex.shutdown()
+
+			all := make([]uint64, sections)
+			for i := range all {
+				all[i] = uint64(i)
+			}
+			requests := make(chan uint, b.N)
+			for i := 0; i < b.N; i++ {
+				requests <- uint(i % 16)
+			}
+			close(requests)
+
+			b.ResetTimer()
+			var wg sync.WaitGroup
+			for i := 0; i < requesters; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for bit := range requests {
+						if task := retrieveBloomBits(dex, bit, all); task.Error != nil {
+							b.Error(task.Error)
+						}
+					}
+				}()
+			}
+			wg.Wait()
+		})
+	}
+}
@@ -95,6 +95,32 @@ func (c *cache) votes(pos coreTypes.Position) []*coreTypes.Vote {
 	return votes
 }
 
+// commitVoters returns the number of distinct nodes the commit votes for the
+// block of the given hash at the position are from. The votes are kept as
+// received, only the ones passing accept are counted.
+func (c *cache) commitVoters(pos coreTypes.Position, hash coreCommon.Hash,
+	accept func(*coreTypes.Vote) bool) int {
+	c.lock.RLock()
+	var votes []*coreTypes.Vote
+	for key, vote := range c.voteCache[pos] {
+		if key.Type == coreTypes.VoteCom && key.BlockHash == hash {
+			votes = append(votes, vote)
+		}
+	}
+	c.lock.RUnlock()
+
+	voters := make(map[coreTypes.NodeID]struct{})
+	for _, vote := range votes {
+		if _, ok := voters[vote.ProposerID]; ok {
+			continue
+		}
+		if accept(vote) {
+			voters[vote.ProposerID] = struct{}{}
+		}
+	}
+	return len(voters)
+}
+
 func (c *cache) addBlocks(blocks []*coreTypes.Block) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
@@ -174,6 +200,24 @@ func (c *cache) blocks(hashes coreCommon.Hashes, includeDB bool) []*coreTypes.Bl
 	return cacheBlocks
 }
 
+// pendingBlocks returns the blocks above the given height which are not
+// finalized yet.
+func (c *cache) pendingBlocks(height uint64) []*coreTypes.Block {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	var blocks []*coreTypes.Block
+	for _, block := range c.blockCache {
+		if block.Position.Height <= height || block.IsFinalized() {
+			continue
+		}
+		if _, exist := c.finalizedBlockCache[block.Position]; exist {
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
 func (c *cache) finalizedBlock(pos coreTypes.Position) *coreTypes.Block {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
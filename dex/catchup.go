@@ -0,0 +1,189 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dexon-foundation/dexon/common/hexutil"
+	"github.com/dexon-foundation/dexon/log"
+)
+
+var (
+	// catchupCheckInterval is the interval between two checks of how far the
+	// local chain lags behind the network while waiting to join consensus.
+	catchupCheckInterval = 30 * time.Second
+
+	// catchupRetryInterval is the interval between two syncs of a manual
+	// catch-up which did not reach the target round yet.
+	catchupRetryInterval = time.Second
+
+	// catchupTimeout is the longest time a manual catch-up runs.
+	catchupTimeout = 30 * time.Minute
+)
+
+var (
+	errCatchupRunning   = errors.New("catch-up already running")
+	errCatchupCancelled = errors.New("catch-up cancelled")
+	errCatchupTimeout   = errors.New("catch-up timed out")
+)
+
+// CatchUpProgress is the progress of a manual catch-up.
+type CatchUpProgress struct {
+	StartRound  hexutil.Uint64 `json:"startRound"`
+	TargetRound hexutil.Uint64 `json:"targetRound"`
+	Round       hexutil.Uint64 `json:"round"`  // Round of the current head
+	Number      hexutil.Uint64 `json:"number"` // Number of the current head
+	Syncs       int            `json:"syncs"`  // Sync cycles run
+	Done        bool           `json:"done"`
+}
+
+// roundsBehind returns the number of rounds the local chain lags behind the
+// best known peer, along with that peer.
+func (s *Dexon) roundsBehind() (uint64, *peer) {
+	best := s.protocolManager.peers.BestPeer()
+	if best == nil {
+		return 0, nil
+	}
+	head := s.blockchain.CurrentBlock()
+	_, number := best.Head()
+	if number <= head.NumberU64() {
+		return 0, best
+	}
+	length := s.governance.DexconConfiguration(head.Round()).RoundLength
+	if length == 0 {
+		return 0, best
+	}
+	return (number - head.NumberU64()) / length, best
+}
+
+// waitForCatchup blocks until the local chain is within MaxCatchupRounds of
+// the network, forcing a sync with the best peer while it is not. It returns
+// false if the node is shut down before catching up.
+func (s *Dexon) waitForCatchup() bool {
+	if s.config.MaxCatchupRounds == 0 {
+		return true
+	}
+	for {
+		behind, best := s.roundsBehind()
+		if behind <= s.config.MaxCatchupRounds {
+			return true
+		}
+		log.Warn("Too far behind to join consensus, syncing chain first",
+			"behind", behind, "max", s.config.MaxCatchupRounds, "peer", best.id)
+		go s.protocolManager.synchronise(best, true)
+
+		select {
+		case <-time.After(catchupCheckInterval):
+		case <-s.shutdownChan:
+			return false
+		}
+	}
+}
+
+// catchUp syncs the chain with the best peer repeatedly until the head reaches
+// the target round. It is refused if the head is already past the target, and
+// stops on timeout, cancellation by CancelCatchUp or the given context. The
+// sync function runs a sync cycle against the given peer.
+func (s *Dexon) catchUp(ctx context.Context, target uint64, sync func(*peer)) (*CatchUpProgress, error) {
+	head := s.blockchain.CurrentBlock()
+	if head.Round() >= target {
+		return nil, fmt.Errorf("already at round %d, not behind target round %d", head.Round(), target)
+	}
+
+	s.catchupLock.Lock()
+	if s.catchupCancel != nil {
+		s.catchupLock.Unlock()
+		return nil, errCatchupRunning
+	}
+	ctx, cancel := context.WithTimeout(ctx, catchupTimeout)
+	s.catchupCancel = cancel
+	s.catchupLock.Unlock()
+
+	defer func() {
+		s.catchupLock.Lock()
+		s.catchupCancel = nil
+		s.catchupLock.Unlock()
+		cancel()
+	}()
+
+	progress := &CatchUpProgress{
+		StartRound:  hexutil.Uint64(head.Round()),
+		TargetRound: hexutil.Uint64(target),
+	}
+	log.Info("Catching up with the network", "round", head.Round(), "target", target)
+	for {
+		// Syncs making progress are retried right away, the loop is stopped
+		// here rather than only while waiting to retry.
+		select {
+		case <-s.shutdownChan:
+			return progress, errCatchupCancelled
+		case <-ctx.Done():
+			return progress, catchupContextErr(ctx)
+		default:
+		}
+		head = s.blockchain.CurrentBlock()
+		progress.Round = hexutil.Uint64(head.Round())
+		progress.Number = hexutil.Uint64(head.NumberU64())
+		if head.Round() >= target {
+			progress.Done = true
+			log.Info("Caught up with the network", "round", head.Round(), "syncs", progress.Syncs)
+			return progress, nil
+		}
+		if best := s.protocolManager.peers.BestPeer(); best != nil {
+			sync(best)
+			progress.Syncs++
+			if s.blockchain.CurrentBlock().NumberU64() > head.NumberU64() {
+				continue
+			}
+		}
+
+		select {
+		case <-time.After(catchupRetryInterval):
+		case <-s.shutdownChan:
+			return progress, errCatchupCancelled
+		case <-ctx.Done():
+			return progress, catchupContextErr(ctx)
+		}
+	}
+}
+
+// catchupContextErr returns the error a catch up stopped by the context
+// fails with.
+func catchupContextErr(ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return errCatchupTimeout
+	}
+	return errCatchupCancelled
+}
+
+// cancelCatchUp stops the running manual catch-up, and reports whether there
+// is one.
+func (s *Dexon) cancelCatchUp() bool {
+	s.catchupLock.Lock()
+	defer s.catchupLock.Unlock()
+
+	if s.catchupCancel == nil {
+		return false
+	}
+	s.catchupCancel()
+	return true
+}
@@ -0,0 +1,207 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/dex/downloader"
+)
+
+func TestWaitForCatchup(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+	dex.protocolManager = pm
+	dex.shutdownChan = make(chan bool)
+
+	// No peers, nothing to catch up with.
+	if behind, _ := dex.roundsBehind(); behind != 0 {
+		t.Errorf("rounds behind mismatch: have %d, want 0", behind)
+	}
+
+	// Simulate a peer five rounds ahead of the local chain.
+	p, _ := newTestPeer("peer", dex64, pm, true)
+	defer p.close()
+	waitForRegister(pm, 1)
+	length := dex.governance.DexconConfiguration(0).RoundLength
+	p.peer.SetHead(common.Hash{}, 5*length+1, 5)
+
+	if behind, best := dex.roundsBehind(); behind != 5 || best != p.peer {
+		t.Fatalf("rounds behind mismatch: have %d, want 5", behind)
+	}
+
+	dex.config.MaxCatchupRounds = 0
+	if !dex.waitForCatchup() {
+		t.Errorf("catch-up check should be disabled")
+	}
+	dex.config.MaxCatchupRounds = 5
+	if !dex.waitForCatchup() {
+		t.Errorf("node within the catch-up window should join consensus")
+	}
+
+	dex.config.MaxCatchupRounds = 2
+	defer func(interval time.Duration) { catchupCheckInterval = interval }(catchupCheckInterval)
+	catchupCheckInterval = 10 * time.Millisecond
+
+	done := make(chan bool)
+	go func() { done <- dex.waitForCatchup() }()
+	select {
+	case <-done:
+		t.Fatalf("node too far behind should not join consensus")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(dex.shutdownChan)
+	select {
+	case joined := <-done:
+		if joined {
+			t.Errorf("node should not join consensus after shutdown")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("catch-up wait not stopped on shutdown")
+	}
+}
+
+func TestCatchUp(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+	dex.protocolManager = pm
+	dex.shutdownChan = make(chan bool)
+
+	p, _ := newTestPeer("peer", dex64, pm, true)
+	defer p.close()
+	waitForRegister(pm, 1)
+
+	defer func(retry, timeout time.Duration) {
+		catchupRetryInterval, catchupTimeout = retry, timeout
+	}(catchupRetryInterval, catchupTimeout)
+	catchupRetryInterval = 10 * time.Millisecond
+
+	// The stubbed sync imports one block each cycle, entering the next
+	// round on the second cycle.
+	var synced []*peer
+	sync := func(p *peer) {
+		synced = append(synced, p)
+		round := uint64(len(synced) - 1)
+		if _, err := deliverTestBlock(dex, key, round, nil); err != nil {
+			t.Errorf("failed to deliver block: %v", err)
+		}
+	}
+	progress, err := dex.catchUp(context.Background(), 1, sync)
+	if err != nil {
+		t.Fatalf("failed to catch up: %v", err)
+	}
+	if !progress.Done || progress.StartRound != 0 || progress.Round != 1 || progress.Syncs != 2 {
+		t.Errorf("progress mismatch: %+v", progress)
+	}
+	if len(synced) != 2 || synced[0] != p.peer {
+		t.Errorf("sync peers mismatch: %v", synced)
+	}
+	if head := dex.blockchain.CurrentBlock(); head.Round() != 1 || head.NumberU64() != 2 {
+		t.Errorf("head mismatch: round %d, number %d", head.Round(), head.NumberU64())
+	}
+
+	// The target is not behind the chain any more.
+	if _, err := dex.catchUp(context.Background(), 1, sync); err == nil {
+		t.Error("expect error for target already reached")
+	}
+	if len(synced) != 2 {
+		t.Errorf("sync run for target already reached")
+	}
+
+	// A sync which makes no progress times out.
+	idle := func(*peer) {}
+	catchupTimeout = 50 * time.Millisecond
+	if _, err := dex.catchUp(context.Background(), 2, idle); err != errCatchupTimeout {
+		t.Errorf("error mismatch: have %v, want %v", err, errCatchupTimeout)
+	}
+
+	// A running catch-up can be cancelled, and only one runs at a time.
+	catchupTimeout = time.Minute
+	errc := make(chan error)
+	go func() {
+		_, err := dex.catchUp(context.Background(), 2, idle)
+		errc <- err
+	}()
+	for i := 0; ; i++ {
+		dex.catchupLock.Lock()
+		running := dex.catchupCancel != nil
+		dex.catchupLock.Unlock()
+		if running {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("catch-up not started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := dex.catchUp(context.Background(), 2, idle); err != errCatchupRunning {
+		t.Errorf("error mismatch: have %v, want %v", err, errCatchupRunning)
+	}
+	if !dex.cancelCatchUp() {
+		t.Fatal("running catch-up not cancelled")
+	}
+	select {
+	case err := <-errc:
+		if err != errCatchupCancelled {
+			t.Errorf("error mismatch: have %v, want %v", err, errCatchupCancelled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("catch-up not stopped on cancel")
+	}
+	if dex.cancelCatchUp() {
+		t.Error("cancelled a catch-up not running")
+	}
+	// A sync making progress on each cycle is stopped by the context too.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	progressing := func(*peer) {
+		if _, err := deliverTestBlock(dex, key, 1, nil); err != nil {
+			t.Errorf("failed to deliver block: %v", err)
+		}
+		if dex.blockchain.CurrentBlock().NumberU64() == 5 {
+			cancel()
+		}
+	}
+	progress, err = dex.catchUp(ctx, 2, progressing)
+	if err != errCatchupCancelled {
+		t.Errorf("error mismatch: have %v, want %v", err, errCatchupCancelled)
+	}
+	if progress == nil || progress.Syncs != 3 {
+		t.Errorf("progress mismatch after cancel: %+v", progress)
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/dex/downloader"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/event"
+	"github.com/dexon-foundation/dexon/log"
+)
+
+var errCompactionNotSupported = errors.New("chain database does not support compaction")
+
+// CompactChainDB compacts the full key range of the chain database, which is
+// fragmented after a large sync.
+func (s *Dexon) CompactChainDB() error {
+	db, ok := s.chainDb.(*ethdb.LDBDatabase)
+	if !ok {
+		return errCompactionNotSupported
+	}
+	before := dirSize(db.Path())
+	log.Info("Compacting chain database", "size", before)
+
+	start := time.Now()
+	if err := db.LDB().CompactRange(util.Range{}); err != nil {
+		log.Error("Failed to compact chain database", "err", err)
+		return err
+	}
+	log.Info("Compacted chain database", "before", before, "after", dirSize(db.Path()),
+		"elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// dirSize returns the total size of the files in the directory.
+func dirSize(dir string) common.StorageSize {
+	var size int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return common.StorageSize(size)
+}
+
+// syncCompactor compacts the chain database once the downloader finishes
+// a sync importing at least threshold blocks. Compaction runs in its own
+// goroutine so the downloader events, posted synchronously, are never held
+// up by it. Syncs finishing while a compaction is running are coalesced
+// into a single following compaction.
+type syncCompactor struct {
+	threshold uint64
+	head      func() uint64
+	compact   func() error
+
+	sub     *event.TypeMuxSubscription
+	trigger chan struct{}
+	quit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newSyncCompactor(threshold uint64, head func() uint64, compact func() error) *syncCompactor {
+	return &syncCompactor{
+		threshold: threshold,
+		head:      head,
+		compact:   compact,
+		trigger:   make(chan struct{}, 1),
+		quit:      make(chan struct{}),
+	}
+}
+
+func (c *syncCompactor) Start(mux *event.TypeMux) {
+	c.sub = mux.Subscribe(downloader.StartEvent{}, downloader.DoneEvent{})
+
+	c.wg.Add(2)
+	go c.eventLoop()
+	go c.compactLoop()
+}
+
+func (c *syncCompactor) Stop() {
+	c.sub.Unsubscribe()
+	close(c.quit)
+	c.wg.Wait()
+}
+
+// eventLoop tracks the syncs of the downloader and triggers a compaction
+// after each large one.
+func (c *syncCompactor) eventLoop() {
+	defer c.wg.Done()
+
+	var start uint64
+	for ev := range c.sub.Chan() {
+		switch ev.Data.(type) {
+		case downloader.StartEvent:
+			start = c.head()
+		case downloader.DoneEvent:
+			if head := c.head(); head >= start+c.threshold {
+				log.Info("Large sync done, compacting chain database",
+					"from", start, "to", head)
+				select {
+				case c.trigger <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}
+}
+
+// compactLoop runs the triggered compactions.
+func (c *syncCompactor) compactLoop() {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case <-c.trigger:
+			c.compact()
+		case <-c.quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dexon-foundation/dexon/dex/downloader"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/event"
+)
+
+func TestCompactChainDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dexon-compaction-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := ethdb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	for i := 0; i < 1000; i++ {
+		if err := db.Put([]byte(fmt.Sprintf("key%d", i)), make([]byte, 100)); err != nil {
+			t.Fatalf("failed to put data: %v", err)
+		}
+	}
+
+	dex := &Dexon{chainDb: db}
+	if err := dex.CompactChainDB(); err != nil {
+		t.Fatalf("failed to compact database: %v", err)
+	}
+	if value, err := db.Get([]byte("key999")); err != nil || len(value) != 100 {
+		t.Errorf("data lost after compaction: %x (%v)", value, err)
+	}
+
+	dex = &Dexon{chainDb: ethdb.NewMemDatabase()}
+	if err := dex.CompactChainDB(); err != errCompactionNotSupported {
+		t.Errorf("error mismatch: have %v, want %v", err, errCompactionNotSupported)
+	}
+}
+
+func TestSyncCompactor(t *testing.T) {
+	var (
+		mux       = new(event.TypeMux)
+		head      uint64
+		heads     = make(chan uint64)
+		compacted = make(chan struct{}, 1)
+	)
+	compactor := newSyncCompactor(100, func() uint64 { return <-heads }, func() error {
+		compacted <- struct{}{}
+		return nil
+	})
+	compactor.Start(mux)
+	defer compactor.Stop()
+
+	// The head is read on both the start and the end of a sync.
+	syncBlocks := func(blocks uint64) {
+		mux.Post(downloader.StartEvent{})
+		heads <- head
+		head += blocks
+		mux.Post(downloader.DoneEvent{})
+		heads <- head
+	}
+
+	// Small syncs do not trigger compaction.
+	syncBlocks(99)
+	select {
+	case <-compacted:
+		t.Fatal("compacted after small sync")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	syncBlocks(100)
+	select {
+	case <-compacted:
+	case <-time.After(time.Second):
+		t.Fatal("not compacted after large sync")
+	}
+}
+
+func TestSyncCompactorCoalesce(t *testing.T) {
+	var (
+		mux       = new(event.TypeMux)
+		heads     = make(chan uint64)
+		started   = make(chan struct{}, 10)
+		release   = make(chan struct{})
+		compacted = make(chan struct{}, 10)
+	)
+	compactor := newSyncCompactor(100, func() uint64 { return <-heads }, func() error {
+		started <- struct{}{}
+		<-release
+		compacted <- struct{}{}
+		return nil
+	})
+	compactor.Start(mux)
+	defer compactor.Stop()
+
+	var head uint64
+	syncBlocks := func(blocks uint64) {
+		mux.Post(downloader.StartEvent{})
+		heads <- head
+		head += blocks
+		mux.Post(downloader.DoneEvent{})
+		heads <- head
+	}
+	syncBlocks(100)
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("compaction not started")
+	}
+
+	// Syncs are not held up by the running compaction, and the ones
+	// finishing meanwhile trigger a single compaction.
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			syncBlocks(100)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("sync events blocked by compaction")
+	}
+	close(release)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-compacted:
+		case <-time.After(time.Second):
+			t.Fatalf("compaction #%d not run", i)
+		}
+	}
+	select {
+	case <-compacted:
+		t.Fatal("syncs not coalesced")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
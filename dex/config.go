@@ -27,6 +27,8 @@ import (
 
 	"github.com/dexon-foundation/dexon/common"
 	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/dex/db"
 	"github.com/dexon-foundation/dexon/dex/downloader"
 	"github.com/dexon-foundation/dexon/eth/gasprice"
 	"github.com/dexon-foundation/dexon/indexer"
@@ -42,15 +44,34 @@ var DefaultConfig = Config{
 	TrieCleanCache: 256,
 	TrieDirtyCache: 256,
 	TrieTimeout:    60 * time.Minute,
+	BlockDBEngine:  db.EngineLevelDB,
+	BloomConfirms:  params.BloomConfirms,
+
+	BloomServiceThreads: bloomServiceThreads,
+
+	CompactAfterSyncBlocks: 100000,
 
 	TxPool: core.DefaultTxPoolConfig,
 	GPO: gasprice.Config{
 		Blocks:     20,
 		Percentile: 60,
 	},
+	PersistGPOWindow:     time.Hour,
+	BroadcastWeights:     DefaultBroadcastWeights,
+	HandshakeExtensions:  2,
+	HandshakeExtension:   time.Second,
+	ImportQueueHighWater: 4096,
 	BlockProposerEnabled: false,
+	ShutdownTimeout:      30 * time.Second,
+	RoundQuorumTimeout:   time.Minute,
+	MaxNotarizations:     1024,
+	MaxProposerScanDepth: 10000,
+	VoteHistoryRounds:    128,
 	DefaultGasPrice:      big.NewInt(params.GWei),
 	Indexer:              indexer.Config{},
+	ProposalRebroadcast: ProposalRebroadcastConfig{
+		MaxRetries: 3,
+	},
 }
 
 func init() {
@@ -67,6 +88,50 @@ func init() {
 	}
 }
 
+// DefaultTestnetConfig returns a configuration of a private test network
+// whose genesis allocates alloc and stakes the minimum stake for each of the
+// validators, which form the initial notary set. The Dexcon parameters are
+// the ones of the testnet, owned by the first validator.
+func DefaultTestnetConfig(alloc core.GenesisAlloc, validators []*ecdsa.PublicKey) *Config {
+	genesis := core.DefaultTestnetGenesisBlock()
+	chainConfig := *genesis.Config
+	dexconConfig := *chainConfig.Dexcon
+	chainConfig.Dexcon = &dexconConfig
+	genesis.Config = &chainConfig
+
+	genesis.Alloc = make(core.GenesisAlloc, len(alloc)+len(validators))
+	for addr, account := range alloc {
+		if account.Balance == nil {
+			account.Balance = new(big.Int)
+		}
+		if account.Staked == nil {
+			account.Staked = new(big.Int)
+		}
+		genesis.Alloc[addr] = account
+	}
+	for _, key := range validators {
+		addr := crypto.PubkeyToAddress(*key)
+		account, ok := genesis.Alloc[addr]
+		if !ok {
+			account = core.GenesisAccount{Balance: new(big.Int)}
+		}
+		account.Balance = new(big.Int).Add(account.Balance, dexconConfig.MinStake)
+		account.Staked = new(big.Int).Set(dexconConfig.MinStake)
+		account.PublicKey = crypto.FromECDSAPub(key)
+		genesis.Alloc[addr] = account
+	}
+	if len(validators) > 0 {
+		dexconConfig.Owner = crypto.PubkeyToAddress(*validators[0])
+		dexconConfig.NotarySetSize = uint32(len(validators))
+		dexconConfig.DKGSetSize = uint32(len(validators))
+	}
+
+	config := DefaultConfig
+	config.Genesis = genesis
+	config.NetworkId = chainConfig.ChainID.Uint64()
+	return &config
+}
+
 //go:generate gencodec -type Config -formats toml -out gen_config.go
 
 type Config struct {
@@ -79,6 +144,10 @@ type Config struct {
 
 	// Protocol options
 	NetworkId uint64 // Network ID to use for selecting peers to connect to
+
+	// Allows a network ID different from the chain ID of the genesis
+	AllowNetworkIdMismatch bool
+
 	SyncMode  downloader.SyncMode
 	NoPruning bool
 
@@ -97,6 +166,20 @@ type Config struct {
 	TrieCleanCache     int
 	TrieDirtyCache     int
 	TrieTimeout        time.Duration
+	FlushOnFinalize    bool   // Flush the trie cache on TrieTimeout at finalized blocks, at twice TrieTimeout otherwise
+	MemoryBudget       int    // Total cache allowance in megabytes, zero for unlimited
+	BlockDBEngine      string // Engine of the consensus block database, "leveldb" or "memory"
+
+	// Number of blocks imported by a sync to trigger a chain database
+	// compaction once it is done, zero to disable
+	CompactAfterSyncBlocks uint64
+
+	// Bloom index options, log filters scan blocks directly without the index
+	NoBloomIndex  bool
+	BloomConfirms uint64 // Number of confirmation blocks before a bloom section is indexed
+
+	// Number of goroutines servicing the bloom bits lookups of all log filters
+	BloomServiceThreads int
 
 	// For calculate gas limit
 	DefaultGasPrice *big.Int
@@ -107,8 +190,65 @@ type Config struct {
 	// Gas Price Oracle options
 	GPO gasprice.Config
 
+	// Persist the block prices last sampled by the gas price oracle on
+	// shutdown and restore them on startup unless older than the window
+	PersistGPO       bool
+	PersistGPOWindow time.Duration
+
+	// Peer slots reserved for peers in the current notary sets
+	ReservedValidatorPeers int
+
+	// Choice of the peer disconnected to make room for a notary set peer when
+	// all the peer slots are taken, none by default
+	PeerEvictionPolicy PeerEvictionPolicy
+
+	// Break ties between sync peers of the same block number by the round
+	// they report, off by default as the round is not verified
+	PreferFinalizedSyncPeers bool
+
+	// Number of times the handshake timeout of a trusted, static or notary
+	// set peer is extended while waiting for its status, and the extra time
+	// given by the first extension, doubled on each further one
+	HandshakeExtensions int
+	HandshakeExtension  time.Duration
+
+	// Number of signed votes remembered as verified in the receive path, so
+	// copies received again are not verified again before being delivered.
+	// Zero leaves the votes to be verified by consensus only.
+	SigVerifyCacheSize int
+
+	// Consensus messages accepted per second from a single peer, the excess
+	// is dropped and peers exceeding the rate persistently are disconnected.
+	// Zero leaves the rate unlimited.
+	PeerConsensusMsgRate int
+
+	// Downloaded blocks awaiting import at which the downloader stops
+	// fetching, resuming when half of them are imported. Zero leaves the
+	// fetches limited by the download cache only.
+	ImportQueueHighWater int
+
+	// Scheduling weights of the message broadcast to each peer
+	BroadcastWeights BroadcastWeights
+
 	// BlockProposer options
 	BlockProposerEnabled bool
+	ConsensusStartDelay  time.Duration // Delay before consensus starts, allowing peers to connect (0 = start at once)
+	MaxCatchupRounds     uint64        // Rounds the chain may lag the network before consensus waits for a sync, zero to disable
+	MaxBlocksPerSecond   float64       // Rate limit of the local block preparation, delaying the proposals in excess, zero for unlimited
+	MaxBlockPayloadBytes uint64        // Size limit of the encoded transactions of a prepared block, zero for unlimited
+	ShutdownTimeout      time.Duration // Time to wait for consensus to stop on shutdown, zero to wait forever
+	RoundQuorumTimeout   time.Duration // Time without agreement on a block before the round is reported stalled, zero to disable
+
+	// Passwords tried to unlock the keystore account of a key rotation saved
+	// before a restart
+	KeyRotationPasswords []string `toml:"-"`
+
+	// Re-broadcast of the node's own unfinalized proposals
+	ProposalRebroadcast ProposalRebroadcastConfig
+
+	// File the consensus messages sent and received are appended to for
+	// replay debugging, empty to disable
+	ConsensusTraceFile string `toml:",omitempty"`
 
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
@@ -125,6 +265,29 @@ type Config struct {
 	// RPCGasCap is the global gas cap for eth-call variants.
 	RPCGasCap *big.Int `toml:",omitempty"`
 
+	// MaxNotarizations is the maximum number of blocks a single request of
+	// notarization proofs may cover, zero for no limit.
+	MaxNotarizations uint64
+
+	// MaxProposerScanDepth is the maximum number of blocks a single lookup of
+	// the blocks of a proposer walks back, zero for no limit. Blocks are not
+	// indexed by proposer, so the lookup scans the chain.
+	MaxProposerScanDepth uint64
+
+	// RejectTxWhenBehind is the number of rounds the node may lag behind the
+	// network before transactions submitted over RPC are rejected, zero to
+	// accept them regardless. They are rejected while consensus is paused.
+	RejectTxWhenBehind uint64
+
+	// MaxConcurrentFilters is the maximum number of log queries of the filter
+	// API and dex_getLogsByRound executed at once, the excess ones are
+	// rejected. Zero for unbounded.
+	MaxConcurrentFilters int
+
+	// VoteHistoryRounds is the number of recent rounds of which the votes
+	// cast by the node are kept, zero to keep them all.
+	VoteHistoryRounds uint64
+
 	// Dexon options
 	DMoment int64
 
@@ -133,4 +296,7 @@ type Config struct {
 
 	// Recovery network RPC
 	RecoveryNetworkRPC string
+
+	// Listening address of the health check endpoint, empty to disable
+	HealthCheckAddr string `toml:",omitempty"`
 }
@@ -0,0 +1,240 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	dkgTypes "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+
+	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+const (
+	consensusTraceMagic   = "dexon-consensus-trace"
+	consensusTraceVersion = 1
+)
+
+var errUnknownTraceMsg = errors.New("unknown consensus message")
+
+// consensusTraceHeader starts a consensus trace file, followed by a stream of
+// RLP encoded records.
+type consensusTraceHeader struct {
+	Magic   string
+	Version uint64
+}
+
+// ConsensusTraceRecord is a consensus message captured in a trace file.
+type ConsensusTraceRecord struct {
+	Time     uint64 // Unix time of the capture in nanoseconds
+	Outbound bool   // Whether the message is sent by the node
+	Code     uint64 // Protocol message code of the message type
+	Round    uint64
+	Sender   string // ID of the sending peer, empty for outbound messages
+	Payload  []byte // RLP encoding of the message
+}
+
+// Decode decodes the payload into the consensus core message it captures.
+func (r *ConsensusTraceRecord) Decode() (interface{}, error) {
+	var msg interface{}
+	switch r.Code {
+	case CoreBlockMsg:
+		msg = new(coreTypes.Block)
+	case VoteMsg:
+		msg = new(coreTypes.Vote)
+	case AgreementMsg:
+		msg = new(coreTypes.AgreementResult)
+	case DKGPrivateShareMsg:
+		msg = new(dkgTypes.PrivateShare)
+	case DKGPartialSignatureMsg:
+		msg = new(dkgTypes.PartialSignature)
+	default:
+		return nil, errUnknownTraceMsg
+	}
+	if err := rlp.DecodeBytes(r.Payload, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// consensusTraceMsg returns the protocol message code and round of a
+// consensus core message.
+func consensusTraceMsg(msg interface{}) (uint64, uint64, bool) {
+	switch m := msg.(type) {
+	case *coreTypes.Block:
+		return CoreBlockMsg, m.Position.Round, true
+	case *coreTypes.Vote:
+		return VoteMsg, m.Position.Round, true
+	case *coreTypes.AgreementResult:
+		return AgreementMsg, m.Position.Round, true
+	case *dkgTypes.PrivateShare:
+		return DKGPrivateShareMsg, m.Round, true
+	case *dkgTypes.PartialSignature:
+		return DKGPartialSignatureMsg, m.Round, true
+	}
+	return 0, 0, false
+}
+
+// consensusTracer appends the consensus messages sent and received by the
+// node to a trace file. A nil tracer captures nothing.
+type consensusTracer struct {
+	lock sync.Mutex
+	file *os.File
+	w    *bufio.Writer
+}
+
+// newConsensusTracer opens the trace file at the given path for appending,
+// writing the header if the file is new.
+func newConsensusTracer(path string) (*consensusTracer, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	t := &consensusTracer{file: file, w: bufio.NewWriter(file)}
+	if info.Size() == 0 {
+		header := &consensusTraceHeader{Magic: consensusTraceMagic, Version: consensusTraceVersion}
+		if err := rlp.Encode(t.w, header); err != nil {
+			file.Close()
+			return nil, err
+		}
+		if err := t.w.Flush(); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// trace captures a consensus message. Messages of unknown types are ignored.
+func (t *consensusTracer) trace(outbound bool, sender string, msg interface{}) {
+	if t == nil {
+		return
+	}
+	code, round, ok := consensusTraceMsg(msg)
+	if !ok {
+		return
+	}
+	payload, err := rlp.EncodeToBytes(msg)
+	if err != nil {
+		log.Warn("Failed to encode traced consensus message", "code", code, "err", err)
+		return
+	}
+	record := &ConsensusTraceRecord{
+		Time:     uint64(time.Now().UnixNano()),
+		Outbound: outbound,
+		Code:     code,
+		Round:    round,
+		Sender:   sender,
+		Payload:  payload,
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if err := rlp.Encode(t.w, record); err != nil {
+		log.Warn("Failed to trace consensus message", "code", code, "err", err)
+		return
+	}
+	if err := t.w.Flush(); err != nil {
+		log.Warn("Failed to trace consensus message", "code", code, "err", err)
+	}
+}
+
+// close flushes and closes the trace file.
+func (t *consensusTracer) close() error {
+	if t == nil {
+		return nil
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if err := t.w.Flush(); err != nil {
+		t.file.Close()
+		return err
+	}
+	return t.file.Close()
+}
+
+// ReadConsensusTrace reads all records of the consensus trace file at the
+// given path, in capture order.
+func ReadConsensusTrace(path string) ([]*ConsensusTraceRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stream := rlp.NewStream(bufio.NewReader(file), 0)
+	var header consensusTraceHeader
+	if err := stream.Decode(&header); err != nil {
+		return nil, fmt.Errorf("invalid consensus trace header: %v", err)
+	}
+	if header.Magic != consensusTraceMagic {
+		return nil, errors.New("not a consensus trace file")
+	}
+	if header.Version != consensusTraceVersion {
+		return nil, fmt.Errorf("unsupported consensus trace version %d", header.Version)
+	}
+	var records []*ConsensusTraceRecord
+	for {
+		record := new(ConsensusTraceRecord)
+		if err := stream.Decode(record); err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return records, fmt.Errorf("invalid consensus trace record %d: %v", len(records), err)
+		}
+		records = append(records, record)
+	}
+}
+
+// ReplayConsensusTrace feeds the inbound messages of the consensus trace file
+// at the given path to the consensus receive channel in capture order, as if
+// they came from the network again. It returns the number of replayed
+// messages.
+func ReplayConsensusTrace(path string, ch chan<- coreTypes.Msg) (int, error) {
+	records, err := ReadConsensusTrace(path)
+	if err != nil {
+		return 0, err
+	}
+	replayed := 0
+	for i, record := range records {
+		if record.Outbound {
+			continue
+		}
+		msg, err := record.Decode()
+		if err != nil {
+			return replayed, fmt.Errorf("invalid consensus trace record %d: %v", i, err)
+		}
+		ch <- coreTypes.Msg{PeerID: record.Sender, Payload: msg}
+		replayed++
+	}
+	return replayed, nil
+}
@@ -0,0 +1,174 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	coreCrypto "github.com/dexon-foundation/dexon-consensus/core/crypto"
+	"github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	dkgTypes "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+
+	"github.com/dexon-foundation/dexon/dex/downloader"
+	"github.com/dexon-foundation/dexon/p2p"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+func TestConsensusTraceReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dex-consensus-trace")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "trace")
+
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+	if pm.consensusTracer, err = newConsensusTracer(path); err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	pm.SetReceiveCoreMessage(true)
+
+	p, _ := newTestPeer("peer", dex64, pm, true)
+	defer p.close()
+
+	vote := &coreTypes.Vote{
+		VoteHeader: coreTypes.VoteHeader{
+			ProposerID: coreTypes.NodeID{Hash: coreCommon.Hash{1, 2, 3}},
+			Period:     10,
+			Position:   coreTypes.Position{Round: 12, Height: 13},
+		},
+		Signature: coreCrypto.Signature{Type: "vote", Signature: []byte("vote")},
+	}
+	privateShare := &dkgTypes.PrivateShare{
+		ProposerID:   coreTypes.NodeID{Hash: coreCommon.Hash{1, 2, 3}},
+		ReceiverID:   coreTypes.NodeID{Hash: coreCommon.Hash{3, 4, 5}},
+		Round:        10,
+		PrivateShare: *dkg.NewPrivateKey(),
+		Signature:    coreCrypto.Signature{Type: "share", Signature: []byte("share")},
+	}
+	psig := &dkgTypes.PartialSignature{
+		ProposerID: coreTypes.NodeID{Hash: coreCommon.Hash{3, 4, 5}},
+		Round:      11,
+		Hash:       coreCommon.Hash{6, 7, 8},
+		Signature:  coreCrypto.Signature{Type: "psig", Signature: []byte("psig")},
+	}
+
+	// Capture the messages received from the peer, and one sent by the node.
+	if err := p2p.Send(p.app, VoteMsg, []*coreTypes.Vote{vote}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	if err := p2p.Send(p.app, DKGPrivateShareMsg, privateShare); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	var received []coreTypes.Msg
+	for len(received) < 2 {
+		select {
+		case msg := <-pm.ReceiveChan():
+			received = append(received, msg)
+		case <-time.After(time.Second):
+			t.Fatalf("message not received")
+		}
+	}
+	NewDexconNetwork(pm).BroadcastDKGPartialSignature(psig)
+	if err := pm.consensusTracer.close(); err != nil {
+		t.Fatalf("failed to close tracer: %v", err)
+	}
+
+	records, err := ReadConsensusTrace(path)
+	if err != nil {
+		t.Fatalf("failed to read trace: %v", err)
+	}
+	want := []struct {
+		outbound    bool
+		code, round uint64
+		sender      string
+	}{
+		{false, VoteMsg, 12, p.ID().String()},
+		{false, DKGPrivateShareMsg, 10, p.ID().String()},
+		{true, DKGPartialSignatureMsg, 11, ""},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("record count mismatch: have %d, want %d", len(records), len(want))
+	}
+	for i, r := range records {
+		if r.Outbound != want[i].outbound || r.Code != want[i].code ||
+			r.Round != want[i].round || r.Sender != want[i].sender {
+			t.Errorf("record %d mismatch: have %+v, want %+v", i, r, want[i])
+		}
+	}
+
+	// Replaying into a fresh consensus receive channel yields the inbound
+	// messages as the consensus core received them.
+	ch := make(chan coreTypes.Msg, len(records))
+	replayed, err := ReplayConsensusTrace(path, ch)
+	if err != nil {
+		t.Fatalf("failed to replay trace: %v", err)
+	}
+	if replayed != len(received) || len(ch) != len(received) {
+		t.Fatalf("replayed count mismatch: have %d, want %d", replayed, len(received))
+	}
+	for i := range received {
+		if msg := <-ch; !reflect.DeepEqual(msg, received[i]) {
+			t.Errorf("replayed message %d mismatch: have %+v, want %+v", i, msg, received[i])
+		}
+	}
+
+	// Reopening the trace appends to it.
+	tracer, err := newConsensusTracer(path)
+	if err != nil {
+		t.Fatalf("failed to reopen tracer: %v", err)
+	}
+	tracer.trace(true, "", vote)
+	tracer.trace(true, "", "not a consensus message")
+	tracer.close()
+	if records, err := ReadConsensusTrace(path); err != nil || len(records) != len(want)+1 {
+		t.Errorf("appended trace mismatch: %d records, err %v", len(records), err)
+	}
+
+	// A nil tracer captures nothing.
+	var disabled *consensusTracer
+	disabled.trace(true, "", vote)
+	if err := disabled.close(); err != nil {
+		t.Errorf("failed to close disabled tracer: %v", err)
+	}
+}
+
+func TestConsensusTraceVersion(t *testing.T) {
+	file, err := ioutil.TempFile("", "dex-consensus-trace")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(file.Name())
+
+	header := &consensusTraceHeader{Magic: consensusTraceMagic, Version: consensusTraceVersion + 1}
+	if err := rlp.Encode(file, header); err != nil {
+		t.Fatalf("failed to write header: %v", err)
+	}
+	file.Close()
+	if _, err := ReadConsensusTrace(file.Name()); err == nil {
+		t.Error("expect error for unsupported trace version")
+	}
+}
@@ -18,6 +18,9 @@
 package db
 
 import (
+	"fmt"
+	"time"
+
 	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
 	coreDKG "github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
 	coreDb "github.com/dexon-foundation/dexon-consensus/core/db"
@@ -26,15 +29,87 @@ import (
 	"github.com/dexon-foundation/dexon/common"
 	"github.com/dexon-foundation/dexon/core/rawdb"
 	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/metrics"
 )
 
+// Writes of consensus data are retried on transient storage errors, backing
+// off exponentially. Consensus treats a write error as fatal, so an error is
+// only surfaced after all the attempts fail.
+var (
+	writeAttempts     = 5
+	writeRetryBackoff = 100 * time.Millisecond
+
+	writeRetryMeter = metrics.NewRegisteredMeter("dex/db/write/retry", nil)
+	writeFailMeter  = metrics.NewRegisteredMeter("dex/db/write/fail", nil)
+)
+
+// Engines of the consensus block database.
+const (
+	EngineLevelDB = "leveldb" // Stored in the chain database
+	EngineMemory  = "memory"  // Kept in memory, lost on restart
+)
+
+// Open returns the consensus block database of the given engine. The leveldb
+// engine, also used if engine is empty, stores into the given chain database.
+func Open(engine string, db ethdb.Database) (coreDb.Database, error) {
+	switch engine {
+	case "", EngineLevelDB:
+		return NewDatabase(db), nil
+	case EngineMemory:
+		return coreDb.NewMemBackedDB()
+	default:
+		return nil, fmt.Errorf("unknown block database engine %q", engine)
+	}
+}
+
 // DB implement dexon-consensus BlockDatabase interface.
 type DB struct {
 	db ethdb.Database
+
+	readMeter  metrics.Meter // Meter for consensus data reads
+	writeMeter metrics.Meter // Meter for consensus data writes
 }
 
 func NewDatabase(db ethdb.Database) *DB {
-	return &DB{db}
+	return &DB{db: db}
+}
+
+// Meter configures the database metrics collectors at the given prefix.
+func (d *DB) Meter(prefix string) {
+	d.readMeter = metrics.NewRegisteredMeter(prefix+"read", nil)
+	d.writeMeter = metrics.NewRegisteredMeter(prefix+"write", nil)
+}
+
+// read marks a read of consensus data if metered.
+func (d *DB) read() {
+	if d.readMeter != nil {
+		d.readMeter.Mark(1)
+	}
+}
+
+// write runs the write operation, retrying on failures.
+func (d *DB) write(op string, write func() error) error {
+	backoff := writeRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := write()
+		if err == nil {
+			if d.writeMeter != nil {
+				d.writeMeter.Mark(1)
+			}
+			return nil
+		}
+		if attempt >= writeAttempts {
+			writeFailMeter.Mark(1)
+			log.Error("Failed to write consensus data", "op", op, "attempts", attempt, "err", err)
+			return err
+		}
+		writeRetryMeter.Mark(1)
+		log.Warn("Failed to write consensus data, retrying", "op", op,
+			"attempt", attempt, "backoff", backoff, "err", err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
 }
 
 func (d *DB) HasBlock(hash coreCommon.Hash) bool {
@@ -42,6 +117,7 @@ func (d *DB) HasBlock(hash coreCommon.Hash) bool {
 }
 
 func (d *DB) GetBlock(hash coreCommon.Hash) (coreTypes.Block, error) {
+	d.read()
 	block := rawdb.ReadCoreBlock(d.db, common.Hash(hash))
 	if block == nil {
 		return coreTypes.Block{}, coreDb.ErrBlockDoesNotExist
@@ -57,19 +133,22 @@ func (d *DB) UpdateBlock(block coreTypes.Block) error {
 	if !d.HasBlock(block.Hash) {
 		return coreDb.ErrBlockDoesNotExist
 	}
-	rawdb.WriteCoreBlock(d.db, common.Hash(block.Hash), &block)
-	return nil
+	return d.write("updateBlock", func() error {
+		return rawdb.WriteCoreBlock(d.db, common.Hash(block.Hash), &block)
+	})
 }
 
 func (d *DB) PutBlock(block coreTypes.Block) error {
 	if d.HasBlock(block.Hash) {
 		return coreDb.ErrBlockExists
 	}
-	rawdb.WriteCoreBlock(d.db, common.Hash(block.Hash), &block)
-	return nil
+	return d.write("putBlock", func() error {
+		return rawdb.WriteCoreBlock(d.db, common.Hash(block.Hash), &block)
+	})
 }
 
 func (d *DB) GetDKGPrivateKey(round, reset uint64) (coreDKG.PrivateKey, error) {
+	d.read()
 	key := rawdb.ReadCoreDKGPrivateKey(d.db, round, reset)
 	if key == nil {
 		return coreDKG.PrivateKey{}, coreDb.ErrDKGPrivateKeyDoesNotExist
@@ -86,7 +165,9 @@ func (d *DB) PutDKGPrivateKey(round, reset uint64, key coreDKG.PrivateKey) error
 		return err
 	}
 
-	return rawdb.WriteCoreDKGPrivateKey(d.db, round, reset, &key)
+	return d.write("putDKGPrivateKey", func() error {
+		return rawdb.WriteCoreDKGPrivateKey(d.db, round, reset, &key)
+	})
 }
 
 func (d *DB) PutCompactionChainTipInfo(hash coreCommon.Hash, height uint64) error {
@@ -94,20 +175,26 @@ func (d *DB) PutCompactionChainTipInfo(hash coreCommon.Hash, height uint64) erro
 	if height <= currentHeight {
 		return coreDb.ErrInvalidCompactionChainTipHeight
 	}
-	return rawdb.WriteCoreCompactionChainTip(d.db, hash, height)
+	return d.write("putCompactionChainTipInfo", func() error {
+		return rawdb.WriteCoreCompactionChainTip(d.db, hash, height)
+	})
 }
 
 func (d *DB) GetCompactionChainTipInfo() (hash coreCommon.Hash, height uint64) {
+	d.read()
 	return rawdb.ReadCoreCompactionChainTip(d.db)
 }
 
 func (d *DB) PutOrUpdateDKGProtocol(
 	protocol coreDb.DKGProtocolInfo) error {
-	return rawdb.WriteCoreDKGProtocol(d.db, &protocol)
+	return d.write("putOrUpdateDKGProtocol", func() error {
+		return rawdb.WriteCoreDKGProtocol(d.db, &protocol)
+	})
 }
 
 func (d *DB) GetDKGProtocol() (
 	protocol coreDb.DKGProtocolInfo, err error) {
+	d.read()
 	dkgProtocol := rawdb.ReadCoreDKGProtocol(d.db)
 	if dkgProtocol == nil {
 		return coreDb.DKGProtocolInfo{}, coreDb.ErrDKGProtocolDoesNotExist
@@ -0,0 +1,130 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package db
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	coreDKG "github.com/dexon-foundation/dexon-consensus/core/crypto/dkg"
+	coreDb "github.com/dexon-foundation/dexon-consensus/core/db"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+
+	"github.com/dexon-foundation/dexon/ethdb"
+)
+
+func TestDKGPrivateKeyPersistence(t *testing.T) {
+	chainDb := ethdb.NewMemDatabase()
+	key := coreDKG.NewPrivateKey()
+
+	db := NewDatabase(chainDb)
+	if err := db.PutDKGPrivateKey(3, 1, *key); err != nil {
+		t.Fatalf("failed to put DKG private key: %v", err)
+	}
+	if err := db.PutDKGPrivateKey(3, 1, *key); err != coreDb.ErrDKGPrivateKeyExists {
+		t.Errorf("duplicated put error mismatch: have %v, want %v", err, coreDb.ErrDKGPrivateKeyExists)
+	}
+
+	// Simulate a restart by opening the consensus database again.
+	db = NewDatabase(chainDb)
+	loaded, err := db.GetDKGPrivateKey(3, 1)
+	if err != nil {
+		t.Fatalf("failed to get DKG private key: %v", err)
+	}
+	if !bytes.Equal(loaded.Bytes(), key.Bytes()) {
+		t.Errorf("DKG private key mismatch: have %x, want %x", loaded.Bytes(), key.Bytes())
+	}
+
+	// A share of another DKG reset, or another round, is not returned.
+	if _, err := db.GetDKGPrivateKey(3, 0); err != coreDb.ErrDKGPrivateKeyDoesNotExist {
+		t.Errorf("stale reset error mismatch: have %v, want %v", err, coreDb.ErrDKGPrivateKeyDoesNotExist)
+	}
+	if _, err := db.GetDKGPrivateKey(4, 1); err != coreDb.ErrDKGPrivateKeyDoesNotExist {
+		t.Errorf("unknown round error mismatch: have %v, want %v", err, coreDb.ErrDKGPrivateKeyDoesNotExist)
+	}
+}
+
+// flakyDatabase fails the given number of writes before passing them through.
+type flakyDatabase struct {
+	*ethdb.MemDatabase
+	failures int
+}
+
+func (db *flakyDatabase) Put(key []byte, value []byte) error {
+	if db.failures > 0 {
+		db.failures--
+		return errors.New("transient write error")
+	}
+	return db.MemDatabase.Put(key, value)
+}
+
+func TestWriteRetry(t *testing.T) {
+	defer func(backoff time.Duration) { writeRetryBackoff = backoff }(writeRetryBackoff)
+	writeRetryBackoff = time.Millisecond
+
+	flaky := &flakyDatabase{MemDatabase: ethdb.NewMemDatabase(), failures: 2}
+	db := NewDatabase(flaky)
+	block := coreTypes.Block{
+		Hash:     coreCommon.NewRandomHash(),
+		Position: coreTypes.Position{Round: 1, Height: 5},
+	}
+	if err := db.PutBlock(block); err != nil {
+		t.Fatalf("failed to put block: %v", err)
+	}
+	if flaky.failures != 0 {
+		t.Errorf("remaining failures mismatch: have %d, want %d", flaky.failures, 0)
+	}
+	if loaded, err := db.GetBlock(block.Hash); err != nil || loaded.Hash != block.Hash {
+		t.Errorf("written block mismatch: have %v (%v), want %v", loaded.Hash, err, block.Hash)
+	}
+
+	// The error is surfaced once all attempts fail.
+	flaky.failures = writeAttempts
+	if err := db.PutCompactionChainTipInfo(block.Hash, 5); err == nil {
+		t.Error("expect error after all attempts fail")
+	}
+	if _, height := db.GetCompactionChainTipInfo(); height != 0 {
+		t.Errorf("tip height mismatch: have %d, want %d", height, 0)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	chainDb := ethdb.NewMemDatabase()
+	for _, engine := range []string{"", EngineLevelDB} {
+		db, err := Open(engine, chainDb)
+		if err != nil {
+			t.Fatalf("engine %q: failed to open: %v", engine, err)
+		}
+		if _, ok := db.(*DB); !ok {
+			t.Errorf("engine %q: database type mismatch: have %T", engine, db)
+		}
+	}
+	db, err := Open(EngineMemory, chainDb)
+	if err != nil {
+		t.Fatalf("failed to open memory database: %v", err)
+	}
+	if _, ok := db.(*coreDb.MemBackedDB); !ok {
+		t.Errorf("memory engine: database type mismatch: have %T", db)
+	}
+	if _, err := Open("rocksdb", chainDb); err == nil {
+		t.Error("expect error for unknown engine")
+	}
+}
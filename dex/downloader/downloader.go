@@ -71,7 +71,7 @@ var (
 	fsHeaderSafetyNet = 2048 // Number of headers to discard in case a chain violation is detected
 	// fsHeaderForceVerify = 24              // Number of headers to verify before and after the pivot to accept it
 	fsHeaderContCheck = 3 * time.Second // Time interval to check for header continuations during state download
-	fsMinFullBlocks   = 64              // Number of blocks to retrieve fully even in fast sync
+	FsMinFullBlocks   = 64              // Number of blocks to retrieve fully even in fast sync
 )
 
 var (
@@ -282,6 +282,13 @@ func (d *Downloader) Progress() ethereum.SyncProgress {
 	}
 }
 
+// SetImportQueueHighWater sets the number of downloaded blocks awaiting import
+// at which fetching is paused, until half of them are imported. Zero disables
+// the throttling.
+func (d *Downloader) SetImportQueueHighWater(blocks int) {
+	d.queue.SetImportHighWater(blocks)
+}
+
 // Synchronising returns whether the downloader is currently retrieving blocks.
 func (d *Downloader) Synchronising() bool {
 	return atomic.LoadInt32(&d.synchronising) > 0
@@ -460,10 +467,10 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, number ui
 	// Ensure our origin point is below any fast sync pivot point
 	pivot := uint64(0)
 	if d.mode == FastSync {
-		if height <= uint64(fsMinFullBlocks) {
+		if height <= uint64(FsMinFullBlocks) {
 			origin = 0
 		} else {
-			pivot = height - uint64(fsMinFullBlocks)
+			pivot = height - uint64(FsMinFullBlocks)
 			if pivot <= origin {
 				origin = pivot - 1
 			}
@@ -1624,8 +1631,8 @@ func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 	// Figure out the ideal pivot block. Note, that this goalpost may move if the
 	// sync takes long enough for the chain head to move significantly.
 	pivot := uint64(0)
-	if height := latest.Number.Uint64(); height > uint64(fsMinFullBlocks) {
-		pivot = height - uint64(fsMinFullBlocks)
+	if height := latest.Number.Uint64(); height > uint64(FsMinFullBlocks) {
+		pivot = height - uint64(FsMinFullBlocks)
 	}
 	// To cater for moving pivot points, track the pivot block and subsequently
 	// accumulated download results separately.
@@ -1658,9 +1665,9 @@ func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 		// Split around the pivot block and process the two sides via fast/full sync
 		if atomic.LoadInt32(&d.committed) == 0 {
 			latest = results[len(results)-1].Header
-			if height := latest.Number.Uint64(); height > pivot+2*uint64(fsMinFullBlocks) {
-				log.Warn("Pivot became stale, moving", "old", pivot, "new", height-uint64(fsMinFullBlocks))
-				pivot = height - uint64(fsMinFullBlocks)
+			if height := latest.Number.Uint64(); height > pivot+2*uint64(FsMinFullBlocks) {
+				log.Warn("Pivot became stale, moving", "old", pivot, "new", height-uint64(FsMinFullBlocks))
+				pivot = height - uint64(FsMinFullBlocks)
 			}
 		}
 		P, beforeP, afterP := splitAroundPivot(pivot, results)
@@ -573,6 +573,62 @@ func testThrottling(t *testing.T, protocol int, mode SyncMode) {
 	}
 }
 
+// Tests that block fetching pauses while too many downloaded blocks await
+// import, and resumes once they are imported.
+func TestImportQueueBackpressure(t *testing.T) {
+	t.Parallel()
+	tester := newTester()
+	defer tester.terminate()
+
+	targetBlocks := testChainBase.len() - 1
+	tester.newPeer("peer", 64, testChainBase)
+
+	highWater := 2 * MaxBlockFetch
+	tester.downloader.SetImportQueueHighWater(highWater)
+
+	// Stall the import of the first batch of blocks
+	proceed := make(chan struct{})
+	tester.downloader.chainInsertHook = func(results []*fetchResult) {
+		<-proceed
+	}
+	errc := make(chan error)
+	go func() {
+		errc <- tester.sync("peer", 0, FullSync)
+	}()
+
+	// Wait for the fetches to settle
+	var cached, settled int
+	for start := time.Now(); time.Since(start) < 3*time.Second && settled < 4; {
+		time.Sleep(25 * time.Millisecond)
+
+		tester.downloader.queue.lock.Lock()
+		current := len(tester.downloader.queue.blockDonePool)
+		tester.downloader.queue.lock.Unlock()
+		if current == cached && current >= highWater {
+			settled++
+		} else {
+			cached, settled = current, 0
+		}
+	}
+	// Fetches in flight when throttled may still complete
+	if cached < highWater || cached >= highWater+MaxBlockFetch || cached >= blockCacheItems {
+		t.Fatalf("cached block count mismatch: have %d, want [%d, %d)", cached, highWater, highWater+MaxBlockFetch)
+	}
+	tester.downloader.queue.lock.Lock()
+	throttled := tester.downloader.queue.importThrottled
+	tester.downloader.queue.lock.Unlock()
+	if !throttled {
+		t.Error("fetching not throttled")
+	}
+
+	// Let the blocks import, the fetches resume to completion
+	close(proceed)
+	if err := <-errc; err != nil {
+		t.Fatalf("block synchronization failed: %v", err)
+	}
+	assertOwnChain(t, tester, targetBlocks+1)
+}
+
 // Tests that an inactive downloader will not accept incoming block headers and
 // bodies.
 func TestInactiveDownloader62(t *testing.T) {
@@ -841,7 +897,7 @@ func testInvalidHeaderRollback(t *testing.T, protocol int, mode SyncMode) {
 	defer tester.terminate()
 
 	// Create a small enough block chain to download
-	targetBlocks := 3*fsHeaderSafetyNet + 256 + fsMinFullBlocks
+	targetBlocks := 3*fsHeaderSafetyNet + 256 + FsMinFullBlocks
 	chain := testChainBase.shorten(targetBlocks)
 
 	// Attempt to sync with an attacker that feeds junk during the fast sync phase.
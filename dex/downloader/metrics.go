@@ -45,4 +45,7 @@ var (
 
 	stateInMeter   = metrics.NewRegisteredMeter("dex/downloader/states/in", nil)
 	stateDropMeter = metrics.NewRegisteredMeter("dex/downloader/states/drop", nil)
+
+	importQueueGauge    = metrics.NewRegisteredGauge("dex/downloader/import/queue", nil)
+	importThrottleMeter = metrics.NewRegisteredMeter("dex/downloader/import/throttle", nil)
 )
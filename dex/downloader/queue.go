@@ -93,6 +93,9 @@ type queue struct {
 	resultOffset uint64             // Offset of the first cached fetch result in the block chain
 	resultSize   common.StorageSize // Approximate size of a block (exponential moving average)
 
+	importHighWater int  // Blocks awaiting import throttling the fetches, zero if unlimited
+	importThrottled bool // Whether the fetches are throttled until the import queue drains
+
 	lock   *sync.Mutex
 	active *sync.Cond
 	closed bool
@@ -141,6 +144,38 @@ func (q *queue) Reset() {
 
 	q.resultCache = make([]*fetchResult, blockCacheItems)
 	q.resultOffset = 0
+	q.importThrottled = false
+}
+
+// SetImportHighWater sets the number of downloaded blocks awaiting import at
+// which block fetches are throttled, resuming once half of them are imported.
+// Zero leaves the fetches limited by the result cache only.
+func (q *queue) SetImportHighWater(blocks int) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.importHighWater = blocks
+	q.importThrottled = false
+}
+
+// importBackpressure reports whether fetches should wait for the blocks
+// awaiting import to drain below the low-water mark.
+//
+// The caller must hold q.lock.
+func (q *queue) importBackpressure() bool {
+	depth := q.countProcessableItems()
+	importQueueGauge.Update(int64(depth))
+	if q.importHighWater <= 0 {
+		return false
+	}
+	switch {
+	case !q.importThrottled && depth >= q.importHighWater:
+		q.importThrottled = true
+		importThrottleMeter.Mark(1)
+	case q.importThrottled && depth <= q.importHighWater/2:
+		q.importThrottled = false
+	}
+	return q.importThrottled
 }
 
 // Close marks the end of the sync, unblocking WaitResults.
@@ -216,21 +251,21 @@ func (q *queue) Idle() bool {
 }
 
 // ShouldThrottleBlocks checks if the download should be throttled (active block (body)
-// fetches exceed block cache).
+// fetches exceed block cache, or too many blocks await import).
 func (q *queue) ShouldThrottleBlocks() bool {
 	q.lock.Lock()
 	defer q.lock.Unlock()
 
-	return q.resultSlots(q.blockPendPool, q.blockDonePool) <= 0
+	return q.importBackpressure() || q.resultSlots(q.blockPendPool, q.blockDonePool) <= 0
 }
 
 // ShouldThrottleReceipts checks if the download should be throttled (active receipt
-// fetches exceed block cache).
+// fetches exceed block cache, or too many blocks await import).
 func (q *queue) ShouldThrottleReceipts() bool {
 	q.lock.Lock()
 	defer q.lock.Unlock()
 
-	return q.resultSlots(q.receiptPendPool, q.receiptDonePool) <= 0
+	return q.importBackpressure() || q.resultSlots(q.receiptPendPool, q.receiptDonePool) <= 0
 }
 
 // resultSlots calculates the number of results slots available for requests
@@ -496,6 +531,12 @@ func (q *queue) reserveHeaders(p *peerConnection, count int, taskPool map[common
 	}
 	// Calculate an upper limit on the items we might fetch (i.e. throttling)
 	space := q.resultSlots(pendPool, donePool)
+	if q.importHighWater > 0 {
+		// Empty blocks complete without fetching, keep them under the mark too
+		if headroom := q.importHighWater - q.countProcessableItems(); headroom < space {
+			space = headroom
+		}
+	}
 
 	// Retrieve a batch of tasks, skipping previously failed ones
 	send := make([]*types.Header, 0, count)
@@ -0,0 +1,189 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"fmt"
+
+	"github.com/dexon-foundation/dexon/common"
+)
+
+// Error codes of the consensus related failures of the dex RPC methods.
+// The codes are part of the API and must not be changed or reused.
+const (
+	ErrCodeRoundNotReached   = -39001 // The round is not started yet
+	ErrCodeRoundNotRecorded  = -39002 // The first block of the round is not known
+	ErrCodeCRSNotReady       = -39003 // The CRS of the round is not proposed yet
+	ErrCodeBlockNotFinalized = -39004 // The block is not finalized by consensus
+	ErrCodeTxNotFound        = -39005 // The transaction is neither finalized nor executable
+	ErrCodeNotValidator      = -39006 // The node does not take part in consensus
+	ErrCodeRangeTooLarge     = -39007 // The requested range exceeds the limit
+	ErrCodeDKGNotFinal       = -39008 // The DKG of the round is not final yet
+	ErrCodeConsensusPaused   = -39009 // Consensus of the node is paused
+	ErrCodeNodeBehind        = -39010 // The node is too far behind the network
+	ErrCodePositionNotFound  = -39011 // No block is finalized at the position
+	ErrCodeScanLimitReached  = -39012 // The blocks scanned for the result exceed the limit
+)
+
+// ErrRoundNotReached is returned for rounds later than the current one.
+type ErrRoundNotReached struct {
+	Round   uint64
+	Current uint64
+}
+
+func (e *ErrRoundNotReached) Error() string {
+	return fmt.Sprintf("round %d not started, current round %d", e.Round, e.Current)
+}
+
+// ErrorCode implements rpc.Error.
+func (e *ErrRoundNotReached) ErrorCode() int { return ErrCodeRoundNotReached }
+
+// ErrRoundNotRecorded is returned for rounds the first block of which is not
+// recorded in the governance state.
+type ErrRoundNotRecorded struct {
+	Round uint64
+}
+
+func (e *ErrRoundNotRecorded) Error() string {
+	return fmt.Sprintf("first block of round %d not recorded", e.Round)
+}
+
+// ErrorCode implements rpc.Error.
+func (e *ErrRoundNotRecorded) ErrorCode() int { return ErrCodeRoundNotRecorded }
+
+// ErrCRSNotReady is returned for rounds the CRS of which is not proposed yet.
+type ErrCRSNotReady struct {
+	Round    uint64
+	CRSRound uint64
+}
+
+func (e *ErrCRSNotReady) Error() string {
+	return fmt.Sprintf("CRS of round %d not available, latest CRS round %d", e.Round, e.CRSRound)
+}
+
+// ErrorCode implements rpc.Error.
+func (e *ErrCRSNotReady) ErrorCode() int { return ErrCodeCRSNotReady }
+
+// ErrBlockNotFinalized is returned for blocks beyond the latest finalized one.
+type ErrBlockNotFinalized struct {
+	Number uint64
+}
+
+func (e *ErrBlockNotFinalized) Error() string {
+	return fmt.Sprintf("block %d not finalized", e.Number)
+}
+
+// ErrorCode implements rpc.Error.
+func (e *ErrBlockNotFinalized) ErrorCode() int { return ErrCodeBlockNotFinalized }
+
+// ErrTxNotFound is returned for transactions neither finalized nor
+// executable in the transaction pool.
+type ErrTxNotFound struct {
+	Hash common.Hash
+}
+
+func (e *ErrTxNotFound) Error() string {
+	return fmt.Sprintf("transaction %x not found or not executable", e.Hash)
+}
+
+// ErrorCode implements rpc.Error.
+func (e *ErrTxNotFound) ErrorCode() int { return ErrCodeTxNotFound }
+
+// ErrNotValidator is returned for operations only a block proposer can
+// perform.
+type ErrNotValidator struct{}
+
+func (e *ErrNotValidator) Error() string { return "node is not a validator" }
+
+// ErrorCode implements rpc.Error.
+func (e *ErrNotValidator) ErrorCode() int { return ErrCodeNotValidator }
+
+// ErrRangeTooLarge is returned for requested ranges exceeding the limit.
+type ErrRangeTooLarge struct {
+	From, To uint64
+	Limit    uint64
+}
+
+func (e *ErrRangeTooLarge) Error() string {
+	return fmt.Sprintf("block range %d - %d exceeds limit of %d blocks", e.From, e.To, e.Limit)
+}
+
+// ErrorCode implements rpc.Error.
+func (e *ErrRangeTooLarge) ErrorCode() int { return ErrCodeRangeTooLarge }
+
+// ErrDKGNotFinal is returned for rounds the DKG of which is not final yet.
+type ErrDKGNotFinal struct {
+	Round uint64
+}
+
+func (e *ErrDKGNotFinal) Error() string {
+	return fmt.Sprintf("DKG of round %d not final", e.Round)
+}
+
+// ErrorCode implements rpc.Error.
+func (e *ErrDKGNotFinal) ErrorCode() int { return ErrCodeDKGNotFinal }
+
+// ErrConsensusPaused is returned for transactions submitted while consensus
+// is paused.
+type ErrConsensusPaused struct{}
+
+func (e *ErrConsensusPaused) Error() string { return "consensus paused, transactions rejected" }
+
+// ErrorCode implements rpc.Error.
+func (e *ErrConsensusPaused) ErrorCode() int { return ErrCodeConsensusPaused }
+
+// ErrNodeBehind is returned for transactions submitted while the node is too
+// far behind the network.
+type ErrNodeBehind struct {
+	Rounds uint64
+	Limit  uint64
+}
+
+func (e *ErrNodeBehind) Error() string {
+	return fmt.Sprintf("node %d rounds behind (limit %d), transactions rejected", e.Rounds, e.Limit)
+}
+
+// ErrorCode implements rpc.Error.
+func (e *ErrNodeBehind) ErrorCode() int { return ErrCodeNodeBehind }
+
+// ErrPositionNotFound is returned for positions no block is finalized at.
+type ErrPositionNotFound struct {
+	Round  uint64
+	Height uint64
+}
+
+func (e *ErrPositionNotFound) Error() string {
+	return fmt.Sprintf("no block at position round %d height %d", e.Round, e.Height)
+}
+
+// ErrorCode implements rpc.Error.
+func (e *ErrPositionNotFound) ErrorCode() int { return ErrCodePositionNotFound }
+
+// ErrScanLimitReached is returned when a lookup scanned as many blocks as it
+// is allowed to before completing. The lookup can be resumed below Next.
+type ErrScanLimitReached struct {
+	Limit uint64
+	Next  uint64
+}
+
+func (e *ErrScanLimitReached) Error() string {
+	return fmt.Sprintf("scan limit of %d blocks reached, continue below block %d", e.Limit, e.Next)
+}
+
+// ErrorCode implements rpc.Error.
+func (e *ErrScanLimitReached) ErrorCode() int { return ErrCodeScanLimitReached }
@@ -0,0 +1,84 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"testing"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/common/hexutil"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/rpc"
+)
+
+func TestRPCErrorCodes(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	dex.config.MaxNotarizations = 4
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("dex", NewPublicDexonAPI(dex)); err != nil {
+		t.Fatalf("failed to register api: %v", err)
+	}
+	if err := server.RegisterName("dex", NewPrivateDexonGovernanceAPI(dex)); err != nil {
+		t.Fatalf("failed to register api: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	validator := crypto.PubkeyToAddress(key.PublicKey)
+	tests := []struct {
+		method string
+		args   []interface{}
+		code   int
+	}{
+		{"dex_roundBlockRange", []interface{}{hexutil.Uint64(2)}, ErrCodeRoundNotReached},
+		{"dex_roundRewards", []interface{}{hexutil.Uint64(2), validator}, ErrCodeRoundNotReached},
+		{"dex_getLogsByRound", []interface{}{hexutil.Uint64(2), nil, nil}, ErrCodeRoundNotReached},
+		{"dex_crs", []interface{}{hexutil.Uint64(100)}, ErrCodeCRSNotReady},
+		{"dex_compactionProvenance", []interface{}{hexutil.Uint64(100)}, ErrCodeBlockNotFinalized},
+		{"dex_estimateFinalityTime", []interface{}{common.Hash{1}}, ErrCodeTxNotFound},
+		{"dex_getNotarizations", []interface{}{hexutil.Uint64(0), hexutil.Uint64(4)}, ErrCodeRangeTooLarge},
+		{"dex_proposeCRS", []interface{}{hexutil.Uint64(1), hexutil.Bytes{1}}, ErrCodeNotValidator},
+		{"dex_getBlockByPosition", []interface{}{hexutil.Uint64(1), hexutil.Uint64(0)}, ErrCodePositionNotFound},
+	}
+	for _, tt := range tests {
+		var result interface{}
+		err := client.Call(&result, tt.method, tt.args...)
+		if err == nil {
+			t.Errorf("%s: expect error", tt.method)
+			continue
+		}
+		rpcErr, ok := err.(rpc.Error)
+		if !ok {
+			t.Errorf("%s: error without code: %v", tt.method, err)
+			continue
+		}
+		if rpcErr.ErrorCode() != tt.code {
+			t.Errorf("%s: code mismatch: have %d, want %d (%v)", tt.method, rpcErr.ErrorCode(), tt.code, err)
+		}
+	}
+
+}
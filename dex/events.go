@@ -0,0 +1,111 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/event"
+)
+
+// eventQueueSize is the number of events queued for posting before posters
+// are blocked.
+const eventQueueSize = 256
+
+// Consensus events posted on the event mux of the node.
+
+// RoundChangeEvent is posted when the chain enters a new DEXON round.
+type RoundChangeEvent struct{ RoundEvent }
+
+// FinalizedBlockEvent is posted when a block delivered by the local
+// consensus is finalized and inserted into the chain.
+type FinalizedBlockEvent struct{ Block *types.Block }
+
+// CRSUpdateEvent is posted when the CRS of a new round is proposed to the
+// governance contract.
+type CRSUpdateEvent struct {
+	Round uint64
+	CRS   common.Hash
+}
+
+// RoundStalledEvent is posted when the consensus agrees on no block for
+// RoundQuorumTimeout, with the notary set members no vote was received from
+// meanwhile.
+type RoundStalledEvent struct {
+	Round   uint64
+	Height  uint64           // Height of the block waiting for agreement
+	Since   time.Time        // Time of the last agreement
+	Missing []common.Address // Node key addresses of the silent notary set members
+}
+
+// TxRejectionEvent is sent when the transaction pool rejects an inbound
+// transaction.
+type TxRejectionEvent struct {
+	Tx  *types.Transaction
+	Err error
+}
+
+// eventQueue posts events on a mux from a single goroutine, in the order they
+// are queued. Posters holding locks are not held up by slow subscribers, yet
+// subscribers see the events in order.
+type eventQueue struct {
+	mux   *event.TypeMux
+	queue chan interface{}
+	quit  chan struct{}
+	wg    sync.WaitGroup
+}
+
+func newEventQueue(mux *event.TypeMux) *eventQueue {
+	q := &eventQueue{
+		mux:   mux,
+		queue: make(chan interface{}, eventQueueSize),
+		quit:  make(chan struct{}),
+	}
+	q.wg.Add(1)
+	go q.loop()
+	return q
+}
+
+// post queues the event, waiting for room if the queue is full.
+func (q *eventQueue) post(ev interface{}) {
+	select {
+	case q.queue <- ev:
+	case <-q.quit:
+	}
+}
+
+func (q *eventQueue) loop() {
+	defer q.wg.Done()
+	for {
+		select {
+		case ev := <-q.queue:
+			q.mux.Post(ev)
+		case <-q.quit:
+			return
+		}
+	}
+}
+
+// stop terminates the queue, dropping the events not posted yet.
+func (q *eventQueue) stop() {
+	close(q.quit)
+	q.wg.Wait()
+}
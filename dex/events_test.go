@@ -0,0 +1,136 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/event"
+)
+
+func TestConsensusEvents(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	mux := new(event.TypeMux)
+	defer mux.Stop()
+	dex.app.events = newEventQueue(mux)
+	defer dex.app.events.stop()
+	dex.roundNotifier.mux = mux
+
+	sub := mux.Subscribe(RoundChangeEvent{}, FinalizedBlockEvent{}, CRSUpdateEvent{})
+	defer sub.Unsubscribe()
+	events := make(chan interface{}, 16)
+	go func() {
+		for ev := range sub.Chan() {
+			events <- ev.Data
+		}
+	}()
+	next := func() interface{} {
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(time.Second):
+			t.Fatal("event not posted")
+		}
+		return nil
+	}
+
+	// Finalization of a block of the current round.
+	block, err := deliverTestBlock(dex, key, 0, nil)
+	if err != nil {
+		t.Fatalf("failed to deliver block: %v", err)
+	}
+	if ev, ok := next().(FinalizedBlockEvent); !ok || ev.Block.Hash() != block.Hash() {
+		t.Errorf("finalized block event mismatch: have %v, want block %x", ev, block.Hash())
+	}
+	dex.roundNotifier.onHead(block)
+	select {
+	case ev := <-events:
+		t.Errorf("unexpected event without round change: %v", ev)
+	default:
+	}
+
+	// Finalization of a block entering the next round.
+	block, err = deliverTestBlock(dex, key, 1, nil)
+	if err != nil {
+		t.Fatalf("failed to deliver block: %v", err)
+	}
+	if ev, ok := next().(FinalizedBlockEvent); !ok || ev.Block.Hash() != block.Hash() {
+		t.Errorf("finalized block event mismatch: have %v, want block %x", ev, block.Hash())
+	}
+	// Wait for the chain to store the governance state of the round first.
+	for i := 0; ; i++ {
+		if _, err := dex.blockchain.GetGovStateByHash(block.Hash()); err == nil {
+			break
+		}
+		if i == 100 {
+			t.Fatal("governance state not stored")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	dex.roundNotifier.onHead(block)
+
+	ev, ok := next().(RoundChangeEvent)
+	if !ok || ev.Round != 1 {
+		t.Errorf("round change event mismatch: have %v, want round %d", ev, 1)
+	}
+	if want := common.Hash(dex.governance.CRS(1)); ev.CRS != want {
+		t.Errorf("round change CRS mismatch: have %x, want %x", ev.CRS, want)
+	}
+
+	// No new CRS is proposed to the governance contract.
+	select {
+	case ev := <-events:
+		t.Errorf("unexpected event: %v", ev)
+	default:
+	}
+}
+
+func TestEventQueueOrder(t *testing.T) {
+	mux := new(event.TypeMux)
+	defer mux.Stop()
+	sub := mux.Subscribe(CRSUpdateEvent{})
+	defer sub.Unsubscribe()
+
+	q := newEventQueue(mux)
+	defer q.stop()
+	// The subscriber is not reading yet, posting must not block.
+	const n = 32
+	for i := 0; i < n; i++ {
+		q.post(CRSUpdateEvent{Round: uint64(i)})
+	}
+	for i := 0; i < n; i++ {
+		select {
+		case ev := <-sub.Chan():
+			if round := ev.Data.(CRSUpdateEvent).Round; round != uint64(i) {
+				t.Fatalf("event order mismatch: have round %d, want %d", round, i)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event %d not posted", i)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dexon-foundation/dexon/p2p/enode"
+)
+
+// PeerEvictionPolicy decides which peer, if any, is disconnected to make room
+// for a notary set peer when all the peer slots are taken.
+type PeerEvictionPolicy int
+
+const (
+	// EvictNone never evicts peers, notary set peers beyond the reserved
+	// slots are rejected when all the peer slots are taken.
+	EvictNone PeerEvictionPolicy = iota
+
+	// EvictIdle evicts the peer that has been idle the longest, the one
+	// sending no consensus messages and lagging behind the most. Peers in a
+	// notary set, pinned validators, trusted and static peers are never
+	// evicted. Inbound notary set peers are let in by the p2p server beyond
+	// its peer limits for the eviction to take place.
+	EvictIdle
+)
+
+// String implements the stringer interface.
+func (policy PeerEvictionPolicy) String() string {
+	switch policy {
+	case EvictNone:
+		return "none"
+	case EvictIdle:
+		return "idle"
+	default:
+		return "unknown"
+	}
+}
+
+func (policy PeerEvictionPolicy) MarshalText() ([]byte, error) {
+	switch policy {
+	case EvictNone, EvictIdle:
+		return []byte(policy.String()), nil
+	default:
+		return nil, fmt.Errorf("unknown peer eviction policy %d", policy)
+	}
+}
+
+func (policy *PeerEvictionPolicy) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "none":
+		*policy = EvictNone
+	case "idle":
+		*policy = EvictIdle
+	default:
+		return fmt.Errorf(`unknown peer eviction policy %q, want "none" or "idle"`, text)
+	}
+	return nil
+}
+
+// exemptPeer reports whether the p2p server lets the peer in beyond its peer
+// limits, which are then enforced by evicting a peer on the handshake.
+func (pm *ProtocolManager) exemptPeer(id enode.ID) bool {
+	if pm.evictionPolicy != EvictIdle || pm.peers == nil {
+		return false
+	}
+	return pm.peers.IsNotaryPeer(id.String())
+}
+
+// evictPeer removes a peer chosen by the eviction policy to make room for a
+// new notary set peer, and reports whether one is evicted. The peer is
+// unregistered right away, so its slot is not counted twice.
+func (pm *ProtocolManager) evictPeer() bool {
+	if pm.evictionPolicy != EvictIdle {
+		return false
+	}
+	p := pm.peers.IdlestPeer()
+	if p == nil {
+		return false
+	}
+	p.Log().Debug("Evicting idle peer for notary set peer")
+	peerEvictionMeter.Mark(1)
+	pm.removePeer(p.id)
+	return true
+}
+
+// IdlestPeer returns the evictable peer which has been idle the longest, or
+// nil if there is none. Peers in a notary set, pinned validators, trusted and
+// static peers are not evictable. Peers idle for the same time are ranked by
+// their head, the one lagging behind the most first.
+func (ps *peerSet) IdlestPeer() *peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	notary := make(map[string]struct{})
+	for label, nodes := range ps.label2Nodes {
+		if label.set != notaryset && label != validatorLabel {
+			continue
+		}
+		for id := range nodes {
+			notary[id] = struct{}{}
+		}
+	}
+	var (
+		idlest     *peer
+		idlestLast time.Time
+		idlestHead uint64
+	)
+	for id, p := range ps.peers {
+		if _, ok := notary[id]; ok {
+			continue
+		}
+		if info := p.Peer.Info().Network; info.Trusted || info.Static {
+			continue
+		}
+		last := p.LastConsensusMsg()
+		_, head := p.Head()
+		if idlest == nil || last.Before(idlestLast) || (last.Equal(idlestLast) && head < idlestHead) {
+			idlest, idlestLast, idlestHead = p, last, head
+		}
+	}
+	return idlest
+}
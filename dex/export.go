@@ -0,0 +1,47 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/dexon-foundation/dexon/log"
+)
+
+// ExportFinalizedBlocks writes the finalized blocks in the inclusive range
+// to w, RLP encoded in ascending order, the same encoding as the chain
+// export. Blocks past the latest finalized one are skipped, an export is
+// resumed by calling again from the block after the last one written.
+func (s *Dexon) ExportFinalizedBlocks(w io.Writer, from, to uint64) error {
+	if from > to {
+		return fmt.Errorf("invalid block range %d - %d", from, to)
+	}
+	if head := s.blockchain.CurrentBlock().NumberU64(); to > head {
+		to = head
+	}
+	if from > to {
+		log.Debug("No finalized blocks to export", "from", from)
+		return nil
+	}
+	if err := s.blockchain.ExportN(w, from, to); err != nil {
+		return err
+	}
+	log.Info("Exported finalized blocks", "from", from, "to", to)
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"testing"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/params"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+func TestExportFinalizedBlocks(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	signer := types.NewEIP155Signer(dex.chainConfig.ChainID)
+	gasPrice := dex.governance.DexconConfiguration(0).MinGasPrice
+	for i := 0; i < 3; i++ {
+		tx, err := types.SignTx(types.NewTransaction(uint64(i), common.Address{1},
+			big.NewInt(1), params.TxGas, gasPrice, nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		if _, err := deliverTestBlock(dex, key, 0, types.Transactions{tx}); err != nil {
+			t.Fatalf("failed to deliver block: %v", err)
+		}
+	}
+
+	// Export in two parts, the second resumed after the first and reaching
+	// past the latest finalized block.
+	var buf bytes.Buffer
+	if err := dex.ExportFinalizedBlocks(&buf, 1, 2); err != nil {
+		t.Fatalf("failed to export blocks: %v", err)
+	}
+	if err := dex.ExportFinalizedBlocks(&buf, 3, 10); err != nil {
+		t.Fatalf("failed to export blocks: %v", err)
+	}
+	// Nothing is finalized past the head yet.
+	if err := dex.ExportFinalizedBlocks(&buf, 4, 10); err != nil {
+		t.Fatalf("failed to export blocks: %v", err)
+	}
+	if err := dex.ExportFinalizedBlocks(&buf, 2, 1); err == nil {
+		t.Error("expect error for reversed range")
+	}
+
+	var blocks types.Blocks
+	stream := rlp.NewStream(&buf, 0)
+	for {
+		var block types.Block
+		if err := stream.Decode(&block); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("failed to decode block %d: %v", len(blocks), err)
+		}
+		blocks = append(blocks, &block)
+	}
+	if len(blocks) != 3 {
+		t.Fatalf("exported block count mismatch: have %d, want 3", len(blocks))
+	}
+	for i, block := range blocks {
+		if want := dex.blockchain.GetBlockByNumber(uint64(i + 1)); block.Hash() != want.Hash() {
+			t.Errorf("block %d hash mismatch: have %x, want %x", i+1, block.Hash(), want.Hash())
+		}
+	}
+
+	// Re-import into a node of the same genesis.
+	imported, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	if _, err := imported.blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import blocks: %v", err)
+	}
+	if have, want := imported.blockchain.CurrentBlock(), dex.blockchain.CurrentBlock(); have.Hash() != want.Hash() ||
+		have.Root() != want.Root() {
+		t.Errorf("imported head mismatch: have #%d %x, want #%d %x",
+			have.NumberU64(), have.Hash(), want.NumberU64(), want.Hash())
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"sync"
+
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/event"
+)
+
+// finalizeFlusher flushes the state trie cache of the chain at finalization
+// points, keeping the disk writes out of the block insertion path while
+// consensus is agreeing on the next blocks.
+type finalizeFlusher struct {
+	chain *core.BlockChain
+
+	sub *event.TypeMuxSubscription
+	wg  sync.WaitGroup
+}
+
+func newFinalizeFlusher(chain *core.BlockChain) *finalizeFlusher {
+	return &finalizeFlusher{chain: chain}
+}
+
+func (f *finalizeFlusher) Start(mux *event.TypeMux) {
+	f.sub = mux.Subscribe(FinalizedBlockEvent{})
+
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		for ev := range f.sub.Chan() {
+			f.chain.FlushFinalized(ev.Data.(FinalizedBlockEvent).Block)
+		}
+	}()
+}
+
+func (f *finalizeFlusher) Stop() {
+	f.sub.Unsubscribe()
+	f.wg.Wait()
+}
@@ -0,0 +1,53 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"errors"
+
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/log"
+)
+
+var errFinalityConflict = errors.New("branch conflicts with finalized blocks")
+
+// newFinalityForkChoice creates the fork choice of the chain. The blocks are
+// ordered by consensus and final once delivered, so the canonical chain only
+// grows from its head. A branch forking below the head conflicts with the
+// finalized blocks and is rejected, however much difficulty it carries.
+func newFinalityForkChoice(chain headerReader) core.ForkChoice {
+	return func(current, block *types.Block) error {
+		if isAncestor(chain, current.Header(), block.Header()) {
+			return nil
+		}
+		rejectedReorgMeter.Mark(1)
+		log.Warn("Rejected branch conflicting with finalized blocks",
+			"head", current.Number(), "headHash", current.Hash(),
+			"number", block.Number(), "hash", block.Hash())
+		return errFinalityConflict
+	}
+}
+
+// isAncestor reports whether ancestor is header or one of its ancestors.
+func isAncestor(chain headerReader, ancestor, header *types.Header) bool {
+	for header != nil && header.Number.Uint64() > ancestor.Number.Uint64() {
+		header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	return header != nil && header.Hash() == ancestor.Hash()
+}
@@ -0,0 +1,82 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/dexon-foundation/dexon/consensus/ethash"
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/params"
+)
+
+func TestFinalityForkChoice(t *testing.T) {
+	var (
+		engine = ethash.NewFaker()
+		db     = ethdb.NewMemDatabase()
+		gspec  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{testBank: {Balance: big.NewInt(1000000), Staked: big.NewInt(0)}},
+		}
+		genesis = gspec.MustCommit(db)
+	)
+	blockchain, err := core.NewBlockChain(db, nil, gspec.Config, engine, vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create blockchain: %v", err)
+	}
+	defer blockchain.Stop()
+	blockchain.SetForkChoice(newFinalityForkChoice(blockchain))
+
+	finalized, _ := core.GenerateChain(gspec.Config, genesis, engine, db, 5, nil)
+	if _, err := blockchain.InsertChain(finalized); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	head := blockchain.CurrentBlock()
+
+	// A longer branch forking below the head carries more difficulty, which
+	// would make it canonical by total difficulty.
+	branch, _ := core.GenerateChain(gspec.Config, finalized[1], engine, db, 8, func(i int, b *core.BlockGen) {
+		b.SetExtra([]byte("branch"))
+	})
+	branchTd := blockchain.GetTd(finalized[1].Hash(), finalized[1].NumberU64())
+	for _, block := range branch {
+		branchTd = new(big.Int).Add(branchTd, block.Difficulty())
+	}
+	if headTd := blockchain.GetTd(head.Hash(), head.NumberU64()); branchTd.Cmp(headTd) <= 0 {
+		t.Fatalf("branch difficulty %v not above head %v", branchTd, headTd)
+	}
+	if _, err := blockchain.InsertChain(branch); err != errFinalityConflict {
+		t.Fatalf("unexpected error: have %v, want %v", err, errFinalityConflict)
+	}
+	if current := blockchain.CurrentBlock(); current.Hash() != head.Hash() {
+		t.Fatalf("head replaced by branch: have %d (%x), want %d (%x)",
+			current.NumberU64(), current.Hash(), head.NumberU64(), head.Hash())
+	}
+
+	// Blocks extending the finalized head are still accepted.
+	next, _ := core.GenerateChain(gspec.Config, head, engine, db, 2, nil)
+	if _, err := blockchain.InsertChain(next); err != nil {
+		t.Fatalf("failed to extend chain: %v", err)
+	}
+	if current := blockchain.CurrentBlock(); current.Hash() != next[1].Hash() {
+		t.Errorf("head mismatch: have %x, want %x", current.Hash(), next[1].Hash())
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"time"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/eth/gasprice"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/log"
+)
+
+// restoreGasPrice warms up the gas price oracle with the block prices sampled
+// by the previous run, unless they are older than the window.
+func restoreGasPrice(db ethdb.Database, gpo *gasprice.Oracle, window time.Duration) {
+	snapshot := rawdb.ReadGasPriceSnapshot(db)
+	if snapshot == nil || len(snapshot.Samples) == 0 {
+		return
+	}
+	age := time.Since(time.Unix(int64(snapshot.Time), 0))
+	if age > window {
+		log.Info("Discarded stale gas price snapshot", "samples", len(snapshot.Samples), "age", common.PrettyDuration(age))
+		return
+	}
+	gpo.RestoreSamples(snapshot.Samples)
+	log.Info("Restored gas price snapshot", "samples", len(snapshot.Samples), "age", common.PrettyDuration(age))
+}
+
+// persistGasPrice persists the block prices last sampled by the gas price
+// oracle. Nothing is written if no block was sampled since startup, keeping
+// the time of the previous snapshot.
+func persistGasPrice(db ethdb.Database, gpo *gasprice.Oracle) {
+	samples := gpo.Samples()
+	if len(samples) == 0 {
+		return
+	}
+	snapshot := &rawdb.GasPriceSnapshot{Samples: samples, Time: uint64(time.Now().Unix())}
+	if err := rawdb.WriteGasPriceSnapshot(db, snapshot); err != nil {
+		log.Warn("Failed to persist gas price snapshot", "err", err)
+	}
+}
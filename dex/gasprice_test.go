@@ -0,0 +1,111 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/eth/gasprice"
+	"github.com/dexon-foundation/dexon/params"
+)
+
+func TestPersistGasPrice(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	sender, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key, sender)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	config := gasprice.Config{Blocks: 1, Percentile: 60, Default: big.NewInt(params.GWei)}
+	newOracle := func() *gasprice.Oracle {
+		return gasprice.NewOracle(dex.APIBackend, config)
+	}
+
+	// Warm up the oracle with a block of expensive transactions, not sent by
+	// the proposer which the oracle ignores.
+	minGasPrice := dex.governance.GetHeadState().Configuration().MinGasPrice
+	price := new(big.Int).Mul(minGasPrice, big.NewInt(5))
+	tx := types.NewTransaction(0, common.HexToAddress("0x1234"), big.NewInt(1), params.TxGas, price, nil)
+	tx, err = types.SignTx(tx, types.NewEIP155Signer(dex.chainConfig.ChainID), sender)
+	if err != nil {
+		t.Fatalf("failed to sign tx: %v", err)
+	}
+	if _, err := deliverTestBlock(dex, key, 0, types.Transactions{tx}); err != nil {
+		t.Fatalf("failed to deliver block: %v", err)
+	}
+	gpo := newOracle()
+	persistGasPrice(dex.chainDb, gpo)
+	if rawdb.ReadGasPriceSnapshot(dex.chainDb) != nil {
+		t.Fatal("default price persisted")
+	}
+	if suggested, err := gpo.SuggestPrice(context.Background()); err != nil || suggested.Cmp(price) != 0 {
+		t.Fatalf("suggested price mismatch: have %v (%v), want %v", suggested, err, price)
+	}
+	persistGasPrice(dex.chainDb, gpo)
+
+	// Quiet blocks after the restart give no samples.
+	for i := 0; i < 6; i++ {
+		if _, err := deliverTestBlock(dex, key, 0, nil); err != nil {
+			t.Fatalf("failed to deliver block: %v", err)
+		}
+	}
+	suggest := func(gpo *gasprice.Oracle) *big.Int {
+		suggested, err := gpo.SuggestPrice(context.Background())
+		if err != nil {
+			t.Fatalf("failed to suggest price: %v", err)
+		}
+		return suggested
+	}
+	if suggested := suggest(newOracle()); suggested.Cmp(config.Default) != 0 {
+		t.Fatalf("cold oracle price mismatch: have %v, want %v", suggested, config.Default)
+	}
+	gpo = newOracle()
+	restoreGasPrice(dex.chainDb, gpo, time.Hour)
+	if suggested := suggest(gpo); suggested.Cmp(price) != 0 {
+		t.Errorf("restored oracle price mismatch: have %v, want %v", suggested, price)
+	}
+	// Restored samples are not persisted again as the oracle's own.
+	if samples := gpo.Samples(); len(samples) != 0 {
+		t.Errorf("restored samples reported as sampled: %v", samples)
+	}
+
+	// Snapshots older than the window are discarded.
+	snapshot := rawdb.ReadGasPriceSnapshot(dex.chainDb)
+	snapshot.Time -= uint64(2 * time.Hour / time.Second)
+	if err := rawdb.WriteGasPriceSnapshot(dex.chainDb, snapshot); err != nil {
+		t.Fatalf("failed to write snapshot: %v", err)
+	}
+	gpo = newOracle()
+	restoreGasPrice(dex.chainDb, gpo, time.Hour)
+	if suggested := suggest(gpo); suggested.Cmp(config.Default) != 0 {
+		t.Errorf("stale snapshot restored: have %v, want %v", suggested, config.Default)
+	}
+}
@@ -18,15 +18,23 @@
 package dex
 
 import (
+	"bytes"
 	"context"
 	"crypto/ecdsa"
+	"encoding/hex"
 	"math/big"
+	"sort"
+	"sync"
 
+	dexCore "github.com/dexon-foundation/dexon-consensus/core"
 	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
 	dkgTypes "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
+	"github.com/hashicorp/golang-lru/simplelru"
 
 	"github.com/dexon-foundation/dexon/common"
 	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/rawdb"
 	"github.com/dexon-foundation/dexon/core/types"
 	"github.com/dexon-foundation/dexon/core/vm"
 	"github.com/dexon-foundation/dexon/crypto"
@@ -34,30 +42,263 @@ import (
 	"github.com/dexon-foundation/dexon/params"
 )
 
+// roundCacheSize is the number of rounds of which the notary set and group
+// public key are kept in memory.
+const roundCacheSize = 5
+
+// DexconGovernance serves the governance parameters of the DEXON consensus
+// from the governance contract state. Nothing is read at construction time:
+// the configuration of a round (round length, lambdas, notary set size,
+// block interval, gas limit and price, ...) is read from the state at the
+// beginning of the round ConfigRoundShift rounds earlier, and the CRS, DKG
+// state, notary set and group public key from the state of the round itself.
+// The values derived from them are cached per round and dropped on each
+// round change, or on demand with Reload.
 type DexconGovernance struct {
 	*core.Governance
 
 	b           *DexAPIBackend
 	chainConfig *params.ChainConfig
-	privateKey  *ecdsa.PrivateKey
+	keyMu       sync.RWMutex
+	signTx      txSigner
 	address     common.Address
+
+	roundCache   *simplelru.LRU
+	notarySets   *simplelru.LRU // Notary sets loaded from the database, nil for the ones persisted since
+	roundCacheMu sync.Mutex
+}
+
+// roundCacheItem holds the group public key of a round, only valid as long
+// as the CRS of the round stays the same.
+type roundCacheItem struct {
+	crs common.Hash
+	gpk []byte
 }
 
 // NewDexconGovernance returns a governance implementation of the DEXON
-// consensus governance interface.
+// consensus governance interface, sending the governance transactions from
+// the validator address signed with signTx.
 func NewDexconGovernance(backend *DexAPIBackend, chainConfig *params.ChainConfig,
-	privKey *ecdsa.PrivateKey) *DexconGovernance {
+	address common.Address, signTx txSigner) *DexconGovernance {
 	g := &DexconGovernance{
 		Governance: core.NewGovernance(
 			core.NewGovernanceStateDB(backend.dex.BlockChain())),
 		b:           backend,
 		chainConfig: chainConfig,
-		privateKey:  privKey,
-		address:     crypto.PubkeyToAddress(privKey.PublicKey),
+		signTx:      signTx,
+		address:     address,
 	}
+	g.roundCache, _ = simplelru.NewLRU(roundCacheSize, nil)
+	g.notarySets, _ = simplelru.NewLRU(roundCacheSize, nil)
 	return g
 }
 
+// LoadRoundCache loads the notary set and group public key of the round
+// persisted in the database, so they are served without being recomputed
+// after a restart. A notary set is dropped unless the CRS and the node set of
+// the round on chain are the ones it was sampled from, a group public key
+// unless the CRS is the one it was recovered with.
+func (d *DexconGovernance) LoadRoundCache(round uint64) {
+	crs := common.Hash(d.CRS(round))
+	if crs == (common.Hash{}) {
+		return
+	}
+	db := d.b.ChainDb()
+	var notarySet map[string]struct{}
+	if set := rawdb.ReadRoundNotarySet(db, round); set != nil {
+		if set.CRS == crs && set.NodeSetHash == d.nodeSetHash(round) {
+			notarySet = make(map[string]struct{}, len(set.PublicKeys))
+			for _, pk := range set.PublicKeys {
+				notarySet[hex.EncodeToString(pk)] = struct{}{}
+			}
+			d.roundCacheMu.Lock()
+			d.notarySets.Add(round, notarySet)
+			d.roundCacheMu.Unlock()
+		} else {
+			log.Warn("Dropped stale cached notary set", "round", round)
+		}
+	}
+	var gpk []byte
+	if stored := rawdb.ReadRoundGroupPublicKey(db, round); stored != nil {
+		if stored.CRS == crs {
+			gpk = stored.PublicKey
+			d.roundCacheMu.Lock()
+			d.roundCache.Add(round, &roundCacheItem{crs: crs, gpk: gpk})
+			d.roundCacheMu.Unlock()
+		} else {
+			log.Warn("Dropped stale cached group public key", "round", round)
+		}
+	}
+	if notarySet == nil && gpk == nil {
+		return
+	}
+	log.Info("Loaded cached round info", "round", round,
+		"notaryset", notarySet != nil, "gpk", gpk != nil)
+}
+
+// nodeSetHash returns the hash of the node set the notary set of the round is
+// sampled from.
+func (d *DexconGovernance) nodeSetHash(round uint64) common.Hash {
+	var keys [][]byte
+	for _, node := range d.GetStateForConfigAtRound(round).QualifiedNodes() {
+		keys = append(keys, node.PublicKey)
+	}
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+	return crypto.Keccak256Hash(keys...)
+}
+
+// cachedRound returns the cache item of the round, reset if the CRS of the
+// round has changed since it was cached. It returns nil if the CRS of the
+// round is not known yet.
+func (d *DexconGovernance) cachedRound(round uint64) *roundCacheItem {
+	crs := common.Hash(d.CRS(round))
+	if crs == (common.Hash{}) {
+		return nil
+	}
+	d.roundCacheMu.Lock()
+	defer d.roundCacheMu.Unlock()
+
+	if v, ok := d.roundCache.Get(round); ok {
+		if item := v.(*roundCacheItem); item.crs == crs {
+			return item
+		}
+	}
+	item := &roundCacheItem{crs: crs}
+	d.roundCache.Add(round, item)
+	return item
+}
+
+// QualifiedNodes returns the public keys, hex encoded like the ones of the
+// notary set, of the nodes currently qualified in the governance contract.
+func (d *DexconGovernance) QualifiedNodes() (map[string]struct{}, error) {
+	nodes := map[string]struct{}{}
+	for _, node := range d.GetHeadState().QualifiedNodes() {
+		nodes[hex.EncodeToString(node.PublicKey)] = struct{}{}
+	}
+	return nodes, nil
+}
+
+// NotarySet returns the notary set of the round, the one loaded by
+// LoadRoundCache if any. Other sets are served by the node set cache of the
+// core governance and persisted the first time they are computed.
+func (d *DexconGovernance) NotarySet(round uint64) (map[string]struct{}, error) {
+	d.roundCacheMu.Lock()
+	v, persisted := d.notarySets.Get(round)
+	d.roundCacheMu.Unlock()
+
+	if loaded, _ := v.(map[string]struct{}); loaded != nil {
+		r := make(map[string]struct{}, len(loaded))
+		for key := range loaded {
+			r[key] = struct{}{}
+		}
+		return r, nil
+	}
+	set, err := d.Governance.NotarySet(round)
+	if err != nil {
+		return nil, err
+	}
+	if !persisted {
+		d.persistNotarySet(round, set)
+	}
+	return set, nil
+}
+
+// persistNotarySet writes the notary set of the round to the database, along
+// with the CRS and the node set it was sampled from.
+func (d *DexconGovernance) persistNotarySet(round uint64, set map[string]struct{}) {
+	crs := common.Hash(d.CRS(round))
+	if crs == (common.Hash{}) {
+		return
+	}
+	stored := &rawdb.RoundNotarySet{CRS: crs, NodeSetHash: d.nodeSetHash(round)}
+	for key := range set {
+		pk, err := hex.DecodeString(key)
+		if err != nil {
+			log.Warn("Invalid notary set key", "round", round, "key", key, "err", err)
+			return
+		}
+		stored.PublicKeys = append(stored.PublicKeys, pk)
+	}
+	if err := rawdb.WriteRoundNotarySet(d.b.ChainDb(), round, stored); err != nil {
+		log.Warn("Failed to persist notary set", "round", round, "err", err)
+		return
+	}
+	// Only the round is recorded, the set is served by the core governance.
+	d.roundCacheMu.Lock()
+	d.notarySets.Add(round, nil)
+	d.roundCacheMu.Unlock()
+}
+
+// PurgeNotarySet purges the notary set of the round, it is recomputed on the
+// next query.
+func (d *DexconGovernance) PurgeNotarySet(round uint64) {
+	d.Governance.PurgeNotarySet(round)
+	d.roundCacheMu.Lock()
+	d.roundCache.Remove(round)
+	d.notarySets.Remove(round)
+	d.roundCacheMu.Unlock()
+}
+
+// Reload drops the notary sets, group public keys and DKG state cached for
+// the recent and upcoming rounds, they are read again from the governance
+// state on the next query.
+func (d *DexconGovernance) Reload() {
+	round := d.Round()
+	from := uint64(0)
+	if round > roundCacheSize {
+		from = round - roundCacheSize
+	}
+	d.roundCacheMu.Lock()
+	for _, key := range d.roundCache.Keys() {
+		if r := key.(uint64); r < from || r > round+dexCore.ConfigRoundShift {
+			d.Governance.PurgeNotarySet(r)
+		}
+	}
+	d.roundCache.Purge()
+	d.notarySets.Purge()
+	d.roundCacheMu.Unlock()
+	for r := from; r <= round+dexCore.ConfigRoundShift; r++ {
+		d.Governance.PurgeNotarySet(r)
+	}
+	d.PurgeDKGCache()
+	log.Debug("Reloaded governance", "round", round)
+}
+
+// GroupPublicKey returns the DKG group public key of the round, available
+// once the DKG of the round is final. Keys are persisted once recovered, see
+// LoadRoundCache.
+func (d *DexconGovernance) GroupPublicKey(round uint64) ([]byte, error) {
+	item := d.cachedRound(round)
+	if item == nil {
+		return nil, &ErrCRSNotReady{Round: round, CRSRound: d.CRSRound()}
+	}
+	d.roundCacheMu.Lock()
+	gpk := item.gpk
+	d.roundCacheMu.Unlock()
+	if gpk != nil {
+		return gpk, nil
+	}
+
+	if !d.IsDKGFinal(round) {
+		return nil, &ErrDKGNotFinal{Round: round}
+	}
+	threshold := coreUtils.GetDKGThreshold(d.Configuration(round))
+	groupPublicKey, err := dkgTypes.NewGroupPublicKey(round,
+		d.DKGMasterPublicKeys(round), d.DKGComplaints(round), threshold)
+	if err != nil {
+		return nil, err
+	}
+	gpk = groupPublicKey.GroupPublicKey.Bytes()
+	if err := rawdb.WriteRoundGroupPublicKey(d.b.ChainDb(), round,
+		&rawdb.RoundGroupPublicKey{CRS: item.crs, PublicKey: gpk}); err != nil {
+		log.Warn("Failed to persist group public key", "round", round, "err", err)
+	}
+	d.roundCacheMu.Lock()
+	item.gpk = gpk
+	d.roundCacheMu.Unlock()
+	return gpk, nil
+}
+
 // DexconConfiguration return raw config in state.
 func (d *DexconGovernance) DexconConfiguration(round uint64) *params.DexconConfig {
 	return d.GetStateForConfigAtRound(round).Configuration()
@@ -69,7 +310,11 @@ func (d *DexconGovernance) sendGovTx(ctx context.Context, data []byte) error {
 		return err
 	}
 
-	nonce, err := d.b.GetPoolNonce(ctx, d.address)
+	d.keyMu.RLock()
+	signTx, address := d.signTx, d.address
+	d.keyMu.RUnlock()
+
+	nonce, err := d.b.GetPoolNonce(ctx, address)
 	if err != nil {
 		return err
 	}
@@ -91,16 +336,22 @@ func (d *DexconGovernance) sendGovTx(ctx context.Context, data []byte) error {
 		gasPrice,
 		data)
 
-	signer := types.NewEIP155Signer(d.chainConfig.ChainID)
-
-	tx, err = types.SignTx(tx, signer, d.privateKey)
+	tx, err = signTx(tx, d.chainConfig.ChainID)
 	if err != nil {
 		return err
 	}
 
 	log.Info("Send governance transaction", "fullhash", tx.Hash().Hex(), "nonce", nonce)
 
-	return d.b.SendTx(ctx, tx)
+	return d.b.sendTx(tx)
+}
+
+// setPrivateKey replaces the key the governance transactions are sent with.
+func (d *DexconGovernance) setPrivateKey(key *ecdsa.PrivateKey) {
+	d.keyMu.Lock()
+	d.signTx = keyTxSigner(key)
+	d.address = crypto.PubkeyToAddress(key.PublicKey)
+	d.keyMu.Unlock()
 }
 
 func (d *DexconGovernance) Round() uint64 {
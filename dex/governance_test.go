@@ -0,0 +1,168 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/crypto"
+)
+
+func TestRoundCacheAfterRestart(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	notarySet, err := dex.governance.NotarySet(0)
+	if err != nil {
+		t.Fatalf("failed to get notary set: %v", err)
+	}
+	crs := common.Hash(dex.governance.CRS(0))
+	nodeSetHash := dex.governance.nodeSetHash(0)
+	stored := rawdb.ReadRoundNotarySet(dex.chainDb, 0)
+	if stored == nil || stored.CRS != crs || stored.NodeSetHash != nodeSetHash ||
+		len(stored.PublicKeys) != len(notarySet) {
+		t.Fatalf("notary set not persisted: %+v", stored)
+	}
+
+	// Replace the persisted set, a restarted node serving it proves the set
+	// is not recomputed. The set is persisted once, further queries don't
+	// write it again.
+	cached := &rawdb.RoundNotarySet{CRS: crs, NodeSetHash: nodeSetHash, PublicKeys: [][]byte{{1, 2, 3}}}
+	if err := rawdb.WriteRoundNotarySet(dex.chainDb, 0, cached); err != nil {
+		t.Fatalf("failed to write notary set: %v", err)
+	}
+	if _, err := dex.governance.NotarySet(0); err != nil {
+		t.Fatalf("failed to get notary set: %v", err)
+	}
+	if stored := rawdb.ReadRoundNotarySet(dex.chainDb, 0); len(stored.PublicKeys) != 1 {
+		t.Errorf("notary set persisted again: %d keys", len(stored.PublicKeys))
+	}
+	gpk := []byte{4, 5, 6}
+	if err := rawdb.WriteRoundGroupPublicKey(dex.chainDb, 0,
+		&rawdb.RoundGroupPublicKey{CRS: crs, PublicKey: gpk}); err != nil {
+		t.Fatalf("failed to write group public key: %v", err)
+	}
+
+	restarted := NewDexconGovernance(dex.APIBackend, dex.chainConfig,
+		crypto.PubkeyToAddress(key.PublicKey), keyTxSigner(key))
+	if _, err := restarted.GroupPublicKey(0); err == nil {
+		t.Error("group public key available before DKG is final")
+	}
+	restarted.LoadRoundCache(0)
+	set, err := restarted.NotarySet(0)
+	if err != nil {
+		t.Fatalf("failed to get notary set: %v", err)
+	}
+	want := map[string]struct{}{hex.EncodeToString([]byte{1, 2, 3}): {}}
+	if !reflect.DeepEqual(set, want) {
+		t.Errorf("notary set not served from cache: have %v, want %v", set, want)
+	}
+	if have, err := restarted.GroupPublicKey(0); err != nil || !bytes.Equal(have, gpk) {
+		t.Errorf("group public key not served from cache: have %x (%v), want %x", have, err, gpk)
+	}
+
+	// Entries not matching the on-chain CRS or node set are dropped on load.
+	for _, stale := range []*rawdb.RoundNotarySet{
+		{CRS: common.Hash{1}, NodeSetHash: nodeSetHash, PublicKeys: cached.PublicKeys},
+		{CRS: crs, NodeSetHash: common.Hash{1}, PublicKeys: cached.PublicKeys},
+	} {
+		if err := rawdb.WriteRoundNotarySet(dex.chainDb, 0, stale); err != nil {
+			t.Fatalf("failed to write notary set: %v", err)
+		}
+		restarted = NewDexconGovernance(dex.APIBackend, dex.chainConfig,
+			crypto.PubkeyToAddress(key.PublicKey), keyTxSigner(key))
+		restarted.LoadRoundCache(0)
+		if set, err = restarted.NotarySet(0); err != nil {
+			t.Fatalf("failed to get notary set: %v", err)
+		}
+		if !reflect.DeepEqual(set, notarySet) {
+			t.Errorf("stale notary set served: have %d keys, want %d", len(set), len(notarySet))
+		}
+		stored := rawdb.ReadRoundNotarySet(dex.chainDb, 0)
+		if stored == nil || stored.CRS != crs || stored.NodeSetHash != nodeSetHash ||
+			len(stored.PublicKeys) != len(notarySet) {
+			t.Errorf("recomputed notary set not persisted: %+v", stored)
+		}
+	}
+}
+
+func TestReloadGovernance(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	notarySet, err := dex.governance.NotarySet(0)
+	if err != nil {
+		t.Fatalf("failed to get notary set: %v", err)
+	}
+
+	// Stale notary sets cached for a round are served until the governance is
+	// reloaded.
+	stale := map[string]struct{}{hex.EncodeToString([]byte{1, 2, 3}): {}}
+	injectStale := func() {
+		dex.governance.roundCacheMu.Lock()
+		dex.governance.notarySets.Add(uint64(0), stale)
+		dex.governance.roundCacheMu.Unlock()
+		if set, err := dex.governance.NotarySet(0); err != nil || !reflect.DeepEqual(set, stale) {
+			t.Fatalf("stale notary set not served: have %v (%v)", set, err)
+		}
+	}
+	check := func(when string) {
+		set, err := dex.governance.NotarySet(0)
+		if err != nil {
+			t.Fatalf("failed to get notary set %s: %v", when, err)
+		}
+		if !reflect.DeepEqual(set, notarySet) {
+			t.Errorf("notary set not refreshed %s: have %d keys, want %d", when, len(set), len(notarySet))
+		}
+	}
+
+	injectStale()
+	api := NewPrivateAdminAPI(dex)
+	if !api.ReloadGovernance() {
+		t.Fatal("reload failed")
+	}
+	check("on reload")
+
+	// Heads within the round keep the cache, the next round refreshes it.
+	injectStale()
+	header := &types.Header{Number: big.NewInt(1), Round: 0}
+	dex.roundNotifier.onHead(types.NewBlockWithHeader(header))
+	if set, _ := dex.governance.NotarySet(0); !reflect.DeepEqual(set, stale) {
+		t.Error("cache dropped within the round")
+	}
+	header = &types.Header{Number: big.NewInt(2), Round: 1}
+	dex.roundNotifier.onHead(types.NewBlockWithHeader(header))
+	check("at the next round")
+}
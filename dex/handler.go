@@ -36,6 +36,7 @@ package dex
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -114,6 +115,22 @@ type ProtocolManager struct {
 	nextPullBlock *sync.Map
 	maxPeers      int
 
+	broadcastWeights       BroadcastWeights   // Scheduling weights of the peer broadcast loops
+	reservedValidatorPeers int                // Peer slots only taken by peers in a notary set
+	evictionPolicy         PeerEvictionPolicy // Choice of the peer evicted for a notary set peer
+	preferFinalizedPeers   bool               // Rank sync peers by the round of their head first
+	consensusTracer        *consensusTracer   // Capture of the consensus messages, nil if disabled
+	handshakeExtensions    int                // Timeout extensions of the handshakes of trusted peers
+	handshakeExtension     time.Duration      // Extra time given by the first handshake timeout extension
+	voteSigCache           *voteSigCache      // Votes verified in the receive path, nil if disabled
+	consensusMsgRate       int                // Consensus messages accepted per second from a peer, zero if unlimited
+	quorumWatchdog         *quorumWatchdog    // Agreement progress tracking, nil if disabled
+	txRejections           *txRejectionFeed   // Transactions rejected by the pool, nil if not reported
+
+	// Propagation latencies of the received core blocks and finalized blocks
+	coreBlockPropagation *propagationTracker
+	blockPropagation     *propagationTracker
+
 	downloader *downloader.Downloader
 	fetcher    *fetcher.Fetcher
 	peers      *peerSet
@@ -168,6 +185,7 @@ func NewProtocolManager(
 	// Create the protocol manager with the base fields
 	manager := &ProtocolManager{
 		networkID:          networkID,
+		broadcastWeights:   DefaultBroadcastWeights,
 		eventMux:           mux,
 		txpool:             txpool,
 		gov:                gov,
@@ -188,6 +206,8 @@ func NewProtocolManager(
 		app:                app,
 		blockNumberGauge:   metrics.GetOrRegisterGauge("dex/blocknumber", nil),
 	}
+	manager.coreBlockPropagation = newPropagationTracker(coreBlockPropagationHist)
+	manager.blockPropagation = newPropagationTracker(blockPropagationHist)
 
 	// Figure out whether to allow fast sync or not
 	if mode == downloader.FastSync && blockchain.CurrentBlock().NumberU64() > 0 {
@@ -225,6 +245,9 @@ func NewProtocolManager(
 				}
 				return nil
 			},
+			Exempt: func(id enode.ID) bool {
+				return manager.exemptPeer(id)
+			},
 		})
 	}
 	if len(manager.SubProtocols) == 0 {
@@ -248,7 +271,7 @@ func NewProtocolManager(
 		atomic.StoreUint32(&manager.acceptTxs, 1) // Mark initial sync done on any fetcher import
 		return manager.blockchain.InsertDexonChain(blocks)
 	}
-	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.BroadcastBlock, heighter, inserter, manager.removePeer)
+	manager.fetcher = fetcher.New(blockchain.GetBlockByHash, validator, manager.broadcastVerifiedBlock, heighter, inserter, manager.removePeer)
 
 	return manager, nil
 }
@@ -283,6 +306,7 @@ func (pm *ProtocolManager) Start(srvr p2pServer, maxPeers int) {
 	pm.maxPeers = maxPeers
 	pm.srvr = srvr
 	pm.peers = newPeerSet(pm.gov, pm.srvr)
+	pm.peers.preferFinalized = pm.preferFinalizedPeers
 
 	// broadcast transactions
 	pm.txsCh = make(chan core.NewTxsEvent, txChanSize)
@@ -340,6 +364,27 @@ func (pm *ProtocolManager) Stop() {
 	log.Info("DEXON protocol stopped")
 }
 
+// rekey switches the p2p identity of the node to the key, reconnecting to the
+// notary sets under the new identity. Other peers stay connected.
+func (pm *ProtocolManager) rekey(key *ecdsa.PrivateKey) error {
+	if err := pm.srvr.Rekey(key); err != nil {
+		return err
+	}
+	pm.peers.RebuildConnections()
+	return nil
+}
+
+// deliverCoreMsg passes a consensus message received from the peer to the
+// consensus core.
+func (pm *ProtocolManager) deliverCoreMsg(p *peer, msg interface{}) {
+	id := p.ID().String()
+	pm.consensusTracer.trace(false, id, msg)
+	pm.receiveCh <- coreTypes.Msg{
+		PeerID:  id,
+		Payload: msg,
+	}
+}
+
 func (pm *ProtocolManager) ReceiveChan() <-chan coreTypes.Msg {
 	return pm.receiveCh
 }
@@ -361,15 +406,27 @@ func (pm *ProtocolManager) badPeerWatchLoop() {
 }
 
 func (pm *ProtocolManager) newPeer(pv int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
-	return newPeer(pv, p, newMeteredMsgWriter(rw))
+	peer := newPeer(pv, p, newMeteredMsgWriter(rw))
+	peer.weights = pm.broadcastWeights
+	return peer
 }
 
 // handle is the callback invoked to manage the life cycle of an eth peer. When
 // this function terminates, the peer is disconnected.
 func (pm *ProtocolManager) handle(p *peer) error {
-	// Ignore maxPeers if this is a trusted peer
-	if pm.peers.Len() >= pm.maxPeers && !p.Peer.Info().Network.Trusted {
-		return p2p.DiscTooManyPeers
+	// Ignore maxPeers if this is a trusted peer. Slots reserved for validators
+	// are only taken by peers in a notary set, which evict another peer if
+	// all the slots are taken.
+	limit := pm.maxPeers
+	notary := pm.peers.IsNotaryPeer(p.id)
+	if !notary {
+		limit -= pm.reservedValidatorPeers
+	}
+	validator := pm.peers.IsValidatorPeer(p.id)
+	if pm.peers.Len() >= limit && !p.Peer.Info().Network.Trusted && !validator {
+		if !notary || !pm.evictPeer() {
+			return p2p.DiscTooManyPeers
+		}
 	}
 	p.Log().Debug("Ethereum peer connected", "name", p.Name())
 
@@ -380,13 +437,23 @@ func (pm *ProtocolManager) handle(p *peer) error {
 		hash    = head.Hash()
 		number  = head.Number.Uint64()
 	)
-	if err := p.Handshake(pm.networkID, number, hash, genesis.Hash()); err != nil {
+	// Peers we depend on, trusted, static, pinned validators or in a notary
+	// set, are given more time on network blips instead of being dropped.
+	var extensions int
+	if info := p.Peer.Info().Network; info.Trusted || info.Static || validator || notary {
+		extensions = pm.handshakeExtensions
+	}
+	if err := p.Handshake(pm.networkID, number, head.Round, hash, genesis.Hash(),
+		pm.chainconfig.DMoment, extensions, pm.handshakeExtension); err != nil {
 		p.Log().Debug("Ethereum handshake failed", "err", err)
 		return err
 	}
 	if rw, ok := p.rw.(*meteredMsgReadWriter); ok {
 		rw.Init(p.version)
 	}
+	if pm.consensusMsgRate > 0 {
+		p.consensusLimiter = newMsgRateLimiter(pm.consensusMsgRate)
+	}
 	// Register the peer locally
 	if err := pm.peers.Register(p); err != nil {
 		p.Log().Error("Ethereum peer registration failed", "err", err)
@@ -465,6 +532,20 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		}
 	}()
 
+	if msg.Code >= CoreBlockMsg && msg.Code <= PullVotesMsg {
+		p.MarkConsensusMsg()
+		if p.consensusLimiter != nil {
+			if ok, flood := p.consensusLimiter.allow(); !ok {
+				throttledConsensusMsgMeter.Mark(1)
+				if flood {
+					consensusMsgFloodMeter.Mark(1)
+					return errResp(ErrConsensusMsgFlood, "peer %s", p.id)
+				}
+				return nil
+			}
+		}
+	}
+
 	// Handle the message depending on its contents
 	switch {
 	case msg.Code == StatusMsg:
@@ -818,9 +899,10 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			trueHead   = block.ParentHash()
 			trueNumber = block.NumberU64() - 1
 		)
-		// Update the peers number if better than the previous
+		// Update the peers number if better than the previous. The round is
+		// the one of the propagated block, which the peer is importing.
 		if _, number := p.Head(); trueNumber > number {
-			p.SetHead(trueHead, trueNumber)
+			p.SetHead(trueHead, trueNumber, block.Round())
 
 			// Schedule a sync if above ours. Note, this will not fire a sync for a gap of
 			// a single block (as the true number is below the propagated block), however this
@@ -849,7 +931,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			p.MarkTransaction(tx.Hash())
 		}
 		types.GlobalSigCache.Add(types.NewEIP155Signer(pm.blockchain.Config().ChainID), txs)
-		pm.txpool.AddRemotes(txs)
+		pm.txRejections.report(txs, pm.txpool.AddRemotes(txs))
 
 	// Block proposer-only messages.
 	case msg.Code == CoreBlockMsg:
@@ -860,12 +942,10 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&blocks); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
+		pm.recordCoreBlockPropagation(blocks, msg.ReceivedAt)
 		pm.cache.addBlocks(blocks)
 		for _, block := range blocks {
-			pm.receiveCh <- coreTypes.Msg{
-				PeerID:  p.ID().String(),
-				Payload: block,
-			}
+			pm.deliverCoreMsg(p, block)
 		}
 	case msg.Code == VoteMsg:
 		if atomic.LoadInt32(&pm.receiveCoreMessage) == 0 {
@@ -876,13 +956,16 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
 		for _, vote := range votes {
+			if pm.voteSigCache != nil && !pm.voteSigCache.verify(vote) {
+				invalidVoteMeter.Mark(1)
+				p.Log().Debug("Dropped vote with invalid signature", "vote", vote)
+				continue
+			}
 			if vote.Type >= coreTypes.VotePreCom {
 				pm.cache.addVote(vote)
 			}
-			pm.receiveCh <- coreTypes.Msg{
-				PeerID:  p.ID().String(),
-				Payload: vote,
-			}
+			pm.quorumWatchdog.onVote(vote)
+			pm.deliverCoreMsg(p, vote)
 		}
 	case msg.Code == AgreementMsg:
 		if atomic.LoadInt32(&pm.receiveCoreMessage) == 0 {
@@ -900,10 +983,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			block[0].Randomness = agreement.Randomness
 			pm.cache.addFinalizedBlock(block[0])
 		}
-		pm.receiveCh <- coreTypes.Msg{
-			PeerID:  p.ID().String(),
-			Payload: &agreement,
-		}
+		pm.deliverCoreMsg(p, &agreement)
 	case msg.Code == DKGPrivateShareMsg:
 		if atomic.LoadInt32(&pm.receiveCoreMessage) == 0 {
 			break
@@ -914,10 +994,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
 		p.MarkDKGPrivateShares(rlpHash(ps))
-		pm.receiveCh <- coreTypes.Msg{
-			PeerID:  p.ID().String(),
-			Payload: &ps,
-		}
+		pm.deliverCoreMsg(p, &ps)
 	case msg.Code == DKGPartialSignatureMsg:
 		if atomic.LoadInt32(&pm.receiveCoreMessage) == 0 {
 			break
@@ -927,10 +1004,7 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&psig); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
-		pm.receiveCh <- coreTypes.Msg{
-			PeerID:  p.ID().String(),
-			Payload: &psig,
-		}
+		pm.deliverCoreMsg(p, &psig)
 	case msg.Code == PullBlocksMsg:
 		if atomic.LoadInt32(&pm.receiveCoreMessage) == 0 {
 			break
@@ -1131,6 +1205,7 @@ func (pm *ProtocolManager) BroadcastCoreBlock(block *coreTypes.Block) {
 
 // BroadcastVote broadcasts the given vote to all peers in same notary set
 func (pm *ProtocolManager) BroadcastVote(vote *coreTypes.Vote) {
+	pm.quorumWatchdog.onOwnVote(vote)
 	if vote.Type >= coreTypes.VotePreCom {
 		pm.cache.addVote(vote)
 	}
@@ -1329,6 +1404,7 @@ func (pm *ProtocolManager) peerSetLoop() {
 			if !pm.isBlockProposer {
 				break
 			}
+			pm.demoteRemovedValidators()
 
 			newRound := pm.gov.CRSRound()
 			if newRound == 0 {
@@ -1369,6 +1445,23 @@ func (pm *ProtocolManager) peerSetLoop() {
 	}
 }
 
+// demoteRemovedValidators stops the consensus traffic to the validators
+// removed from the governance node set, treating them as regular peers. The
+// demoted peers are disconnected if all peer slots are taken.
+func (pm *ProtocolManager) demoteRemovedValidators() {
+	qualified, err := pm.gov.QualifiedNodes()
+	if err != nil {
+		log.Warn("Failed to get qualified nodes", "err", err)
+		return
+	}
+	for _, id := range pm.peers.DemoteRemovedNodes(qualified) {
+		log.Info("Demoted validator removed from node set", "id", id)
+		if p := pm.peers.Peer(id); p != nil && pm.peers.Len() >= pm.maxPeers && !pm.peers.IsValidatorPeer(id) {
+			p.Disconnect(p2p.DiscTooManyPeers)
+		}
+	}
+}
+
 // NodeInfo represents a short summary of the Ethereum sub-protocol metadata
 // known about the host peer.
 type NodeInfo struct {
@@ -1439,6 +1532,43 @@ func (pm *ProtocolManager) NotaryInfo() (*NotaryInfo, error) {
 	return info, nil
 }
 
+// consensusMsgTimeout is the duration after which a peer without consensus
+// messages is no longer considered to be exchanging them with us.
+const consensusMsgTimeout = time.Minute
+
+// PeersInfo returns the consensus sync state of all connected peers.
+func (pm *ProtocolManager) PeersInfo() []*DexPeerInfo {
+	round := pm.blockchain.CurrentBlock().Round()
+	receiving := atomic.LoadInt32(&pm.receiveCoreMessage) == 1
+	notaries := make(map[string]struct{})
+	for _, p := range pm.peers.PeersWithLabel(peerLabel{set: notaryset, round: round}) {
+		notaries[p.id] = struct{}{}
+	}
+
+	infos := []*DexPeerInfo{}
+	for _, p := range pm.peers.Peers() {
+		info := &DexPeerInfo{
+			ID:       p.id,
+			Name:     p.Name(),
+			PeerInfo: *p.Info(),
+		}
+		hash, _ := p.Head()
+		if header := pm.blockchain.GetHeaderByHash(hash); header != nil {
+			info.Round = header.Round
+		}
+		if position := p.LastKnownAgreementPosition(); position.Round > info.Round {
+			info.Round = position.Round
+		}
+		_, info.Notary = notaries[p.id]
+		if last := p.LastConsensusMsg(); !last.IsZero() {
+			info.LastConsensusMsg = &last
+			info.Consensus = receiving && time.Since(last) < consensusMsgTimeout
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
 func (pm *ProtocolManager) buildNotaryNodeInfo(
 	pubkeys map[string]struct{}) ([]*NotaryNodeInfo, bool, error) {
 
@@ -0,0 +1,91 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/dexon-foundation/dexon/log"
+)
+
+// healthStallTimeout is the maximum time since the last finalized block for
+// a block proposer to be considered participating in the agreement.
+const healthStallTimeout = 30 * time.Second
+
+// healthStatus is the response body of the health endpoint.
+type healthStatus struct {
+	Healthy bool   `json:"healthy"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// checkHealth returns an error describing why the node is not healthy: it is
+// still syncing, or it is a block proposer not participating in the agreement
+// of the current round.
+func (s *Dexon) checkHealth() error {
+	pm := s.protocolManager
+	if atomic.LoadUint32(&pm.acceptTxs) == 0 || pm.downloader.Synchronising() {
+		return errors.New("node is syncing")
+	}
+	if !s.config.BlockProposerEnabled {
+		return nil
+	}
+	if !s.bp.IsProposing() {
+		return errors.New("block proposer is not running consensus")
+	}
+	head := s.blockchain.CurrentBlock()
+	elapsed := time.Since(time.Unix(0, int64(head.Time())*int64(time.Millisecond)))
+	if elapsed > healthStallTimeout {
+		return fmt.Errorf("no block finalized in round %d for %v", head.Round(),
+			elapsed.Round(time.Second))
+	}
+	return nil
+}
+
+// newHealthHandler returns a handler replying 200 if check passes, and 503
+// with the reason otherwise.
+func newHealthHandler(check func() error) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		status := healthStatus{Healthy: true}
+		w.Header().Set("Content-Type", "application/json")
+		if err := check(); err != nil {
+			status = healthStatus{Reason: err.Error()}
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(status)
+	})
+	return mux
+}
+
+// startHealthServer starts serving the health endpoint on the given address.
+func startHealthServer(addr string, check func() error) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	server := &http.Server{Handler: newHealthHandler(check)}
+	go server.Serve(listener)
+	log.Info("Health check endpoint opened", "url", fmt.Sprintf("http://%s/health", listener.Addr()))
+	return server, nil
+}
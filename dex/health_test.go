@@ -0,0 +1,55 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthHandler(t *testing.T) {
+	var checkErr error
+	handler := newHealthHandler(func() error { return checkErr })
+
+	request := func() (int, healthStatus) {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/health", nil))
+
+		var status healthStatus
+		if err := json.NewDecoder(recorder.Body).Decode(&status); err != nil {
+			t.Fatalf("failed to decode health status: %v", err)
+		}
+		return recorder.Code, status
+	}
+
+	if code, status := request(); code != http.StatusOK || !status.Healthy {
+		t.Errorf("healthy node reported as: %d %+v", code, status)
+	}
+
+	checkErr = errors.New("node is syncing")
+	code, status := request()
+	if code != http.StatusServiceUnavailable || status.Healthy {
+		t.Errorf("unhealthy node reported as: %d %+v", code, status)
+	}
+	if status.Reason != checkErr.Error() {
+		t.Errorf("reason mismatch: have %q, want %q", status.Reason, checkErr.Error())
+	}
+}
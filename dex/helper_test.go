@@ -21,13 +21,21 @@ package dex
 
 import (
 	"crypto/ecdsa"
+	"errors"
 	"math/big"
 	"net"
 	"sort"
 	"sync"
 	"testing"
+	"time"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	coreEcdsa "github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
 
 	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/consensus/dexcon"
 	"github.com/dexon-foundation/dexon/consensus/ethash"
 	"github.com/dexon-foundation/dexon/core"
 	"github.com/dexon-foundation/dexon/core/types"
@@ -39,6 +47,7 @@ import (
 	"github.com/dexon-foundation/dexon/p2p"
 	"github.com/dexon-foundation/dexon/p2p/enode"
 	"github.com/dexon-foundation/dexon/params"
+	"github.com/dexon-foundation/dexon/rlp"
 )
 
 var (
@@ -66,13 +75,25 @@ func newTestP2PServer(privkey *ecdsa.PrivateKey) *testP2PServer {
 }
 
 func (s *testP2PServer) Self() *enode.Node {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.self
 }
 
 func (s *testP2PServer) GetPrivateKey() *ecdsa.PrivateKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.privkey
 }
 
+func (s *testP2PServer) Rekey(privkey *ecdsa.PrivateKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.self = enode.NewV4(&privkey.PublicKey, net.IP{}, 0, 0)
+	s.privkey = privkey
+	return nil
+}
+
 func (s *testP2PServer) AddDirectPeer(node *enode.Node) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -208,9 +229,10 @@ func newTestTransaction(from *ecdsa.PrivateKey, nonce uint64, datasize int) *typ
 
 // testGovernance is a fake, helper governance for testing purposes
 type testGovernance struct {
-	lenCRSFunc    func() uint64
-	notarySetFunc func(uint64) (map[string]struct{}, error)
-	dkgSetFunc    func(uint64) (map[string]struct{}, error)
+	lenCRSFunc         func() uint64
+	notarySetFunc      func(uint64) (map[string]struct{}, error)
+	dkgSetFunc         func(uint64) (map[string]struct{}, error)
+	qualifiedNodesFunc func() (map[string]struct{}, error)
 }
 
 func (g *testGovernance) Round() uint64 {
@@ -232,6 +254,13 @@ func (g *testGovernance) NotarySet(
 	return g.notarySetFunc(round)
 }
 
+func (g *testGovernance) QualifiedNodes() (map[string]struct{}, error) {
+	if g.qualifiedNodesFunc == nil {
+		return nil, errors.New("qualified nodes unknown")
+	}
+	return g.qualifiedNodesFunc()
+}
+
 func (g *testGovernance) DKGSet(round uint64) (map[string]struct{}, error) {
 	return g.dkgSetFunc(round)
 }
@@ -249,14 +278,19 @@ type testPeer struct {
 
 // newTestPeer creates a new peer registered at the given protocol manager.
 func newTestPeer(name string, version int, pm *ProtocolManager, shake bool) (*testPeer, <-chan error) {
-	// Create a message pipe to communicate through
-	app, pipenet := p2p.MsgPipe()
-
 	// Generate a random key and create the peer
 	key, err := crypto.GenerateKey()
 	if err != nil {
 		panic(err)
 	}
+	return newTestPeerWithKey(name, version, pm, shake, key)
+}
+
+// newTestPeerWithKey creates a new peer with the given node key.
+func newTestPeerWithKey(name string, version int, pm *ProtocolManager, shake bool,
+	key *ecdsa.PrivateKey) (*testPeer, <-chan error) {
+	// Create a message pipe to communicate through
+	app, pipenet := p2p.MsgPipe()
 
 	node := enode.NewV4(&key.PublicKey, net.IP{}, 0, 0)
 	peer := pm.newPeer(version, p2p.NewPeerWithEnode(node, name, nil), pipenet)
@@ -279,21 +313,15 @@ func newTestPeer(name string, version int, pm *ProtocolManager, shake bool) (*te
 			head    = pm.blockchain.CurrentHeader()
 			number  = head.Number.Uint64()
 		)
-		tp.handshake(nil, number, head.Hash(), genesis.Hash())
+		tp.handshake(nil, number, head.Round, head.Hash(), genesis.Hash())
 	}
 	return tp, errc
 }
 
 // handshake simulates a trivial handshake that expects the same state from the
 // remote side as we are simulating locally.
-func (p *testPeer) handshake(t *testing.T, number uint64, head common.Hash, genesis common.Hash) {
-	msg := &statusData{
-		ProtocolVersion: uint32(p.version),
-		NetworkId:       DefaultConfig.NetworkId,
-		Number:          number,
-		CurrentBlock:    head,
-		GenesisBlock:    genesis,
-	}
+func (p *testPeer) handshake(t *testing.T, number uint64, round uint64, head common.Hash, genesis common.Hash) {
+	msg := newStatusData(p.version, DefaultConfig.NetworkId, number, head, genesis, round, 0)
 	if err := p2p.ExpectMsg(p.app, StatusMsg, msg); err != nil {
 		t.Fatalf("status recv: %v", err)
 	}
@@ -307,3 +335,124 @@ func (p *testPeer) handshake(t *testing.T, number uint64, head common.Hash, gene
 func (p *testPeer) close() {
 	p.app.Close()
 }
+
+// newTestDexon creates a Dexon instance backed by an in-memory database whose
+// genesis registers every given key as a node, so that each of them is able
+// to propose blocks.
+func newTestDexon(nodeKeys ...*ecdsa.PrivateKey) (*Dexon, error) {
+	db := ethdb.NewMemDatabase()
+
+	genesis := core.DefaultTestnetGenesisBlock()
+	genesisConfig := *genesis.Config
+	dexconConfig := *genesisConfig.Dexcon
+	genesisConfig.Dexcon = &dexconConfig
+	genesis.Config = &genesisConfig
+
+	for _, key := range nodeKeys {
+		genesis.Alloc[crypto.PubkeyToAddress(key.PublicKey)] = core.GenesisAccount{
+			Balance:   big.NewInt(100000000000000000),
+			Staked:    big.NewInt(50000000000000000),
+			PublicKey: crypto.FromECDSAPub(&key.PublicKey),
+		}
+	}
+	genesis.Config.Dexcon.BlockGasLimit = 2000000
+	genesis.Config.Dexcon.RoundLength = 600
+	if len(nodeKeys) > 0 {
+		genesis.Config.Dexcon.Owner = crypto.PubkeyToAddress(nodeKeys[0].PublicKey)
+	}
+
+	chainConfig, _, err := core.SetupGenesisBlock(db, genesis)
+	if err != nil {
+		return nil, err
+	}
+
+	config := DefaultConfig
+	if len(nodeKeys) > 0 {
+		config.PrivateKey = nodeKeys[0]
+	} else if config.PrivateKey, err = crypto.GenerateKey(); err != nil {
+		return nil, err
+	}
+	engine := dexcon.New()
+
+	dex := &Dexon{
+		config:       &config,
+		chainDb:      db,
+		chainConfig:  chainConfig,
+		networkID:    config.NetworkId,
+		eventMux:     new(event.TypeMux),
+		engine:       engine,
+		bloomIndexer: NewBloomIndexer(db, params.BloomBitsBlocks, params.BloomConfirms),
+		signer:       newFencedSigner(coreEcdsa.NewPrivateKeyFromECDSA(config.PrivateKey)),
+		rotator:      new(keyRotator),
+		txRejections: new(txRejectionFeed),
+	}
+	dex.blockchain, err = core.NewBlockChain(db, nil, chainConfig, engine,
+		vm.Config{IsBlockProposer: true}, nil)
+	if err != nil {
+		return nil, err
+	}
+	txPoolConfig := core.DefaultTxPoolConfig
+	txPoolConfig.Journal = ""
+	dex.txPool = core.NewTxPool(txPoolConfig, chainConfig, dex.blockchain)
+
+	dex.APIBackend = &DexAPIBackend{dex: dex}
+	dex.governance = NewDexconGovernance(dex.APIBackend, dex.chainConfig,
+		crypto.PubkeyToAddress(config.PrivateKey.PublicKey), keyTxSigner(config.PrivateKey))
+	engine.SetGovStateFetcher(dex.governance)
+	dex.app = NewDexconApp(dex.txPool, dex.blockchain, dex.governance, db, &config)
+	dex.app.rotator = dex.rotator
+	dex.roundNotifier = newRoundNotifier(dex.governance, dex.blockchain, nil)
+	return dex, nil
+}
+
+// deliverTestBlock runs a block proposed by proposer through the confirm and
+// deliver path of DexconApp, appending it to the local chain. A nil proposer
+// produces an empty block.
+func deliverTestBlock(dex *Dexon, proposer *ecdsa.PrivateKey, round uint64,
+	txs types.Transactions) (*types.Block, error) {
+	block, err := newTestCoreBlock(dex, proposer, round, txs)
+	if err != nil {
+		return nil, err
+	}
+	dex.app.BlockConfirmed(*block)
+	dex.app.BlockDelivered(block.Hash, block.Position, block.Randomness)
+	return dex.blockchain.CurrentBlock(), nil
+}
+
+// newTestCoreBlock creates the consensus core block of the given transactions
+// following the current block.
+func newTestCoreBlock(dex *Dexon, proposer *ecdsa.PrivateKey, round uint64,
+	txs types.Transactions) (*coreTypes.Block, error) {
+	current := dex.blockchain.CurrentBlock()
+	witnessData, err := rlp.EncodeToBytes(current.Hash())
+	if err != nil {
+		return nil, err
+	}
+	payload, err := rlp.EncodeToBytes(txs)
+	if err != nil {
+		return nil, err
+	}
+
+	block := coreTypes.Block{
+		Position: coreTypes.Position{
+			Round:  round,
+			Height: current.NumberU64() + 1,
+		},
+		Timestamp: time.Now(),
+		Witness: coreTypes.Witness{
+			Height: current.NumberU64(),
+			Data:   witnessData,
+		},
+		Payload:     payload,
+		PayloadHash: coreCommon.Hash(crypto.Keccak256Hash(payload)),
+		Randomness:  []byte{0x1},
+	}
+	if proposer != nil {
+		block.ProposerID = coreTypes.NewNodeID(
+			coreEcdsa.NewPrivateKeyFromECDSA(proposer).PublicKey())
+	}
+	if block.Hash, err = coreUtils.HashBlock(&block); err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
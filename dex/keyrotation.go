@@ -0,0 +1,558 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	dexCore "github.com/dexon-foundation/dexon-consensus/core"
+	coreCrypto "github.com/dexon-foundation/dexon-consensus/core/crypto"
+	coreEcdsa "github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+
+	"github.com/dexon-foundation/dexon/accounts"
+	"github.com/dexon-foundation/dexon/accounts/keystore"
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/log"
+)
+
+const (
+	datadirKeyRotation = "keyrotation" // Path within the datadir to the scheduled key rotation
+	datadirNodeKey     = "nodekey"     // Path within the datadir to the node key, see node.Config
+
+	nodeKeyBackupSuffix = ".old" // Suffix of the node key replaced by a key rotation
+)
+
+var errKeyRetired = errors.New("validator key retired by key rotation")
+
+// fencedSigner is the consensus signer of a validator key. Once the key is
+// retired by a key rotation it refuses to sign, so a consensus core still
+// running with the key can't sign anything for the rounds of the new key.
+type fencedSigner struct {
+	coreCrypto.PrivateKey
+
+	mu      sync.RWMutex
+	retired bool
+}
+
+func newFencedSigner(key coreCrypto.PrivateKey) *fencedSigner {
+	return &fencedSigner{PrivateKey: key}
+}
+
+// Sign implements coreCrypto.PrivateKey.
+func (s *fencedSigner) Sign(hash coreCommon.Hash) (coreCrypto.Signature, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.retired {
+		return coreCrypto.Signature{}, errKeyRetired
+	}
+	return s.PrivateKey.Sign(hash)
+}
+
+// retire stops the signer. Signatures in progress complete before it
+// returns, none is made afterwards.
+func (s *fencedSigner) retire() {
+	s.mu.Lock()
+	s.retired = true
+	s.mu.Unlock()
+}
+
+// isRetired reports whether the signer is retired.
+func (s *fencedSigner) isRetired() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.retired
+}
+
+// keyRotation is a switch of the validator key scheduled at a round.
+type keyRotation struct {
+	round  uint64            // Round the new key takes over at
+	oldID  coreTypes.NodeID  // Node ID of the retiring key
+	oldKey *ecdsa.PrivateKey // Retiring validator key
+	signer *fencedSigner     // Signer of the retiring key
+	key    *ecdsa.PrivateKey // New validator key
+	ready  func() error      // Checks the new key can take over at the round
+
+	once      sync.Once
+	retired   chan struct{} // Closed once the retiring key stopped signing
+	switched  chan struct{} // Closed once the new key is in use
+	cancelled chan struct{} // Closed if the new key is not ready at the round
+}
+
+// keyRotator fences the retiring key of the scheduled key rotations. The key
+// stops signing as soon as the consensus reaches the rotation round, which
+// may be ahead of the chain, and messages of the key for the round are never
+// broadcast.
+type keyRotator struct {
+	mu        sync.Mutex
+	rotations []*keyRotation // Scheduled and completed rotations, oldest first
+}
+
+// pending returns the rotation not yet retiring its key, nil if none.
+func (k *keyRotator) pending() *keyRotation {
+	if k == nil {
+		return nil
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if n := len(k.rotations); n > 0 {
+		if r := k.rotations[n-1]; !r.signer.isRetired() {
+			return r
+		}
+	}
+	return nil
+}
+
+// schedule adds a rotation, failing if another one is still pending.
+func (k *keyRotator) schedule(r *keyRotation) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if n := len(k.rotations); n > 0 {
+		if last := k.rotations[n-1]; !last.signer.isRetired() {
+			return fmt.Errorf("key rotation at round %d already scheduled", last.round)
+		}
+	}
+	k.rotations = append(k.rotations, r)
+	return nil
+}
+
+// cancel drops the pending rotation, the current key stays in use.
+func (k *keyRotator) cancel(r *keyRotation) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if n := len(k.rotations); n > 0 && k.rotations[n-1] == r {
+		k.rotations = k.rotations[:n-1]
+	}
+}
+
+// observe is called with the round of each consensus position the node
+// handles. It retires the key of the pending rotation once the round is
+// reached, or cancels the rotation if the new key is not ready to take over.
+func (k *keyRotator) observe(round uint64) {
+	r := k.pending()
+	if r == nil || round < r.round {
+		return
+	}
+	r.once.Do(func() {
+		if r.ready != nil {
+			if err := r.ready(); err != nil {
+				k.cancel(r)
+				close(r.cancelled)
+				log.Error("Cancelled validator key rotation", "round", r.round, "err", err)
+				return
+			}
+		}
+		r.signer.retire()
+		close(r.retired)
+		log.Warn("Retired validator key", "round", round, "rotation", r.round)
+	})
+}
+
+// fenced reports whether a message of the node at the given round must not
+// be broadcast, as it is signed by a key retired before the round.
+func (k *keyRotator) fenced(id coreTypes.NodeID, round uint64) bool {
+	if k == nil {
+		return false
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	for _, r := range k.rotations {
+		if r.oldID == id && round >= r.round {
+			return true
+		}
+	}
+	return false
+}
+
+// consensusRound returns the latest round the node knows of, the consensus
+// may confirm blocks of a round before the chain enters it.
+func (s *Dexon) consensusRound() uint64 {
+	round := s.blockchain.CurrentBlock().Round()
+	s.app.appMu.RLock()
+	defer s.app.appMu.RUnlock()
+	if s.app.confirmedRound > round {
+		round = s.app.confirmedRound
+	}
+	return round
+}
+
+// ScheduleKeyRotation switches the validator key to the given key at the
+// beginning of the round. The current key keeps signing until the consensus
+// reaches the round, then the block proposer is restarted with the new key,
+// taking part in consensus and DKG under the new node identity, and the p2p
+// server switches to the new identity, keeping its peers. Both keys never
+// sign in the same round, the node signs nothing while switching.
+//
+// The rotation is saved in the datadir until the new key replaces the node
+// key there, so a restart resumes it, see resumeKeyRotation. Only the address
+// of the new key is saved, the key is unlocked from the keystore on restart.
+//
+// The new key must be registered as a node for the round, and the round must
+// not have started yet. The rotation is cancelled at the round if the new key
+// has DKG duties in it, see rotationDKGReady.
+func (s *Dexon) ScheduleKeyRotation(key *ecdsa.PrivateKey, round uint64) error {
+	if !s.config.BlockProposerEnabled {
+		return &ErrNotValidator{}
+	}
+	if current := s.consensusRound(); round <= current {
+		return fmt.Errorf("round %d already started, consensus is at round %d", round, current)
+	}
+	currentKey, signer := s.validator()
+	oldKey := crypto.FromECDSAPub(&currentKey.PublicKey)
+	newKey := crypto.FromECDSAPub(&key.PublicKey)
+	if bytes.Equal(oldKey, newKey) {
+		return errors.New("new key is the current validator key")
+	}
+	if !s.isRegisteredNode(&key.PublicKey, round) {
+		return fmt.Errorf("key %x is not a registered node for round %d",
+			crypto.PubkeyToAddress(key.PublicKey), round)
+	}
+	if s.governance.IsDKGFinal(round) {
+		if err := s.rotationDKGReady(&key.PublicKey, round); err != nil {
+			return err
+		}
+	}
+
+	r := &keyRotation{
+		round:  round,
+		oldID:  coreTypes.NewNodeID(signer.PublicKey()),
+		oldKey: currentKey,
+		signer: signer,
+		key:    key,
+		ready: func() error {
+			return s.rotationDKGReady(&key.PublicKey, round)
+		},
+		retired:   make(chan struct{}),
+		switched:  make(chan struct{}),
+		cancelled: make(chan struct{}),
+	}
+	if err := s.rotator.schedule(r); err != nil {
+		return err
+	}
+	saved := &savedKeyRotation{Round: round, Address: crypto.PubkeyToAddress(key.PublicKey)}
+	if err := saveKeyRotation(s.rotationFile, saved); err != nil {
+		s.rotator.cancel(r)
+		return fmt.Errorf("failed to save key rotation: %v", err)
+	}
+	log.Warn("Scheduled validator key rotation", "round", round,
+		"key", crypto.PubkeyToAddress(key.PublicKey))
+
+	rounds := make(chan RoundEvent, 1)
+	sub := s.roundNotifier.SubscribeRoundEvent(rounds)
+	go func() {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case ev := <-rounds:
+				// The chain entering the round is the latest point to
+				// retire the key, the consensus usually reaches it first.
+				s.rotator.observe(ev.Round)
+			case <-r.retired:
+				s.switchKey(r)
+				return
+			case <-r.cancelled:
+				removeKeyRotation(s.rotationFile)
+				return
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// isRegisteredNode reports whether the public key is registered as a node
+// in the governance state the node set of the round is read from. The state
+// of a round not reached yet is approximated by the current state.
+func (s *Dexon) isRegisteredNode(pubkey *ecdsa.PublicKey, round uint64) bool {
+	var state *vm.GovernanceState
+	if round <= s.governance.Round()+dexCore.ConfigRoundShift {
+		state = s.governance.GetStateForConfigAtRound(round)
+	} else {
+		state = s.governance.GetHeadState()
+	}
+	offset := state.NodesOffsetByNodeKeyAddress(crypto.PubkeyToAddress(*pubkey))
+	if offset.Sign() < 0 {
+		return false
+	}
+	return bytes.Equal(state.Node(offset).PublicKey, crypto.FromECDSAPub(pubkey))
+}
+
+// rotationDKGReady checks the new key of a rotation has no DKG duties at the
+// round it takes over at. The node runs DKG under its current key only, so
+// the new key holds no DKG shares until it runs the DKG of the rounds after
+// the switch: the DKG of the round must be final without the new key in the
+// set.
+func (s *Dexon) rotationDKGReady(pubkey *ecdsa.PublicKey, round uint64) error {
+	if !s.governance.IsDKGFinal(round) {
+		return fmt.Errorf("DKG of round %d not final", round)
+	}
+	set, err := s.governance.NotarySet(round)
+	if err != nil {
+		return err
+	}
+	if _, ok := set[hex.EncodeToString(crypto.FromECDSAPub(pubkey))]; ok {
+		return fmt.Errorf("key %x in the DKG set of round %d without DKG shares",
+			crypto.PubkeyToAddress(*pubkey), round)
+	}
+	return nil
+}
+
+// switchKey replaces the retired validator key by the new key of the
+// rotation, along with every node identity derived from it, restarting the
+// block proposer if it was running.
+func (s *Dexon) switchKey(r *keyRotation) {
+	running := s.bp.IsRunning()
+	s.bp.Stop()
+
+	s.keyMu.Lock()
+	s.config.PrivateKey = r.key
+	s.signer = newFencedSigner(coreEcdsa.NewPrivateKeyFromECDSA(r.key))
+	s.keyMu.Unlock()
+
+	nodeID := coreTypes.NewNodeID(coreEcdsa.NewPublicKeyFromECDSA(&r.key.PublicKey))
+	s.governance.setPrivateKey(r.key)
+	if s.recovery != nil {
+		s.recovery.setKey(&r.key.PublicKey, keyTxSigner(r.key))
+	}
+	if s.network != nil {
+		if s.network.voteHistory != nil {
+			s.network.voteHistory.setNodeID(nodeID)
+		}
+		if s.network.rebroadcaster != nil {
+			s.network.rebroadcaster.setSelf(nodeID)
+		}
+	}
+	if s.protocolManager != nil && s.protocolManager.srvr != nil {
+		if err := s.protocolManager.rekey(r.key); err != nil {
+			log.Error("Failed to switch p2p identity after key rotation", "err", err)
+		}
+	}
+	log.Warn("Switched validator key", "round", r.round,
+		"key", crypto.PubkeyToAddress(r.key.PublicKey))
+	if s.saveNodeKey(r.oldKey, r.key) {
+		removeKeyRotation(s.rotationFile)
+	}
+	close(r.switched)
+
+	if running {
+		if err := s.bp.Start(); err != nil {
+			log.Error("Failed to restart block proposer after key rotation", "err", err)
+		}
+	}
+}
+
+// saveNodeKey replaces the old key by the new key in the node key file of
+// the datadir, so the node restarts with the new key. The old key is kept in
+// a backup file next to it, and the new key is renamed into place, so a crash
+// leaves either key in the node key file. It reports whether the new key is
+// saved, which fails if the node key is not read from the datadir.
+func (s *Dexon) saveNodeKey(oldKey, key *ecdsa.PrivateKey) bool {
+	if s.nodeKeyFile == "" {
+		return false
+	}
+	stored, err := crypto.LoadECDSA(s.nodeKeyFile)
+	if err != nil || !bytes.Equal(crypto.FromECDSA(stored), crypto.FromECDSA(oldKey)) {
+		log.Warn("Node key not loaded from the datadir, configure the new key before restarting",
+			"key", crypto.PubkeyToAddress(key.PublicKey))
+		return false
+	}
+	if err := crypto.SaveECDSA(s.nodeKeyFile+nodeKeyBackupSuffix, oldKey); err != nil {
+		log.Error("Failed to back up node key", "err", err)
+		return false
+	}
+	tmp := s.nodeKeyFile + ".tmp"
+	if err := crypto.SaveECDSA(tmp, key); err != nil {
+		log.Error("Failed to save node key", "err", err)
+		os.Remove(tmp)
+		return false
+	}
+	if err := os.Rename(tmp, s.nodeKeyFile); err != nil {
+		log.Error("Failed to save node key", "err", err)
+		os.Remove(tmp)
+		return false
+	}
+	return true
+}
+
+// resumeKeyRotation resumes the key rotation saved before a restart. The
+// node restarting with the old key switches right away if the consensus
+// already passed the rotation round, the old key is retired then. The
+// rotation is scheduled again otherwise.
+//
+// The new key is unlocked from the keystore with the passwords of
+// Config.KeyRotationPasswords. If it can't be unlocked, the rotation is kept
+// for the node to be restarted with the new key, and the old key is retired
+// if the rotation round was already reached.
+func (s *Dexon) resumeKeyRotation() {
+	saved, err := loadKeyRotation(s.rotationFile)
+	if err != nil {
+		log.Error("Failed to load key rotation", "err", err)
+		return
+	}
+	if saved == nil {
+		return
+	}
+	currentKey, signer := s.validator()
+	if crypto.PubkeyToAddress(currentKey.PublicKey) == saved.Address {
+		// Restarted with the new key, nothing left to do.
+		removeKeyRotation(s.rotationFile)
+		return
+	}
+	key, err := s.unlockRotationKey(saved.Address)
+	if err != nil {
+		log.Error("Failed to unlock the key of the key rotation, restart with the new key",
+			"round", saved.Round, "key", saved.Address, "err", err)
+		if saved.Round <= s.consensusRound() {
+			signer.retire()
+			log.Warn("Retired validator key before restart", "rotation", saved.Round)
+		}
+		return
+	}
+	if saved.Round > s.consensusRound() {
+		if err := s.ScheduleKeyRotation(key, saved.Round); err != nil {
+			log.Error("Failed to resume key rotation", "round", saved.Round, "err", err)
+		}
+		return
+	}
+	r := &keyRotation{
+		round:     saved.Round,
+		oldID:     coreTypes.NewNodeID(signer.PublicKey()),
+		oldKey:    currentKey,
+		signer:    signer,
+		key:       key,
+		retired:   make(chan struct{}),
+		switched:  make(chan struct{}),
+		cancelled: make(chan struct{}),
+	}
+	if err := s.rotator.schedule(r); err != nil {
+		log.Error("Failed to resume key rotation", "round", saved.Round, "err", err)
+		return
+	}
+	signer.retire()
+	close(r.retired)
+	log.Warn("Retired validator key before restart", "rotation", saved.Round)
+	s.switchKey(r)
+}
+
+// unlockRotationKey decrypts the key of the keystore account with the first
+// of the configured passwords unlocking it.
+func (s *Dexon) unlockRotationKey(address common.Address) (*ecdsa.PrivateKey, error) {
+	if len(s.config.KeyRotationPasswords) == 0 {
+		return nil, errors.New("no password")
+	}
+	var err error
+	for _, passphrase := range s.config.KeyRotationPasswords {
+		var key *ecdsa.PrivateKey
+		if key, err = loadAccountKey(s.accountManager, address, passphrase); err == nil {
+			return key, nil
+		}
+	}
+	return nil, err
+}
+
+// savedKeyRotation is a scheduled key rotation saved in the datadir. The new
+// key is referred to by its keystore account, it is never saved decrypted.
+type savedKeyRotation struct {
+	Round   uint64         `json:"round"`
+	Address common.Address `json:"address"`
+}
+
+// saveKeyRotation writes the rotation to the file, nothing is written for
+// an ephemeral node.
+func saveKeyRotation(path string, r *savedKeyRotation) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// loadKeyRotation reads the rotation from the file, nil if none is saved.
+func loadKeyRotation(path string) (*savedKeyRotation, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var r savedKeyRotation
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// removeKeyRotation deletes the saved rotation.
+func removeKeyRotation(path string) {
+	if path == "" {
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Error("Failed to remove key rotation", "err", err)
+	}
+}
+
+// loadAccountKey decrypts the key of the account from the keystore.
+func loadAccountKey(am *accounts.Manager, address common.Address,
+	passphrase string) (*ecdsa.PrivateKey, error) {
+	if am == nil {
+		return nil, errors.New("no account manager")
+	}
+	backends := am.Backends(keystore.KeyStoreType)
+	if len(backends) == 0 {
+		return nil, errors.New("no keystore")
+	}
+	ks := backends[0].(*keystore.KeyStore)
+	account, err := ks.Find(accounts.Account{Address: address})
+	if err != nil {
+		return nil, err
+	}
+	keyJSON, err := ks.Export(account, passphrase, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	key, err := keystore.DecryptKey(keyJSON, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return key.PrivateKey, nil
+}
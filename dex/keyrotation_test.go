@@ -0,0 +1,373 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	coreCrypto "github.com/dexon-foundation/dexon-consensus/core/crypto"
+	coreEcdsa "github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+
+	"github.com/dexon-foundation/dexon/accounts"
+	"github.com/dexon-foundation/dexon/accounts/keystore"
+	"github.com/dexon-foundation/dexon/crypto"
+)
+
+func TestFencedSigner(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer := newFencedSigner(coreEcdsa.NewPrivateKeyFromECDSA(key))
+
+	// The fenced signer is handed to the consensus core as its private key.
+	var _ coreCrypto.PrivateKey = signer
+
+	hash := coreCommon.NewRandomHash()
+	sig, err := signer.Sign(hash)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if !coreEcdsa.NewPublicKeyFromECDSA(&key.PublicKey).VerifySignature(hash, sig) {
+		t.Error("signature not verified by the node key")
+	}
+
+	signer.retire()
+	if _, err := signer.Sign(hash); err != errKeyRetired {
+		t.Errorf("error mismatch: have %v, want %v", err, errKeyRetired)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	newKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	unknownKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(oldKey, newKey)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	dex.config.BlockProposerEnabled = true
+	dex.bp = NewBlockProposer(dex, nil, time.Time{})
+
+	if err := dex.ScheduleKeyRotation(newKey, 0); err == nil {
+		t.Error("rotation at a started round accepted")
+	}
+	if err := dex.ScheduleKeyRotation(unknownKey, 1); err == nil {
+		t.Error("rotation to an unregistered key accepted")
+	}
+	if err := dex.ScheduleKeyRotation(oldKey, 1); err == nil {
+		t.Error("rotation to the current key accepted")
+	}
+
+	dex.recovery = NewRecovery(dex.chainConfig.Recovery, "", dex.governance,
+		&oldKey.PublicKey, keyTxSigner(oldKey))
+
+	dir, err := ioutil.TempDir("", "dex-key-rotation")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	dex.rotationFile = filepath.Join(dir, datadirKeyRotation)
+	dex.nodeKeyFile = filepath.Join(dir, datadirNodeKey)
+	if err := crypto.SaveECDSA(dex.nodeKeyFile, oldKey); err != nil {
+		t.Fatalf("failed to save node key: %v", err)
+	}
+
+	oldSigner := dex.signer
+	oldID := coreTypes.NewNodeID(oldSigner.PublicKey())
+	if err := dex.ScheduleKeyRotation(newKey, 1); err != nil {
+		t.Fatalf("failed to schedule key rotation: %v", err)
+	}
+	if err := dex.ScheduleKeyRotation(newKey, 1); err == nil {
+		t.Error("second pending rotation accepted")
+	}
+	saved, err := loadKeyRotation(dex.rotationFile)
+	if err != nil {
+		t.Fatalf("failed to load key rotation: %v", err)
+	}
+	if saved == nil || saved.Round != 1 || saved.Address != crypto.PubkeyToAddress(newKey.PublicKey) {
+		t.Errorf("saved rotation mismatch: %+v", saved)
+	}
+	rotation := dex.rotator.pending()
+	// No DKG runs on the test chain, take the new key as ready.
+	if err := rotation.ready(); err == nil {
+		t.Error("new key ready without DKG")
+	}
+	rotation.ready = func() error { return nil }
+
+	// The old key keeps signing until the boundary.
+	for i := 0; i < 3; i++ {
+		if _, err := deliverTestBlock(dex, oldKey, 0, nil); err != nil {
+			t.Fatalf("failed to deliver block: %v", err)
+		}
+		if _, err := oldSigner.Sign(coreCommon.NewRandomHash()); err != nil {
+			t.Fatalf("old key refused to sign in round 0: %v", err)
+		}
+	}
+	if dex.rotator.fenced(oldID, 0) {
+		t.Error("old key fenced before the rotation round")
+	}
+	if !dex.rotator.fenced(oldID, 1) {
+		t.Error("old key not fenced at the rotation round")
+	}
+
+	// Keep signing with the old key while the consensus crosses the
+	// boundary, no signature may start once the round is reached.
+	var (
+		signed, overlap uint64
+		crossed         int32
+		stop            = make(chan struct{})
+		wg              sync.WaitGroup
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				after := atomic.LoadInt32(&crossed) == 1
+				if _, err := oldSigner.Sign(coreCommon.NewRandomHash()); err == nil {
+					if after {
+						atomic.AddUint64(&overlap, 1)
+					} else {
+						atomic.AddUint64(&signed, 1)
+					}
+				}
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	dex.app.BlockConfirmed(coreTypes.Block{
+		Position:  coreTypes.Position{Round: 1, Height: dex.blockchain.CurrentBlock().NumberU64() + 1},
+		Timestamp: time.Now(),
+	})
+	atomic.StoreInt32(&crossed, 1)
+	time.Sleep(10 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+	if signed == 0 {
+		t.Error("old key never signed before the boundary")
+	}
+	if overlap != 0 {
+		t.Errorf("old key signed %d times in the rotation round", overlap)
+	}
+
+	select {
+	case <-rotation.switched:
+	case <-time.After(5 * time.Second):
+		t.Fatal("key not switched")
+	}
+	if dex.signer == oldSigner {
+		t.Fatal("signer not replaced")
+	}
+	if got, want := dex.signer.PublicKey().Bytes(), crypto.FromECDSAPub(&newKey.PublicKey); string(got) != string(want) {
+		t.Errorf("signer key mismatch: have %x, want %x", got, want)
+	}
+	if _, err := dex.signer.Sign(coreCommon.NewRandomHash()); err != nil {
+		t.Errorf("new key failed to sign: %v", err)
+	}
+	if dex.config.PrivateKey != newKey {
+		t.Error("node key not replaced")
+	}
+	if dex.governance.address != crypto.PubkeyToAddress(newKey.PublicKey) {
+		t.Error("governance key not replaced")
+	}
+	if _, address, _ := dex.recovery.account(); address != crypto.PubkeyToAddress(newKey.PublicKey) {
+		t.Error("recovery key not replaced")
+	}
+	if dex.rotator.fenced(coreTypes.NewNodeID(dex.signer.PublicKey()), 1) {
+		t.Error("new key fenced")
+	}
+	if dex.rotator.pending() != nil {
+		t.Error("rotation still pending")
+	}
+
+	// The node restarts with the new key.
+	if key, err := crypto.LoadECDSA(dex.nodeKeyFile); err != nil || !bytes.Equal(crypto.FromECDSA(key), crypto.FromECDSA(newKey)) {
+		t.Errorf("node key not saved: %v", err)
+	}
+	if _, err := os.Stat(dex.rotationFile); !os.IsNotExist(err) {
+		t.Errorf("key rotation not removed: %v", err)
+	}
+}
+
+func TestKeyRotationResume(t *testing.T) {
+	oldKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	newKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dir, err := ioutil.TempDir("", "dex-key-rotation")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	ks := keystore.NewKeyStore(filepath.Join(dir, "keystore"), keystore.LightScryptN, keystore.LightScryptP)
+	if _, err := ks.ImportECDSA(newKey, "secret"); err != nil {
+		t.Fatalf("failed to import key: %v", err)
+	}
+	saved := func(round uint64) *savedKeyRotation {
+		return &savedKeyRotation{Round: round, Address: crypto.PubkeyToAddress(newKey.PublicKey)}
+	}
+
+	restart := func() *Dexon {
+		dex, err := newTestDexon(oldKey, newKey)
+		if err != nil {
+			t.Fatalf("failed to create dexon: %v", err)
+		}
+		dex.config.BlockProposerEnabled = true
+		dex.bp = NewBlockProposer(dex, nil, time.Time{})
+		dex.accountManager = accounts.NewManager(ks)
+		dex.config.KeyRotationPasswords = []string{"wrong", "secret"}
+		dex.rotationFile = filepath.Join(dir, datadirKeyRotation)
+		dex.nodeKeyFile = filepath.Join(dir, datadirNodeKey)
+		if err := crypto.SaveECDSA(dex.nodeKeyFile, oldKey); err != nil {
+			t.Fatalf("failed to save node key: %v", err)
+		}
+		return dex
+	}
+
+	// A rotation of a round not reached yet is scheduled again.
+	dex := restart()
+	if err := saveKeyRotation(dex.rotationFile, saved(1)); err != nil {
+		t.Fatalf("failed to save key rotation: %v", err)
+	}
+	dex.resumeKeyRotation()
+	if r := dex.rotator.pending(); r == nil || r.round != 1 || r.key.D.Cmp(newKey.D) != 0 {
+		t.Fatalf("rotation not resumed: %+v", r)
+	}
+	if dex.config.PrivateKey != oldKey {
+		t.Error("key switched before the rotation round")
+	}
+
+	// The old key is retired if the rotation round was reached before the
+	// restart, the node switches right away.
+	dex = restart()
+	if err := saveKeyRotation(dex.rotationFile, saved(0)); err != nil {
+		t.Fatalf("failed to save key rotation: %v", err)
+	}
+	oldSigner := dex.signer
+	dex.resumeKeyRotation()
+	if _, err := oldSigner.Sign(coreCommon.NewRandomHash()); err != errKeyRetired {
+		t.Errorf("error mismatch: have %v, want %v", err, errKeyRetired)
+	}
+	if !bytes.Equal(crypto.FromECDSA(dex.config.PrivateKey), crypto.FromECDSA(newKey)) {
+		t.Error("node key not replaced")
+	}
+	if key, err := crypto.LoadECDSA(dex.nodeKeyFile); err != nil || !bytes.Equal(crypto.FromECDSA(key), crypto.FromECDSA(newKey)) {
+		t.Errorf("node key not saved: %v", err)
+	}
+	if key, err := crypto.LoadECDSA(dex.nodeKeyFile + nodeKeyBackupSuffix); err != nil || !bytes.Equal(crypto.FromECDSA(key), crypto.FromECDSA(oldKey)) {
+		t.Errorf("old node key not backed up: %v", err)
+	}
+	if saved, err := loadKeyRotation(dex.rotationFile); saved != nil || err != nil {
+		t.Errorf("key rotation left: %+v, %v", saved, err)
+	}
+
+	// Without a password unlocking the new key, the old key is retired and
+	// the rotation kept until the node restarts with the new key.
+	dex = restart()
+	dex.config.KeyRotationPasswords = []string{"wrong"}
+	if err := saveKeyRotation(dex.rotationFile, saved(0)); err != nil {
+		t.Fatalf("failed to save key rotation: %v", err)
+	}
+	oldSigner = dex.signer
+	dex.resumeKeyRotation()
+	if _, err := oldSigner.Sign(coreCommon.NewRandomHash()); err != errKeyRetired {
+		t.Errorf("error mismatch: have %v, want %v", err, errKeyRetired)
+	}
+	if dex.config.PrivateKey != oldKey {
+		t.Error("key switched without the new key")
+	}
+	if saved, err := loadKeyRotation(dex.rotationFile); saved == nil || err != nil {
+		t.Errorf("key rotation dropped: %v", err)
+	}
+}
+
+func TestKeyRotationNotReady(t *testing.T) {
+	oldKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	newKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(oldKey, newKey)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	dex.config.BlockProposerEnabled = true
+	dex.bp = NewBlockProposer(dex, nil, time.Time{})
+
+	oldSigner := dex.signer
+	oldID := coreTypes.NewNodeID(oldSigner.PublicKey())
+	if err := dex.ScheduleKeyRotation(newKey, 1); err != nil {
+		t.Fatalf("failed to schedule key rotation: %v", err)
+	}
+	rotation := dex.rotator.pending()
+
+	// Without the DKG of the round done, the rotation is cancelled at the
+	// boundary and the old key keeps signing.
+	dex.app.BlockConfirmed(coreTypes.Block{
+		Position:  coreTypes.Position{Round: 1, Height: dex.blockchain.CurrentBlock().NumberU64() + 1},
+		Timestamp: time.Now(),
+	})
+	select {
+	case <-rotation.cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("rotation not cancelled")
+	}
+	if _, err := oldSigner.Sign(coreCommon.NewRandomHash()); err != nil {
+		t.Errorf("old key refused to sign: %v", err)
+	}
+	if dex.signer != oldSigner || dex.config.PrivateKey != oldKey {
+		t.Error("key switched")
+	}
+	if dex.rotator.fenced(oldID, 1) {
+		t.Error("old key fenced")
+	}
+	if dex.rotator.pending() != nil {
+		t.Error("rotation still pending")
+	}
+}
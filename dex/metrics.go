@@ -39,6 +39,7 @@ var (
 	propCoreBlockInTrafficMeter            = metrics.NewRegisteredMeter("dex/prop/coreblocks/in/traffic", nil)
 	propCoreBlockOutPacketsMeter           = metrics.NewRegisteredMeter("dex/prop/coreblocks/out/packets", nil)
 	propCoreBlockOutTrafficMeter           = metrics.NewRegisteredMeter("dex/prop/coreblocks/out/traffic", nil)
+	propCoreBlockRebroadcastMeter          = metrics.NewRegisteredMeter("dex/prop/coreblocks/rebroadcast", nil)
 	propVoteInPacketsMeter                 = metrics.NewRegisteredMeter("dex/prop/votes/in/packets", nil)
 	propVoteInTrafficMeter                 = metrics.NewRegisteredMeter("dex/prop/votes/in/traffic", nil)
 	propVoteOutPacketsMeter                = metrics.NewRegisteredMeter("dex/prop/votes/out/packets", nil)
@@ -79,6 +80,18 @@ var (
 	miscInTrafficMeter                     = metrics.NewRegisteredMeter("dex/misc/in/traffic", nil)
 	miscOutPacketsMeter                    = metrics.NewRegisteredMeter("dex/misc/out/packets", nil)
 	miscOutTrafficMeter                    = metrics.NewRegisteredMeter("dex/misc/out/traffic", nil)
+	finalizedReorgMeter                    = metrics.NewRegisteredMeter("dex/reorg/finalized", nil)
+	rejectedReorgMeter                     = metrics.NewRegisteredMeter("dex/reorg/rejected", nil)
+	handshakeExtendMeter                   = metrics.NewRegisteredMeter("dex/handshake/extensions", nil)
+	voteSigCacheHitMeter                   = metrics.NewRegisteredMeter("dex/sigcache/votes/hits", nil)
+	voteSigCacheMissMeter                  = metrics.NewRegisteredMeter("dex/sigcache/votes/misses", nil)
+	invalidVoteMeter                       = metrics.NewRegisteredMeter("dex/votes/invalid", nil)
+	throttledConsensusMsgMeter             = metrics.NewRegisteredMeter("dex/ratelimit/consensus/dropped", nil)
+	consensusMsgFloodMeter                 = metrics.NewRegisteredMeter("dex/ratelimit/consensus/disconnects", nil)
+	roundStalledMeter                      = metrics.NewRegisteredMeter("dex/consensus/stalled", nil)
+	peerEvictionMeter                      = metrics.NewRegisteredMeter("dex/peers/evicted", nil)
+	coreBlockPropagationHist               = metrics.NewRegisteredHistogram("dex/propagation/coreblocks", nil, metrics.NewExpDecaySample(1028, 0.015))
+	blockPropagationHist                   = metrics.NewRegisteredHistogram("dex/propagation/blocks", nil, metrics.NewExpDecaySample(1028, 0.015))
 )
 
 // meteredMsgReadWriter is a wrapper around a p2p.MsgReadWriter, capable of
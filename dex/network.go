@@ -18,14 +18,25 @@
 package dex
 
 import (
+	"sync/atomic"
+
 	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
 	"github.com/dexon-foundation/dexon-consensus/core/crypto"
 	"github.com/dexon-foundation/dexon-consensus/core/types"
 	dkgTypes "github.com/dexon-foundation/dexon-consensus/core/types/dkg"
+
+	"github.com/dexon-foundation/dexon/log"
 )
 
 type DexconNetwork struct {
-	pm *ProtocolManager
+	// Round of the latest vote broadcast plus one, zero if none is broadcast.
+	// Accessed atomically and kept first for 64-bit alignment.
+	lastVotedRound uint64
+
+	pm            *ProtocolManager
+	rebroadcaster *proposalRebroadcaster
+	voteHistory   *voteHistory
+	rotator       *keyRotator // Fences the keys retired by key rotations, nil to disable
 }
 
 func NewDexconNetwork(pm *ProtocolManager) *DexconNetwork {
@@ -45,29 +56,76 @@ func (n *DexconNetwork) PullVotes(pos types.Position) {
 	n.pm.BroadcastPullVotes(pos)
 }
 
-// BroadcastVote broadcasts vote to all nodes in DEXON network.
+// BroadcastVote broadcasts vote to all nodes in DEXON network. A vote of the
+// node conflicting with one it cast before, or signed by a key retired for
+// the round by a key rotation, is refused. The consensus core signs votes
+// through the private key, which sees their hash only, so this is where
+// conflicting votes are caught.
 func (n *DexconNetwork) BroadcastVote(vote *types.Vote) {
+	n.rotator.observe(vote.Position.Round)
+	if n.rotator.fenced(vote.ProposerID, vote.Position.Round) {
+		log.Error("Refused to cast vote of retired key", "vote", vote)
+		return
+	}
+	if n.voteHistory != nil && n.voteHistory.isOwn(vote.ProposerID) {
+		if err := n.voteHistory.record(vote); err == errConflictingVote {
+			log.Error("Refused to cast conflicting vote", "vote", vote)
+			return
+		} else if err != nil {
+			log.Warn("Failed to record vote", "vote", vote, "err", err)
+		}
+	}
+	n.markVoted(vote.Position.Round)
+	n.pm.consensusTracer.trace(true, "", vote)
 	n.pm.BroadcastVote(vote)
 }
 
-// BroadcastBlock broadcasts block to all nodes in DEXON network.
+func (n *DexconNetwork) markVoted(round uint64) {
+	atomic.StoreUint64(&n.lastVotedRound, round+1)
+}
+
+// LastVotedRound returns the round of the latest vote broadcast, and whether
+// there is any.
+func (n *DexconNetwork) LastVotedRound() (uint64, bool) {
+	round := atomic.LoadUint64(&n.lastVotedRound)
+	if round == 0 {
+		return 0, false
+	}
+	return round - 1, true
+}
+
+// BroadcastBlock broadcasts block to all nodes in DEXON network. A block
+// proposed with a key retired for the round by a key rotation is refused.
 func (n *DexconNetwork) BroadcastBlock(block *types.Block) {
+	if !block.IsFinalized() {
+		n.rotator.observe(block.Position.Round)
+		if n.rotator.fenced(block.ProposerID, block.Position.Round) {
+			log.Error("Refused to propose block of retired key", "block", block)
+			return
+		}
+	}
+	n.pm.consensusTracer.trace(true, "", block)
 	if block.IsFinalized() {
 		n.pm.BroadcastFinalizedBlock(block)
 	} else {
 		n.pm.BroadcastCoreBlock(block)
+		if n.rebroadcaster != nil {
+			n.rebroadcaster.track(block)
+		}
 	}
 }
 
 // SendDKGPrivateShare sends PrivateShare to a DKG participant.
 func (n *DexconNetwork) SendDKGPrivateShare(
 	pub crypto.PublicKey, prvShare *dkgTypes.PrivateShare) {
+	n.pm.consensusTracer.trace(true, "", prvShare)
 	n.pm.SendDKGPrivateShare(pub, prvShare)
 }
 
 // BroadcastDKGPrivateShare broadcasts PrivateShare to all DKG participants.
 func (n *DexconNetwork) BroadcastDKGPrivateShare(
 	prvShare *dkgTypes.PrivateShare) {
+	n.pm.consensusTracer.trace(true, "", prvShare)
 	n.pm.BroadcastDKGPrivateShare(prvShare)
 }
 
@@ -75,11 +133,28 @@ func (n *DexconNetwork) BroadcastDKGPrivateShare(
 // DKG participants.
 func (n *DexconNetwork) BroadcastDKGPartialSignature(
 	psig *dkgTypes.PartialSignature) {
+	n.pm.consensusTracer.trace(true, "", psig)
 	n.pm.BroadcastDKGPartialSignature(psig)
 }
 
-// BroadcastAgreementResult broadcasts rand request to DKG set.
+// BroadcastAgreementResult broadcasts rand request to DKG set. A result
+// carrying a vote of the node conflicting with one it cast before is refused.
 func (n *DexconNetwork) BroadcastAgreementResult(result *types.AgreementResult) {
+	if n.voteHistory != nil {
+		for i := range result.Votes {
+			vote := &result.Votes[i]
+			if !n.voteHistory.isOwn(vote.ProposerID) {
+				continue
+			}
+			if err := n.voteHistory.record(vote); err == errConflictingVote {
+				log.Error("Refused to cast agreement result with conflicting vote", "result", result)
+				return
+			} else if err != nil {
+				log.Warn("Failed to record vote", "vote", vote, "err", err)
+			}
+		}
+	}
+	n.pm.consensusTracer.trace(true, "", result)
 	n.pm.BroadcastAgreementResult(result)
 }
 
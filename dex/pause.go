@@ -0,0 +1,71 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"sync/atomic"
+
+	"github.com/dexon-foundation/dexon/log"
+)
+
+// StopProposing pauses the consensus of the block proposer until
+// StartProposing is called. Transactions are rejected while paused, as they
+// are not processed by the node.
+func (s *Dexon) StopProposing() error {
+	if !s.config.BlockProposerEnabled {
+		return &ErrNotValidator{}
+	}
+	atomic.StoreInt32(&s.consensusPaused, 1)
+	s.bp.Stop()
+	log.Warn("Consensus paused")
+	return nil
+}
+
+// StartProposing resumes the consensus paused by StopProposing.
+func (s *Dexon) StartProposing() error {
+	if !s.config.BlockProposerEnabled {
+		return &ErrNotValidator{}
+	}
+	if err := s.bp.Start(); err != nil {
+		return err
+	}
+	atomic.StoreInt32(&s.consensusPaused, 0)
+	log.Info("Consensus resumed")
+	return nil
+}
+
+// IsConsensusPaused reports whether consensus is paused by StopProposing.
+func (s *Dexon) IsConsensusPaused() bool {
+	return atomic.LoadInt32(&s.consensusPaused) == 1
+}
+
+// checkTxSubmission returns the reason transactions submitted over RPC are
+// rejected for, nil if they are accepted. They are rejected while consensus
+// is paused, or while the node is more than RejectTxWhenBehind rounds behind
+// the network.
+func (s *Dexon) checkTxSubmission() error {
+	if s.IsConsensusPaused() {
+		return &ErrConsensusPaused{}
+	}
+	if limit := s.config.RejectTxWhenBehind; limit > 0 && s.protocolManager != nil {
+		if behind, _ := s.roundsBehind(); behind > limit {
+			return &ErrNodeBehind{Rounds: behind, Limit: limit}
+		}
+	}
+	return nil
+}
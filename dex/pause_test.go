@@ -0,0 +1,124 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"context"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/dex/downloader"
+	"github.com/dexon-foundation/dexon/params"
+)
+
+func TestRejectTxWhenPaused(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	signer := types.NewEIP155Signer(dex.chainConfig.ChainID)
+	gasPrice := dex.governance.DexconConfiguration(0).MinGasPrice
+	newTx := func(nonce uint64) *types.Transaction {
+		tx, err := types.SignTx(types.NewTransaction(nonce, common.Address{1},
+			big.NewInt(1), params.TxGas, gasPrice, nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		return tx
+	}
+
+	// Observers have no consensus to pause.
+	if err := dex.StopProposing(); err == nil {
+		t.Fatal("observer paused consensus")
+	}
+
+	dex.config.BlockProposerEnabled = true
+	dex.bp = NewBlockProposer(dex, nil, time.Now())
+	if err := dex.StopProposing(); err != nil {
+		t.Fatalf("failed to pause consensus: %v", err)
+	}
+	if err := dex.APIBackend.SendTx(context.Background(), newTx(0)); err == nil {
+		t.Error("transaction accepted while paused")
+	} else if _, ok := err.(*ErrConsensusPaused); !ok {
+		t.Errorf("error mismatch: have %v, want %T", err, &ErrConsensusPaused{})
+	}
+	for i, err := range dex.APIBackend.SendTxs(context.Background(), types.Transactions{newTx(0), newTx(1)}) {
+		if _, ok := err.(*ErrConsensusPaused); !ok {
+			t.Errorf("tx %d: error mismatch: have %v, want %T", i, err, &ErrConsensusPaused{})
+		}
+	}
+	// Reads remain available.
+	if nonce, err := dex.APIBackend.GetPoolNonce(context.Background(), crypto.PubkeyToAddress(key.PublicKey)); err != nil || nonce != 0 {
+		t.Errorf("pool nonce mismatch: have %d (%v), want 0", nonce, err)
+	}
+
+	// Resumed, without running consensus in the test.
+	atomic.StoreInt32(&dex.consensusPaused, 0)
+	if err := dex.APIBackend.SendTx(context.Background(), newTx(0)); err != nil {
+		t.Errorf("transaction rejected after resume: %v", err)
+	}
+}
+
+func TestRejectTxWhenBehind(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+	dex.protocolManager = pm
+
+	tx, err := types.SignTx(types.NewTransaction(0, common.Address{1}, big.NewInt(1), params.TxGas,
+		dex.governance.DexconConfiguration(0).MinGasPrice, nil),
+		types.NewEIP155Signer(dex.chainConfig.ChainID), key)
+	if err != nil {
+		t.Fatalf("failed to sign tx: %v", err)
+	}
+
+	// Simulate a peer five rounds ahead of the local chain.
+	p, _ := newTestPeer("peer", dex64, pm, true)
+	defer p.close()
+	waitForRegister(pm, 1)
+	length := dex.governance.DexconConfiguration(0).RoundLength
+	p.peer.SetHead(common.Hash{}, 5*length+1, 5)
+
+	dex.config.RejectTxWhenBehind = 4
+	if err := dex.APIBackend.SendTx(context.Background(), tx); err == nil {
+		t.Error("transaction accepted while behind")
+	} else if behind, ok := err.(*ErrNodeBehind); !ok || behind.Rounds != 5 || behind.Limit != 4 {
+		t.Errorf("error mismatch: have %v, want %T", err, &ErrNodeBehind{})
+	}
+
+	dex.config.RejectTxWhenBehind = 5
+	if err := dex.APIBackend.SendTx(context.Background(), tx); err != nil {
+		t.Errorf("transaction rejected within the limit: %v", err)
+	}
+}
@@ -38,6 +38,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	mapset "github.com/deckarep/golang-set"
@@ -90,26 +91,46 @@ const (
 	maxQueuedPullVotes            = 128
 	maxQueuedPullRandomness       = 128
 
-	handshakeTimeout = 5 * time.Second
-
 	groupConnNum     = 3
 	groupConnTimeout = 3 * time.Minute
 )
 
+// handshakeTimeout is the time the status of the remote peer is awaited in a
+// handshake attempt.
+var handshakeTimeout = 5 * time.Second
+
 // PeerInfo represents a short summary of the Ethereum sub-protocol metadata known
 // about a connected peer.
 type PeerInfo struct {
-	Version int    `json:"version"` // Ethereum protocol version negotiated
-	Number  uint64 `json:"number"`  // Number the peer's blockchain
-	Head    string `json:"head"`    // SHA3 hash of the peer's best owned block
+	Version   int    `json:"version"`   // Ethereum protocol version negotiated
+	Number    uint64 `json:"number"`    // Number the peer's blockchain
+	HeadRound uint64 `json:"headRound"` // Round of the peer's best owned block
+	Head      string `json:"head"`      // SHA3 hash of the peer's best owned block
+}
+
+// DexPeerInfo extends PeerInfo with the DEXON consensus state of a peer.
+type DexPeerInfo struct {
+	ID   string `json:"id"`   // Unique node identifier
+	Name string `json:"name"` // Name of the node, including client type, version, OS, custom data
+	PeerInfo
+
+	Round            uint64     `json:"round"`            // Latest round known to the peer
+	Notary           bool       `json:"notary"`           // Whether the peer is in the notary set of the current round
+	Consensus        bool       `json:"consensus"`        // Whether consensus messages are being exchanged
+	LastConsensusMsg *time.Time `json:"lastConsensusMsg"` // Time of the latest consensus message received
 }
 
 type setType uint32
 
 const (
 	notaryset = iota
+	validatorset
 )
 
+// validatorLabel labels the validator peers pinned by the operator, kept
+// regardless of the rounds.
+var validatorLabel = peerLabel{set: validatorset}
+
 type peerLabel struct {
 	set   setType
 	round uint64
@@ -120,11 +141,17 @@ func (p peerLabel) String() string {
 	switch p.set {
 	case notaryset:
 		t = fmt.Sprintf("NotarySet round: %d", p.round)
+	case validatorset:
+		t = "ValidatorSet"
 	}
 	return t
 }
 
 type peer struct {
+	// Unix time in nanoseconds of the latest consensus message received,
+	// accessed atomically and kept first for 64-bit alignment.
+	lastConsensusMsg int64
+
 	id string
 
 	*p2p.Peer
@@ -134,6 +161,7 @@ type peer struct {
 
 	head   common.Hash
 	number uint64
+	round  uint64
 	lock   sync.RWMutex
 
 	lastKnownAgreementPositionLock sync.RWMutex
@@ -153,7 +181,9 @@ type peer struct {
 	queuedPullBlocks               chan coreCommon.Hashes
 	queuedPullVotes                chan coreTypes.Position
 	queuedPullRandomness           chan coreCommon.Hashes
-	term                           chan struct{} // Termination channel to stop the broadcaster
+	weights                        BroadcastWeights // Weights of the broadcast loop scheduling
+	consensusLimiter               *msgRateLimiter  // Rate limit of the consensus messages, nil if unlimited
+	term                           chan struct{}    // Termination channel to stop the broadcaster
 }
 
 func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
@@ -177,92 +207,253 @@ func newPeer(version int, p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
 		queuedPullBlocks:           make(chan coreCommon.Hashes, maxQueuedPullBlocks),
 		queuedPullVotes:            make(chan coreTypes.Position, maxQueuedPullVotes),
 		queuedPullRandomness:       make(chan coreCommon.Hashes, maxQueuedPullRandomness),
+		weights:                    DefaultBroadcastWeights,
 		term:                       make(chan struct{}),
 	}
 }
 
+// BroadcastWeights are the number of messages of each class the broadcast
+// loop of a peer sends per scheduling cycle. Consensus messages always go
+// first in a cycle, so a flood of blocks or transactions delays them by at
+// most one cycle, while the weights keep either side from being starved.
+type BroadcastWeights struct {
+	Consensus int // Consensus messages (core blocks, votes, agreements, DKG, pulls)
+	Block     int // Block propagations and announcements
+	Tx        int // Transaction batches
+}
+
+// DefaultBroadcastWeights are the broadcast weights used by default.
+var DefaultBroadcastWeights = BroadcastWeights{
+	Consensus: 8,
+	Block:     2,
+	Tx:        1,
+}
+
+// sanitize returns a copy of the weights where every class is sent at least
+// once per cycle, so that no class is starved.
+func (w BroadcastWeights) sanitize() BroadcastWeights {
+	if w.Consensus < 1 {
+		w.Consensus = 1
+	}
+	if w.Block < 1 {
+		w.Block = 1
+	}
+	if w.Tx < 1 {
+		w.Tx = 1
+	}
+	return w
+}
+
 // broadcast is a write loop that multiplexes block propagations, announcements,
 // transaction broadcasts into the remote peer.
 // The goal is to have an async writer that does not lock up node internals.
+//
+// Queued messages are sent in weighted cycles, see BroadcastWeights. While
+// nothing is queued the loop blocks until any message arrives.
 func (p *peer) broadcast() {
-	queuedVotes := make([]*coreTypes.Vote, 0, maxQueuedVotes)
+	weights := p.weights.sanitize()
 	for {
-	PriorityBroadcastVote:
-		for {
-			select {
-			case votes := <-p.queuedVotes:
-				queuedVotes = append(queuedVotes, votes...)
-			default:
-				break PriorityBroadcastVote
-			}
-		}
-		if len(queuedVotes) != 0 {
-			if err := p.SendVotes(queuedVotes); err != nil {
+		idle := true
+		for i := 0; i < weights.Consensus; i++ {
+			sent, err := p.sendQueuedConsensus()
+			if err != nil {
 				return
 			}
-			p.Log().Trace("Broadcast votes", "count", len(queuedVotes))
-			queuedVotes = queuedVotes[:0]
+			if !sent {
+				break
+			}
+			idle = false
 		}
-		select {
-		case block := <-p.queuedProps:
-			if err := p.SendNewBlock(block); err != nil {
+		for i := 0; i < weights.Block; i++ {
+			sent, err := p.sendQueuedBlock()
+			if err != nil {
 				return
 			}
-			p.Log().Trace("Propagated block", "number", block.Number(), "hash", block.Hash())
-
-		case block := <-p.queuedAnns:
-			if err := p.SendNewBlockHashes([]common.Hash{block.Hash()}, []uint64{block.NumberU64()}); err != nil {
-				return
+			if !sent {
+				break
 			}
-			p.Log().Trace("Announced block", "number", block.Number(), "hash", block.Hash())
-		case blocks := <-p.queuedCoreBlocks:
-			if err := p.SendCoreBlocks(blocks); err != nil {
+			idle = false
+		}
+		for i := 0; i < weights.Tx; i++ {
+			sent, err := p.sendQueuedTxs()
+			if err != nil {
 				return
 			}
-			p.Log().Trace("Broadcast core blocks", "count", len(blocks))
-		case votes := <-p.queuedVotes:
-			if err := p.SendVotes(votes); err != nil {
-				return
+			if !sent {
+				break
 			}
-			p.Log().Trace("Broadcast votes", "count", len(votes))
+			idle = false
+		}
+		if !idle {
+			continue
+		}
+
+		// Nothing queued, wait for the next message of any class.
+		var err error
+		select {
+		case votes := <-p.queuedVotes:
+			err = p.sendVotes(votes)
 		case agreement := <-p.queuedAgreements:
-			if err := p.SendAgreement(agreement); err != nil {
-				return
-			}
-			p.Log().Trace("Broadcast agreement")
+			err = p.sendAgreement(agreement)
+		case blocks := <-p.queuedCoreBlocks:
+			err = p.sendCoreBlocks(blocks)
 		case privateShare := <-p.queuedDKGPrivateShares:
-			if err := p.SendDKGPrivateShare(privateShare); err != nil {
-				return
-			}
-			p.Log().Trace("Broadcast DKG private share")
+			err = p.sendDKGPrivateShare(privateShare)
 		case psig := <-p.queuedDKGPartialSignatures:
-			if err := p.SendDKGPartialSignature(psig); err != nil {
-				return
-			}
-			p.Log().Trace("Broadcast DKG partial signature")
+			err = p.sendDKGPartialSignature(psig)
 		case hashes := <-p.queuedPullBlocks:
-			if err := p.SendPullBlocks(hashes); err != nil {
-				return
-			}
-			p.Log().Trace("Pulling Blocks", "hashes", hashes)
+			err = p.sendPullBlocks(hashes)
 		case pos := <-p.queuedPullVotes:
-			if err := p.SendPullVotes(pos); err != nil {
-				return
-			}
-			p.Log().Trace("Pulling Votes", "position", pos)
+			err = p.sendPullVotes(pos)
+		case block := <-p.queuedProps:
+			err = p.propagateBlock(block)
+		case block := <-p.queuedAnns:
+			err = p.announceBlock(block)
+		case txs := <-p.queuedTxs:
+			err = p.sendTxs(txs)
 		case <-p.term:
 			return
-		case <-time.After(100 * time.Millisecond):
 		}
-		select {
-		case txs := <-p.queuedTxs:
-			if err := p.SendTransactions(txs); err != nil {
-				return
+		if err != nil {
+			return
+		}
+	}
+}
+
+// sendQueuedConsensus sends a queued consensus message, all queued votes are
+// batched into a single message. It reports false if nothing is queued.
+func (p *peer) sendQueuedConsensus() (bool, error) {
+	select {
+	case votes := <-p.queuedVotes:
+	Batch:
+		for {
+			select {
+			case more := <-p.queuedVotes:
+				votes = append(votes, more...)
+			default:
+				break Batch
 			}
-			p.Log().Trace("Broadcast transactions", "count", len(txs))
-		default:
 		}
+		return true, p.sendVotes(votes)
+	case agreement := <-p.queuedAgreements:
+		return true, p.sendAgreement(agreement)
+	case blocks := <-p.queuedCoreBlocks:
+		return true, p.sendCoreBlocks(blocks)
+	case privateShare := <-p.queuedDKGPrivateShares:
+		return true, p.sendDKGPrivateShare(privateShare)
+	case psig := <-p.queuedDKGPartialSignatures:
+		return true, p.sendDKGPartialSignature(psig)
+	case hashes := <-p.queuedPullBlocks:
+		return true, p.sendPullBlocks(hashes)
+	case pos := <-p.queuedPullVotes:
+		return true, p.sendPullVotes(pos)
+	default:
+		return false, nil
+	}
+}
+
+// sendQueuedBlock sends a queued block propagation or announcement. It
+// reports false if nothing is queued.
+func (p *peer) sendQueuedBlock() (bool, error) {
+	select {
+	case block := <-p.queuedProps:
+		return true, p.propagateBlock(block)
+	case block := <-p.queuedAnns:
+		return true, p.announceBlock(block)
+	default:
+		return false, nil
+	}
+}
+
+// sendQueuedTxs sends a queued transaction batch. It reports false if nothing
+// is queued.
+func (p *peer) sendQueuedTxs() (bool, error) {
+	select {
+	case txs := <-p.queuedTxs:
+		return true, p.sendTxs(txs)
+	default:
+		return false, nil
+	}
+}
+
+func (p *peer) sendVotes(votes []*coreTypes.Vote) error {
+	if err := p.SendVotes(votes); err != nil {
+		return err
+	}
+	p.Log().Trace("Broadcast votes", "count", len(votes))
+	return nil
+}
+
+func (p *peer) sendAgreement(agreement *coreTypes.AgreementResult) error {
+	if err := p.SendAgreement(agreement); err != nil {
+		return err
+	}
+	p.Log().Trace("Broadcast agreement")
+	return nil
+}
+
+func (p *peer) sendCoreBlocks(blocks []*coreTypes.Block) error {
+	if err := p.SendCoreBlocks(blocks); err != nil {
+		return err
+	}
+	p.Log().Trace("Broadcast core blocks", "count", len(blocks))
+	return nil
+}
+
+func (p *peer) sendDKGPrivateShare(privateShare *dkgTypes.PrivateShare) error {
+	if err := p.SendDKGPrivateShare(privateShare); err != nil {
+		return err
+	}
+	p.Log().Trace("Broadcast DKG private share")
+	return nil
+}
+
+func (p *peer) sendDKGPartialSignature(psig *dkgTypes.PartialSignature) error {
+	if err := p.SendDKGPartialSignature(psig); err != nil {
+		return err
+	}
+	p.Log().Trace("Broadcast DKG partial signature")
+	return nil
+}
+
+func (p *peer) sendPullBlocks(hashes coreCommon.Hashes) error {
+	if err := p.SendPullBlocks(hashes); err != nil {
+		return err
+	}
+	p.Log().Trace("Pulling Blocks", "hashes", hashes)
+	return nil
+}
+
+func (p *peer) sendPullVotes(pos coreTypes.Position) error {
+	if err := p.SendPullVotes(pos); err != nil {
+		return err
+	}
+	p.Log().Trace("Pulling Votes", "position", pos)
+	return nil
+}
+
+func (p *peer) propagateBlock(block *types.Block) error {
+	if err := p.SendNewBlock(block); err != nil {
+		return err
+	}
+	p.Log().Trace("Propagated block", "number", block.Number(), "hash", block.Hash())
+	return nil
+}
+
+func (p *peer) announceBlock(block *types.Block) error {
+	if err := p.SendNewBlockHashes([]common.Hash{block.Hash()}, []uint64{block.NumberU64()}); err != nil {
+		return err
+	}
+	p.Log().Trace("Announced block", "number", block.Number(), "hash", block.Hash())
+	return nil
+}
+
+func (p *peer) sendTxs(txs types.Transactions) error {
+	if err := p.SendTransactions(txs); err != nil {
+		return err
 	}
+	p.Log().Trace("Broadcast transactions", "count", len(txs))
+	return nil
 }
 
 // close signals the broadcast goroutine to terminate.
@@ -275,9 +466,10 @@ func (p *peer) Info() *PeerInfo {
 	hash, number := p.Head()
 
 	return &PeerInfo{
-		Version: p.version,
-		Number:  number,
-		Head:    hash.Hex(),
+		Version:   p.version,
+		Number:    number,
+		HeadRound: p.HeadRound(),
+		Head:      hash.Hex(),
 	}
 }
 
@@ -291,13 +483,23 @@ func (p *peer) Head() (hash common.Hash, number uint64) {
 	return hash, p.number
 }
 
-// SetHead updates the head hash and number of the peer.
-func (p *peer) SetHead(hash common.Hash, number uint64) {
+// HeadRound retrieves the round of the head block of the peer, the latest
+// round it has finalized blocks of.
+func (p *peer) HeadRound() uint64 {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.round
+}
+
+// SetHead updates the head hash, number and round of the peer.
+func (p *peer) SetHead(hash common.Hash, number, round uint64) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
 	copy(p.head[:], hash[:])
 	p.number = number
+	p.round = round
 }
 
 // MarkBlock marks a block as known for the peer, ensuring that the block will
@@ -330,6 +532,29 @@ func (p *peer) MarkAgreement(position coreTypes.Position) bool {
 	return false
 }
 
+// LastKnownAgreementPosition returns the position of the latest agreement
+// known to the peer.
+func (p *peer) LastKnownAgreementPosition() coreTypes.Position {
+	p.lastKnownAgreementPositionLock.RLock()
+	defer p.lastKnownAgreementPositionLock.RUnlock()
+	return p.lastKnownAgreementPosition
+}
+
+// MarkConsensusMsg records that a consensus message is received from the peer.
+func (p *peer) MarkConsensusMsg() {
+	atomic.StoreInt64(&p.lastConsensusMsg, time.Now().UnixNano())
+}
+
+// LastConsensusMsg returns the time of the latest consensus message received
+// from the peer, or the zero time if there is none.
+func (p *peer) LastConsensusMsg() time.Time {
+	last := atomic.LoadInt64(&p.lastConsensusMsg)
+	if last == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, last)
+}
+
 func (p *peer) MarkDKGPrivateShares(hash common.Hash) {
 	for p.knownDKGPrivateShares.Cardinality() >= maxKnownDKGPrivateShares {
 		p.knownDKGPrivateShares.Pop()
@@ -591,40 +816,55 @@ func (p *peer) RequestReceipts(hashes []common.Hash) error {
 
 // Handshake executes the eth protocol handshake, negotiating version number,
 // network IDs, difficulties, head and genesis blocks.
-func (p *peer) Handshake(network uint64, number uint64, head common.Hash, genesis common.Hash) error {
+//
+// A handshake timing out has its timeout extended up to extensions times,
+// awaiting the status of the remote peer extension longer, doubled on each
+// further extension. The own status is sent once, as the remote would take a
+// second one for a protocol violation. Other failures are final: a mismatching
+// status cannot be fixed by waiting and a broken connection is redialed by
+// the p2p server.
+func (p *peer) Handshake(network uint64, number uint64, round uint64, head common.Hash, genesis common.Hash,
+	dMoment uint64, extensions int, extension time.Duration) error {
 	// Send out own handshake in a new thread
 	errc := make(chan error, 2)
 	var status statusData // safe to read after two values have been received from errc
 
 	go func() {
-		errc <- p2p.Send(p.rw, StatusMsg, &statusData{
-			ProtocolVersion: uint32(p.version),
-			NetworkId:       network,
-			Number:          number,
-			CurrentBlock:    head,
-			GenesisBlock:    genesis,
-		})
+		errc <- p2p.Send(p.rw, StatusMsg, newStatusData(p.version,
+			network, number, head, genesis, round, dMoment))
 	}()
 	go func() {
-		errc <- p.readStatus(network, &status, genesis)
+		errc <- p.readStatus(network, &status, genesis, dMoment)
 	}()
-	timeout := time.NewTimer(handshakeTimeout)
+	wait := handshakeTimeout
+	timeout := time.NewTimer(wait)
 	defer timeout.Stop()
-	for i := 0; i < 2; i++ {
+	for i := 0; i < 2; {
 		select {
 		case err := <-errc:
 			if err != nil {
 				return err
 			}
+			i++
 		case <-timeout.C:
-			return p2p.DiscReadTimeout
+			if extensions <= 0 {
+				return p2p.DiscReadTimeout
+			}
+			extensions--
+			wait += extension
+			extension *= 2
+			handshakeExtendMeter.Mark(1)
+			p.Log().Debug("Extending timed out handshake", "wait", wait, "left", extensions)
+			timeout.Reset(wait)
 		}
 	}
-	p.number, p.head = status.Number, status.CurrentBlock
+	// dex64 peers don't send their head round, they are assumed at the
+	// genesis round until they announce a block.
+	p.round, p.number, p.head = status.Round, status.Number, status.CurrentBlock
 	return nil
 }
 
-func (p *peer) readStatus(network uint64, status *statusData, genesis common.Hash) (err error) {
+func (p *peer) readStatus(network uint64, status *statusData, genesis common.Hash, dMoment uint64) (err error) {
 	msg, err := p.rw.ReadMsg()
 	if err != nil {
 		return err
@@ -636,7 +876,19 @@ func (p *peer) readStatus(network uint64, status *statusData, genesis common.Has
 		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
 	}
 	// Decode the handshake and make sure everything matches
-	if err := msg.Decode(&status); err != nil {
+	if p.version < dex65 {
+		var legacy statusData64
+		if err := msg.Decode(&legacy); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		*status = statusData{
+			ProtocolVersion: legacy.ProtocolVersion,
+			NetworkId:       legacy.NetworkId,
+			Number:          legacy.Number,
+			CurrentBlock:    legacy.CurrentBlock,
+			GenesisBlock:    legacy.GenesisBlock,
+		}
+	} else if err := msg.Decode(&status); err != nil {
 		return errResp(ErrDecode, "msg %v: %v", msg, err)
 	}
 	if status.GenesisBlock != genesis {
@@ -648,6 +900,13 @@ func (p *peer) readStatus(network uint64, status *statusData, genesis common.Has
 	if int(status.ProtocolVersion) != p.version {
 		return errResp(ErrProtocolVersionMismatch, "%d (!= %d)", status.ProtocolVersion, p.version)
 	}
+	// Nodes starting consensus at different times never agree on a block,
+	// refuse them right away. dex64 peers don't send it and can't be checked.
+	if p.version >= dex65 && status.DMoment != dMoment {
+		p.Log().Warn("Peer configured with a different DMoment",
+			"local", time.Unix(int64(dMoment), 0), "remote", time.Unix(int64(status.DMoment), 0))
+		return errResp(ErrDMomentMismatch, "%d (!= %d)", status.DMoment, dMoment)
+	}
 	return nil
 }
 
@@ -669,15 +928,22 @@ type peerSet struct {
 	srvr p2pServer
 	gov  governance
 
+	// Rank peers of the same head number by the round of their head.
+	preferFinalized bool
+
 	label2Nodes    map[peerLabel]map[string]*enode.Node
 	directConn     map[peerLabel]struct{}
 	groupConnPeers map[peerLabel]map[string]time.Time
 	allDirectPeers map[string]map[peerLabel]struct{}
+
+	// IDs of the notary set nodes, read without the lock by the p2p server
+	// which may be blocked on adding a direct peer while the lock is held.
+	notaryIDs atomic.Value // map[string]struct{}
 }
 
 // newPeerSet creates a new peer set to track the active participants.
 func newPeerSet(gov governance, srvr p2pServer) *peerSet {
-	return &peerSet{
+	ps := &peerSet{
 		peers:          make(map[string]*peer),
 		gov:            gov,
 		srvr:           srvr,
@@ -687,6 +953,8 @@ func newPeerSet(gov governance, srvr p2pServer) *peerSet {
 		groupConnPeers: make(map[peerLabel]map[string]time.Time),
 		allDirectPeers: make(map[string]map[peerLabel]struct{}),
 	}
+	ps.notaryIDs.Store(map[string]struct{}{})
+	return ps
 }
 
 // Register injects a new peer into the working set, or returns an error if the
@@ -820,7 +1088,11 @@ func (ps *peerSet) PeersWithoutDKGPrivateShares(hash common.Hash) []*peer {
 	return list
 }
 
-// BestPeer retrieves the known peer with the currently highest total difficulty.
+// BestPeer retrieves the known peer with the highest head. Peers of the same
+// head number are ranked by the round of their head if finalized rounds are
+// preferred. The round is reported by the peer and not verified, so it never
+// outranks the number: a higher round of a single chain is at a higher number
+// anyway.
 func (ps *peerSet) BestPeer() *peer {
 	ps.lock.RLock()
 	defer ps.lock.RUnlock()
@@ -828,10 +1100,17 @@ func (ps *peerSet) BestPeer() *peer {
 	var (
 		bestPeer   *peer
 		bestNumber uint64
+		bestRound  uint64
 	)
 	for _, p := range ps.peers {
-		if _, number := p.Head(); bestPeer == nil || number > bestNumber {
-			bestPeer, bestNumber = p, number
+		_, number := p.Head()
+		round := p.HeadRound()
+		better := number > bestNumber
+		if ps.preferFinalized && number == bestNumber {
+			better = round > bestRound
+		}
+		if bestPeer == nil || better {
+			bestPeer, bestNumber, bestRound = p, number, round
 		}
 	}
 	return bestPeer
@@ -849,10 +1128,123 @@ func (ps *peerSet) Close() {
 	ps.closed = true
 }
 
+// IsNotaryPeer reports whether the peer of the given id belongs to the
+// notary set of any round connections are built for.
+// It does not take the lock, so it is safe to call from the p2p server.
+func (ps *peerSet) IsNotaryPeer(id string) bool {
+	_, ok := ps.notaryIDs.Load().(map[string]struct{})[id]
+	return ok
+}
+
+// updateNotaryIDs refreshes the notary node IDs read by IsNotaryPeer after the
+// notary sets changed. It must be called with the lock held.
+func (ps *peerSet) updateNotaryIDs() {
+	ids := make(map[string]struct{})
+	for label, nodes := range ps.label2Nodes {
+		if label.set != notaryset {
+			continue
+		}
+		for id := range nodes {
+			ids[id] = struct{}{}
+		}
+	}
+	ps.notaryIDs.Store(ids)
+}
+
+// AddValidatorPeer pins the node as a validator peer, kept as a direct peer
+// regardless of the notary sets and exempt from the peer limit.
+func (ps *peerSet) AddValidatorPeer(node *enode.Node) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	id := node.ID().String()
+	if ps.label2Nodes[validatorLabel] == nil {
+		ps.label2Nodes[validatorLabel] = make(map[string]*enode.Node)
+	}
+	if _, ok := ps.label2Nodes[validatorLabel][id]; ok {
+		return
+	}
+	ps.label2Nodes[validatorLabel][id] = node
+	ps.addDirectPeer(id, validatorLabel)
+}
+
+// RemoveValidatorPeer unpins the validator peer of the given id. It reports
+// whether the peer was pinned.
+func (ps *peerSet) RemoveValidatorPeer(id string) bool {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	if _, ok := ps.label2Nodes[validatorLabel][id]; !ok {
+		return false
+	}
+	ps.removeDirectPeer(id, validatorLabel)
+	delete(ps.label2Nodes[validatorLabel], id)
+	return true
+}
+
+// IsValidatorPeer reports whether the peer of the given id is a pinned
+// validator peer.
+func (ps *peerSet) IsValidatorPeer(id string) bool {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	_, ok := ps.label2Nodes[validatorLabel][id]
+	return ok
+}
+
+// ValidatorPeers returns the pinned validator peers.
+func (ps *peerSet) ValidatorPeers() []*enode.Node {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	nodes := make([]*enode.Node, 0, len(ps.label2Nodes[validatorLabel]))
+	for _, node := range ps.label2Nodes[validatorLabel] {
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// DemoteRemovedNodes drops the nodes not in the given set of qualified node
+// public keys from the notary sets connections are built for, so they stop
+// receiving consensus messages and are no longer kept as direct peers. It
+// returns the IDs of the demoted nodes.
+func (ps *peerSet) DemoteRemovedNodes(qualified map[string]struct{}) []string {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	active := ps.pksToNodes(qualified)
+	self := ps.srvr.Self().ID().String()
+	demoted := make(map[string]struct{})
+	for label, nodes := range ps.label2Nodes {
+		if label.set != notaryset {
+			continue
+		}
+		for id := range nodes {
+			if _, ok := active[id]; ok || id == self {
+				continue
+			}
+			ps.removeDirectPeer(id, label)
+			delete(ps.groupConnPeers[label], id)
+			delete(nodes, id)
+			demoted[id] = struct{}{}
+		}
+	}
+	ps.updateNotaryIDs()
+	ids := make([]string, 0, len(demoted))
+	for id := range demoted {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
 func (ps *peerSet) BuildConnection(round uint64) {
 	ps.lock.Lock()
 	defer ps.lock.Unlock()
 
+	ps.buildConnection(round)
+}
+
+func (ps *peerSet) buildConnection(round uint64) {
 	log.Info("Build connection", "round", round)
 
 	notaryLabel := peerLabel{set: notaryset, round: round}
@@ -865,6 +1257,7 @@ func (ps *peerSet) BuildConnection(round uint64) {
 
 		nodes := ps.pksToNodes(notaryPKs)
 		ps.label2Nodes[notaryLabel] = nodes
+		ps.updateNotaryIDs()
 
 		if _, exists := nodes[ps.srvr.Self().ID().String()]; exists {
 			ps.buildDirectConn(notaryLabel)
@@ -874,6 +1267,32 @@ func (ps *peerSet) BuildConnection(round uint64) {
 	}
 }
 
+// RebuildConnections builds the connections to the notary sets again after
+// the identity of the node changed, as whether the node is in a set decides
+// how it is connected to the set. The direct peers of the sets are dropped
+// and dialed again, so they know the node under its new identity.
+func (ps *peerSet) RebuildConnections() {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+
+	ps.selfPK = hex.EncodeToString(crypto.FromECDSAPub(&ps.srvr.GetPrivateKey().PublicKey))
+	var rounds []uint64
+	for label := range ps.label2Nodes {
+		if label.set != notaryset {
+			continue
+		}
+		ps.forgetDirectConn(label)
+		ps.forgetGroupConn(label)
+		delete(ps.groupConnPeers, label)
+		delete(ps.label2Nodes, label)
+		rounds = append(rounds, label.round)
+	}
+	ps.updateNotaryIDs()
+	for _, round := range rounds {
+		ps.buildConnection(round)
+	}
+}
+
 func (ps *peerSet) ForgetLabelConnection(label peerLabel) {
 	ps.lock.Lock()
 	defer ps.lock.Unlock()
@@ -884,6 +1303,7 @@ func (ps *peerSet) ForgetLabelConnection(label peerLabel) {
 	delete(ps.directConn, label)
 	delete(ps.groupConnPeers, label)
 	delete(ps.label2Nodes, label)
+	ps.updateNotaryIDs()
 }
 
 func (ps *peerSet) ForgetConnection(round uint64) {
@@ -905,10 +1325,11 @@ func (ps *peerSet) ForgetConnection(round uint64) {
 	}
 
 	for label := range ps.label2Nodes {
-		if label.round <= round {
+		if label.set == notaryset && label.round <= round {
 			delete(ps.label2Nodes, label)
 		}
 	}
+	ps.updateNotaryIDs()
 }
 
 func (ps *peerSet) EnsureGroupConn() {
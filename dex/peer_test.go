@@ -3,10 +3,18 @@ package dex
 import (
 	"crypto/ecdsa"
 	"encoding/hex"
+	"math/big"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/types"
 	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/p2p"
 	"github.com/dexon-foundation/dexon/p2p/enode"
 )
 
@@ -221,6 +229,63 @@ func TestPeerSetBuildAndForgetConn(t *testing.T) {
 	}
 }
 
+func TestPeerSetRebuildConnections(t *testing.T) {
+	oldKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := newTestP2PServer(oldKey)
+	oldSelf := server.Self()
+	newSelf := enode.NewV4(&newKey.PublicKey, nil, 0, 0)
+	other := randomV4CompactNode()
+
+	gov := &testGovernance{}
+	gov.notarySetFunc = func(round uint64) (map[string]struct{}, error) {
+		m := map[uint64][]*enode.Node{
+			10: {oldSelf, other},
+			11: {newSelf, other},
+		}
+		return newTestNodeSet(m[round]), nil
+	}
+	pinned := randomV4CompactNode()
+	ps := newPeerSet(gov, server)
+	ps.AddValidatorPeer(pinned)
+	ps.BuildConnection(10)
+	ps.BuildConnection(11)
+
+	inSet := func(round uint64) bool {
+		_, ok := ps.directConn[peerLabel{set: notaryset, round: round}]
+		return ok
+	}
+	if !inSet(10) || inSet(11) {
+		t.Fatalf("connections mismatch before rekey: %v", ps.directConn)
+	}
+
+	// The node joins the notary sets of the new key once rekeyed.
+	if err := server.Rekey(newKey); err != nil {
+		t.Fatal(err)
+	}
+	ps.RebuildConnections()
+	if inSet(10) || !inSet(11) {
+		t.Errorf("connections mismatch after rekey: %v", ps.directConn)
+	}
+	if _, ok := ps.groupConnPeers[peerLabel{set: notaryset, round: 10}]; !ok {
+		t.Error("no group connection to the set of the old key")
+	}
+	if want := hex.EncodeToString(crypto.FromECDSAPub(&newKey.PublicKey)); ps.selfPK != want {
+		t.Errorf("self public key mismatch: have %s, want %s", ps.selfPK, want)
+	}
+	for _, node := range []*enode.Node{other, pinned} {
+		if _, ok := server.direct[node.ID()]; !ok {
+			t.Errorf("direct peer %v dropped", node.ID())
+		}
+	}
+}
+
 func newTestNodeSet(nodes []*enode.Node) map[string]struct{} {
 	m := make(map[string]struct{})
 	for _, node := range nodes {
@@ -241,3 +306,194 @@ func randomV4CompactNode() *enode.Node {
 	}
 	return enode.NewV4(&privkey.PublicKey, nil, 0, 0)
 }
+
+func TestBroadcastConsensusUnderTxFlood(t *testing.T) {
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+	p := newPeer(dex64, p2p.NewPeer(enode.ID{1}, "peer", nil), net)
+	go p.broadcast()
+	defer p.close()
+
+	// Keep the transaction queue of the peer saturated.
+	txs := []*types.Transaction{
+		types.NewTransaction(0, common.Address{}, big.NewInt(0), 0, big.NewInt(0), nil),
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case p.queuedTxs <- txs:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	readMsg := func() uint64 {
+		msg, err := app.ReadMsg()
+		if err != nil {
+			t.Fatalf("failed to read message: %v", err)
+		}
+		msg.Discard()
+		return msg.Code
+	}
+	for i := 0; i < 100; i++ {
+		if code := readMsg(); code != TxMsg {
+			t.Fatalf("message code mismatch: have %d, want %d", code, TxMsg)
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		start := time.Now()
+		p.AsyncSendVotes([]*coreTypes.Vote{{}})
+
+		var txMsgs int
+		for readMsg() != VoteMsg {
+			txMsgs++
+		}
+		// The cycle in progress may still send its transactions.
+		if txMsgs > DefaultBroadcastWeights.Tx {
+			t.Errorf("transactions sent before vote: have %d, want at most %d",
+				txMsgs, DefaultBroadcastWeights.Tx)
+		}
+		if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+			t.Errorf("vote broadcast latency too high: %v", elapsed)
+		}
+	}
+}
+
+func TestBroadcastWeights(t *testing.T) {
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+	p := newPeer(dex64, p2p.NewPeer(enode.ID{1}, "peer", nil), net)
+	p.weights = BroadcastWeights{Consensus: 3, Block: 1, Tx: 2}
+
+	// Queue everything up front, so the loop runs full cycles.
+	txs := []*types.Transaction{
+		types.NewTransaction(0, common.Address{}, big.NewInt(0), 0, big.NewInt(0), nil),
+	}
+	for i := 0; i < 4; i++ {
+		p.queuedTxs <- txs
+	}
+	for i := 0; i < 6; i++ {
+		p.queuedAgreements <- &coreTypes.AgreementResult{}
+	}
+	go p.broadcast()
+	defer p.close()
+
+	want := []uint64{
+		AgreementMsg, AgreementMsg, AgreementMsg, TxMsg, TxMsg,
+		AgreementMsg, AgreementMsg, AgreementMsg, TxMsg, TxMsg,
+	}
+	for i, code := range want {
+		msg, err := app.ReadMsg()
+		if err != nil {
+			t.Fatalf("failed to read message: %v", err)
+		}
+		msg.Discard()
+		if msg.Code != code {
+			t.Errorf("message %d code mismatch: have %d, want %d", i, msg.Code, code)
+		}
+	}
+}
+
+func TestHandshakeExtension(t *testing.T) {
+	defer func(timeout time.Duration) { handshakeTimeout = timeout }(handshakeTimeout)
+	handshakeTimeout = 100 * time.Millisecond
+
+	genesis, head := common.Hash{1}, common.Hash{2}
+	status := newStatusData(dex65, DefaultConfig.NetworkId, 1, head, genesis, 0, 0)
+
+	for _, extensions := range []int{0, 1} {
+		app, net := p2p.MsgPipe()
+		p := newPeer(dex65, p2p.NewPeer(enode.ID{1}, "peer", nil), net)
+
+		// The remote peer misses the first attempt, replying only after the
+		// status timed out.
+		delay := handshakeTimeout * 3 / 2
+		go func() {
+			msg, err := app.ReadMsg()
+			if err != nil {
+				return
+			}
+			msg.Discard()
+			time.Sleep(delay)
+			p2p.Send(app, StatusMsg, status)
+		}()
+		err := p.Handshake(DefaultConfig.NetworkId, 0, 0, common.Hash{}, genesis,
+			0, extensions, handshakeTimeout)
+		switch {
+		case extensions == 0 && err != p2p.DiscReadTimeout:
+			t.Errorf("handshake without extensions: have %v, want %v", err, p2p.DiscReadTimeout)
+		case extensions > 0 && err != nil:
+			t.Errorf("handshake with %d extensions failed: %v", extensions, err)
+		case extensions > 0 && (p.number != 1 || p.head != head):
+			t.Errorf("remote status mismatch: have number %d head %x", p.number, p.head)
+		}
+		app.Close()
+	}
+}
+
+func TestHandshakeStatusVersions(t *testing.T) {
+	genesis, head := common.Hash{1}, common.Hash{2}
+
+	// dex64 peers send neither their round nor their DMoment, a DMoment
+	// mismatch can't be detected.
+	legacy := newStatusData(dex64, DefaultConfig.NetworkId, 1, head, genesis, 3, 1)
+	current := newStatusData(dex65, DefaultConfig.NetworkId, 1, head, genesis, 3, 0)
+
+	tests := []struct {
+		version int
+		status  interface{}
+		dMoment uint64
+		round   uint64
+	}{
+		{dex64, legacy, 0, 0},
+		{dex65, current, 0, 3},
+	}
+	for i, test := range tests {
+		app, net := p2p.MsgPipe()
+		p := newPeer(test.version, p2p.NewPeer(enode.ID{1}, "peer", nil), net)
+		go func() {
+			msg, err := app.ReadMsg()
+			if err != nil {
+				return
+			}
+			msg.Discard()
+			p2p.Send(app, StatusMsg, test.status)
+		}()
+		if err := p.Handshake(DefaultConfig.NetworkId, 0, 0, common.Hash{}, genesis,
+			test.dMoment, 0, handshakeTimeout); err != nil {
+			t.Errorf("test %d: handshake failed: %v", i, err)
+		} else if p.number != 1 || p.head != head || p.round != test.round {
+			t.Errorf("test %d: remote status mismatch: have number %d head %x round %d",
+				i, p.number, p.head, p.round)
+		}
+		app.Close()
+	}
+}
+
+// Tests that a dex64 peer rejects the status of a newer version, as it
+// decodes the status strictly.
+func TestHandshakeStatusStrict64(t *testing.T) {
+	genesis, head := common.Hash{1}, common.Hash{2}
+	status := newStatusData(dex65, DefaultConfig.NetworkId, 1, head, genesis, 3, 0).(*statusData)
+	status.ProtocolVersion = dex64
+
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+	p := newPeer(dex64, p2p.NewPeer(enode.ID{1}, "peer", nil), net)
+	go func() {
+		msg, err := app.ReadMsg()
+		if err != nil {
+			return
+		}
+		msg.Discard()
+		p2p.Send(app, StatusMsg, status)
+	}()
+	err := p.Handshake(DefaultConfig.NetworkId, 0, 0, common.Hash{}, genesis, 0, 0, handshakeTimeout)
+	if err == nil || !strings.HasPrefix(err.Error(), errCode(ErrDecode).String()) {
+		t.Errorf("error mismatch: have %v, want decode error", err)
+	}
+}
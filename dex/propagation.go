@@ -0,0 +1,169 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
+
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/metrics"
+)
+
+// propagationLatencyLimit is the number of latest received blocks whose
+// propagation latencies are kept.
+const propagationLatencyLimit = 1024
+
+// LatencyDistribution summarizes propagation latencies in milliseconds.
+type LatencyDistribution struct {
+	Min    int64 `json:"min"`
+	Median int64 `json:"median"`
+	P90    int64 `json:"p90"`
+	P99    int64 `json:"p99"`
+	Max    int64 `json:"max"`
+}
+
+// PropagationStats is the distribution of the time blocks took to reach this
+// node, from the timestamp given by their proposer to their receipt. Clocks
+// behind the proposer's give negative latencies, which are counted as zero in
+// the latency distribution but kept in the raw one.
+type PropagationStats struct {
+	Samples int                 `json:"samples"` // Number of latest blocks the stats are of
+	Skewed  int                 `json:"skewed"`  // Number of blocks received before their timestamp
+	Latency LatencyDistribution `json:"latency"` // Latencies, negative ones counted as zero
+	Raw     LatencyDistribution `json:"raw"`     // Latencies as measured, negative ones included
+}
+
+// propagationTracker keeps the propagation latencies of the latest received
+// blocks.
+type propagationTracker struct {
+	hist metrics.Histogram // Histogram of the non-negative latencies, nil to disable
+
+	mu        sync.Mutex
+	latencies []int64 // Ring buffer of the latest latencies in milliseconds
+	next      int     // Position in the ring buffer of the next latency
+}
+
+func newPropagationTracker(hist metrics.Histogram) *propagationTracker {
+	return &propagationTracker{hist: hist}
+}
+
+// record keeps the latency of a block with the given timestamp received at
+// the given time, now if unknown.
+func (t *propagationTracker) record(timestamp, receivedAt time.Time) {
+	if receivedAt.IsZero() {
+		receivedAt = time.Now()
+	}
+	latency := int64(receivedAt.Sub(timestamp) / time.Millisecond)
+	if t.hist != nil {
+		if latency < 0 {
+			t.hist.Update(0)
+		} else {
+			t.hist.Update(latency)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.latencies) < propagationLatencyLimit {
+		t.latencies = append(t.latencies, latency)
+		return
+	}
+	t.latencies[t.next] = latency
+	t.next = (t.next + 1) % propagationLatencyLimit
+}
+
+// Stats returns the distributions of the kept latencies.
+func (t *propagationTracker) Stats() PropagationStats {
+	t.mu.Lock()
+	raw := make([]int64, len(t.latencies))
+	copy(raw, t.latencies)
+	t.mu.Unlock()
+
+	sort.Slice(raw, func(i, j int) bool { return raw[i] < raw[j] })
+	clamped := make([]int64, len(raw))
+	stats := PropagationStats{Samples: len(raw)}
+	for i, latency := range raw {
+		if latency < 0 {
+			stats.Skewed++
+			latency = 0
+		}
+		clamped[i] = latency
+	}
+	stats.Latency = newLatencyDistribution(clamped)
+	stats.Raw = newLatencyDistribution(raw)
+	return stats
+}
+
+// newLatencyDistribution summarizes the sorted latencies.
+func newLatencyDistribution(sorted []int64) LatencyDistribution {
+	if len(sorted) == 0 {
+		return LatencyDistribution{}
+	}
+	percentile := func(p int) int64 {
+		return sorted[(len(sorted)-1)*p/100]
+	}
+	return LatencyDistribution{
+		Min:    sorted[0],
+		Median: percentile(50),
+		P90:    percentile(90),
+		P99:    percentile(99),
+		Max:    sorted[len(sorted)-1],
+	}
+}
+
+// recordCoreBlockPropagation records the propagation latencies of the core
+// blocks received at the given time, the ones already received excluded.
+// Only blocks signed by their proposer are recorded, the timestamp of any
+// other one is not to be trusted.
+func (pm *ProtocolManager) recordCoreBlockPropagation(blocks []*coreTypes.Block, receivedAt time.Time) {
+	hashes := make(coreCommon.Hashes, len(blocks))
+	for i, block := range blocks {
+		hashes[i] = block.Hash
+	}
+	known := make(map[coreCommon.Hash]struct{})
+	for _, block := range pm.cache.blocks(hashes, false) {
+		known[block.Hash] = struct{}{}
+	}
+	for _, block := range blocks {
+		if _, ok := known[block.Hash]; ok {
+			continue
+		}
+		known[block.Hash] = struct{}{}
+		if coreUtils.VerifyBlockSignature(block) != nil {
+			continue
+		}
+		pm.coreBlockPropagation.record(block.Timestamp, receivedAt)
+	}
+}
+
+// broadcastVerifiedBlock is the block broadcaster of the fetcher, which
+// propagates a block once its header is verified. The propagation latency
+// of the block is recorded then, from its first receipt.
+func (pm *ProtocolManager) broadcastVerifiedBlock(block *types.Block, propagate bool) {
+	if propagate {
+		timestamp := time.Unix(0, int64(block.Time())*int64(time.Millisecond))
+		pm.blockPropagation.record(timestamp, block.ReceivedAt)
+	}
+	pm.BroadcastBlock(block, propagate)
+}
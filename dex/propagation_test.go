@@ -0,0 +1,152 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	coreEcdsa "github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
+
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/dex/downloader"
+	"github.com/dexon-foundation/dexon/p2p"
+)
+
+func TestPropagationTracker(t *testing.T) {
+	tracker := newPropagationTracker(nil)
+	if stats := tracker.Stats(); stats != (PropagationStats{}) {
+		t.Errorf("stats of no blocks mismatch: have %+v", stats)
+	}
+
+	// Latencies of -20 to 79 milliseconds, a clock skewed by 20 milliseconds.
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		tracker.record(now, now.Add(time.Duration(i-20)*time.Millisecond))
+	}
+	stats := tracker.Stats()
+	want := PropagationStats{
+		Samples: 100,
+		Skewed:  20,
+		Latency: LatencyDistribution{Min: 0, Median: 29, P90: 69, P99: 78, Max: 79},
+		Raw:     LatencyDistribution{Min: -20, Median: 29, P90: 69, P99: 78, Max: 79},
+	}
+	if stats != want {
+		t.Errorf("stats mismatch: have %+v, want %+v", stats, want)
+	}
+
+	// Only the latest latencies are kept.
+	for i := 0; i < propagationLatencyLimit; i++ {
+		tracker.record(now, now.Add(time.Second))
+	}
+	stats = tracker.Stats()
+	if stats.Samples != propagationLatencyLimit || stats.Skewed != 0 || stats.Raw.Min != 1000 {
+		t.Errorf("stats of latest blocks mismatch: have %+v", stats)
+	}
+}
+
+func TestBlockPropagationLatency(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	pm.SetReceiveCoreMessage(true)
+	p, _ := newTestPeer("peer", dex64, pm, true)
+	defer pm.Stop()
+	defer p.close()
+
+	const delay = 400 * time.Millisecond
+	waitSamples := func(tracker *propagationTracker, n int) PropagationStats {
+		for i := 0; ; i++ {
+			stats := tracker.Stats()
+			if stats.Samples == n {
+				return stats
+			}
+			if i == 100 {
+				t.Fatalf("sample count mismatch: have %d, want %d", stats.Samples, n)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	checkLatency := func(name string, latency int64) {
+		if min, max := int64(delay/time.Millisecond), int64((delay+2*time.Second)/time.Millisecond); latency < min || latency > max {
+			t.Errorf("%s latency out of range: have %d, want %d to %d", name, latency, min, max)
+		}
+	}
+
+	// Core blocks are measured from their consensus timestamp, a copy
+	// received again is not measured twice. Blocks not signed by their
+	// proposer are not measured.
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signer := coreUtils.NewSigner(coreEcdsa.NewPrivateKeyFromECDSA(key))
+	block := &coreTypes.Block{Timestamp: time.Now().Add(-delay).UTC()}
+	skewed := &coreTypes.Block{Timestamp: time.Now().Add(time.Hour).UTC()}
+	for _, b := range []*coreTypes.Block{block, skewed} {
+		if err := signer.SignBlock(b); err != nil {
+			t.Fatalf("failed to sign block: %v", err)
+		}
+	}
+	forged := &coreTypes.Block{
+		Hash:      coreCommon.Hash{1},
+		Timestamp: time.Now().Add(-time.Hour).UTC(),
+	}
+	if err := p2p.Send(p.app, CoreBlockMsg, []*coreTypes.Block{block, forged, skewed}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	waitSamples(pm.coreBlockPropagation, 2)
+	if err := p2p.Send(p.app, CoreBlockMsg, []*coreTypes.Block{block}); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	stats := waitSamples(pm.coreBlockPropagation, 2)
+	if stats.Skewed != 1 || stats.Raw.Min > -int64(59*time.Minute/time.Millisecond) || stats.Latency.Min != 0 {
+		t.Errorf("skewed core block mismatch: have %+v", stats)
+	}
+	checkLatency("core block", stats.Latency.Max)
+
+	// Finalized blocks are measured from their header timestamp, once their
+	// header is verified by the fetcher before propagating them.
+	header := &types.Header{
+		Number: big.NewInt(100),
+		Time:   uint64(time.Now().Add(-delay).UnixNano() / int64(time.Millisecond)),
+	}
+	if err := p2p.Send(p.app, NewBlockMsg, types.NewBlockWithHeader(header)); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if stats := pm.blockPropagation.Stats(); stats.Samples != 0 {
+		t.Errorf("unverified block measured: have %+v", stats)
+	}
+	verified := types.NewBlockWithHeader(&types.Header{
+		ParentHash: pm.blockchain.Genesis().Hash(),
+		Number:     big.NewInt(1),
+		Time:       header.Time,
+	})
+	verified.ReceivedAt = time.Now()
+	pm.broadcastVerifiedBlock(verified, true)
+	stats = waitSamples(pm.blockPropagation, 1)
+	if stats.Skewed != 0 {
+		t.Errorf("finalized block skewed: have %+v", stats)
+	}
+	checkLatency("finalized block", stats.Latency.Max)
+}
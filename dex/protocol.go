@@ -50,16 +50,17 @@ import (
 // Constants to match up protocol versions and messages
 const (
 	dex64 = 64
+	dex65 = 65
 )
 
 // ProtocolName is the official short name of the protocol used during capability negotiation.
 var ProtocolName = "dex"
 
 // ProtocolVersions are the supported versions of the eth protocol (first is primary).
-var ProtocolVersions = []uint{dex64}
+var ProtocolVersions = []uint{dex65, dex64}
 
 // ProtocolLengths are the number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{43}
+var ProtocolLengths = []uint64{43, 43}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
@@ -107,6 +108,8 @@ const (
 	ErrExtraStatusMsg
 	ErrSuspendedPeer
 	ErrInvalidGovStateMsg
+	ErrConsensusMsgFlood
+	ErrDMomentMismatch
 )
 
 const (
@@ -130,6 +133,8 @@ var errorToString = map[int]string{
 	ErrNoStatusMsg:             "No status message",
 	ErrExtraStatusMsg:          "Extra status message",
 	ErrSuspendedPeer:           "Suspended peer",
+	ErrConsensusMsgFlood:       "Consensus message rate exceeded",
+	ErrDMomentMismatch:         "DMoment mismatch",
 }
 
 type txPool interface {
@@ -154,6 +159,8 @@ type governance interface {
 
 	NotarySet(uint64) (map[string]struct{}, error)
 
+	QualifiedNodes() (map[string]struct{}, error)
+
 	PurgeNotarySet(uint64)
 
 	DKGResetCount(uint64) uint64
@@ -169,18 +176,56 @@ type p2pServer interface {
 
 	GetPrivateKey() *ecdsa.PrivateKey
 
+	Rekey(*ecdsa.PrivateKey) error
+
 	AddDirectPeer(*enode.Node)
 
 	RemoveDirectPeer(*enode.Node)
 }
 
-// statusData is the network packet for the status message.
+// statusData64 is the network packet for the status message of dex64.
+type statusData64 struct {
+	ProtocolVersion uint32
+	NetworkId       uint64
+	Number          uint64
+	CurrentBlock    common.Hash
+	GenesisBlock    common.Hash
+}
+
+// statusData is the network packet for the status message. Since dex65 it
+// carries the round of the head block, the latest finalized round, and the
+// consensus start time of the network.
 type statusData struct {
 	ProtocolVersion uint32
 	NetworkId       uint64
 	Number          uint64
 	CurrentBlock    common.Hash
 	GenesisBlock    common.Hash
+	Round           uint64
+	DMoment         uint64 // Unix seconds
+}
+
+// newStatusData returns the status message of the protocol version.
+func newStatusData(version int, network, number uint64, head, genesis common.Hash,
+	round, dMoment uint64) interface{} {
+	if version < dex65 {
+		return &statusData64{
+			ProtocolVersion: uint32(version),
+			NetworkId:       network,
+			Number:          number,
+			CurrentBlock:    head,
+			GenesisBlock:    genesis,
+		}
+	}
+	return &statusData{
+		ProtocolVersion: uint32(version),
+		NetworkId:       network,
+		Number:          number,
+		CurrentBlock:    head,
+		GenesisBlock:    genesis,
+		Round:           round,
+		DMoment:         dMoment,
+	}
 }
 
 // newBlockHashesData is the network packet for the block announcements.
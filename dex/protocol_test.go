@@ -18,8 +18,11 @@ package dex
 
 import (
 	"crypto/ecdsa"
+	"encoding/json"
 	"fmt"
+	"net"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -48,6 +51,7 @@ var testAccount, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6
 // Tests that handshake failures are detected and reported correctly.
 func TestStatusMsgErrors62(t *testing.T) { testStatusMsgErrors(t, 62) }
 func TestStatusMsgErrors63(t *testing.T) { testStatusMsgErrors(t, 63) }
+func TestStatusMsgErrors65(t *testing.T) { testStatusMsgErrors(t, dex65) }
 
 func testStatusMsgErrors(t *testing.T, protocol int) {
 	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
@@ -58,6 +62,14 @@ func testStatusMsgErrors(t *testing.T, protocol int) {
 	)
 	defer pm.Stop()
 
+	wrongVersion := newStatusData(protocol, DefaultConfig.NetworkId, number, head.Hash(), genesis.Hash(), head.Round, 0)
+	switch status := wrongVersion.(type) {
+	case *statusData64:
+		status.ProtocolVersion = 10
+	case *statusData:
+		status.ProtocolVersion = 10
+	}
+
 	tests := []struct {
 		code      uint64
 		data      interface{}
@@ -68,18 +80,28 @@ func testStatusMsgErrors(t *testing.T, protocol int) {
 			wantError: errResp(ErrNoStatusMsg, "first msg has code 2 (!= 0)"),
 		},
 		{
-			code: StatusMsg, data: statusData{10, DefaultConfig.NetworkId, number, head.Hash(), genesis.Hash()},
+			code: StatusMsg, data: wrongVersion,
 			wantError: errResp(ErrProtocolVersionMismatch, "10 (!= %d)", protocol),
 		},
 		{
-			code: StatusMsg, data: statusData{uint32(protocol), 999, number, head.Hash(), genesis.Hash()},
+			code: StatusMsg, data: newStatusData(protocol, 999, number, head.Hash(), genesis.Hash(), head.Round, 0),
 			wantError: errResp(ErrNetworkIdMismatch, "999 (!= 237)"),
 		},
 		{
-			code: StatusMsg, data: statusData{uint32(protocol), DefaultConfig.NetworkId, number, head.Hash(), common.Hash{3}},
+			code: StatusMsg, data: newStatusData(protocol, DefaultConfig.NetworkId, number, head.Hash(), common.Hash{3}, head.Round, 0),
 			wantError: errResp(ErrGenesisBlockMismatch, "0300000000000000 (!= %x)", genesis.Hash().Bytes()[:8]),
 		},
 	}
+	if protocol >= dex65 {
+		tests = append(tests, struct {
+			code      uint64
+			data      interface{}
+			wantError error
+		}{
+			code: StatusMsg, data: newStatusData(protocol, DefaultConfig.NetworkId, number, head.Hash(), genesis.Hash(), head.Round, 1),
+			wantError: errResp(ErrDMomentMismatch, "1 (!= 0)"),
+		})
+	}
 
 	for i, test := range tests {
 		p, errc := newTestPeer("peer", protocol, pm, false)
@@ -425,6 +447,46 @@ func TestRecvVotes(t *testing.T) {
 	}
 }
 
+func TestRecvVotesSigCache(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	pm.voteSigCache = newVoteSigCache(16)
+	pm.SetReceiveCoreMessage(true)
+
+	p, _ := newTestPeer("peer", dex64, pm, true)
+	defer pm.Stop()
+	defer p.close()
+
+	vote := newSignedTestVote(t, 12)
+	forged := vote.Clone()
+	forged.BlockHash = coreCommon.Hash{2}
+
+	// Both copies of the valid vote reach consensus, the second one is not
+	// verified again.
+	votes := []*coreTypes.Vote{forged, vote, vote.Clone()}
+	if err := p2p.Send(p.app, VoteMsg, votes); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	ch := pm.ReceiveChan()
+	for i := 0; i < 2; i++ {
+		select {
+		case msg := <-ch:
+			if rvote := msg.Payload.(*coreTypes.Vote); voteSigKey(rvote) != voteSigKey(vote) {
+				t.Errorf("vote mismatch: have %v, want %v", rvote, vote)
+			}
+		case <-time.After(1 * time.Second):
+			t.Fatal("no vote received within 1 seconds")
+		}
+	}
+	select {
+	case msg := <-ch:
+		t.Errorf("unexpected vote received: %v", msg.Payload)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if votes := pm.cache.votes(vote.Position); len(votes) != 1 {
+		t.Errorf("cached vote count mismatch: have %d, want 1", len(votes))
+	}
+}
+
 func TestSendVotes(t *testing.T) {
 	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
 	defer pm.Stop()
@@ -519,6 +581,80 @@ func TestSendVotes(t *testing.T) {
 	wg.Wait()
 }
 
+func TestDemoteRemovedValidator(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	var keys []*ecdsa.PrivateKey
+	for i := 0; i < 2; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		keys = append(keys, key)
+	}
+	staying, _ := newTestPeerWithKey("staying", dex64, pm, true, keys[0])
+	removed, _ := newTestPeerWithKey("removed", dex64, pm, true, keys[1])
+	defer staying.close()
+	defer removed.close()
+	waitForRegister(pm, 2)
+
+	label := peerLabel{set: notaryset, round: 10}
+	pm.peers.lock.Lock()
+	pm.peers.label2Nodes = map[peerLabel]map[string]*enode.Node{label: {}}
+	for _, p := range []*testPeer{staying, removed} {
+		pm.peers.label2Nodes[label][p.ID().String()] = p.Node()
+		pm.peers.addDirectPeer(p.ID().String(), label)
+	}
+	pm.peers.updateNotaryIDs()
+	pm.peers.lock.Unlock()
+
+	// The governance node set no longer has the removed validator.
+	pm.gov.(*testGovernance).qualifiedNodesFunc = func() (map[string]struct{}, error) {
+		return newTestNodeSet([]*enode.Node{staying.Node()}), nil
+	}
+	pm.demoteRemovedValidators()
+
+	if peers := pm.peers.PeersWithLabel(label); len(peers) != 1 || peers[0].id != staying.ID().String() {
+		t.Errorf("notary peers mismatch: have %v, want only %v", peers, staying.ID())
+	}
+	if pm.peers.IsNotaryPeer(removed.ID().String()) {
+		t.Error("removed validator still a notary peer")
+	}
+	pm.peers.lock.RLock()
+	_, direct := pm.peers.allDirectPeers[removed.ID().String()]
+	pm.peers.lock.RUnlock()
+	if direct {
+		t.Error("removed validator still a direct peer")
+	}
+	if pm.peers.Peer(removed.ID().String()) == nil {
+		t.Error("removed validator disconnected with free peer slots")
+	}
+
+	// Consensus messages only go to the validator staying.
+	vote := &coreTypes.Vote{
+		VoteHeader: coreTypes.VoteHeader{
+			Position: coreTypes.Position{Round: 10, Height: 13},
+		},
+	}
+	pm.BroadcastVote(vote)
+	msg, err := staying.app.ReadMsg()
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	if msg.Code != VoteMsg {
+		t.Errorf("message code mismatch: have %d, want %d", msg.Code, VoteMsg)
+	}
+	msg.Discard()
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		removed.close()
+	}()
+	if msg, err := removed.app.ReadMsg(); err != p2p.ErrPipeClosed {
+		t.Errorf("removed validator received message %v, err %v", msg, err)
+	}
+}
+
 type mockPublicKey ecdsa.PublicKey
 
 func (p *mockPublicKey) VerifySignature(hash coreCommon.Hash, signature coreCrypto.Signature) bool {
@@ -726,3 +862,487 @@ func waitForRegister(pm *ProtocolManager, num int) {
 		}
 	}
 }
+
+func waitForPeer(pm *ProtocolManager, id string) {
+	for pm.peers.Peer(id) == nil {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPeersInfo(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	pm.SetReceiveCoreMessage(true)
+
+	p, _ := newTestPeer("peer", dex64, pm, true)
+	defer pm.Stop()
+	defer p.close()
+	waitForRegister(pm, 1)
+
+	infos := pm.PeersInfo()
+	if len(infos) != 1 {
+		t.Fatalf("peer count mismatch: have %d, want %d", len(infos), 1)
+	}
+	if infos[0].ID != p.id || infos[0].Version != dex64 {
+		t.Errorf("peer info mismatch: %+v", infos[0])
+	}
+	if infos[0].Consensus || infos[0].LastConsensusMsg != nil {
+		t.Errorf("consensus messages exchanged before any is received: %+v", infos[0])
+	}
+
+	agreement := coreTypes.AgreementResult{
+		BlockHash: coreCommon.Hash{9, 9, 9},
+		Position: coreTypes.Position{
+			Round:  3,
+			Height: 13,
+		},
+	}
+	if err := p2p.Send(p.app, AgreementMsg, &agreement); err != nil {
+		t.Fatalf("send error: %v", err)
+	}
+	select {
+	case <-pm.ReceiveChan():
+	case <-time.After(1 * time.Second):
+		t.Fatalf("no agreement received within 1 seconds")
+	}
+
+	infos = pm.PeersInfo()
+	if !infos[0].Consensus || infos[0].LastConsensusMsg == nil {
+		t.Errorf("consensus messages not reported: %+v", infos[0])
+	}
+	if infos[0].Round != agreement.Position.Round {
+		t.Errorf("round mismatch: have %d, want %d", infos[0].Round, agreement.Position.Round)
+	}
+	// Both rounds are reported.
+	var fields map[string]interface{}
+	enc, _ := json.Marshal(infos[0])
+	if err := json.Unmarshal(enc, &fields); err != nil {
+		t.Fatalf("failed to decode peer info: %v", err)
+	}
+	for _, field := range []string{"round", "headRound"} {
+		if _, ok := fields[field]; !ok {
+			t.Errorf("peer info misses %q: %s", field, enc)
+		}
+	}
+
+	// Consensus messages are not exchanged once we stop receiving them.
+	pm.SetReceiveCoreMessage(false)
+	if infos = pm.PeersInfo(); infos[0].Consensus {
+		t.Errorf("consensus messages reported while not receiving: %+v", infos[0])
+	}
+}
+
+func TestDexVersions(t *testing.T) {
+	defer func(versions []uint, lengths []uint64) {
+		ProtocolVersions, ProtocolLengths = versions, lengths
+	}(ProtocolVersions, ProtocolLengths)
+	ProtocolVersions = []uint{dex64 + 1, dex64}
+	ProtocolLengths = []uint64{ProtocolLengths[0], ProtocolLengths[0]}
+
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+	dex := &Dexon{protocolManager: pm}
+
+	versions := dex.SupportedDexVersions()
+	if len(versions) != 2 || versions[0] != dex64+1 || versions[1] != dex64 {
+		t.Fatalf("supported versions mismatch: have %v, want %v", versions, ProtocolVersions)
+	}
+	if dex.DexVersion() != dex64+1 {
+		t.Errorf("primary version mismatch: have %d, want %d", dex.DexVersion(), dex64+1)
+	}
+
+	p, _ := newTestPeer("peer", dex64, pm, true)
+	defer p.close()
+	waitForRegister(pm, 1)
+	if version, ok := dex.NegotiatedVersion(p.id); !ok || version != dex64 {
+		t.Errorf("negotiated version mismatch: have %d (%v), want %d", version, ok, dex64)
+	}
+	if _, ok := dex.NegotiatedVersion("unknown"); ok {
+		t.Error("negotiated version reported for unknown peer")
+	}
+}
+
+func TestReservedValidatorPeers(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+	pm.maxPeers = 3
+	pm.reservedValidatorPeers = 1
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	validator := enode.NewV4(&key.PublicKey, nil, 0, 0)
+	pm.peers.lock.Lock()
+	pm.peers.label2Nodes[peerLabel{set: notaryset, round: 0}] = map[string]*enode.Node{
+		validator.ID().String(): validator,
+	}
+	pm.peers.updateNotaryIDs()
+	pm.peers.lock.Unlock()
+
+	// Saturate the unreserved slots with non-validator peers.
+	for i := 0; i < 2; i++ {
+		p, _ := newTestPeer(fmt.Sprintf("peer #%d", i), dex64, pm, true)
+		defer p.close()
+	}
+	waitForRegister(pm, 2)
+
+	p, errc := newTestPeer("peer #2", dex64, pm, false)
+	defer p.close()
+	select {
+	case err := <-errc:
+		if err != p2p.DiscTooManyPeers {
+			t.Errorf("error mismatch: have %v, want %v", err, p2p.DiscTooManyPeers)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("non-validator peer not rejected")
+	}
+
+	// The reserved slot is still free for the validator.
+	vp, _ := newTestPeerWithKey("validator", dex64, pm, true, key)
+	defer vp.close()
+	waitForRegister(pm, 3)
+	if pm.peers.Peer(vp.id) == nil {
+		t.Error("validator peer not registered")
+	}
+}
+
+func TestPeerEvictionPolicy(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+	pm.maxPeers = 3
+
+	notaries := make([]*ecdsa.PrivateKey, 3)
+	nodes := make(map[string]*enode.Node)
+	for i := range notaries {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		notaries[i] = key
+		node := enode.NewV4(&key.PublicKey, nil, 0, 0)
+		nodes[node.ID().String()] = node
+	}
+	pm.peers.lock.Lock()
+	pm.peers.label2Nodes[peerLabel{set: notaryset, round: 0}] = nodes
+	pm.peers.updateNotaryIDs()
+	pm.peers.lock.Unlock()
+	expectRejected := func(errc <-chan error) {
+		select {
+		case err := <-errc:
+			if err != p2p.DiscTooManyPeers {
+				t.Errorf("error mismatch: have %v, want %v", err, p2p.DiscTooManyPeers)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("peer beyond the limit not rejected")
+		}
+	}
+
+	// Saturate the peer slots, the first peer being an active one.
+	active, _ := newTestPeer("active", dex64, pm, true)
+	defer active.close()
+	waitForRegister(pm, 1)
+	active.peer.MarkConsensusMsg()
+	idle, _ := newTestPeer("idle", dex64, pm, true)
+	defer idle.close()
+	np, _ := newTestPeerWithKey("notary #0", dex64, pm, true, notaries[0])
+	defer np.close()
+	waitForRegister(pm, 3)
+
+	// No peer is evicted by default, and the p2p server enforces its limits.
+	if pm.exemptPeer(enode.PubkeyToIDV4(&notaries[1].PublicKey)) {
+		t.Error("notary peer exempted from the peer limits with eviction disabled")
+	}
+	p, errc := newTestPeerWithKey("notary #1", dex64, pm, false, notaries[1])
+	expectRejected(errc)
+	p.close()
+
+	// The idle peer is evicted first, then the active one. Only notary set
+	// peers are let in beyond the p2p server limits.
+	pm.evictionPolicy = EvictIdle
+	if !pm.exemptPeer(enode.PubkeyToIDV4(&notaries[1].PublicKey)) {
+		t.Error("notary peer not exempted from the peer limits")
+	}
+	if pm.exemptPeer(idle.peer.Peer.ID()) {
+		t.Error("peer out of the notary sets exempted from the peer limits")
+	}
+	for i, evicted := range []*testPeer{idle, active} {
+		p, _ := newTestPeerWithKey(fmt.Sprintf("notary #%d", i+1), dex64, pm, true, notaries[i+1])
+		defer p.close()
+		waitForPeer(pm, p.id)
+		if pm.peers.Peer(evicted.id) != nil {
+			t.Errorf("peer %s not evicted", evicted.Name())
+		}
+		waitForRegister(pm, 3)
+	}
+
+	// Notary set peers are never evicted, not even for another one.
+	pm.peers.lock.Lock()
+	key, _ := crypto.GenerateKey()
+	extra := enode.NewV4(&key.PublicKey, nil, 0, 0)
+	nodes[extra.ID().String()] = extra
+	pm.peers.lock.Unlock()
+
+	p, errc = newTestPeerWithKey("notary #3", dex64, pm, false, key)
+	defer p.close()
+	expectRejected(errc)
+	for _, key := range notaries {
+		if pm.peers.Peer(enode.PubkeyToIDV4(&key.PublicKey).String()) == nil {
+			t.Errorf("notary peer %x evicted", crypto.PubkeyToAddress(key.PublicKey))
+		}
+	}
+}
+
+func TestPinnedValidatorPeers(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+	pm.maxPeers = 2
+	srvr := pm.srvr.(*testP2PServer)
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	validator := enode.NewV4(&key.PublicKey, nil, 0, 0)
+	pm.peers.AddValidatorPeer(validator)
+	isDirect := func() bool {
+		srvr.mu.Lock()
+		defer srvr.mu.Unlock()
+		_, ok := srvr.direct[validator.ID()]
+		return ok
+	}
+	if !isDirect() {
+		t.Fatal("pinned peer not added as direct peer")
+	}
+	// Round changes do not drop pinned peers.
+	pm.peers.ForgetConnection(100)
+	if !isDirect() || !pm.peers.IsValidatorPeer(validator.ID().String()) {
+		t.Fatal("pinned peer dropped on round change")
+	}
+	for i := 0; i < 2; i++ {
+		p, _ := newTestPeer(fmt.Sprintf("peer #%d", i), dex64, pm, true)
+		defer p.close()
+	}
+	waitForRegister(pm, 2)
+
+	// The pinned peer is accepted beyond the peer limit, others are not.
+	vp, _ := newTestPeerWithKey("validator", dex64, pm, true, key)
+	defer vp.close()
+	waitForRegister(pm, 3)
+	if pm.peers.Peer(vp.id) == nil {
+		t.Error("pinned peer not registered")
+	}
+	p, errc := newTestPeer("peer #2", dex64, pm, false)
+	defer p.close()
+	select {
+	case err := <-errc:
+		if err != p2p.DiscTooManyPeers {
+			t.Errorf("error mismatch: have %v, want %v", err, p2p.DiscTooManyPeers)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("peer beyond the limit not rejected")
+	}
+
+	if !pm.peers.RemoveValidatorPeer(validator.ID().String()) {
+		t.Fatal("pinned peer not removed")
+	}
+	if isDirect() || pm.peers.IsValidatorPeer(validator.ID().String()) {
+		t.Error("unpinned peer still kept")
+	}
+	if pm.peers.RemoveValidatorPeer(validator.ID().String()) {
+		t.Error("unpinned peer removed twice")
+	}
+}
+
+func TestBestPeerPrefersFinalizedRound(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+	genesis := pm.blockchain.Genesis()
+
+	// Connect peers advertising their own head in the handshake.
+	connect := func(name string, number, round uint64) *testPeer {
+		p, _ := newTestPeer(name, dex65, pm, false)
+		msg, err := p.app.ReadMsg()
+		if err != nil {
+			t.Fatalf("%s: status recv: %v", name, err)
+		}
+		msg.Discard()
+		status := newStatusData(dex65, DefaultConfig.NetworkId, number,
+			common.Hash{byte(number)}, genesis.Hash(), round, 0)
+		if err := p2p.Send(p.app, StatusMsg, status); err != nil {
+			t.Fatalf("%s: status send: %v", name, err)
+		}
+		return p
+	}
+	higher := connect("higher", 100, 1)
+	defer higher.close()
+	// A peer claiming a higher round at a lower number never wins.
+	lying := connect("lying", 80, 3)
+	defer lying.close()
+	waitForRegister(pm, 2)
+	if round := pm.peers.Peer(lying.id).HeadRound(); round != 3 {
+		t.Errorf("handshake round mismatch: have %d, want %d", round, 3)
+	}
+	if best := pm.peers.BestPeer(); best.id != higher.id {
+		t.Errorf("best peer by number mismatch: have %s, want %s", best.Name(), higher.Name())
+	}
+
+	finalized := connect("finalized", 100, 2)
+	defer finalized.close()
+	waitForRegister(pm, 3)
+	pm.peers.lock.Lock()
+	pm.peers.preferFinalized = true
+	pm.peers.lock.Unlock()
+	if best := pm.peers.BestPeer(); best.id != finalized.id {
+		t.Errorf("best peer by round mismatch: have %s, want %s", best.Name(), finalized.Name())
+	}
+}
+
+// Tests that nodes configured with different DMoments refuse to peer.
+func TestDMomentMismatch(t *testing.T) {
+	for _, test := range []struct {
+		dMoment uint64
+		peered  bool
+	}{
+		{0, true},
+		{1556150400, false},
+	} {
+		local, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+		remote, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+		config := *remote.chainconfig
+		config.DMoment = test.dMoment
+		remote.chainconfig = &config
+
+		// Run each side of a pipe as a peer of the other node.
+		localRW, remoteRW := p2p.MsgPipe()
+		errc := make(chan error, 2)
+		connect := func(pm *ProtocolManager, rw p2p.MsgReadWriter) {
+			key, _ := crypto.GenerateKey()
+			node := enode.NewV4(&key.PublicKey, net.IP{}, 0, 0)
+			p := pm.newPeer(dex65, p2p.NewPeerWithEnode(node, "peer", nil), rw)
+			go func() {
+				pm.newPeerCh <- p
+				errc <- pm.handle(p)
+			}()
+		}
+		connect(local, localRW)
+		connect(remote, remoteRW)
+
+		if test.peered {
+			waitForRegister(local, 1)
+			waitForRegister(remote, 1)
+		} else {
+			for i := 0; i < 2; i++ {
+				select {
+				case err := <-errc:
+					if err == nil || !strings.HasPrefix(err.Error(), errCode(ErrDMomentMismatch).String()) {
+						t.Errorf("dMoment %d: handshake error mismatch: have %v, want %q", test.dMoment, err, errCode(ErrDMomentMismatch))
+					}
+				case <-time.After(2 * time.Second):
+					t.Fatalf("dMoment %d: handshake not refused", test.dMoment)
+				}
+			}
+			if local.peers.Len() != 0 || remote.peers.Len() != 0 {
+				t.Errorf("dMoment %d: nodes peered", test.dMoment)
+			}
+		}
+		localRW.Close()
+		remoteRW.Close()
+		local.Stop()
+		remote.Stop()
+	}
+}
+
+func TestMsgRateLimiterDecay(t *testing.T) {
+	l := newMsgRateLimiter(10)
+	now := l.last
+	// send sends n messages per 100ms for the given number of ticks, bursting
+	// extra messages every 5 seconds, reporting whether the peer is flooding.
+	send := func(ticks, n, burst int) bool {
+		for i := 1; i <= ticks; i++ {
+			now = now.Add(100 * time.Millisecond)
+			count := n
+			if i%50 == 0 {
+				count += burst
+			}
+			for j := 0; j < count; j++ {
+				if _, flood := l.allowAt(now); flood {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	// A peer sending at the rate, bursting over it now and then, e.g. during
+	// agreements, is never considered flooding.
+	if send(10000, 1, 20) {
+		t.Fatal("peer at the rate flooding")
+	}
+	// Persistently exceeding the rate is.
+	if !send(100, 6, 0) {
+		t.Fatal("peer over the rate not flooding")
+	}
+}
+
+func TestConsensusMsgRateLimit(t *testing.T) {
+	pm, _ := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	pm.consensusMsgRate = 5
+	pm.SetReceiveCoreMessage(true)
+	defer pm.Stop()
+
+	flooder, _ := newTestPeer("flooder", dex64, pm, true)
+	defer flooder.close()
+	honest, _ := newTestPeer("honest", dex64, pm, true)
+	defer honest.close()
+
+	send := func(p *testPeer, n int) {
+		for i := 0; i < n; i++ {
+			vote := newSignedTestVote(t, uint64(i))
+			if err := p2p.Send(p.app, VoteMsg, []*coreTypes.Vote{vote}); err != nil {
+				t.Fatalf("send error: %v", err)
+			}
+		}
+	}
+	received := func() map[string]int {
+		count := make(map[string]int)
+		for {
+			select {
+			case msg := <-pm.ReceiveChan():
+				count[msg.PeerID.(string)]++
+			case <-time.After(100 * time.Millisecond):
+				return count
+			}
+		}
+	}
+
+	// Messages beyond the burst of the flooding peer are dropped, the ones
+	// of other peers still get through.
+	send(flooder, 20)
+	send(honest, 3)
+	count := received()
+	if have := count[flooder.peer.ID().String()]; have < 5 || have > 6 {
+		t.Errorf("flooder messages mismatch: have %d, want 5", have)
+	}
+	if have := count[honest.peer.ID().String()]; have != 3 {
+		t.Errorf("honest messages mismatch: have %d, want 3", have)
+	}
+
+	// Keeping the flood up gets the peer disconnected, the messages after it
+	// are never read.
+	go func() {
+		for i := 0; i < 100; i++ {
+			if err := p2p.Send(flooder.app, VoteMsg, []*coreTypes.Vote{}); err != nil {
+				return
+			}
+		}
+	}()
+	for i := 0; pm.peers.Peer(flooder.peer.id) != nil; i++ {
+		if i == 100 {
+			t.Fatal("flooding peer not disconnected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if pm.peers.Peer(honest.peer.id) == nil {
+		t.Error("honest peer disconnected")
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	coreEcdsa "github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/log"
+)
+
+// quorumWatchdog reports a round stalled once the consensus agrees on no
+// block for longer than the quorum timeout, along with the notary set
+// members no vote was seen from meanwhile. A stall is reported once, until
+// the next block is agreed on.
+type quorumWatchdog struct {
+	timeout   time.Duration
+	notarySet func(round uint64) (map[string]struct{}, error)
+	active    func() bool                // Whether the node takes part in consensus
+	verify    func(*coreTypes.Vote) bool // Verifies the signature of a vote received
+	events    *eventQueue                // Queue RoundStalledEvent is posted on
+
+	mu       sync.Mutex
+	progress time.Time                     // Time of the last agreement, or of becoming active
+	round    uint64                        // Round of the latest position seen
+	height   uint64                        // Height of the latest block agreed on
+	voters   map[coreTypes.NodeID]struct{} // Voters for the positions after height
+	stalled  bool
+
+	wg     sync.WaitGroup
+	quitCh chan struct{}
+}
+
+func newQuorumWatchdog(timeout time.Duration,
+	notarySet func(round uint64) (map[string]struct{}, error),
+	active func() bool, events *eventQueue) *quorumWatchdog {
+	return &quorumWatchdog{
+		timeout:   timeout,
+		notarySet: notarySet,
+		active:    active,
+		verify:    verifyVoteSignature,
+		events:    events,
+		progress:  time.Now(),
+		voters:    make(map[coreTypes.NodeID]struct{}),
+		quitCh:    make(chan struct{}),
+	}
+}
+
+// onConfirmed records the agreement on a block.
+func (w *quorumWatchdog) onConfirmed(position coreTypes.Position) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if position.Height < w.height {
+		return
+	}
+	if w.stalled {
+		log.Info("Round recovered from stall", "round", position.Round,
+			"height", position.Height, "stalled", common.PrettyDuration(time.Since(w.progress)))
+	}
+	w.progress = time.Now()
+	w.height = position.Height
+	if position.Round > w.round {
+		w.round = position.Round
+	}
+	w.voters = make(map[coreTypes.NodeID]struct{})
+	w.stalled = false
+}
+
+// verifyVoteSignature reports whether the vote is signed by its voter.
+func verifyVoteSignature(vote *coreTypes.Vote) bool {
+	ok, err := coreUtils.VerifyVoteSignature(vote)
+	return err == nil && ok
+}
+
+// onVote records a vote received for a position not agreed on yet. The first
+// vote of each voter is verified, so no peer can mask the silence of others
+// with votes in their name.
+func (w *quorumWatchdog) onVote(vote *coreTypes.Vote) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	_, seen := w.voters[vote.ProposerID]
+	pending := vote.Position.Height > w.height
+	w.mu.Unlock()
+
+	if seen || !pending || !w.verify(vote) {
+		return
+	}
+	w.record(vote)
+}
+
+// onOwnVote records a vote of the node for a position not agreed on yet.
+func (w *quorumWatchdog) onOwnVote(vote *coreTypes.Vote) {
+	if w == nil {
+		return
+	}
+	w.record(vote)
+}
+
+func (w *quorumWatchdog) record(vote *coreTypes.Vote) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if vote.Position.Height <= w.height {
+		return
+	}
+	if vote.Position.Round > w.round {
+		w.round = vote.Position.Round
+	}
+	w.voters[vote.ProposerID] = struct{}{}
+}
+
+// tick reports the stall of the round if no block was agreed on within the
+// timeout before now.
+func (w *quorumWatchdog) tick(now time.Time) {
+	w.mu.Lock()
+	if !w.active() {
+		// Only time spent in consensus counts towards a stall.
+		w.progress = now
+		w.mu.Unlock()
+		return
+	}
+	if w.stalled || now.Sub(w.progress) < w.timeout {
+		w.mu.Unlock()
+		return
+	}
+	w.stalled = true
+	ev := RoundStalledEvent{
+		Round:  w.round,
+		Height: w.height + 1,
+		Since:  w.progress,
+	}
+	voters := make(map[coreTypes.NodeID]struct{}, len(w.voters))
+	for id := range w.voters {
+		voters[id] = struct{}{}
+	}
+	w.mu.Unlock()
+
+	notarySet, err := w.notarySet(ev.Round)
+	if err != nil {
+		log.Warn("Failed to get notary set of stalled round", "round", ev.Round, "err", err)
+	}
+	for key := range notarySet {
+		b, err := hex.DecodeString(key)
+		if err != nil {
+			continue
+		}
+		pubkey, err := coreEcdsa.NewPublicKeyFromByteSlice(b)
+		if err != nil {
+			continue
+		}
+		if _, ok := voters[coreTypes.NewNodeID(pubkey)]; ok {
+			continue
+		}
+		if pk, err := crypto.UnmarshalPubkey(b); err == nil {
+			ev.Missing = append(ev.Missing, crypto.PubkeyToAddress(*pk))
+		}
+	}
+	sort.Slice(ev.Missing, func(i, j int) bool {
+		return ev.Missing[i].Hex() < ev.Missing[j].Hex()
+	})
+
+	roundStalledMeter.Mark(1)
+	log.Warn("Round stalled without quorum", "round", ev.Round, "height", ev.Height,
+		"elapsed", common.PrettyDuration(now.Sub(ev.Since)),
+		"notaryset", len(notarySet), "missing", ev.Missing)
+	if w.events != nil {
+		w.events.post(ev)
+	}
+}
+
+func (w *quorumWatchdog) Start() {
+	interval := w.timeout / 4
+	if interval < 100*time.Millisecond {
+		interval = 100 * time.Millisecond
+	}
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				w.tick(now)
+			case <-w.quitCh:
+				return
+			}
+		}
+	}()
+}
+
+func (w *quorumWatchdog) Stop() {
+	close(w.quitCh)
+	w.wg.Wait()
+}
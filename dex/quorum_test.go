@@ -0,0 +1,134 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	coreEcdsa "github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/event"
+)
+
+func TestQuorumWatchdog(t *testing.T) {
+	var keys []*ecdsa.PrivateKey
+	notarySet := map[string]struct{}{}
+	for i := 0; i < 4; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		keys = append(keys, key)
+		notarySet[hex.EncodeToString(crypto.FromECDSAPub(&key.PublicKey))] = struct{}{}
+	}
+	vote := func(key *ecdsa.PrivateKey, height uint64) *coreTypes.Vote {
+		vote := coreTypes.NewVote(coreTypes.VotePreCom, coreCommon.Hash{1}, 1)
+		vote.Position = coreTypes.Position{Round: 1, Height: height}
+		signer := coreUtils.NewSigner(coreEcdsa.NewPrivateKeyFromECDSA(key))
+		if err := signer.SignVote(vote); err != nil {
+			t.Fatalf("failed to sign vote: %v", err)
+		}
+		return vote
+	}
+
+	var active int32
+	timeout := 200 * time.Millisecond
+	mux := new(event.TypeMux)
+	defer mux.Stop()
+	sub := mux.Subscribe(RoundStalledEvent{})
+	events := newEventQueue(mux)
+	defer events.stop()
+	w := newQuorumWatchdog(timeout,
+		func(uint64) (map[string]struct{}, error) { return notarySet, nil },
+		func() bool { return atomic.LoadInt32(&active) == 1 }, events)
+	w.Start()
+	defer w.Stop()
+
+	// Nothing is reported while the node is not in consensus.
+	select {
+	case ev := <-sub.Chan():
+		t.Fatalf("stall reported while inactive: %+v", ev.Data)
+	case <-time.After(2 * timeout):
+	}
+
+	// Half of the notary set votes, withholding the quorum.
+	atomic.StoreInt32(&active, 1)
+	w.onConfirmed(coreTypes.Position{Round: 1, Height: 10})
+	start := time.Now()
+	w.onOwnVote(vote(keys[0], 11))
+	w.onVote(vote(keys[1], 11))
+	w.onVote(vote(keys[2], 10)) // For a block agreed on already
+	// A vote in the name of another member is not counted.
+	forged := vote(keys[1], 11)
+	forged.ProposerID = coreTypes.NewNodeID(coreEcdsa.NewPrivateKeyFromECDSA(keys[3]).PublicKey())
+	w.onVote(forged)
+	var stalled RoundStalledEvent
+	select {
+	case ev := <-sub.Chan():
+		stalled = ev.Data.(RoundStalledEvent)
+	case <-time.After(10 * timeout):
+		t.Fatal("stall not reported")
+	}
+	if elapsed := time.Since(start); elapsed < timeout {
+		t.Errorf("stall reported after %v, before the timeout %v", elapsed, timeout)
+	}
+	if stalled.Round != 1 || stalled.Height != 11 {
+		t.Errorf("position mismatch: have %d/%d, want %d/%d", stalled.Round, stalled.Height, 1, 11)
+	}
+	missing := []common.Address{
+		crypto.PubkeyToAddress(keys[2].PublicKey),
+		crypto.PubkeyToAddress(keys[3].PublicKey),
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i].Hex() < missing[j].Hex() })
+	if len(stalled.Missing) != len(missing) {
+		t.Fatalf("missing signers mismatch: have %x, want %x", stalled.Missing, missing)
+	}
+	for i := range missing {
+		if stalled.Missing[i] != missing[i] {
+			t.Errorf("missing signer %d mismatch: have %x, want %x", i, stalled.Missing[i], missing[i])
+		}
+	}
+
+	// A stall is reported once.
+	select {
+	case ev := <-sub.Chan():
+		t.Fatalf("stall reported twice: %+v", ev.Data)
+	case <-time.After(2 * timeout):
+	}
+
+	// Agreement resets the watchdog, the next stall is reported again.
+	w.onConfirmed(coreTypes.Position{Round: 1, Height: 11})
+	select {
+	case ev := <-sub.Chan():
+		if h := ev.Data.(RoundStalledEvent).Height; h != 12 {
+			t.Errorf("stalled height mismatch: have %d, want %d", h, 12)
+		}
+	case <-time.After(10 * timeout):
+		t.Fatal("second stall not reported")
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	// msgFloodFactor is the number of seconds worth of messages a peer may
+	// have dropped for exceeding its rate before it is disconnected.
+	msgFloodFactor = 10
+
+	// msgViolationHalfLife is the number of seconds in which the count of the
+	// messages dropped halves, so short bursts over the rate are forgotten
+	// and only peers exceeding it persistently get disconnected.
+	msgViolationHalfLife = 10
+)
+
+// msgRateLimiter is a token bucket limiting the rate of the messages received
+// from a peer. The bucket holds up to one second worth of messages.
+type msgRateLimiter struct {
+	rate       float64 // Messages allowed per second
+	tokens     float64 // Messages allowed right now
+	last       time.Time
+	violations float64 // Messages dropped, decaying over time
+	lock       sync.Mutex
+}
+
+func newMsgRateLimiter(rate int) *msgRateLimiter {
+	return &msgRateLimiter{
+		rate:   float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+// allow takes a token for a message received, returning false if the message
+// exceeds the rate and should be dropped. flood is set once the peer kept
+// exceeding the rate for long enough to be disconnected.
+func (l *msgRateLimiter) allow() (ok bool, flood bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	return l.allowAt(time.Now())
+}
+
+func (l *msgRateLimiter) allowAt(now time.Time) (ok bool, flood bool) {
+	elapsed := now.Sub(l.last).Seconds()
+	l.last = now
+	l.tokens = math.Min(l.tokens+elapsed*l.rate, l.rate)
+	l.violations *= math.Exp2(-elapsed / msgViolationHalfLife)
+	if l.tokens >= 1 {
+		l.tokens--
+		return true, false
+	}
+	l.violations++
+	return false, l.violations > l.rate*msgFloodFactor
+}
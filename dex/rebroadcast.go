@@ -0,0 +1,160 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"sync"
+	"time"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+
+	"github.com/dexon-foundation/dexon/log"
+)
+
+// ProposalRebroadcastConfig controls re-broadcasting of the node's own
+// proposals that are not yet finalized.
+type ProposalRebroadcastConfig struct {
+	Interval   time.Duration // Time to wait for finalization before re-broadcasting, zero disables
+	MaxRetries int           // Maximum number of re-broadcasts of a single proposal
+}
+
+type pendingProposal struct {
+	block   *coreTypes.Block
+	retries int
+	next    time.Time
+}
+
+// proposalRebroadcaster keeps track of the blocks proposed by this node and
+// broadcasts them again if they are not finalized in time, which helps the
+// network recover from lost proposal messages.
+type proposalRebroadcaster struct {
+	config    ProposalRebroadcastConfig
+	broadcast func(*coreTypes.Block)
+	finalized func() uint64 // Returns the height of the latest finalized block
+
+	mu      sync.Mutex
+	self    coreTypes.NodeID
+	pending map[coreCommon.Hash]*pendingProposal
+
+	wg     sync.WaitGroup
+	quitCh chan struct{}
+}
+
+func newProposalRebroadcaster(config ProposalRebroadcastConfig,
+	self coreTypes.NodeID, broadcast func(*coreTypes.Block),
+	finalized func() uint64) *proposalRebroadcaster {
+	return &proposalRebroadcaster{
+		config:    config,
+		self:      self,
+		broadcast: broadcast,
+		finalized: finalized,
+		pending:   make(map[coreCommon.Hash]*pendingProposal),
+		quitCh:    make(chan struct{}),
+	}
+}
+
+// track records a proposal broadcast by this node. Proposals at the same or
+// lower height are superseded by the new one and are no longer re-broadcast.
+func (r *proposalRebroadcaster) track(block *coreTypes.Block) {
+	if block.IsFinalized() {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if block.ProposerID != r.self {
+		return
+	}
+	for hash, p := range r.pending {
+		if p.block.Position.Height <= block.Position.Height {
+			delete(r.pending, hash)
+		}
+	}
+	r.pending[block.Hash] = &pendingProposal{
+		block: block,
+		next:  time.Now().Add(r.config.Interval),
+	}
+}
+
+// setSelf changes the node ID the proposals are tracked for after a key
+// rotation, the proposals of the retired key are dropped.
+func (r *proposalRebroadcaster) setSelf(id coreTypes.NodeID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.self = id
+	r.pending = make(map[coreCommon.Hash]*pendingProposal)
+}
+
+// tick re-broadcasts every pending proposal that is due at the given time and
+// drops the ones already finalized or out of retries.
+func (r *proposalRebroadcaster) tick(now time.Time) {
+	finalized := r.finalized()
+
+	var due []*coreTypes.Block
+	r.mu.Lock()
+	for hash, p := range r.pending {
+		if p.block.Position.Height <= finalized {
+			delete(r.pending, hash)
+			continue
+		}
+		if now.Before(p.next) {
+			continue
+		}
+		if p.retries >= r.config.MaxRetries {
+			log.Debug("Give up re-broadcasting proposal", "hash", hash,
+				"position", p.block.Position, "retries", p.retries)
+			delete(r.pending, hash)
+			continue
+		}
+		p.retries++
+		p.next = now.Add(r.config.Interval)
+		due = append(due, p.block)
+	}
+	r.mu.Unlock()
+
+	for _, block := range due {
+		log.Debug("Re-broadcast unfinalized proposal", "hash", block.Hash,
+			"position", block.Position)
+		propCoreBlockRebroadcastMeter.Mark(1)
+		r.broadcast(block)
+	}
+}
+
+func (r *proposalRebroadcaster) Start() {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(r.config.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				r.tick(now)
+			case <-r.quitCh:
+				return
+			}
+		}
+	}()
+}
+
+func (r *proposalRebroadcaster) Stop() {
+	close(r.quitCh)
+	r.wg.Wait()
+}
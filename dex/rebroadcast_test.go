@@ -0,0 +1,102 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"testing"
+	"time"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+)
+
+func TestProposalRebroadcast(t *testing.T) {
+	self := coreTypes.NodeID{Hash: coreCommon.Hash{1}}
+	config := ProposalRebroadcastConfig{Interval: time.Second, MaxRetries: 2}
+
+	var (
+		broadcasted []coreCommon.Hash
+		finalized   uint64
+	)
+	r := newProposalRebroadcaster(config, self,
+		func(block *coreTypes.Block) { broadcasted = append(broadcasted, block.Hash) },
+		func() uint64 { return finalized })
+
+	// The proposal is dropped by the network and never finalized.
+	block := &coreTypes.Block{
+		ProposerID: self,
+		Hash:       coreCommon.Hash{2},
+		Position:   coreTypes.Position{Height: 10},
+	}
+	r.track(block)
+
+	// Blocks proposed by others are not tracked.
+	r.track(&coreTypes.Block{
+		ProposerID: coreTypes.NodeID{Hash: coreCommon.Hash{3}},
+		Hash:       coreCommon.Hash{4},
+		Position:   coreTypes.Position{Height: 10},
+	})
+
+	now := time.Now()
+	r.tick(now)
+	if len(broadcasted) != 0 {
+		t.Fatalf("re-broadcast before interval: have %d", len(broadcasted))
+	}
+	for i := 1; i <= 5; i++ {
+		r.tick(now.Add(time.Duration(i) * config.Interval))
+	}
+	if len(broadcasted) != config.MaxRetries {
+		t.Fatalf("re-broadcast count mismatch: have %d, want %d",
+			len(broadcasted), config.MaxRetries)
+	}
+	for _, hash := range broadcasted {
+		if hash != block.Hash {
+			t.Errorf("unexpected block re-broadcast: have %x, want %x", hash, block.Hash)
+		}
+	}
+
+	// A finalized proposal is never re-broadcast.
+	broadcasted = nil
+	block = &coreTypes.Block{
+		ProposerID: self,
+		Hash:       coreCommon.Hash{5},
+		Position:   coreTypes.Position{Height: 11},
+	}
+	r.track(block)
+	finalized = 11
+	r.tick(time.Now().Add(2 * config.Interval))
+	if len(broadcasted) != 0 {
+		t.Errorf("finalized proposal re-broadcast: have %d", len(broadcasted))
+	}
+
+	// A newer proposal supersedes the older one.
+	r.track(&coreTypes.Block{
+		ProposerID: self,
+		Hash:       coreCommon.Hash{6},
+		Position:   coreTypes.Position{Height: 12},
+	})
+	r.track(&coreTypes.Block{
+		ProposerID: self,
+		Hash:       coreCommon.Hash{7},
+		Position:   coreTypes.Position{Height: 13},
+	})
+	r.tick(time.Now().Add(2 * config.Interval))
+	if len(broadcasted) != 1 || broadcasted[0] != (coreCommon.Hash{7}) {
+		t.Errorf("superseded proposal re-broadcast: have %x", broadcasted)
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"math/big"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/dexon-foundation/dexon/accounts/abi"
 	"github.com/dexon-foundation/dexon/common"
@@ -260,29 +261,48 @@ type Recovery struct {
 	gov          *DexconGovernance
 	contract     common.Address
 	confirmation int
-	publicKey    string
-	privateKey   *ecdsa.PrivateKey
-	nodeAddress  common.Address
 	client       *ethrpc.EthRPC
+
+	keyMu       sync.RWMutex
+	publicKey   string
+	signTx      txSigner
+	nodeAddress common.Address
 }
 
 func NewRecovery(config *params.RecoveryConfig, networkRPC string,
-	gov *DexconGovernance, privKey *ecdsa.PrivateKey) *Recovery {
+	gov *DexconGovernance, pubKey *ecdsa.PublicKey, signTx txSigner) *Recovery {
 	client := ethrpc.New(networkRPC)
 	return &Recovery{
 		gov:          gov,
 		contract:     config.Contract,
 		confirmation: config.Confirmation,
-		publicKey:    hex.EncodeToString(crypto.FromECDSAPub(&privKey.PublicKey)),
-		privateKey:   privKey,
-		nodeAddress:  crypto.PubkeyToAddress(privKey.PublicKey),
 		client:       client,
+		publicKey:    hex.EncodeToString(crypto.FromECDSAPub(pubKey)),
+		signTx:       signTx,
+		nodeAddress:  crypto.PubkeyToAddress(*pubKey),
 	}
 }
 
+// setKey replaces the node key the skip block votes are sent with.
+func (r *Recovery) setKey(pubKey *ecdsa.PublicKey, signTx txSigner) {
+	r.keyMu.Lock()
+	defer r.keyMu.Unlock()
+	r.publicKey = hex.EncodeToString(crypto.FromECDSAPub(pubKey))
+	r.signTx = signTx
+	r.nodeAddress = crypto.PubkeyToAddress(*pubKey)
+}
+
+// account returns the node key the skip block votes are sent with.
+func (r *Recovery) account() (publicKey string, address common.Address, signTx txSigner) {
+	r.keyMu.RLock()
+	defer r.keyMu.RUnlock()
+	return r.publicKey, r.nodeAddress, r.signTx
+}
+
 func (r *Recovery) callRPC(data []byte, tag string) ([]byte, error) {
+	_, address, _ := r.account()
 	res, err := r.client.EthCall(ethrpc.T{
-		From: r.nodeAddress.String(),
+		From: address.String(),
 		To:   r.contract.String(),
 		Data: "0x" + hex.EncodeToString(data),
 	}, tag)
@@ -308,7 +328,8 @@ func (r *Recovery) genVoteForSkipBlockTx(height uint64) (*types.Transaction, err
 		return nil, err
 	}
 
-	data, err := abiObject.Pack("voted", big.NewInt(int64(height)), r.nodeAddress)
+	_, address, signTx := r.account()
+	data, err := abiObject.Pack("voted", big.NewInt(int64(height)), address)
 	if err != nil {
 		return nil, err
 	}
@@ -355,7 +376,7 @@ func (r *Recovery) genVoteForSkipBlockTx(height uint64) (*types.Transaction, err
 		return nil, err
 	}
 
-	nonce, err := r.client.EthGetTransactionCount(r.nodeAddress.String(), "pending")
+	nonce, err := r.client.EthGetTransactionCount(address.String(), "pending")
 	if err != nil {
 		return nil, err
 	}
@@ -372,8 +393,7 @@ func (r *Recovery) genVoteForSkipBlockTx(height uint64) (*types.Transaction, err
 		useGasPrice,
 		data)
 
-	signer := types.NewEIP155Signer(big.NewInt(int64(networkID)))
-	return types.SignTx(tx, signer, r.privateKey)
+	return signTx(tx, big.NewInt(int64(networkID)))
 }
 
 func (r *Recovery) ProposeSkipBlock(height uint64) error {
@@ -381,7 +401,8 @@ func (r *Recovery) ProposeSkipBlock(height uint64) error {
 	if err != nil {
 		return err
 	}
-	if _, ok := notarySet[r.publicKey]; !ok {
+	publicKey, _, _ := r.account()
+	if _, ok := notarySet[publicKey]; !ok {
 		return errors.New("not in notary set")
 	}
 
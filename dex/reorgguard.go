@@ -0,0 +1,96 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"sync"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/log"
+)
+
+// headerReader is the chain access needed to verify the ancestry of a head.
+type headerReader interface {
+	GetHeader(hash common.Hash, number uint64) *types.Header
+}
+
+// reorgGuard watches chain head events and reports any reorganization. Every
+// block delivered by DEXON consensus is final, so a reorg across them reveals
+// a serious bug and transactions reinjected by it must not be trusted.
+type reorgGuard struct {
+	chain headerReader
+	head  *types.Header
+
+	wg     sync.WaitGroup
+	quitCh chan struct{}
+}
+
+func newReorgGuard(chain headerReader, head *types.Header) *reorgGuard {
+	return &reorgGuard{
+		chain:  chain,
+		head:   head,
+		quitCh: make(chan struct{}),
+	}
+}
+
+// check verifies the new head extends the previous one. It returns false if
+// a reorg dropping finalized blocks is detected.
+func (g *reorgGuard) check(head *types.Header) bool {
+	prev := g.head
+	g.head = head
+	if prev == nil {
+		return true
+	}
+
+	if isAncestor(g.chain, prev, head) {
+		return true
+	}
+	finalizedReorgMeter.Mark(1)
+	log.Error("Reorg across finalized blocks detected",
+		"oldNumber", prev.Number, "oldHash", prev.Hash(),
+		"newNumber", head.Number, "newHash", head.Hash())
+	return false
+}
+
+func (g *reorgGuard) Start(bc *core.BlockChain) {
+	ch := make(chan core.ChainHeadEvent, 16)
+	sub := bc.SubscribeChainHeadEvent(ch)
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case ev := <-ch:
+				g.check(ev.Block.Header())
+			case <-sub.Err():
+				return
+			case <-g.quitCh:
+				return
+			}
+		}
+	}()
+}
+
+func (g *reorgGuard) Stop() {
+	close(g.quitCh)
+	g.wg.Wait()
+}
@@ -0,0 +1,75 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/types"
+)
+
+type testHeaderReader map[common.Hash]*types.Header
+
+func (r testHeaderReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return r[hash]
+}
+
+// makeTestHeaders creates a chain of n headers on top of parent, using extra
+// to distinguish forks.
+func (r testHeaderReader) makeTestHeaders(parent *types.Header, n int, extra byte) []*types.Header {
+	var headers []*types.Header
+	for i := 0; i < n; i++ {
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Number:     new(big.Int).Add(parent.Number, big.NewInt(1)),
+			Extra:      []byte{extra},
+		}
+		r[header.Hash()] = header
+		headers = append(headers, header)
+		parent = header
+	}
+	return headers
+}
+
+func TestReorgGuard(t *testing.T) {
+	reader := testHeaderReader{}
+	genesis := &types.Header{Number: big.NewInt(0)}
+	reader[genesis.Hash()] = genesis
+
+	guard := newReorgGuard(reader, genesis)
+	chain := reader.makeTestHeaders(genesis, 10, 0)
+	for i, header := range chain {
+		if !guard.check(header) {
+			t.Fatalf("reorg reported on extending head %d", i+1)
+		}
+	}
+
+	// A head skipping blocks but still extending the chain is allowed.
+	skip := reader.makeTestHeaders(chain[len(chain)-1], 3, 0)
+	if !guard.check(skip[len(skip)-1]) {
+		t.Fatal("reorg reported on extending head")
+	}
+
+	// A fork replacing finalized blocks, even if it is longer, is reported.
+	fork := reader.makeTestHeaders(chain[4], 20, 1)
+	if guard.check(fork[len(fork)-1]) {
+		t.Fatal("deep reorg not detected")
+	}
+}
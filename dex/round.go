@@ -0,0 +1,255 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"math"
+	"sync"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/event"
+	"github.com/dexon-foundation/dexon/metrics"
+)
+
+// roundLatencyLimit is the number of latest rounds whose latencies are kept.
+const roundLatencyLimit = 128
+
+// blockIntervalMinSamples is the number of completed rounds below which the
+// block interval estimated from round latencies is considered unreliable.
+const blockIntervalMinSamples = 3
+
+// RoundEvent is posted when the chain enters a new DEXON round.
+type RoundEvent struct {
+	Round     uint64
+	CRS       common.Hash
+	BeginTime uint64 // Timestamp in milliseconds of the first block of the round
+	EndTime   uint64 // Estimated from round length and minimum block interval
+}
+
+// RoundLatency is the time a round took from its first block to the first
+// block of the round after it.
+type RoundLatency struct {
+	Round     uint64
+	BeginTime uint64 // Timestamp in milliseconds of the first block of the round
+	EndTime   uint64 // Timestamp in milliseconds of the first block of the next round
+}
+
+// Latency returns the duration of the round in milliseconds.
+func (l RoundLatency) Latency() uint64 {
+	if l.EndTime < l.BeginTime {
+		return 0
+	}
+	return l.EndTime - l.BeginTime
+}
+
+// roundNotifier watches chain head events and notifies subscribers each time
+// the chain advances to a new round.
+type roundNotifier struct {
+	gov *DexconGovernance
+
+	mu        sync.Mutex
+	current   *RoundEvent
+	latencies []RoundLatency // Latest completed rounds, oldest first
+
+	latencyHist metrics.Histogram
+
+	mux      *event.TypeMux // Mux the typed round events are posted on, nil to disable
+	crsRound uint64         // Latest round with its CRS proposed
+
+	feed  event.Feed
+	scope event.SubscriptionScope
+
+	wg     sync.WaitGroup
+	quitCh chan struct{}
+}
+
+func newRoundNotifier(gov *DexconGovernance, bc *core.BlockChain,
+	latencyHist metrics.Histogram) *roundNotifier {
+	n := &roundNotifier{
+		gov:         gov,
+		latencyHist: latencyHist,
+		quitCh:      make(chan struct{}),
+	}
+	head := bc.CurrentBlock()
+	begin := bc.GetBlockByNumber(gov.GetRoundHeight(head.Round()))
+	if begin == nil {
+		begin = head
+	}
+	n.current = n.newRoundEvent(head.Round(), begin)
+	n.crsRound = gov.CRSRound()
+	return n
+}
+
+func (n *roundNotifier) newRoundEvent(round uint64, begin *types.Block) *RoundEvent {
+	config := n.gov.DexconConfiguration(round)
+	return &RoundEvent{
+		Round:     round,
+		CRS:       common.Hash(n.gov.CRS(round)),
+		BeginTime: begin.Time(),
+		EndTime:   begin.Time() + config.RoundLength*config.MinBlockInterval,
+	}
+}
+
+// onHead posts a RoundEvent for every round entered by the new head block,
+// and a CRSUpdateEvent if the block proposed the CRS of a new round.
+func (n *roundNotifier) onHead(block *types.Block) {
+	n.mu.Lock()
+	var events []RoundEvent
+	if n.current.Round < block.Round() {
+		// Only the round the chain leaves has blocks, the ones skipped over
+		// have no latency to record.
+		n.recordLatency(RoundLatency{
+			Round:     n.current.Round,
+			BeginTime: n.current.BeginTime,
+			EndTime:   block.Time(),
+		})
+	}
+	for n.current.Round < block.Round() {
+		n.current = n.newRoundEvent(n.current.Round+1, block)
+		events = append(events, *n.current)
+	}
+	n.mu.Unlock()
+
+	if len(events) > 0 {
+		// Refresh the round-scoped governance values before the round change
+		// is announced.
+		n.gov.Reload()
+	}
+	for _, ev := range events {
+		n.feed.Send(ev)
+	}
+	if n.mux == nil {
+		return
+	}
+	for _, ev := range events {
+		n.mux.Post(RoundChangeEvent{ev})
+	}
+	if crsRound := n.gov.CRSRound(); crsRound > n.crsRound {
+		n.crsRound = crsRound
+		n.mux.Post(CRSUpdateEvent{
+			Round: crsRound,
+			CRS:   common.Hash(n.gov.CRS(crsRound)),
+		})
+	}
+}
+
+// recordLatency keeps the latency of a completed round. The caller must hold
+// the lock.
+func (n *roundNotifier) recordLatency(l RoundLatency) {
+	if n.latencyHist != nil {
+		n.latencyHist.Update(int64(l.Latency()))
+	}
+	n.latencies = append(n.latencies, l)
+	if len(n.latencies) > roundLatencyLimit {
+		n.latencies = n.latencies[len(n.latencies)-roundLatencyLimit:]
+	}
+}
+
+// Latencies returns the latencies of at most the given number of latest
+// completed rounds, oldest first.
+func (n *roundNotifier) Latencies(count int) []RoundLatency {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if count > len(n.latencies) {
+		count = len(n.latencies)
+	}
+	latencies := make([]RoundLatency, count)
+	copy(latencies, n.latencies[len(n.latencies)-count:])
+	return latencies
+}
+
+// Current returns the round the chain is in.
+func (n *roundNotifier) Current() RoundEvent {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return *n.current
+}
+
+// SubscribeRoundEvent registers a subscription of RoundEvent.
+func (n *roundNotifier) SubscribeRoundEvent(ch chan<- RoundEvent) event.Subscription {
+	return n.scope.Track(n.feed.Subscribe(ch))
+}
+
+func (n *roundNotifier) Start(bc *core.BlockChain) {
+	ch := make(chan core.ChainHeadEvent, 16)
+	sub := bc.SubscribeChainHeadEvent(ch)
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case ev := <-ch:
+				n.onHead(ev.Block)
+			case <-sub.Err():
+				return
+			case <-n.quitCh:
+				return
+			}
+		}
+	}()
+}
+
+func (n *roundNotifier) Stop() {
+	close(n.quitCh)
+	n.wg.Wait()
+	n.scope.Close()
+}
+
+// estimateBlockInterval derives the block interval in milliseconds, with its
+// lower and upper bounds, from the latencies of completed rounds. With too few
+// rounds known, the bounds are widened around their mean interval, or around
+// the minimum block interval if none is known.
+func estimateBlockInterval(latencies []RoundLatency, roundLength func(round uint64) uint64,
+	minInterval uint64) (interval, lower, upper uint64, samples int) {
+	var intervals []float64
+	for _, l := range latencies {
+		if length := roundLength(l.Round); length > 0 {
+			intervals = append(intervals, float64(l.Latency())/float64(length))
+		}
+	}
+
+	var sum float64
+	for _, i := range intervals {
+		sum += i
+	}
+	if len(intervals) < blockIntervalMinSamples {
+		interval = minInterval
+		if len(intervals) > 0 {
+			interval = uint64(sum / float64(len(intervals)))
+		}
+		return interval, 0, 4 * interval, len(intervals)
+	}
+
+	mean := sum / float64(len(intervals))
+	var variance float64
+	for _, i := range intervals {
+		variance += (i - mean) * (i - mean)
+	}
+	deviation := math.Sqrt(variance / float64(len(intervals)))
+
+	low := mean - 2*deviation
+	if low < 0 {
+		low = 0
+	}
+	return uint64(mean), uint64(low), uint64(mean + 2*deviation), len(intervals)
+}
@@ -0,0 +1,105 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/consensus"
+	"github.com/dexon-foundation/dexon/core/types"
+)
+
+var errEmptySegment = errors.New("empty chain segment")
+
+// InvalidBlockError reports the first block of a chain segment failing
+// validation.
+type InvalidBlockError struct {
+	Index  int // Position of the block in the segment
+	Number uint64
+	Hash   common.Hash
+	Err    error
+}
+
+func (e *InvalidBlockError) Error() string {
+	return fmt.Sprintf("invalid block #%d %x (index %d): %v", e.Number, e.Hash, e.Index, e.Err)
+}
+
+// ValidateChainSegment validates a contiguous segment of blocks extending a
+// block of the local chain, as if it were imported. Each block is checked
+// against the rules of DexconApp and executed on the state left by the one
+// before, on a copy of the state never written to the database. The chain is
+// left untouched, the first invalid block is reported as an
+// InvalidBlockError.
+func (s *Dexon) ValidateChainSegment(blocks []*types.Block) error {
+	if len(blocks) == 0 {
+		return errEmptySegment
+	}
+	chain := s.blockchain
+	first := blocks[0]
+	parent := chain.GetBlock(first.ParentHash(), first.NumberU64()-1)
+	if parent == nil {
+		return &InvalidBlockError{Number: first.NumberU64(), Hash: first.Hash(),
+			Err: consensus.ErrUnknownAncestor}
+	}
+	statedb, err := chain.StateAt(parent.Root())
+	if err != nil {
+		return &InvalidBlockError{Number: first.NumberU64(), Hash: first.Hash(),
+			Err: fmt.Errorf("parent state unavailable: %v", err)}
+	}
+	statedb = statedb.Copy()
+
+	for i, block := range blocks {
+		err := s.validateSegmentBlock(block, parent)
+		if err == nil {
+			err = s.app.validateTransactions(block.Transactions(), block.Round(), statedb, nil, nil)
+		}
+		if err != nil {
+			return &InvalidBlockError{Index: i, Number: block.NumberU64(), Hash: block.Hash(), Err: err}
+		}
+		receipts, _, usedGas, err := chain.Processor().Process(block, statedb, *chain.GetVMConfig())
+		if err == nil {
+			err = chain.Validator().ValidateState(block, parent, statedb, receipts, usedGas)
+		}
+		if err != nil {
+			return &InvalidBlockError{Index: i, Number: block.NumberU64(), Hash: block.Hash(), Err: err}
+		}
+		parent = block
+	}
+	return nil
+}
+
+// validateSegmentBlock runs the checks of the block header not depending on
+// the state, the transactions are checked by the rules of DexconApp.
+func (s *Dexon) validateSegmentBlock(block, parent *types.Block) error {
+	if block.ParentHash() != parent.Hash() || block.NumberU64() != parent.NumberU64()+1 {
+		return fmt.Errorf("not linked to block #%d %x", parent.NumberU64(), parent.Hash())
+	}
+	if round := parent.Round(); block.Round() != round && block.Round() != round+1 {
+		return fmt.Errorf("invalid round %d after round %d", block.Round(), round)
+	}
+	header := block.Header()
+	if err := s.engine.VerifyHeader(s.blockchain, header, false); err != nil {
+		return err
+	}
+	if hash := types.DeriveSha(block.Transactions()); hash != header.TxHash {
+		return fmt.Errorf("transaction root hash mismatch: have %x, want %x", hash, header.TxHash)
+	}
+	return nil
+}
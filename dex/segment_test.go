@@ -0,0 +1,90 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/params"
+)
+
+func TestValidateChainSegment(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+
+	signer := types.NewEIP155Signer(dex.chainConfig.ChainID)
+	gasPrice := dex.governance.DexconConfiguration(0).MinGasPrice
+	var segment []*types.Block
+	for i := 0; i < 3; i++ {
+		tx, err := types.SignTx(types.NewTransaction(uint64(i), common.Address{1},
+			big.NewInt(1), params.TxGas, gasPrice, nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		block, err := deliverTestBlock(dex, key, 0, types.Transactions{tx})
+		if err != nil {
+			t.Fatalf("failed to deliver block: %v", err)
+		}
+		segment = append(segment, block)
+	}
+	// Rewind the chain, the segment is validated as received from a peer.
+	dex.blockchain.SetHead(0)
+	head := dex.blockchain.CurrentBlock().Hash()
+
+	if err := dex.ValidateChainSegment(segment); err != nil {
+		t.Fatalf("valid segment rejected: %v", err)
+	}
+	if dex.blockchain.CurrentBlock().Hash() != head {
+		t.Fatal("chain modified by validation")
+	}
+
+	// Corrupt the state root of the middle block.
+	header := segment[1].Header()
+	header.Root = common.Hash{1}
+	corrupted := []*types.Block{
+		segment[0],
+		types.NewBlockWithHeader(header).WithBody(segment[1].Transactions(), nil),
+		segment[2],
+	}
+	err = dex.ValidateChainSegment(corrupted)
+	invalid, ok := err.(*InvalidBlockError)
+	if !ok {
+		t.Fatalf("error mismatch: have %v, want %T", err, invalid)
+	}
+	if invalid.Index != 1 || invalid.Hash != corrupted[1].Hash() || invalid.Number != 2 {
+		t.Errorf("invalid block mismatch: %v", invalid)
+	}
+
+	// Segments not extending the local chain are rejected.
+	if err := dex.ValidateChainSegment(segment[1:]); err == nil {
+		t.Error("unlinked segment accepted")
+	}
+	if dex.blockchain.CurrentBlock().Hash() != head {
+		t.Fatal("chain modified by validation")
+	}
+}
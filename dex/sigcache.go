@@ -0,0 +1,72 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
+	lru "github.com/hashicorp/golang-lru"
+
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/log"
+)
+
+// voteSigCache remembers the votes the signature of which is verified, so
+// copies of a vote received again, e.g. from several peers or in responses to
+// vote pulls, are not verified again. The copies are delivered to consensus
+// all the same, the cache only saves their verification.
+//
+// A vote is keyed by the hash of its content, which covers the signer and the
+// position, along with its signature. Only a copy identical to a verified vote
+// hits the cache, a vote altered in any way is verified on its own.
+type voteSigCache struct {
+	cache *lru.Cache
+}
+
+func newVoteSigCache(size int) *voteSigCache {
+	cache, _ := lru.New(size)
+	return &voteSigCache{cache: cache}
+}
+
+// voteSigKey returns the key of the signed vote.
+func voteSigKey(vote *coreTypes.Vote) coreCommon.Hash {
+	hash := coreUtils.HashVote(vote)
+	return coreCommon.Hash(crypto.Keccak256Hash(hash[:],
+		[]byte(vote.Signature.Type), vote.Signature.Signature))
+}
+
+// verify reports whether the signature of the vote is valid, the signature
+// of a vote identical to one verified before is not checked again.
+func (c *voteSigCache) verify(vote *coreTypes.Vote) bool {
+	key := voteSigKey(vote)
+	if c.cache.Contains(key) {
+		voteSigCacheHitMeter.Mark(1)
+		return true
+	}
+	voteSigCacheMissMeter.Mark(1)
+	valid, err := coreUtils.VerifyVoteSignature(vote)
+	if err != nil {
+		log.Debug("Failed to verify vote signature", "vote", vote, "err", err)
+		return false
+	}
+	if valid {
+		c.cache.Add(key, struct{}{})
+	}
+	return valid
+}
@@ -0,0 +1,116 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"testing"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	coreEcdsa "github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
+
+	"github.com/dexon-foundation/dexon/crypto"
+)
+
+func newSignedTestVote(t testing.TB, round uint64) *coreTypes.Vote {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	vote := coreTypes.NewVote(coreTypes.VoteCom, coreCommon.Hash{1}, 1)
+	vote.Position = coreTypes.Position{Round: round, Height: 10}
+	signer := coreUtils.NewSigner(coreEcdsa.NewPrivateKeyFromECDSA(key))
+	if err := signer.SignVote(vote); err != nil {
+		t.Fatalf("failed to sign vote: %v", err)
+	}
+	return vote
+}
+
+func TestVoteSigCache(t *testing.T) {
+	c := newVoteSigCache(16)
+	vote := newSignedTestVote(t, 1)
+	cached := func(vote *coreTypes.Vote) bool {
+		return c.cache.Contains(voteSigKey(vote))
+	}
+
+	if !c.verify(vote) {
+		t.Fatal("valid vote rejected")
+	}
+	if !cached(vote.Clone()) {
+		t.Fatal("verified vote not cached")
+	}
+	if !c.verify(vote.Clone()) {
+		t.Fatal("copy of verified vote rejected")
+	}
+
+	// Votes differing from the verified one in the payload or the signature
+	// never hit the cache.
+	altered := vote.Clone()
+	altered.Period++
+	if cached(altered) || c.verify(altered) {
+		t.Error("altered payload accepted")
+	}
+	altered = vote.Clone()
+	altered.Position.Round++
+	if cached(altered) || c.verify(altered) {
+		t.Error("altered round accepted")
+	}
+	altered = vote.Clone()
+	altered.Signature.Signature = append([]byte{}, vote.Signature.Signature...)
+	altered.Signature.Signature[0] ^= 0xff
+	if cached(altered) || c.verify(altered) {
+		t.Error("altered signature accepted")
+	}
+	other := newSignedTestVote(t, 1)
+	altered = vote.Clone()
+	altered.Signature = other.Signature
+	if cached(altered) || c.verify(altered) {
+		t.Error("signature of another signer accepted")
+	}
+	// Invalid votes are not remembered.
+	if cached(altered) {
+		t.Error("invalid vote cached")
+	}
+
+	// The least recently verified votes are evicted.
+	for i := 0; i < 16; i++ {
+		c.verify(newSignedTestVote(t, 1))
+	}
+	if cached(vote) {
+		t.Error("vote not evicted")
+	}
+	if !c.verify(vote) {
+		t.Error("evicted vote rejected")
+	}
+}
+
+func BenchmarkVoteSigCache(b *testing.B) {
+	vote := newSignedTestVote(b, 1)
+	b.Run("verify", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			coreUtils.VerifyVoteSignature(vote)
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		c := newVoteSigCache(16)
+		for i := 0; i < b.N; i++ {
+			c.verify(vote)
+		}
+	})
+}
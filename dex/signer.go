@@ -0,0 +1,49 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+
+	"github.com/dexon-foundation/dexon/core/types"
+)
+
+// txSigner signs a transaction of the validator account for the chain.
+type txSigner func(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+
+// keyTxSigner returns a txSigner signing with the in-memory key.
+func keyTxSigner(key *ecdsa.PrivateKey) txSigner {
+	return func(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+		return types.SignTx(tx, types.NewEIP155Signer(chainID), key)
+	}
+}
+
+// validator returns the validator key and the consensus signer of the node,
+// which a key rotation replaces together.
+func (s *Dexon) validator() (*ecdsa.PrivateKey, *fencedSigner) {
+	s.keyMu.RLock()
+	defer s.keyMu.RUnlock()
+	return s.config.PrivateKey, s.signer
+}
+
+// validatorKey returns the public key of the validator.
+func (s *Dexon) validatorKey() *ecdsa.PublicKey {
+	key, _ := s.validator()
+	return &key.PublicKey
+}
@@ -0,0 +1,125 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
+
+	"github.com/dexon-foundation/dexon/common/hexutil"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+// Types of the slashing evidence.
+const (
+	EvidenceForkVote  = "forkVote"  // Conflicting votes of the same period
+	EvidenceForkBlock = "forkBlock" // Conflicting blocks of the same position
+)
+
+var (
+	errEvidenceNoConflict    = errors.New("messages do not conflict")
+	errEvidenceBadSignature  = errors.New("invalid message signature")
+	errEvidenceAlreadyFined  = errors.New("evidence already reported")
+	errEvidenceUnknownSigner = errors.New("signer not a registered node")
+)
+
+// SlashingEvidence is a pair of conflicting consensus messages signed by the
+// same node, proving it equivocated.
+type SlashingEvidence struct {
+	Type     string        `json:"type"`
+	Message1 hexutil.Bytes `json:"message1"` // RLP encoded vote or block
+	Message2 hexutil.Bytes `json:"message2"` // RLP encoded vote or block
+}
+
+// packSlashingEvidence validates the evidence against the governance state,
+// and packs it into the input of a report to the governance contract. Only
+// evidence the contract fines the signer for is accepted, so that the report
+// does not revert.
+func packSlashingEvidence(state *vm.GovernanceState, evidence *SlashingEvidence) ([]byte, error) {
+	var (
+		signer coreTypes.NodeID
+		data   []byte
+		err    error
+	)
+	switch evidence.Type {
+	case EvidenceForkVote:
+		var vote1, vote2 coreTypes.Vote
+		if err := rlp.DecodeBytes(evidence.Message1, &vote1); err != nil {
+			return nil, fmt.Errorf("invalid vote 1: %v", err)
+		}
+		if err := rlp.DecodeBytes(evidence.Message2, &vote2); err != nil {
+			return nil, fmt.Errorf("invalid vote 2: %v", err)
+		}
+		if vote1.ProposerID != vote2.ProposerID || vote1.Type != vote2.Type ||
+			vote1.Period != vote2.Period || vote1.Position != vote2.Position ||
+			vote1.BlockHash == vote2.BlockHash {
+			return nil, errEvidenceNoConflict
+		}
+		if ok, err := coreUtils.NeedPenaltyForkVote(&vote1, &vote2); err != nil || !ok {
+			return nil, errEvidenceBadSignature
+		}
+		signer = vote1.ProposerID
+		data, err = vm.PackReportForkVote(&vote1, &vote2)
+	case EvidenceForkBlock:
+		var block1, block2 coreTypes.Block
+		if err := rlp.DecodeBytes(evidence.Message1, &block1); err != nil {
+			return nil, fmt.Errorf("invalid block 1: %v", err)
+		}
+		if err := rlp.DecodeBytes(evidence.Message2, &block2); err != nil {
+			return nil, fmt.Errorf("invalid block 2: %v", err)
+		}
+		if block1.ProposerID != block2.ProposerID || block1.Position != block2.Position ||
+			block1.Hash == block2.Hash {
+			return nil, errEvidenceNoConflict
+		}
+		if ok, err := coreUtils.NeedPenaltyForkBlock(&block1, &block2); err == coreUtils.ErrPayloadNotEmpty {
+			return nil, errors.New("blocks with payload not accepted as evidence")
+		} else if err != nil || !ok {
+			return nil, errEvidenceBadSignature
+		}
+		signer = block1.ProposerID
+		data, err = vm.PackReportForkBlock(&block1, &block2)
+	default:
+		return nil, fmt.Errorf("unknown evidence type %q", evidence.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := state.GetNodeByID(signer); err != nil {
+		return nil, errEvidenceUnknownSigner
+	}
+	if state.FineRecords(evidenceRecordHash(evidence.Message1, evidence.Message2)) {
+		return nil, errEvidenceAlreadyFined
+	}
+	return data, nil
+}
+
+// evidenceRecordHash returns the hash the governance contract records fined
+// evidence by, which is independent of the order of the messages.
+func evidenceRecordHash(message1, message2 []byte) vm.Bytes32 {
+	if bytes.Compare(message1, message2) > 0 {
+		message1, message2 = message2, message1
+	}
+	return vm.Bytes32(crypto.Keccak256Hash(message1, message2))
+}
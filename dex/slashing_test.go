@@ -0,0 +1,154 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	coreEcdsa "github.com/dexon-foundation/dexon-consensus/core/crypto/ecdsa"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+	coreUtils "github.com/dexon-foundation/dexon-consensus/core/utils"
+
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/core/vm"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/params"
+	"github.com/dexon-foundation/dexon/rlp"
+)
+
+func TestSubmitSlashingEvidence(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	offender, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	stranger, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	dex, err := newTestDexon(key, offender)
+	if err != nil {
+		t.Fatalf("failed to create dexon: %v", err)
+	}
+	api := NewPrivateValidatorAPI(dex)
+
+	// Governance transactions are priced well above the minimum gas price;
+	// top up the reporter so it can afford one.
+	gasPrice := dex.governance.MinGasPrice(0)
+	topUp, err := types.SignTx(types.NewTransaction(0, crypto.PubkeyToAddress(key.PublicKey),
+		big.NewInt(4e16), params.TxGas, gasPrice, nil),
+		types.NewEIP155Signer(dex.chainConfig.ChainID), offender)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if _, err := deliverTestBlock(dex, key, 0, types.Transactions{topUp}); err != nil {
+		t.Fatalf("failed to deliver block: %v", err)
+	}
+	for i := 0; ; i++ {
+		if dex.txPool.State().GetNonce(crypto.PubkeyToAddress(offender.PublicKey)) == 1 {
+			break
+		}
+		if i == 100 {
+			t.Fatal("transaction pool not reset to the new head")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Conflicting votes for blocks at the same position.
+	vote := func(signer *coreUtils.Signer, hash coreCommon.Hash) []byte {
+		vote := coreTypes.NewVote(coreTypes.VoteCom, hash, 0)
+		vote.Position = coreTypes.Position{Round: 0, Height: 1}
+		if err := signer.SignVote(vote); err != nil {
+			t.Fatalf("failed to sign vote: %v", err)
+		}
+		data, err := rlp.EncodeToBytes(vote)
+		if err != nil {
+			t.Fatalf("failed to encode vote: %v", err)
+		}
+		return data
+	}
+	signer := coreUtils.NewSigner(coreEcdsa.NewPrivateKeyFromECDSA(offender))
+	vote1, vote2 := vote(signer, coreCommon.Hash{1}), vote(signer, coreCommon.Hash{2})
+	evidence := SlashingEvidence{Type: EvidenceForkVote, Message1: vote1, Message2: vote2}
+
+	if _, err := api.SubmitSlashingEvidence(context.Background(), evidence); err == nil {
+		t.Fatal("evidence accepted by a node not proposing blocks")
+	}
+	dex.config.BlockProposerEnabled = true
+
+	strangerSigner := coreUtils.NewSigner(coreEcdsa.NewPrivateKeyFromECDSA(stranger))
+	forged := append([]byte{}, vote2...)
+	forged[len(forged)-1] ^= 0xff
+	for i, invalid := range []SlashingEvidence{
+		{Type: EvidenceForkVote, Message1: vote1, Message2: vote1},
+		{Type: EvidenceForkVote, Message1: vote1, Message2: forged},
+		{Type: EvidenceForkVote, Message1: vote1, Message2: []byte{1, 2, 3}},
+		{Type: EvidenceForkVote, Message1: vote(strangerSigner, coreCommon.Hash{1}),
+			Message2: vote(strangerSigner, coreCommon.Hash{2})},
+		{Type: EvidenceForkBlock, Message1: vote1, Message2: vote2},
+		{Type: "unknown", Message1: vote1, Message2: vote2},
+	} {
+		if _, err := api.SubmitSlashingEvidence(context.Background(), invalid); err == nil {
+			t.Errorf("invalid evidence %d accepted", i)
+		}
+	}
+	if pending, _ := dex.txPool.Stats(); pending != 0 {
+		t.Fatalf("transactions sent for invalid evidence: %d", pending)
+	}
+
+	if _, err := api.SubmitSlashingEvidence(context.Background(), evidence); err != nil {
+		t.Fatalf("failed to submit evidence: %v", err)
+	}
+	pending, err := dex.txPool.Pending()
+	if err != nil {
+		t.Fatalf("failed to get pending transactions: %v", err)
+	}
+	txs := pending[crypto.PubkeyToAddress(key.PublicKey)]
+	if len(txs) != 1 {
+		t.Fatalf("slashing transaction count mismatch: have %d, want 1", len(txs))
+	}
+	method := vm.GovernanceABI.Name2Method["report"]
+	if to := txs[0].To(); to == nil || *to != vm.GovernanceContractAddress ||
+		!bytes.HasPrefix(txs[0].Data(), method.Id()) {
+		t.Fatalf("transaction is not a governance report: %v", txs[0])
+	}
+
+	// The report fines the offender once included.
+	if _, err := deliverTestBlock(dex, key, 0, types.Transactions(txs)); err != nil {
+		t.Fatalf("failed to deliver block: %v", err)
+	}
+	state := dex.governance.GetHeadState()
+	node, err := state.GetNodeByID(coreTypes.NewNodeID(coreEcdsa.NewPublicKeyFromECDSA(&offender.PublicKey)))
+	if err != nil {
+		t.Fatalf("failed to get offender: %v", err)
+	}
+	if want := state.FineValue(big.NewInt(vm.FineTypeForkVote)); node.Fined.Cmp(want) != 0 {
+		t.Errorf("fine mismatch: have %v, want %v", node.Fined, want)
+	}
+	if _, err := api.SubmitSlashingEvidence(context.Background(), evidence); err == nil {
+		t.Error("evidence accepted twice")
+	}
+}
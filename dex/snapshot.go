@@ -0,0 +1,161 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/dex/db"
+	"github.com/dexon-foundation/dexon/ethdb"
+	"github.com/dexon-foundation/dexon/log"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// Files of a snapshot written by Snapshot. The chain database directory is
+// named like the one of the node, restoring is copying it into a data
+// directory.
+const (
+	snapshotDatabase = "chaindata"
+	snapshotManifest = "snapshot.json"
+)
+
+var (
+	errSnapshotNotPersistent = errors.New("chain database is not persistent")
+	errSnapshotMemoryBlockDB = errors.New("consensus block database is kept in memory")
+)
+
+// SnapshotManifest describes the point a snapshot was taken at.
+type SnapshotManifest struct {
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+	Root   common.Hash `json:"root"`
+	Round  uint64      `json:"round"`
+	Time   time.Time   `json:"time"`
+}
+
+// Snapshot writes a consistent point in time copy of the chain database,
+// which also holds the consensus block database, into dir with a manifest of
+// the head block at the time. Block delivery and import are paused while the
+// head state is flushed and the database snapshot is taken, the copy itself
+// runs concurrently with them.
+func (s *Dexon) Snapshot(dir string) error {
+	chainDb, ok := s.chainDb.(*ethdb.LDBDatabase)
+	if !ok {
+		return errSnapshotNotPersistent
+	}
+	if _, ok := s.blockDB.(*db.DB); !ok {
+		return errSnapshotMemoryBlockDB
+	}
+	path := filepath.Join(dir, snapshotDatabase)
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("snapshot database %s already exists", path)
+	}
+
+	var (
+		snap     *leveldb.Snapshot
+		manifest *SnapshotManifest
+	)
+	s.app.appMu.Lock()
+	err := s.blockchain.FreezeHead(func(head *types.Block) (err error) {
+		if snap, err = chainDb.LDB().GetSnapshot(); err != nil {
+			return err
+		}
+		manifest = &SnapshotManifest{
+			Number: head.NumberU64(),
+			Hash:   head.Hash(),
+			Root:   head.Root(),
+			Round:  head.Round(),
+			Time:   time.Now(),
+		}
+		return nil
+	})
+	s.app.appMu.Unlock()
+	if err != nil {
+		return err
+	}
+	defer snap.Release()
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := copySnapshot(snap, path); err != nil {
+		os.RemoveAll(path)
+		return err
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, snapshotManifest), data, 0644); err != nil {
+		return err
+	}
+	log.Info("Wrote database snapshot", "dir", dir, "number", manifest.Number,
+		"hash", manifest.Hash, "round", manifest.Round)
+	return nil
+}
+
+// copySnapshot writes all the entries of the database snapshot into a new
+// database at path.
+func copySnapshot(snap *leveldb.Snapshot, path string) error {
+	dst, err := ethdb.NewLDBDatabase(path, 16, 16)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	it := snap.NewIterator(nil, nil)
+	defer it.Release()
+
+	batch := dst.NewBatch()
+	for it.Next() {
+		if err := batch.Put(it.Key(), it.Value()); err != nil {
+			return err
+		}
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// ReadSnapshotManifest reads the manifest of the snapshot written into dir.
+func ReadSnapshotManifest(dir string) (*SnapshotManifest, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, snapshotManifest))
+	if err != nil {
+		return nil, err
+	}
+	manifest := new(SnapshotManifest)
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
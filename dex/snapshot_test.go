@@ -0,0 +1,146 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"crypto/ecdsa"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/node"
+	"github.com/dexon-foundation/dexon/p2p"
+	"github.com/dexon-foundation/dexon/params"
+)
+
+// newTestPersistentNode starts a networkless node keeping its databases in
+// dir, validated by key alone.
+func newTestPersistentNode(t *testing.T, dir string, key *ecdsa.PrivateKey,
+	alloc core.GenesisAlloc) (*node.Node, *Dexon) {
+	stack, err := node.New(&node.Config{
+		Name:    "dex",
+		DataDir: dir,
+		P2P:     p2p.Config{PrivateKey: key, NoDiscovery: true, MaxPeers: 1},
+	})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	config := DefaultTestnetConfig(alloc, []*ecdsa.PublicKey{&key.PublicKey})
+	config.PrivateKey = key
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		return New(ctx, config)
+	}); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+	var dex *Dexon
+	if err := stack.Service(&dex); err != nil {
+		stack.Stop()
+		t.Fatalf("failed to get service: %v", err)
+	}
+	return stack, dex
+}
+
+func TestSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dex-snapshot")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	sender, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	alloc := core.GenesisAlloc{crypto.PubkeyToAddress(sender.PublicKey): {Balance: big.NewInt(params.Ether)}}
+
+	stack, dex := newTestPersistentNode(t, filepath.Join(dir, "origin"), key, alloc)
+	signer := types.NewEIP155Signer(dex.chainConfig.ChainID)
+	gasPrice := dex.governance.DexconConfiguration(0).MinGasPrice
+	deliver := func(dex *Dexon, nonce uint64) *types.Block {
+		tx, err := types.SignTx(types.NewTransaction(nonce, common.Address{1}, big.NewInt(1),
+			params.TxGas, gasPrice, nil), signer, sender)
+		if err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		block, err := deliverTestBlock(dex, key, 0, types.Transactions{tx})
+		if err != nil {
+			t.Fatalf("failed to deliver block: %v", err)
+		}
+		return block
+	}
+	deliver(dex, 0)
+	head := deliver(dex, 1)
+
+	snapDir := filepath.Join(dir, "snapshot")
+	if err := dex.Snapshot(snapDir); err != nil {
+		t.Fatalf("failed to snapshot: %v", err)
+	}
+	if err := dex.Snapshot(snapDir); err == nil {
+		t.Error("snapshot overwritten")
+	}
+	// Blocks after the snapshot are not part of it.
+	deliver(dex, 2)
+	stack.Stop()
+
+	manifest, err := ReadSnapshotManifest(snapDir)
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	if manifest.Number != head.NumberU64() || manifest.Hash != head.Hash() || manifest.Root != head.Root() {
+		t.Errorf("manifest mismatch: have %+v, want block %d %x", manifest, head.NumberU64(), head.Hash())
+	}
+
+	// Restore into a new node, which resumes from the snapshot head.
+	restored := filepath.Join(dir, "restored")
+	if err := os.MkdirAll(filepath.Join(restored, "dex"), 0755); err != nil {
+		t.Fatalf("failed to create data dir: %v", err)
+	}
+	if err := os.Rename(filepath.Join(snapDir, snapshotDatabase), filepath.Join(restored, "dex", "chaindata")); err != nil {
+		t.Fatalf("failed to restore snapshot: %v", err)
+	}
+	stack, dex = newTestPersistentNode(t, restored, key, alloc)
+	defer stack.Stop()
+
+	if current := dex.blockchain.CurrentBlock(); current.Hash() != manifest.Hash {
+		t.Fatalf("restored head mismatch: have %d %x, want %d %x",
+			current.NumberU64(), current.Hash(), manifest.Number, manifest.Hash)
+	}
+	if block := deliver(dex, 2); block.NumberU64() != manifest.Number+1 {
+		t.Errorf("resumed block number mismatch: have %d, want %d", block.NumberU64(), manifest.Number+1)
+	}
+	state, err := dex.blockchain.State()
+	if err != nil {
+		t.Fatalf("failed to get state: %v", err)
+	}
+	if nonce := state.GetNonce(crypto.PubkeyToAddress(sender.PublicKey)); nonce != 3 {
+		t.Errorf("sender nonce mismatch: have %d, want 3", nonce)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"context"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/state"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/rlp"
+	"github.com/dexon-foundation/dexon/trie"
+)
+
+// stateDiffStats counts the changes between two states.
+type stateDiffStats struct {
+	newAccounts     uint64
+	deletedAccounts uint64
+	changedSlots    uint64
+	sizeDelta       int64 // Size change of the trie leaves in bytes
+}
+
+// leafDiff returns the leaves of trie b missing or with another value in
+// trie a, by their hashed keys.
+func leafDiff(a, b state.Trie) (map[common.Hash][]byte, error) {
+	diff, _ := trie.NewDifferenceIterator(a.NodeIterator(nil), b.NodeIterator(nil))
+	it := trie.NewIterator(diff)
+	leaves := make(map[common.Hash][]byte)
+	for it.Next() {
+		leaves[common.BytesToHash(it.Key)] = it.Value
+	}
+	return leaves, it.Err
+}
+
+// trieDiff returns the leaves added or changed, and removed or changed, from
+// trie a to trie b.
+func trieDiff(a, b state.Trie) (added, removed map[common.Hash][]byte, err error) {
+	if added, err = leafDiff(a, b); err != nil {
+		return nil, nil, err
+	}
+	if removed, err = leafDiff(b, a); err != nil {
+		return nil, nil, err
+	}
+	return added, removed, nil
+}
+
+// leavesSize returns the size of the keys and values of the leaves.
+func leavesSize(leaves map[common.Hash][]byte) int64 {
+	var size int64
+	for _, value := range leaves {
+		size += int64(common.HashLength + len(value))
+	}
+	return size
+}
+
+// diffState compares the states of the given roots. The size delta counts the
+// leaves of the account and storage tries only, not the intermediate nodes,
+// so it's an approximation of the growth of the state on disk.
+func diffState(ctx context.Context, db state.Database, oldRoot, newRoot common.Hash) (*stateDiffStats, error) {
+	oldTrie, err := db.OpenTrie(oldRoot)
+	if err != nil {
+		return nil, err
+	}
+	newTrie, err := db.OpenTrie(newRoot)
+	if err != nil {
+		return nil, err
+	}
+	added, removed, err := trieDiff(oldTrie, newTrie)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &stateDiffStats{sizeDelta: leavesSize(added) - leavesSize(removed)}
+	storageRoot := func(leaves map[common.Hash][]byte, key common.Hash) (common.Hash, error) {
+		blob, ok := leaves[key]
+		if !ok {
+			return types.EmptyRootHash, nil
+		}
+		var account state.Account
+		if err := rlp.DecodeBytes(blob, &account); err != nil {
+			return common.Hash{}, err
+		}
+		return account.Root, nil
+	}
+	// Accounts changed are in both sets, created ones only in the added and
+	// deleted ones only in the removed.
+	keys := make(map[common.Hash]struct{}, len(added))
+	for key := range added {
+		if _, ok := removed[key]; !ok {
+			stats.newAccounts++
+		}
+		keys[key] = struct{}{}
+	}
+	for key := range removed {
+		if _, ok := added[key]; !ok {
+			stats.deletedAccounts++
+		}
+		keys[key] = struct{}{}
+	}
+	for key := range keys {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		oldStorageRoot, err := storageRoot(removed, key)
+		if err != nil {
+			return nil, err
+		}
+		newStorageRoot, err := storageRoot(added, key)
+		if err != nil {
+			return nil, err
+		}
+		if oldStorageRoot == newStorageRoot {
+			continue
+		}
+		oldStorage, err := db.OpenStorageTrie(key, oldStorageRoot)
+		if err != nil {
+			return nil, err
+		}
+		newStorage, err := db.OpenStorageTrie(key, newStorageRoot)
+		if err != nil {
+			return nil, err
+		}
+		addedSlots, removedSlots, err := trieDiff(oldStorage, newStorage)
+		if err != nil {
+			return nil, err
+		}
+		stats.changedSlots += uint64(len(addedSlots))
+		for slot := range removedSlots {
+			if _, ok := addedSlots[slot]; !ok {
+				stats.changedSlots++
+			}
+		}
+		stats.sizeDelta += leavesSize(addedSlots) - leavesSize(removedSlots)
+	}
+	return stats, nil
+}
@@ -0,0 +1,318 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+// Package testnode runs a network of DEXON nodes in a single process for
+// testing. The nodes share a genesis registering all of them as validators,
+// keep their databases in memory and are connected over in-memory pipes, so
+// links between them can be cut to partition the network.
+package testnode
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/dexon-foundation/dexon/core"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/dex"
+	"github.com/dexon-foundation/dexon/dex/db"
+	"github.com/dexon-foundation/dexon/node"
+	"github.com/dexon-foundation/dexon/p2p"
+	"github.com/dexon-foundation/dexon/p2p/enode"
+)
+
+var errPartitioned = errors.New("link cut by partition")
+
+// Config is the configuration of a test network.
+type Config struct {
+	Nodes            int           // Number of validator nodes
+	RoundLength      uint64        // Number of blocks of a round
+	MinBlockInterval uint64        // Minimum interval between blocks in milliseconds
+	LambdaBA         uint64        // Agreement timeout in milliseconds
+	LambdaDKG        uint64        // DKG phase timeout in milliseconds
+	StartDelay       time.Duration // Time before consensus begins, for the nodes to connect
+
+	// Configure adjusts the configuration of each node before it's created.
+	Configure func(i int, config *dex.Config)
+}
+
+// DefaultConfig is a network of 4 nodes agreeing on blocks quickly.
+var DefaultConfig = Config{
+	Nodes:            4,
+	RoundLength:      60,
+	MinBlockInterval: 500,
+	LambdaBA:         250,
+	LambdaDKG:        1000,
+	StartDelay:       3 * time.Second,
+}
+
+// Node is a node of the test network.
+type Node struct {
+	Key   *ecdsa.PrivateKey
+	Stack *node.Node
+	Dexon *dex.Dexon
+
+	enode *enode.Node
+}
+
+// Network is a set of DEXON nodes connected in process.
+type Network struct {
+	Genesis *core.Genesis
+	Nodes   []*Node
+
+	mu  sync.Mutex
+	cut map[[2]enode.ID]struct{} // Links cut by partitions, both directions
+}
+
+// New creates a network of validator nodes with the given configuration. The
+// nodes are not started.
+func New(config Config) (*Network, error) {
+	if config.Nodes <= 0 {
+		return nil, fmt.Errorf("invalid node count %d", config.Nodes)
+	}
+	n := &Network{cut: make(map[[2]enode.ID]struct{})}
+	keys := make([]*ecdsa.PrivateKey, config.Nodes)
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+	}
+	n.Genesis = newGenesis(config, keys)
+
+	for i, key := range keys {
+		stack, err := node.New(&node.Config{
+			Name: fmt.Sprintf("testnode%d", i),
+			P2P: p2p.Config{
+				PrivateKey:  key,
+				MaxPeers:    config.Nodes * 2,
+				NoDiscovery: true,
+				Dialer:      &dialer{network: n, from: enode.PubkeyToIDV4(&key.PublicKey)},
+			},
+			NoUSB: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		dexConfig := dex.DefaultConfig
+		dexConfig.Genesis = n.Genesis
+		dexConfig.NetworkId = n.Genesis.Config.ChainID.Uint64()
+		dexConfig.PrivateKey = key
+		dexConfig.BlockProposerEnabled = true
+		dexConfig.BlockDBEngine = db.EngineMemory
+		if config.Configure != nil {
+			config.Configure(i, &dexConfig)
+		}
+		if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+			return dex.New(ctx, &dexConfig)
+		}); err != nil {
+			return nil, err
+		}
+		// The pipes are set up by the dialer regardless of the address,
+		// which only has to be complete for the node to be dialed.
+		n.Nodes = append(n.Nodes, &Node{
+			Key:   key,
+			Stack: stack,
+			enode: enode.NewV4(&key.PublicKey, net.IP{127, 0, 0, 1}, 30303+i, 0),
+		})
+	}
+	return n, nil
+}
+
+// newGenesis creates a genesis registering the keys as validators, with the
+// consensus beginning after the start delay.
+func newGenesis(config Config, keys []*ecdsa.PrivateKey) *core.Genesis {
+	alloc := make(core.GenesisAlloc, len(keys))
+	validators := make([]*ecdsa.PublicKey, len(keys))
+	for i, key := range keys {
+		alloc[crypto.PubkeyToAddress(key.PublicKey)] = core.GenesisAccount{
+			Balance: new(big.Int).Mul(big.NewInt(1e18), big.NewInt(1e6)),
+		}
+		validators[i] = &key.PublicKey
+	}
+	genesis := dex.DefaultTestnetConfig(alloc, validators).Genesis
+
+	chainConfig := genesis.Config
+	chainConfig.DMoment = uint64(time.Now().Add(config.StartDelay).Unix()) + 1
+	chainConfig.Dexcon.RoundLength = config.RoundLength
+	chainConfig.Dexcon.LambdaBA = config.LambdaBA
+	chainConfig.Dexcon.LambdaDKG = config.LambdaDKG
+	chainConfig.Dexcon.MinBlockInterval = config.MinBlockInterval
+	genesis.Timestamp = chainConfig.DMoment * 1000
+	return genesis
+}
+
+// Start starts all the nodes and connects each of them to the others.
+func (n *Network) Start() error {
+	for _, node := range n.Nodes {
+		if err := node.Stack.Start(); err != nil {
+			n.Stop()
+			return err
+		}
+		if err := node.Stack.Service(&node.Dexon); err != nil {
+			n.Stop()
+			return err
+		}
+	}
+	for i, node := range n.Nodes {
+		for _, peer := range n.Nodes[i+1:] {
+			node.Stack.Server().AddPeer(peer.enode)
+		}
+	}
+	return nil
+}
+
+// Stop stops all the nodes.
+func (n *Network) Stop() {
+	for _, node := range n.Nodes {
+		node.Stack.Stop()
+	}
+}
+
+// node returns the node of the given ID.
+func (n *Network) node(id enode.ID) *Node {
+	for _, node := range n.Nodes {
+		if node.enode.ID() == id {
+			return node
+		}
+	}
+	return nil
+}
+
+// Partition cuts the links between the given groups of nodes, by their
+// indexes, disconnecting them. Nodes in no group keep their links.
+func (n *Network) Partition(groups ...[]int) {
+	n.mu.Lock()
+	for i, group := range groups {
+		for _, other := range groups[i+1:] {
+			for _, a := range group {
+				for _, b := range other {
+					ida, idb := n.Nodes[a].enode.ID(), n.Nodes[b].enode.ID()
+					n.cut[[2]enode.ID{ida, idb}] = struct{}{}
+					n.cut[[2]enode.ID{idb, ida}] = struct{}{}
+				}
+			}
+		}
+	}
+	n.mu.Unlock()
+
+	for _, node := range n.Nodes {
+		for _, peer := range node.Stack.Server().Peers() {
+			if n.isCut(node.enode.ID(), peer.ID()) {
+				peer.Disconnect(p2p.DiscRequested)
+			}
+		}
+	}
+}
+
+// Heal restores all the links cut by partitions. The nodes reconnect as they
+// redial their peers.
+func (n *Network) Heal() {
+	n.mu.Lock()
+	n.cut = make(map[[2]enode.ID]struct{})
+	n.mu.Unlock()
+}
+
+func (n *Network) isCut(from, to enode.ID) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, ok := n.cut[[2]enode.ID{from, to}]
+	return ok
+}
+
+// WaitForBlock waits until every node has finalized the block of the given
+// number.
+func (n *Network) WaitForBlock(number uint64, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for _, node := range n.Nodes {
+		for node.Dexon.BlockChain().CurrentBlock().NumberU64() < number {
+			if time.Now().After(deadline) {
+				return fmt.Errorf("node %x: block %d not finalized in %v, head %d",
+					node.enode.ID().Bytes()[:4], number, timeout,
+					node.Dexon.BlockChain().CurrentBlock().NumberU64())
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	return nil
+}
+
+// WaitForRound waits until every node has finalized a block of the given
+// round. Rounds advance every RoundLength blocks, the DKG of a round is run
+// by the nodes in the round before it.
+func (n *Network) WaitForRound(round uint64, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for _, node := range n.Nodes {
+		for node.Dexon.BlockChain().CurrentBlock().Round() < round {
+			if time.Now().After(deadline) {
+				return fmt.Errorf("node %x: round %d not reached in %v, round %d",
+					node.enode.ID().Bytes()[:4], round, timeout,
+					node.Dexon.BlockChain().CurrentBlock().Round())
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	return nil
+}
+
+// AdvanceRound drives the network into the round after the latest one any
+// node has reached, and returns it. The nodes keep agreeing on blocks until
+// every one of them has finalized a block of the new round. The round has to
+// be long enough for the configuration of the next one to be agreed on before
+// it ends, the network stalls on rounds much shorter than DefaultConfig's.
+func (n *Network) AdvanceRound(timeout time.Duration) (uint64, error) {
+	var round uint64
+	for _, node := range n.Nodes {
+		if r := node.Dexon.BlockChain().CurrentBlock().Round(); r > round {
+			round = r
+		}
+	}
+	round++
+	if err := n.WaitForRound(round, timeout); err != nil {
+		return 0, err
+	}
+	return round, nil
+}
+
+// dialer connects a node to the others over in-memory pipes.
+type dialer struct {
+	network *Network
+	from    enode.ID
+}
+
+// Dial implements p2p.NodeDialer.
+func (d *dialer) Dial(dest *enode.Node) (net.Conn, error) {
+	if d.network.isCut(d.from, dest.ID()) {
+		return nil, errPartitioned
+	}
+	node := d.network.node(dest.ID())
+	if node == nil {
+		return nil, fmt.Errorf("unknown node %s", dest.ID())
+	}
+	srv := node.Stack.Server()
+	if srv == nil {
+		return nil, fmt.Errorf("node %s not running", dest.ID())
+	}
+	local, remote := net.Pipe()
+	go srv.SetupConn(remote, 0, nil)
+	return local, nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package testnode
+
+import (
+	"flag"
+	"testing"
+	"time"
+)
+
+var longrunning = flag.Bool("longrunning", false, "do run long-running tests")
+
+func TestNetworkFinalizesBlocks(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping network test in short mode")
+	}
+	network, err := New(DefaultConfig)
+	if err != nil {
+		t.Fatalf("failed to create network: %v", err)
+	}
+	if err := network.Start(); err != nil {
+		t.Fatalf("failed to start network: %v", err)
+	}
+	defer network.Stop()
+
+	if err := network.WaitForBlock(1, time.Minute); err != nil {
+		t.Fatalf("failed to finalize block: %v", err)
+	}
+	want := network.Nodes[0].Dexon.BlockChain().GetBlockByNumber(1).Hash()
+	for i, node := range network.Nodes[1:] {
+		if have := node.Dexon.BlockChain().GetBlockByNumber(1).Hash(); have != want {
+			t.Errorf("node %d: block 1 mismatch: have %x, want %x", i+1, have, want)
+		}
+	}
+}
+
+// TestNetworkAdvancesRound runs a whole round, which takes a minute or more
+// depending on the load of the machine. It only runs with -longrunning.
+func TestNetworkAdvancesRound(t *testing.T) {
+	if !*longrunning {
+		t.Skip("skipping long-running network test, run with -longrunning")
+	}
+	network, err := New(DefaultConfig)
+	if err != nil {
+		t.Fatalf("failed to create network: %v", err)
+	}
+	if err := network.Start(); err != nil {
+		t.Fatalf("failed to start network: %v", err)
+	}
+	defer network.Stop()
+
+	round, err := network.AdvanceRound(2 * time.Minute)
+	if err != nil {
+		t.Fatalf("failed to advance round: %v", err)
+	}
+	if round != 1 {
+		t.Fatalf("round mismatch: have %d, want 1", round)
+	}
+	// The nodes agree on the first block of the new round.
+	chain := network.Nodes[0].Dexon.BlockChain()
+	number := chain.CurrentBlock().NumberU64()
+	for chain.GetBlockByNumber(number-1).Round() == round {
+		number--
+	}
+	want := chain.GetBlockByNumber(number).Hash()
+	for i, node := range network.Nodes[1:] {
+		if have := node.Dexon.BlockChain().GetBlockByNumber(number).Hash(); have != want {
+			t.Errorf("node %d: block %d mismatch: have %x, want %x", i+1, number, have, want)
+		}
+	}
+}
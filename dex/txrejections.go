@@ -0,0 +1,139 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/dexon-foundation/dexon/core/types"
+)
+
+const (
+	// txRejectionRate is the number of transaction rejections notified per
+	// second to a subscriber, the ones beyond are dropped.
+	txRejectionRate = 50
+
+	// txRejectionBuffer is the number of rejections queued for a subscriber,
+	// the ones beyond are dropped until the subscriber catches up.
+	txRejectionBuffer = 128
+)
+
+// txRejectionFeed reports the inbound transactions rejected by the
+// transaction pool, from peers or submitted through the API. Rejections
+// are only collected while subscribed. Reporting never blocks: it runs on
+// the peer message handling and RPC paths, so a slow subscriber only loses
+// its own notifications.
+type txRejectionFeed struct {
+	lock   sync.Mutex
+	subs   map[*txRejectionSub]struct{}
+	closed bool
+}
+
+// txRejectionSub is a subscription of TxRejectionEvent.
+type txRejectionSub struct {
+	feed    *txRejectionFeed
+	ch      chan TxRejectionEvent
+	limiter *msgRateLimiter
+	quit    chan struct{}
+	once    sync.Once
+}
+
+// subscribe registers a subscription of TxRejectionEvent.
+func (f *txRejectionFeed) subscribe() *txRejectionSub {
+	sub := &txRejectionSub{
+		feed:    f,
+		ch:      make(chan TxRejectionEvent, txRejectionBuffer),
+		limiter: newMsgRateLimiter(txRejectionRate),
+		quit:    make(chan struct{}),
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		close(sub.quit)
+		return sub
+	}
+	if f.subs == nil {
+		f.subs = make(map[*txRejectionSub]struct{})
+	}
+	f.subs[sub] = struct{}{}
+	return sub
+}
+
+// count returns the number of subscriptions.
+func (f *txRejectionFeed) count() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return len(f.subs)
+}
+
+// report sends an event for each of the transactions rejected with an error.
+// Transactions already known to the pool, like the ones relayed by several
+// peers, are not rejections.
+func (f *txRejectionFeed) report(txs []*types.Transaction, errs []error) {
+	if f == nil {
+		return
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if len(f.subs) == 0 {
+		return
+	}
+	for i, err := range errs {
+		if err == nil || strings.HasPrefix(err.Error(), "known transaction") {
+			continue
+		}
+		ev := TxRejectionEvent{Tx: txs[i], Err: err}
+		for sub := range f.subs {
+			if ok, _ := sub.limiter.allow(); !ok {
+				continue
+			}
+			select {
+			case sub.ch <- ev:
+			default:
+			}
+		}
+	}
+}
+
+// close terminates all the subscriptions.
+func (f *txRejectionFeed) close() {
+	if f == nil {
+		return
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.closed = true
+	for sub := range f.subs {
+		sub.once.Do(func() { close(sub.quit) })
+	}
+	f.subs = nil
+}
+
+// Unsubscribe removes the subscription from the feed.
+func (s *txRejectionSub) Unsubscribe() {
+	s.feed.lock.Lock()
+	defer s.feed.lock.Unlock()
+
+	delete(s.feed.subs, s)
+	s.once.Do(func() { close(s.quit) })
+}
@@ -0,0 +1,80 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/types"
+)
+
+// Tests that reporting never blocks on a subscriber not reading, and that the
+// rate limit applies before queueing.
+func TestTxRejectionFeedNonBlocking(t *testing.T) {
+	feed := new(txRejectionFeed)
+	limited := feed.subscribe()
+	slow := feed.subscribe()
+	slow.limiter = newMsgRateLimiter(4 * txRejectionBuffer)
+
+	txs := make([]*types.Transaction, 2*txRejectionBuffer)
+	errs := make([]error, len(txs))
+	for i := range txs {
+		txs[i] = types.NewTransaction(uint64(i), common.Address{1}, big.NewInt(1), 21000, big.NewInt(1), nil)
+		errs[i] = errors.New("rejected")
+	}
+	done := make(chan struct{})
+	go func() {
+		feed.report(txs, errs)
+		close(done)
+	}()
+	<-done
+
+	if n := len(limited.ch); n != txRejectionRate {
+		t.Errorf("rate limited rejections mismatch: have %d, want %d", n, txRejectionRate)
+	}
+	if n := len(slow.ch); n != txRejectionBuffer {
+		t.Errorf("queued rejections mismatch: have %d, want %d", n, txRejectionBuffer)
+	}
+	limited.Unsubscribe()
+
+	// A new subscriber gets its own rate and is not affected by the full one.
+	fast := feed.subscribe()
+	feed.report(txs[:1], errs[:1])
+	if n := len(fast.ch); n != 1 {
+		t.Errorf("queued rejections mismatch: have %d, want 1", n)
+	}
+	fast.Unsubscribe()
+	if feed.count() != 1 {
+		t.Errorf("subscription count mismatch: have %d, want 1", feed.count())
+	}
+	select {
+	case <-fast.quit:
+	default:
+		t.Error("unsubscribed subscription not terminated")
+	}
+
+	feed.close()
+	select {
+	case <-slow.quit:
+	default:
+		t.Error("subscription not terminated on close")
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/log"
+	"github.com/dexon-foundation/dexon/p2p/enode"
+)
+
+// AddValidatorPeer pins the node as a validator peer: the connection to it is
+// maintained and it is exempt from the peer limit. The pinned peers are
+// persisted and restored on startup.
+func (s *Dexon) AddValidatorPeer(node *enode.Node) error {
+	s.protocolManager.peers.AddValidatorPeer(node)
+	return s.persistValidatorPeers()
+}
+
+// RemoveValidatorPeer unpins the validator peer. It reports whether the peer
+// was pinned.
+func (s *Dexon) RemoveValidatorPeer(node *enode.Node) (bool, error) {
+	if !s.protocolManager.peers.RemoveValidatorPeer(node.ID().String()) {
+		return false, nil
+	}
+	return true, s.persistValidatorPeers()
+}
+
+func (s *Dexon) persistValidatorPeers() error {
+	nodes := s.protocolManager.peers.ValidatorPeers()
+	urls := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		urls = append(urls, node.String())
+	}
+	return rawdb.WriteValidatorPeers(s.chainDb, urls)
+}
+
+// restoreValidatorPeers pins the validator peers persisted by the previous
+// runs. The protocol manager must be started.
+func (s *Dexon) restoreValidatorPeers() {
+	for _, url := range rawdb.ReadValidatorPeers(s.chainDb) {
+		node, err := enode.ParseV4(url)
+		if err != nil {
+			log.Warn("Dropped invalid validator peer", "url", url, "err", err)
+			continue
+		}
+		s.protocolManager.peers.AddValidatorPeer(node)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"errors"
+	"sync"
+
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/ethdb"
+)
+
+var errConflictingVote = errors.New("conflicting vote already cast")
+
+// voteHistory keeps the votes cast by the node in the chain database, as an
+// auditable record that it never voted for conflicting blocks. Votes are
+// recorded with their signature when cast, a vote conflicting with the record
+// never leaves the node. Only the votes of the latest rounds are kept.
+type voteHistory struct {
+	db     ethdb.Database
+	nodeID coreTypes.NodeID
+	rounds uint64 // Number of rounds of which votes are kept, zero for all
+	pruned uint64 // Rounds below are pruned
+
+	lock sync.Mutex
+}
+
+// voteSlot is the position, period and type a node votes at most once for.
+type voteSlot struct {
+	voteType uint8
+	round    uint64
+	height   uint64
+	period   uint64
+}
+
+func newVoteSlot(vote *coreTypes.Vote) voteSlot {
+	return voteSlot{
+		voteType: uint8(vote.Type),
+		round:    vote.Position.Round,
+		height:   vote.Position.Height,
+		period:   vote.Period,
+	}
+}
+
+func newVoteHistory(db ethdb.Database, nodeID coreTypes.NodeID, rounds uint64) *voteHistory {
+	return &voteHistory{db: db, nodeID: nodeID, rounds: rounds}
+}
+
+// isOwn reports whether the node ID is the one of the node.
+func (h *voteHistory) isOwn(id coreTypes.NodeID) bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return h.nodeID == id
+}
+
+// setNodeID changes the node ID the votes are recorded for, after a key
+// rotation.
+func (h *voteHistory) setNodeID(id coreTypes.NodeID) {
+	h.lock.Lock()
+	h.nodeID = id
+	h.lock.Unlock()
+}
+
+// voted returns the block the node voted for at the slot, and whether it did.
+func (h *voteHistory) voted(slot voteSlot) (common.Hash, bool) {
+	return rawdb.ReadVotedBlockHash(h.db, slot.voteType, slot.round, slot.height, slot.period)
+}
+
+// record persists a vote of the node. It refuses the vote if the node voted
+// for another block at the same position, period and type before. Votes cast
+// again, like the ones re-broadcast, are recorded only once.
+func (h *voteHistory) record(vote *coreTypes.Vote) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	slot := newVoteSlot(vote)
+	if hash, voted := h.voted(slot); voted {
+		if hash != common.Hash(vote.BlockHash) {
+			return errConflictingVote
+		}
+		return nil
+	}
+	round := vote.Position.Round
+	batch := h.db.NewBatch()
+	err := rawdb.WriteVoteRecord(batch, rawdb.ReadVoteRecordCount(h.db, round), &rawdb.VoteRecord{
+		Type:          uint8(vote.Type),
+		Round:         round,
+		Height:        vote.Position.Height,
+		Period:        vote.Period,
+		BlockHash:     common.Hash(vote.BlockHash),
+		SignatureType: vote.Signature.Type,
+		Signature:     vote.Signature.Signature,
+	})
+	if err != nil {
+		return err
+	}
+	if err := h.prune(batch, round); err != nil {
+		return err
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// prune removes the votes of the rounds too old to be kept at the given
+// round.
+func (h *voteHistory) prune(batch ethdb.Batch, round uint64) error {
+	if h.rounds == 0 || round < h.rounds {
+		return nil
+	}
+	for ; h.pruned <= round-h.rounds; h.pruned++ {
+		count := rawdb.ReadVoteRecordCount(h.db, h.pruned)
+		for index := uint64(0); index < count; index++ {
+			record := rawdb.ReadVoteRecord(h.db, h.pruned, index)
+			if record == nil {
+				continue
+			}
+			if err := rawdb.DeleteVoteRecord(batch, index, record); err != nil {
+				return err
+			}
+		}
+		if count > 0 {
+			if err := rawdb.DeleteVoteRecordCount(batch, h.pruned); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// votes returns the votes cast by the node in the given inclusive range of
+// rounds, in the order they were cast.
+func (h *voteHistory) votes(fromRound, toRound uint64) []*rawdb.VoteRecord {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	var records []*rawdb.VoteRecord
+	if fromRound > toRound {
+		return records
+	}
+	for round := fromRound; ; round++ {
+		count := rawdb.ReadVoteRecordCount(h.db, round)
+		for index := uint64(0); index < count; index++ {
+			if record := rawdb.ReadVoteRecord(h.db, round, index); record != nil {
+				records = append(records, record)
+			}
+		}
+		if round == toRound {
+			return records
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2018 The dexon-consensus Authors
+// This file is part of the dexon-consensus library.
+//
+// The dexon-consensus library is free software: you can redistribute it
+// and/or modify it under the terms of the GNU Lesser General Public License as
+// published by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// The dexon-consensus library is distributed in the hope that it will be
+// useful, but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU Lesser
+// General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the dexon-consensus library. If not, see
+// <http://www.gnu.org/licenses/>.
+
+package dex
+
+import (
+	"testing"
+
+	coreCommon "github.com/dexon-foundation/dexon-consensus/common"
+	coreCrypto "github.com/dexon-foundation/dexon-consensus/core/crypto"
+	coreTypes "github.com/dexon-foundation/dexon-consensus/core/types"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/rawdb"
+	"github.com/dexon-foundation/dexon/dex/downloader"
+	"github.com/dexon-foundation/dexon/ethdb"
+)
+
+func TestVoteHistoryRefusesConflictingVote(t *testing.T) {
+	pm, db := newTestProtocolManagerMust(t, downloader.FullSync, 0, nil, nil)
+	defer pm.Stop()
+
+	nodeID := coreTypes.NodeID{Hash: coreCommon.Hash{1}}
+	network := NewDexconNetwork(pm)
+	network.voteHistory = newVoteHistory(db, nodeID, 0)
+
+	newVote := func(proposer coreTypes.NodeID, hash coreCommon.Hash, period uint64) *coreTypes.Vote {
+		vote := coreTypes.NewVote(coreTypes.VoteCom, hash, period)
+		vote.ProposerID = proposer
+		vote.Position = coreTypes.Position{Round: 1, Height: 10}
+		vote.Signature = coreCrypto.Signature{Type: "ecdsa", Signature: hash[:]}
+		return vote
+	}
+	vote := newVote(nodeID, coreCommon.Hash{2}, 0)
+	network.BroadcastVote(vote)
+	// Re-broadcasting the same vote is recorded only once.
+	network.BroadcastVote(vote)
+	// A vote of another node is not recorded.
+	network.BroadcastVote(newVote(coreTypes.NodeID{Hash: coreCommon.Hash{3}}, coreCommon.Hash{4}, 0))
+
+	conflict := newVote(nodeID, coreCommon.Hash{5}, 0)
+	network.BroadcastVote(conflict)
+	if err := network.voteHistory.record(conflict); err != errConflictingVote {
+		t.Errorf("error mismatch: have %v, want %v", err, errConflictingVote)
+	}
+	// Voting for another block in a later period is not an equivocation.
+	next := newVote(nodeID, coreCommon.Hash{5}, 1)
+	network.BroadcastVote(next)
+	// Own votes carried by agreement results are checked and recorded too.
+	network.BroadcastAgreementResult(&coreTypes.AgreementResult{
+		Votes: []coreTypes.Vote{*conflict},
+	})
+	agreed := newVote(nodeID, coreCommon.Hash{6}, 2)
+	network.BroadcastAgreementResult(&coreTypes.AgreementResult{
+		Votes: []coreTypes.Vote{*agreed},
+	})
+
+	// The record survives a restart.
+	history := newVoteHistory(db, nodeID, 0)
+	if err := history.record(conflict); err != errConflictingVote {
+		t.Errorf("error mismatch after restart: have %v, want %v", err, errConflictingVote)
+	}
+
+	api := NewPrivateDexonAPI(&Dexon{network: network})
+	votes, err := api.VoteHistory(0, 2)
+	if err != nil {
+		t.Fatalf("failed to get vote history: %v", err)
+	}
+	want := []*coreTypes.Vote{vote, next, agreed}
+	if len(votes) != len(want) {
+		t.Fatalf("vote count mismatch: have %d, want %d", len(votes), len(want))
+	}
+	for i, v := range want {
+		have := votes[i]
+		if uint64(have.Type) != uint64(v.Type) || uint64(have.Round) != v.Position.Round ||
+			uint64(have.Height) != v.Position.Height || uint64(have.Period) != v.Period ||
+			have.BlockHash != common.Hash(v.BlockHash) ||
+			have.SignatureType != v.Signature.Type || string(have.Signature) != string(v.Signature.Signature) {
+			t.Errorf("vote %d mismatch: %+v", i, have)
+		}
+	}
+	if votes, _ := api.VoteHistory(2, 3); len(votes) != 0 {
+		t.Errorf("unexpected votes in rounds without votes: %v", votes)
+	}
+	if _, err := api.VoteHistory(2, 1); err == nil {
+		t.Error("expect error for reversed range")
+	}
+}
+
+func TestVoteHistoryPrune(t *testing.T) {
+	db := ethdb.NewMemDatabase()
+	nodeID := coreTypes.NodeID{Hash: coreCommon.Hash{1}}
+	history := newVoteHistory(db, nodeID, 2)
+
+	for round := uint64(0); round < 4; round++ {
+		vote := coreTypes.NewVote(coreTypes.VoteCom, coreCommon.Hash{2}, 0)
+		vote.ProposerID = nodeID
+		vote.Position = coreTypes.Position{Round: round, Height: round}
+		if err := history.record(vote); err != nil {
+			t.Fatalf("failed to record vote: %v", err)
+		}
+	}
+	records := history.votes(0, 3)
+	if len(records) != 2 || records[0].Round != 2 || records[1].Round != 3 {
+		t.Fatalf("kept votes mismatch: %+v", records)
+	}
+	for round := uint64(0); round < 2; round++ {
+		if _, voted := rawdb.ReadVotedBlockHash(db, uint8(coreTypes.VoteCom), round, round, 0); voted {
+			t.Errorf("vote index of round %d not pruned", round)
+		}
+		if count := rawdb.ReadVoteRecordCount(db, round); count != 0 {
+			t.Errorf("vote count of round %d not pruned: %d", round, count)
+		}
+	}
+}
@@ -38,6 +38,10 @@ var (
 	deadline = 5 * time.Minute // consider a filter inactive if it has not been polled for within deadline
 )
 
+// ErrServerBusy is returned for log queries exceeding the maximum number of
+// queries executed at once.
+var ErrServerBusy = errors.New("server busy")
+
 // filter is a helper struct that holds meta information over the filter type
 // and associated subscription in the event system.
 type filter struct {
@@ -59,6 +63,7 @@ type PublicFilterAPI struct {
 	events    *EventSystem
 	filtersMu sync.Mutex
 	filters   map[rpc.ID]*filter
+	queries   *QueryLimiter // Bound of the log queries executed at once, nil if unbounded
 }
 
 // NewPublicFilterAPI returns a new PublicFilterAPI instance.
@@ -75,6 +80,42 @@ func NewPublicFilterAPI(backend Backend, lightMode bool) *PublicFilterAPI {
 	return api
 }
 
+// QueryLimiter bounds the number of log queries executed at once, rejecting
+// the excess ones with ErrServerBusy. It may be shared by all the APIs running
+// log queries on behalf of users. A nil limiter leaves the queries unbounded.
+type QueryLimiter struct {
+	slots chan struct{}
+}
+
+// NewQueryLimiter returns a limiter of n log queries executed at once, or nil
+// for unbounded queries if n is not positive.
+func NewQueryLimiter(n int) *QueryLimiter {
+	if n <= 0 {
+		return nil
+	}
+	return &QueryLimiter{slots: make(chan struct{}, n)}
+}
+
+// Logs executes the log query of the filter unless too many are being
+// executed already.
+func (l *QueryLimiter) Logs(ctx context.Context, filter *Filter) ([]*types.Log, error) {
+	if l != nil {
+		select {
+		case l.slots <- struct{}{}:
+			defer func() { <-l.slots }()
+		default:
+			return nil, ErrServerBusy
+		}
+	}
+	return filter.Logs(ctx)
+}
+
+// SetQueryLimiter bounds the log queries of the API with the limiter. It must
+// be called before the API is served.
+func (api *PublicFilterAPI) SetQueryLimiter(limiter *QueryLimiter) {
+	api.queries = limiter
+}
+
 // timeoutLoop runs every 5 minutes and deletes filters that have not been recently used.
 // Tt is started when the api is created.
 func (api *PublicFilterAPI) timeoutLoop() {
@@ -342,7 +383,7 @@ func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([
 		filter = NewRangeFilter(api.backend, begin, end, crit.Addresses, crit.Topics)
 	}
 	// Run the filter and return all the logs
-	logs, err := filter.Logs(ctx)
+	logs, err := api.queries.Logs(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -397,7 +438,7 @@ func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*ty
 		filter = NewRangeFilter(api.backend, begin, end, f.crit.Addresses, f.crit.Topics)
 	}
 	// Run the filter and return all the logs
-	logs, err := filter.Logs(ctx)
+	logs, err := api.queries.Logs(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -378,6 +378,73 @@ func TestInvalidGetLogsRequest(t *testing.T) {
 	}
 }
 
+// blockingBackend blocks header lookups until released, to hold log queries
+// in execution.
+type blockingBackend struct {
+	*testBackend
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	b.started <- struct{}{}
+	<-b.release
+	return b.testBackend.HeaderByNumber(ctx, blockNr)
+}
+
+// TestMaxConcurrentQueries tests that log queries exceeding the maximum number
+// of queries executed at once are rejected, whichever API runs them.
+func TestMaxConcurrentQueries(t *testing.T) {
+	var (
+		mux        = new(event.TypeMux)
+		db         = ethdb.NewMemDatabase()
+		txFeed     = new(event.Feed)
+		rmLogsFeed = new(event.Feed)
+		logsFeed   = new(event.Feed)
+		chainFeed  = new(event.Feed)
+		backend    = &blockingBackend{
+			testBackend: &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed},
+			started:     make(chan struct{}),
+			release:     make(chan struct{}),
+		}
+		api  = NewPublicFilterAPI(backend, false)
+		crit = FilterCriteria{FromBlock: big.NewInt(0), ToBlock: big.NewInt(0)}
+	)
+	const limit = 2
+	limiter := NewQueryLimiter(limit)
+	api.SetQueryLimiter(limiter)
+
+	errc := make(chan error, limit)
+	for i := 0; i < limit; i++ {
+		go func() {
+			_, err := api.GetLogs(context.Background(), crit)
+			errc <- err
+		}()
+		<-backend.started
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := api.GetLogs(context.Background(), crit); err != ErrServerBusy {
+			t.Errorf("excess query %d: error mismatch: have %v, want %v", i, err, ErrServerBusy)
+		}
+	}
+	filter := NewRangeFilter(backend, 0, 0, nil, nil)
+	if _, err := limiter.Logs(context.Background(), filter); err != ErrServerBusy {
+		t.Errorf("excess shared query: error mismatch: have %v, want %v", err, ErrServerBusy)
+	}
+
+	close(backend.release)
+	for i := 0; i < limit; i++ {
+		if err := <-errc; err != nil {
+			t.Errorf("query %d failed: %v", i, err)
+		}
+	}
+	// Finished queries free their slots.
+	go func() { <-backend.started }()
+	if _, err := api.GetLogs(context.Background(), crit); err != nil {
+		t.Errorf("query after release failed: %v", err)
+	}
+}
+
 // TestLogFilter tests whether log filters match the correct logs that are posted to the event feed.
 func TestLogFilter(t *testing.T) {
 	t.Parallel()
@@ -21,6 +21,7 @@ import (
 	"math/big"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/dexon-foundation/dexon/common"
 	"github.com/dexon-foundation/dexon/core/types"
@@ -31,10 +32,17 @@ import (
 
 var maxPrice = big.NewInt(500 * params.GWei)
 
+// maxWindowBlocks is the maximum number of blocks sampled in time window mode.
+const maxWindowBlocks = 1024
+
 type Config struct {
 	Blocks     int
 	Percentile int
 	Default    *big.Int `toml:",omitempty"`
+
+	// SampleWindow enables sampling all blocks produced within the window
+	// before the head instead of a fixed number of blocks.
+	SampleWindow time.Duration `toml:",omitempty"`
 }
 
 // Oracle recommends gas prices based on the content of recent
@@ -43,11 +51,14 @@ type Oracle struct {
 	backend   ethapi.Backend
 	lastHead  common.Hash
 	lastPrice *big.Int
+	samples   []*big.Int // Block prices sampled for the last price
+	restored  []*big.Int // Block prices sampled by a previous run
 	cacheLock sync.RWMutex
 	fetchLock sync.Mutex
 
 	checkBlocks, maxEmpty, maxBlocks int
 	percentile                       int
+	sampleWindow                     time.Duration
 }
 
 // NewOracle returns a new oracle.
@@ -64,12 +75,13 @@ func NewOracle(backend ethapi.Backend, params Config) *Oracle {
 		percent = 100
 	}
 	return &Oracle{
-		backend:     backend,
-		lastPrice:   params.Default,
-		checkBlocks: blocks,
-		maxEmpty:    blocks / 2,
-		maxBlocks:   blocks * 5,
-		percentile:  percent,
+		backend:      backend,
+		lastPrice:    params.Default,
+		checkBlocks:  blocks,
+		maxEmpty:     blocks / 2,
+		maxBlocks:    blocks * 5,
+		percentile:   percent,
+		sampleWindow: params.SampleWindow,
 	}
 }
 
@@ -93,23 +105,33 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	gpo.cacheLock.RLock()
 	lastHead = gpo.lastHead
 	lastPrice = gpo.lastPrice
+	restored := gpo.restored
 	gpo.cacheLock.RUnlock()
 	if headHash == lastHead {
 		return lastPrice, nil
 	}
 
+	checkBlocks, maxEmpty, maxBlocks := gpo.checkBlocks, gpo.maxEmpty, gpo.maxBlocks
+	if gpo.sampleWindow > 0 {
+		// Sample every block within the window, skipping the empty ones.
+		n, err := gpo.windowBlocks(ctx, head)
+		if err != nil {
+			return lastPrice, err
+		}
+		checkBlocks, maxEmpty, maxBlocks = n, n, n
+	}
+
 	blockNum := head.Number.Uint64()
-	ch := make(chan getBlockPricesResult, gpo.checkBlocks)
+	ch := make(chan getBlockPricesResult, checkBlocks)
 	sent := 0
 	exp := 0
 	var blockPrices []*big.Int
-	for sent < gpo.checkBlocks && blockNum > 0 {
+	for sent < checkBlocks && blockNum > 0 {
 		go gpo.getBlockPrices(ctx, types.MakeSigner(gpo.backend.ChainConfig(), big.NewInt(int64(blockNum))), blockNum, ch)
 		sent++
 		exp++
 		blockNum--
 	}
-	maxEmpty := gpo.maxEmpty
 	for exp > 0 {
 		res := <-ch
 		if res.err != nil {
@@ -124,29 +146,99 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 			maxEmpty--
 			continue
 		}
-		if blockNum > 0 && sent < gpo.maxBlocks {
+		if blockNum > 0 && sent < maxBlocks {
 			go gpo.getBlockPrices(ctx, types.MakeSigner(gpo.backend.ChainConfig(), big.NewInt(int64(blockNum))), blockNum, ch)
 			sent++
 			exp++
 			blockNum--
 		}
 	}
+	samples := blockPrices
+	// Prices sampled by a previous run make up for the blocks without one.
+	if missing := checkBlocks - len(blockPrices); missing > 0 && len(restored) > 0 {
+		if missing > len(restored) {
+			missing = len(restored)
+		}
+		blockPrices = append(blockPrices[:len(blockPrices):len(blockPrices)], restored[:missing]...)
+	}
 	price := lastPrice
 	if len(blockPrices) > 0 {
-		sort.Sort(bigIntArray(blockPrices))
-		price = blockPrices[(len(blockPrices)-1)*gpo.percentile/100]
-	}
-	if price.Cmp(maxPrice) > 0 {
-		price = new(big.Int).Set(maxPrice)
+		price = gpo.percentilePrice(blockPrices)
 	}
 
 	gpo.cacheLock.Lock()
 	gpo.lastHead = headHash
 	gpo.lastPrice = price
+	gpo.samples = samples
 	gpo.cacheLock.Unlock()
 	return price, nil
 }
 
+// percentilePrice returns the price at the configured percentile of the
+// prices, capped to maxPrice. The prices are sorted in place.
+func (gpo *Oracle) percentilePrice(prices []*big.Int) *big.Int {
+	sort.Sort(bigIntArray(prices))
+	price := prices[(len(prices)-1)*gpo.percentile/100]
+	if price.Cmp(maxPrice) > 0 {
+		price = new(big.Int).Set(maxPrice)
+	}
+	return price
+}
+
+// Samples returns the block prices sampled for the last suggested price. Only
+// prices of blocks are returned, not the ones restored from a previous run.
+func (gpo *Oracle) Samples() []*big.Int {
+	gpo.cacheLock.RLock()
+	defer gpo.cacheLock.RUnlock()
+	return append([]*big.Int(nil), gpo.samples...)
+}
+
+// RestoreSamples provides the block prices sampled by a previous run. They
+// make up for the blocks without price when sampling, and give the suggested
+// price until the blocks are sampled.
+func (gpo *Oracle) RestoreSamples(samples []*big.Int) {
+	if len(samples) == 0 {
+		return
+	}
+	restored := make([]*big.Int, len(samples))
+	for i, price := range samples {
+		restored[i] = new(big.Int).Set(price)
+	}
+	gpo.cacheLock.Lock()
+	defer gpo.cacheLock.Unlock()
+	gpo.restored = restored
+	if gpo.lastHead == (common.Hash{}) {
+		gpo.lastPrice = gpo.percentilePrice(append([]*big.Int(nil), restored...))
+	}
+}
+
+// windowBlocks returns the number of blocks, counting down from the head,
+// produced within the sample window of the head block.
+func (gpo *Oracle) windowBlocks(ctx context.Context, head *types.Header) (int, error) {
+	// Block timestamps are in seconds, but in milliseconds on DEXON chains.
+	window := uint64(gpo.sampleWindow / time.Second)
+	if gpo.backend.ChainConfig().Dexcon != nil {
+		window = uint64(gpo.sampleWindow / time.Millisecond)
+	}
+	var from uint64
+	if head.Time > window {
+		from = head.Time - window
+	}
+
+	n := 0
+	for num := head.Number.Uint64(); num > 0 && n < maxWindowBlocks; num-- {
+		header, err := gpo.backend.HeaderByNumber(ctx, rpc.BlockNumber(num))
+		if header == nil {
+			return n, err
+		}
+		if header.Time < from {
+			break
+		}
+		n++
+	}
+	return n, nil
+}
+
 type getBlockPricesResult struct {
 	price *big.Int
 	err   error
@@ -0,0 +1,111 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/dexon-foundation/dexon/common"
+	"github.com/dexon-foundation/dexon/core/types"
+	"github.com/dexon-foundation/dexon/crypto"
+	"github.com/dexon-foundation/dexon/internal/ethapi"
+	"github.com/dexon-foundation/dexon/params"
+	"github.com/dexon-foundation/dexon/rpc"
+)
+
+type testBackend struct {
+	ethapi.Backend
+	config *params.ChainConfig
+	blocks []*types.Block
+}
+
+func (b *testBackend) block(number rpc.BlockNumber) *types.Block {
+	if number == rpc.LatestBlockNumber {
+		return b.blocks[len(b.blocks)-1]
+	}
+	if int(number) >= len(b.blocks) {
+		return nil
+	}
+	return b.blocks[number]
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if block := b.block(number); block != nil {
+		return block.Header(), nil
+	}
+	return nil, nil
+}
+
+func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	return b.block(number), nil
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig {
+	return b.config
+}
+
+func TestSuggestPriceSampleWindow(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	config := *params.TestChainConfig
+	config.Dexcon = &params.DexconConfig{}
+	signer := types.NewEIP155Signer(config.ChainID)
+
+	// Ten slow blocks ten seconds apart paying 100 GWei, followed by ten
+	// fast blocks 100ms apart paying 10 GWei. Timestamps are in milliseconds.
+	backend := &testBackend{config: &config}
+	backend.blocks = append(backend.blocks, types.NewBlock(&types.Header{Number: big.NewInt(0)}, nil, nil, nil))
+	for i := 1; i <= 20; i++ {
+		price, timestamp := big.NewInt(100*params.GWei), uint64(i*10000)
+		if i > 10 {
+			price, timestamp = big.NewInt(10*params.GWei), uint64(200000+(i-10)*100)
+		}
+		tx, err := types.SignTx(types.NewTransaction(uint64(i), common.Address{}, nil, 21000, price, nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign tx: %v", err)
+		}
+		header := &types.Header{Number: big.NewInt(int64(i)), Time: timestamp}
+		backend.blocks = append(backend.blocks, types.NewBlock(header, []*types.Transaction{tx}, nil, nil))
+	}
+
+	tests := []struct {
+		config Config
+		want   *big.Int
+	}{
+		// A fixed number of blocks spans both rates.
+		{Config{Blocks: 20, Percentile: 60}, big.NewInt(100 * params.GWei)},
+		// A short window only covers the fast blocks.
+		{Config{Blocks: 20, Percentile: 60, SampleWindow: 5 * time.Second}, big.NewInt(10 * params.GWei)},
+		// A long window reaches back into the slow blocks.
+		{Config{Blocks: 2, Percentile: 100, SampleWindow: 150 * time.Second}, big.NewInt(100 * params.GWei)},
+	}
+	for i, tt := range tests {
+		tt.config.Default = big.NewInt(params.GWei)
+		price, err := NewOracle(backend, tt.config).SuggestPrice(context.Background())
+		if err != nil {
+			t.Fatalf("test %d: failed to suggest price: %v", i, err)
+		}
+		if price.Cmp(tt.want) != 0 {
+			t.Errorf("test %d: price mismatch: have %v, want %v", i, price, tt.want)
+		}
+	}
+}
@@ -24,6 +24,7 @@ var Modules = map[string]string{
 	"clique":     Clique_JS,
 	"ethash":     Ethash_JS,
 	"debug":      Debug_JS,
+	"dex":        Dex_JS,
 	"eth":        Eth_JS,
 	"miner":      Miner_JS,
 	"net":        Net_JS,
@@ -32,6 +33,7 @@ var Modules = map[string]string{
 	"shh":        Shh_JS,
 	"swarmfs":    SWARMFS_JS,
 	"txpool":     TxPool_JS,
+	"validator":  Validator_JS,
 }
 
 const Chequebook_JS = `
@@ -169,11 +171,67 @@ web3._extend({
 			params: 1,
 			inputFormatter: [null]
 		}),
+		new web3._extend.Method({
+			name: 'exportFinalizedBlocks',
+			call: 'admin_exportFinalizedBlocks',
+			params: 3,
+			inputFormatter: [null, web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
+		}),
 		new web3._extend.Method({
 			name: 'importChain',
 			call: 'admin_importChain',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'exportBlockDB',
+			call: 'admin_exportBlockDB',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'importBlockDB',
+			call: 'admin_importBlockDB',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'compactChainDB',
+			call: 'admin_compactChainDB',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'flushTxJournal',
+			call: 'admin_flushTxJournal',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'addValidatorPeer',
+			call: 'admin_addValidatorPeer',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'removeValidatorPeer',
+			call: 'admin_removeValidatorPeer',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'reloadGovernance',
+			call: 'admin_reloadGovernance',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'negotiatedVersion',
+			call: 'admin_negotiatedVersion',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'catchUp',
+			call: 'admin_catchUp',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'cancelCatchUp',
+			call: 'admin_cancelCatchUp'
+		}),
 		new web3._extend.Method({
 			name: 'sleepBlocks',
 			call: 'admin_sleepBlocks',
@@ -233,6 +291,14 @@ web3._extend({
 			name: 'notaryInfo',
 			getter: 'admin_notaryInfo'
 		}),
+		new web3._extend.Property({
+			name: 'dexPeers',
+			getter: 'admin_dexPeers'
+		}),
+		new web3._extend.Property({
+			name: 'dexVersions',
+			getter: 'admin_dexVersions'
+		}),
 	]
 });
 `
@@ -429,6 +495,12 @@ web3._extend({
 			params: 2,
 			inputFormatter: [null, null]
 		}),
+		new web3._extend.Method({
+			name: 'traceFinalizedBlock',
+			call: 'debug_traceFinalizedBlock',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
 		new web3._extend.Method({
 			name: 'traceTransaction',
 			call: 'debug_traceTransaction',
@@ -446,6 +518,21 @@ web3._extend({
 			call: 'debug_getBadBlocks',
 			params: 0,
 		}),
+		new web3._extend.Method({
+			name: 'verifyBlock',
+			call: 'debug_verifyBlock',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'pendingAcks',
+			call: 'debug_pendingAcks',
+			params: 0,
+		}),
+		new web3._extend.Method({
+			name: 'finalizationPipeline',
+			call: 'debug_finalizationPipeline',
+			params: 0,
+		}),
 		new web3._extend.Method({
 			name: 'storageRangeAt',
 			call: 'debug_storageRangeAt',
@@ -726,6 +813,174 @@ web3._extend({
 });
 `
 
+const Dex_JS = `
+web3._extend({
+	property: 'dex',
+	methods: [
+		new web3._extend.Method({
+			name: 'getBlockByProposer',
+			call: 'dex_getBlockByProposer',
+			params: 3
+		}),
+		new web3._extend.Method({
+			name: 'getConfigDiff',
+			call: 'dex_getConfigDiff',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'getLogsByRound',
+			call: 'dex_getLogsByRound',
+			params: 3,
+			inputFormatter: [web3._extend.utils.fromDecimal, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'roundBlockRange',
+			call: 'dex_roundBlockRange',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'crs',
+			call: 'dex_crs',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'roundRewards',
+			call: 'dex_roundRewards',
+			params: 2,
+			inputFormatter: [web3._extend.utils.fromDecimal, web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'proposeCRS',
+			call: 'dex_proposeCRS',
+			params: 2,
+			inputFormatter: [web3._extend.utils.fromDecimal, null]
+		}),
+		new web3._extend.Method({
+			name: 'validatorStatus',
+			call: 'dex_validatorStatus',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'pendingTransactions',
+			call: 'dex_pendingTransactions',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'cancelTransaction',
+			call: 'dex_cancelTransaction',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'compactionProvenance',
+			call: 'dex_compactionProvenance',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'roundLatencies',
+			call: 'dex_roundLatencies',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getNotarizations',
+			call: 'dex_getNotarizations',
+			params: 2,
+			inputFormatter: [web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'voteHistory',
+			call: 'dex_voteHistory',
+			params: 2,
+			inputFormatter: [web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'gasParameters',
+			call: 'dex_gasParameters',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'blockFinalizationRound',
+			call: 'dex_blockFinalizationRound',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'estimateFinalityTime',
+			call: 'dex_estimateFinalityTime',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'chainTip',
+			call: 'dex_chainTip',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'getAccountNonceStatus',
+			call: 'dex_getAccountNonceStatus',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'groupPublicKey',
+			call: 'dex_groupPublicKey',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'notarySet',
+			call: 'dex_notarySet',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'consensusTimestamp',
+			call: 'dex_consensusTimestamp',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'pendingProposals',
+			call: 'dex_pendingProposals',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'proposedBlocks',
+			call: 'dex_proposedBlocks',
+			params: 2,
+			inputFormatter: [web3._extend.utils.fromDecimal, web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'chainConfigAt',
+			call: 'dex_chainConfigAt',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'roundStorageStats',
+			call: 'dex_roundStorageStats',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'blockPropagationStats',
+			call: 'dex_blockPropagationStats',
+			params: 0
+		}),
+		new web3._extend.Method({
+			name: 'getBlockByPosition',
+			call: 'dex_getBlockByPosition',
+			params: 2,
+			inputFormatter: [web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'finalizationDepth',
+			call: 'dex_finalizationDepth',
+			params: 0
+		}),
+	]
+});
+`
+
 const Accounting_JS = `
 web3._extend({
 	property: 'accounting',
@@ -769,3 +1024,22 @@ web3._extend({
 	]
 });
 `
+
+const Validator_JS = `
+web3._extend({
+	property: 'validator',
+	methods: [
+		new web3._extend.Method({
+			name: 'scheduleKeyRotation',
+			call: 'validator_scheduleKeyRotation',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'submitSlashingEvidence',
+			call: 'validator_submitSlashingEvidence',
+			params: 1
+		}),
+	]
+});
+`
@@ -173,6 +173,14 @@ func (s *dialstate) removeDirect(n *enode.Node) {
 	s.hist.remove(n.ID())
 }
 
+// setSelf switches the dialer to a new identity of the local node, with the
+// discovery table running under it.
+func (s *dialstate) setSelf(self enode.ID, ntab discoverTable) {
+	s.self = self
+	s.ntab = ntab
+	s.lookupBuf = s.lookupBuf[:0]
+}
+
 func (s *dialstate) newTasks(nRunning int, peers map[enode.ID]*Peer, now time.Time) []task {
 	if s.start.IsZero() {
 		s.start = now
@@ -352,7 +360,8 @@ func (t *dialTask) Do(srv *Server) {
 // discovery network with useless queries for nodes that don't exist.
 // The backoff delay resets when the node is found.
 func (t *dialTask) resolve(srv *Server) bool {
-	if srv.ntab == nil {
+	ntab := srv.discoverTable()
+	if ntab == nil {
 		log.Debug("Can't resolve node", "id", t.dest.ID(), "err", "discovery is disabled")
 		return false
 	}
@@ -362,7 +371,7 @@ func (t *dialTask) resolve(srv *Server) bool {
 	if time.Since(t.lastResolved) < t.resolveDelay {
 		return false
 	}
-	resolved := srv.ntab.Resolve(t.dest)
+	resolved := ntab.Resolve(t.dest)
 	t.lastResolved = time.Now()
 	if resolved == nil {
 		t.resolveDelay *= 2
@@ -407,7 +416,7 @@ func (t *discoverTask) Do(srv *Server) {
 		time.Sleep(next.Sub(now))
 	}
 	srv.lastLookup = time.Now()
-	t.results = srv.ntab.LookupRandom()
+	t.results = srv.discoverTable().LookupRandom()
 }
 
 func (t *discoverTask) String() string {
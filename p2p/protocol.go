@@ -54,6 +54,12 @@ type Protocol struct {
 	// but returns nil, it is assumed that the protocol handshake is still running.
 	PeerInfo func(id enode.ID) interface{}
 
+	// Exempt is an optional helper method reporting whether a peer is let in
+	// beyond MaxPeers and the inbound connection limit, like a trusted peer.
+	// The protocol is then responsible for keeping the peer count in bounds,
+	// e.g. by disconnecting another peer.
+	Exempt func(id enode.ID) bool
+
 	// Attributes contains protocol specific information for the node record.
 	Attributes []enr.Entry
 }
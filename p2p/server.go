@@ -182,6 +182,7 @@ type Server struct {
 	removedirect  chan *enode.Node
 	addtrusted    chan *enode.Node
 	removetrusted chan *enode.Node
+	rekey         chan rekeyOp
 	posthandshake chan *conn
 	addpeer       chan *conn
 	delpeer       chan peerDrop
@@ -192,6 +193,11 @@ type Server struct {
 
 type peerOpFunc func(map[enode.ID]*Peer)
 
+type rekeyOp struct {
+	key *ecdsa.PrivateKey
+	err chan error
+}
+
 type peerDrop struct {
 	*Peer
 	err       error
@@ -376,11 +382,11 @@ func (srv *Server) SubscribeEvents(ch chan *PeerEvent) event.Subscription {
 // Self returns the local node's endpoint information.
 func (srv *Server) Self() *enode.Node {
 	srv.lock.Lock()
-	ln := srv.localnode
+	ln, key := srv.localnode, srv.PrivateKey
 	srv.lock.Unlock()
 
 	if ln == nil {
-		return enode.NewV4(&srv.PrivateKey.PublicKey, net.ParseIP("0.0.0.0"), 0, 0)
+		return enode.NewV4(&key.PublicKey, net.ParseIP("0.0.0.0"), 0, 0)
 	}
 	return ln.Node()
 }
@@ -416,9 +422,30 @@ func (srv *Server) tcpAddr(listener net.Listener) net.TCPAddr {
 }
 
 func (srv *Server) GetPrivateKey() *ecdsa.PrivateKey {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
 	return srv.PrivateKey
 }
 
+// Rekey switches the server to a new node key, which changes the identity
+// of the local node. Connected peers are kept, and so are the static, direct
+// and trusted nodes. Discovery is restarted under the new identity.
+func (srv *Server) Rekey(key *ecdsa.PrivateKey) error {
+	srv.lock.Lock()
+	running := srv.running
+	srv.lock.Unlock()
+	if !running {
+		return errServerStopped
+	}
+	op := rekeyOp{key: key, err: make(chan error, 1)}
+	select {
+	case srv.rekey <- op:
+		return <-op.err
+	case <-srv.quit:
+		return errServerStopped
+	}
+}
+
 // Stop terminates the server and all active peer connections.
 // It blocks until all active connections have been closed.
 func (srv *Server) Stop() {
@@ -500,6 +527,7 @@ func (srv *Server) Start() (err error) {
 	srv.removedirect = make(chan *enode.Node)
 	srv.addtrusted = make(chan *enode.Node)
 	srv.removetrusted = make(chan *enode.Node)
+	srv.rekey = make(chan rekeyOp)
 	srv.peerOp = make(chan peerOpFunc)
 	srv.peerOpDone = make(chan struct{})
 
@@ -523,29 +551,13 @@ func (srv *Server) Start() (err error) {
 }
 
 func (srv *Server) setupLocalNode() error {
-	// Create the devp2p handshake.
-	pubkey := crypto.FromECDSAPub(&srv.PrivateKey.PublicKey)
-	srv.ourHandshake = &protoHandshake{Version: baseProtocolVersion, Name: srv.Name, ID: pubkey[1:]}
-	for _, p := range srv.Protocols {
-		srv.ourHandshake.Caps = append(srv.ourHandshake.Caps, p.cap())
-	}
-	sort.Sort(capsByNameAndVersion(srv.ourHandshake.Caps))
-
-	// Create the local node.
 	db, err := enode.OpenDB(srv.Config.NodeDatabase)
 	if err != nil {
 		return err
 	}
 	srv.nodedb = db
-	srv.localnode = enode.NewLocalNode(db, srv.PrivateKey)
-	srv.localnode.SetFallbackIP(net.IP{127, 0, 0, 1})
-	srv.localnode.Set(capsByNameAndVersion(srv.ourHandshake.Caps))
-	// TODO: check conflicts
-	for _, p := range srv.Protocols {
-		for _, e := range p.Attributes {
-			srv.localnode.Set(e)
-		}
-	}
+	srv.initLocalNode()
+
 	switch srv.NAT.(type) {
 	case nil:
 		// No NAT interface, do nothing.
@@ -556,37 +568,76 @@ func (srv *Server) setupLocalNode() error {
 	default:
 		// Ask the router about the IP. This takes a while and blocks startup,
 		// do it in the background.
+		ln := srv.localnode
 		srv.loopWG.Add(1)
 		go func() {
 			defer srv.loopWG.Done()
 			if ip, err := srv.NAT.ExternalIP(); err == nil {
-				srv.localnode.SetStaticIP(ip)
+				ln.SetStaticIP(ip)
 			}
 		}()
 	}
 	return nil
 }
 
+// initLocalNode creates the devp2p handshake and the local node of the
+// node key.
+func (srv *Server) initLocalNode() {
+	// Create the devp2p handshake.
+	pubkey := crypto.FromECDSAPub(&srv.PrivateKey.PublicKey)
+	srv.ourHandshake = &protoHandshake{Version: baseProtocolVersion, Name: srv.Name, ID: pubkey[1:]}
+	for _, p := range srv.Protocols {
+		srv.ourHandshake.Caps = append(srv.ourHandshake.Caps, p.cap())
+	}
+	sort.Sort(capsByNameAndVersion(srv.ourHandshake.Caps))
+
+	// Create the local node.
+	srv.localnode = enode.NewLocalNode(srv.nodedb, srv.PrivateKey)
+	srv.localnode.SetFallbackIP(net.IP{127, 0, 0, 1})
+	srv.localnode.Set(capsByNameAndVersion(srv.ourHandshake.Caps))
+	// TODO: check conflicts
+	for _, p := range srv.Protocols {
+		for _, e := range p.Attributes {
+			srv.localnode.Set(e)
+		}
+	}
+}
+
 func (srv *Server) setupDiscovery() error {
 	if srv.NoDiscovery && !srv.DiscoveryV5 {
 		return nil
 	}
 
-	addr, err := net.ResolveUDPAddr("udp", srv.ListenAddr)
-	if err != nil {
-		return err
-	}
-	conn, err := net.ListenUDP("udp", addr)
+	conn, err := srv.listenDiscovery()
 	if err != nil {
 		return err
 	}
 	realaddr := conn.LocalAddr().(*net.UDPAddr)
-	srv.log.Debug("UDP listener up", "addr", realaddr)
 	if srv.NAT != nil {
 		if !realaddr.IP.IsLoopback() {
 			go nat.Map(srv.NAT, srv.quit, "udp", realaddr.Port, realaddr.Port, "ethereum discovery")
 		}
 	}
+	return srv.startDiscovery(conn)
+}
+
+// listenDiscovery opens the UDP socket of discovery.
+func (srv *Server) listenDiscovery() (*net.UDPConn, error) {
+	addr, err := net.ResolveUDPAddr("udp", srv.ListenAddr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	srv.log.Debug("UDP listener up", "addr", conn.LocalAddr())
+	return conn, nil
+}
+
+// startDiscovery runs discovery on the UDP socket under the local node.
+func (srv *Server) startDiscovery(conn *net.UDPConn) error {
+	realaddr := conn.LocalAddr().(*net.UDPAddr)
 	srv.localnode.SetFallbackUDP(realaddr.Port)
 
 	// Discovery V4
@@ -629,6 +680,52 @@ func (srv *Server) setupDiscovery() error {
 	return nil
 }
 
+// setKey replaces the node key, along with the local node and discovery
+// running under it. It is called by the run loop, which owns the dialer.
+func (srv *Server) setKey(key *ecdsa.PrivateKey, dialstate dialer) error {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+
+	// Discovery answers under the old identity, stop it first so its
+	// socket can be reused.
+	if srv.ntab != nil {
+		srv.ntab.Close()
+		srv.ntab = nil
+	}
+	if srv.DiscV5 != nil {
+		srv.DiscV5.Close()
+		srv.DiscV5 = nil
+	}
+	old := srv.localnode.Node()
+	srv.PrivateKey = key
+	srv.initLocalNode()
+	if srv.listener != nil {
+		srv.localnode.Set(enr.TCP(srv.listener.Addr().(*net.TCPAddr).Port))
+	}
+	if srv.NAT != nil && !old.IP().IsLoopback() {
+		srv.localnode.SetStaticIP(old.IP())
+	}
+	if !srv.NoDiscovery || srv.DiscoveryV5 {
+		conn, err := srv.listenDiscovery()
+		if err != nil {
+			return err
+		}
+		if err := srv.startDiscovery(conn); err != nil {
+			return err
+		}
+	}
+	dialstate.setSelf(srv.localnode.ID(), srv.ntab)
+	srv.log.Info("Switched P2P node key", "self", srv.localnode.Node())
+	return nil
+}
+
+// discoverTable returns the discovery table, nil if discovery is disabled.
+func (srv *Server) discoverTable() discoverTable {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	return srv.ntab
+}
+
 func (srv *Server) setupListening() error {
 	// Launch the TCP listener.
 	listener, err := net.Listen("tcp", srv.ListenAddr)
@@ -661,6 +758,7 @@ type dialer interface {
 	removeStatic(*enode.Node)
 	addDirect(*enode.Node)
 	removeDirect(*enode.Node)
+	setSelf(enode.ID, discoverTable)
 }
 
 func (srv *Server) run(dialstate dialer) {
@@ -770,6 +868,9 @@ running:
 			if p, ok := peers[n.ID()]; ok {
 				p.rw.set(trustedConn, false)
 			}
+		case op := <-srv.rekey:
+			// This channel is used by Rekey.
+			op.err <- srv.setKey(op.key, dialstate)
 		case op := <-srv.peerOp:
 			// This channel is used by Peers and PeerCount.
 			op(peers)
@@ -870,9 +971,9 @@ func (srv *Server) protoHandshakeChecks(peers map[enode.ID]*Peer, inboundCount i
 
 func (srv *Server) encHandshakeChecks(peers map[enode.ID]*Peer, inboundCount int, c *conn) error {
 	switch {
-	case !c.is(trustedConn|staticDialedConn|directDialedConn) && len(peers) >= srv.MaxPeers:
+	case !c.is(trustedConn|staticDialedConn|directDialedConn) && len(peers) >= srv.MaxPeers && !srv.exempt(c.node.ID()):
 		return DiscTooManyPeers
-	case !c.is(trustedConn|directDialedConn) && c.is(inboundConn) && inboundCount >= srv.maxInboundConns():
+	case !c.is(trustedConn|directDialedConn) && c.is(inboundConn) && inboundCount >= srv.maxInboundConns() && !srv.exempt(c.node.ID()):
 		return DiscTooManyPeers
 	case peers[c.node.ID()] != nil:
 		return DiscAlreadyConnected
@@ -883,6 +984,16 @@ func (srv *Server) encHandshakeChecks(peers map[enode.ID]*Peer, inboundCount int
 	}
 }
 
+// exempt reports whether a protocol lets the peer in beyond the peer limits.
+func (srv *Server) exempt(id enode.ID) bool {
+	for _, proto := range srv.Protocols {
+		if proto.Exempt != nil && proto.Exempt(id) {
+			return true
+		}
+	}
+	return false
+}
+
 func (srv *Server) maxInboundConns() int {
 	return srv.MaxPeers - srv.maxDialedConns()
 }
@@ -972,6 +1083,7 @@ func (srv *Server) setupConn(c *conn, flags connFlag, dialDest *enode.Node) erro
 	// Prevent leftover pending conns from entering the handshake.
 	srv.lock.Lock()
 	running := srv.running
+	key, ourHandshake := srv.PrivateKey, srv.ourHandshake
 	srv.lock.Unlock()
 	if !running {
 		return errServerStopped
@@ -985,7 +1097,7 @@ func (srv *Server) setupConn(c *conn, flags connFlag, dialDest *enode.Node) erro
 		}
 	}
 	// Run the encryption handshake.
-	remotePubkey, err := c.doEncHandshake(srv.PrivateKey, dialPubkey)
+	remotePubkey, err := c.doEncHandshake(key, dialPubkey)
 	if err != nil {
 		srv.log.Trace("Failed RLPx handshake", "addr", c.fd.RemoteAddr(), "conn", c.flags, "err", err)
 		return err
@@ -1009,7 +1121,7 @@ func (srv *Server) setupConn(c *conn, flags connFlag, dialDest *enode.Node) erro
 		return err
 	}
 	// Run the protocol handshake
-	phs, err := c.doProtoHandshake(srv.ourHandshake)
+	phs, err := c.doProtoHandshake(ourHandshake)
 	if err != nil {
 		clog.Trace("Failed proto handshake", "err", err)
 		return err
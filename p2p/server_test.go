@@ -17,6 +17,7 @@
 package p2p
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"errors"
 	"math/rand"
@@ -121,6 +122,82 @@ func TestServerListen(t *testing.T) {
 	}
 }
 
+func TestServerRekey(t *testing.T) {
+	connected := make(chan *Peer)
+	remid := &newkey().PublicKey
+	srv := startTestServer(t, remid, func(p *Peer) { connected <- p })
+	defer srv.Stop()
+	addr := srv.ListenAddr
+
+	dial := func() net.Conn {
+		conn, err := net.DialTimeout("tcp", srv.ListenAddr, 5*time.Second)
+		if err != nil {
+			t.Fatalf("could not dial: %v", err)
+		}
+		select {
+		case <-connected:
+		case <-time.After(1 * time.Second):
+			t.Fatal("server did not accept within one second")
+		}
+		return conn
+	}
+	conn := dial()
+	defer conn.Close()
+
+	key := newkey()
+	if err := srv.Rekey(key); err != nil {
+		t.Fatalf("failed to rekey: %v", err)
+	}
+	if id := srv.Self().ID(); id != enode.PubkeyToIDV4(&key.PublicKey) {
+		t.Errorf("self id mismatch: have %v, want %v", id, enode.PubkeyToIDV4(&key.PublicKey))
+	}
+	if srv.GetPrivateKey() != key {
+		t.Error("private key not replaced")
+	}
+	if peers := srv.Peers(); len(peers) != 1 {
+		t.Errorf("peers dropped by rekey: %v", peers)
+	}
+	if srv.ListenAddr != addr {
+		t.Errorf("listen address changed: have %v, want %v", srv.ListenAddr, addr)
+	}
+	if pubkey := crypto.FromECDSAPub(&key.PublicKey); !bytes.Equal(srv.ourHandshake.ID, pubkey[1:]) {
+		t.Error("handshake not made with the new key")
+	}
+
+	srv.Stop()
+	if err := srv.Rekey(newkey()); err != errServerStopped {
+		t.Errorf("rekey of stopped server: have %v, want %v", err, errServerStopped)
+	}
+}
+
+func TestServerRekeyDiscovery(t *testing.T) {
+	srv := &Server{
+		Config: Config{
+			Name:       "test",
+			MaxPeers:   10,
+			ListenAddr: "127.0.0.1:0",
+			PrivateKey: newkey(),
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+	port := srv.Self().UDP()
+
+	key := newkey()
+	if err := srv.Rekey(key); err != nil {
+		t.Fatalf("failed to rekey: %v", err)
+	}
+	id := enode.PubkeyToIDV4(&key.PublicKey)
+	if self := srv.discoverTable().Self(); self.ID() != id {
+		t.Errorf("discovery id mismatch: have %v, want %v", self.ID(), id)
+	}
+	if self := srv.Self(); self.ID() != id || self.UDP() != port {
+		t.Errorf("self mismatch: have %v, want id %v with UDP port %d", self, id, port)
+	}
+}
+
 func TestServerDial(t *testing.T) {
 	// run a one-shot TCP server to handle the connection.
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
@@ -343,6 +420,8 @@ func (tg taskgen) addDirect(*enode.Node) {
 }
 func (tg taskgen) removeDirect(*enode.Node) {
 }
+func (tg taskgen) setSelf(enode.ID, discoverTable) {
+}
 
 type testTask struct {
 	index  int
@@ -419,6 +498,48 @@ func TestServerAtCap(t *testing.T) {
 	}
 }
 
+func TestServerExemptPeers(t *testing.T) {
+	exemptID := randomID()
+	srv := &Server{
+		Config: Config{
+			PrivateKey: newkey(),
+			MaxPeers:   10,
+			NoDial:     true,
+			Protocols: []Protocol{{
+				Name:   "exempt",
+				Length: 1,
+				Run: func(p *Peer, rw MsgReadWriter) error {
+					_, err := rw.ReadMsg()
+					return err
+				},
+				Exempt: func(id enode.ID) bool { return id == exemptID },
+			}},
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("could not start: %v", err)
+	}
+	defer srv.Stop()
+
+	newconn := func(id enode.ID) *conn {
+		fd, _ := net.Pipe()
+		tx := newTestTransport(&newkey().PublicKey, fd)
+		node := enode.SignNull(new(enr.Record), id)
+		return &conn{fd: fd, transport: tx, flags: inboundConn, node: node, caps: []Cap{{Name: "exempt"}}, cont: make(chan error)}
+	}
+	for i := 0; i < 10; i++ {
+		if err := srv.checkpoint(newconn(randomID()), srv.addpeer); err != nil {
+			t.Fatalf("could not add conn %d: %v", i, err)
+		}
+	}
+	if err := srv.checkpoint(newconn(randomID()), srv.posthandshake); err != DiscTooManyPeers {
+		t.Error("wrong error for insert:", err)
+	}
+	if err := srv.checkpoint(newconn(exemptID), srv.posthandshake); err != nil {
+		t.Error("unexpected error for exempt conn @posthandshake:", err)
+	}
+}
+
 func TestServerPeerLimits(t *testing.T) {
 	srvkey := newkey()
 	clientkey := newkey()
@@ -32,6 +32,9 @@ func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []str
 	// Register all the APIs exposed by the services
 	handler := NewServer()
 	for _, api := range apis {
+		if api.IPCOnly {
+			continue
+		}
 		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
 				return nil, nil, err
@@ -62,6 +65,9 @@ func StartWSEndpoint(endpoint string, apis []API, modules []string, wsOrigins []
 	// Register all the APIs exposed by the services
 	handler := NewServer()
 	for _, api := range apis {
+		if api.IPCOnly {
+			continue
+		}
 		if exposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
 				return nil, nil, err
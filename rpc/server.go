@@ -313,8 +313,12 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	if req.callb.errPos >= 0 { // test if method returned an error
 		if !reply[req.callb.errPos].IsNil() {
 			e := reply[req.callb.errPos].Interface().(error)
-			res := codec.CreateErrorResponse(&req.id, &callbackError{e.Error()})
-			return res, nil
+			// Keep the code of errors defining their own.
+			rpcErr, ok := e.(Error)
+			if !ok {
+				rpcErr = &callbackError{e.Error()}
+			}
+			return codec.CreateErrorResponse(&req.id, rpcErr), nil
 		}
 	}
 	return codec.CreateResponse(req.id, reply[0].Interface()), nil
@@ -33,6 +33,7 @@ type API struct {
 	Version   string      // api version for DApp's
 	Service   interface{} // receiver instance which holds the methods
 	Public    bool        // indication if the methods must be considered safe for public use
+	IPCOnly   bool        // indication if the methods must only be exposed over IPC and in-process
 }
 
 // callback is a method callback which was registered in the server